@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/abbot/go-http-auth"
+	"github.com/containous/flaeg"
+)
+
+// GraphConfiguration holds the flags of the graph command
+type GraphConfiguration struct {
+	URL      string `description:"URL of the web provider of the running traefik"`
+	Format   string `description:"Format of the graph, dot or json"`
+	User     string `description:"User of the basic or digest authentication of the web provider"`
+	Password string `description:"Password of the user of the web provider"`
+	Token    string `description:"Bearer token of the web provider, e.g. a view token"`
+}
+
+// newGraphCmd builds a new Graph command
+func newGraphCmd() *flaeg.Command {
+	config := &GraphConfiguration{
+		URL:    "http://127.0.0.1:8080/",
+		Format: "dot",
+	}
+	return &flaeg.Command{
+		Name:                  "graph",
+		Description:           `Print the routing graph of a running traefik`,
+		Config:                config,
+		DefaultPointersConfig: &GraphConfiguration{},
+		Run: func() error {
+			return printGraph(os.Stdout, config)
+		},
+	}
+}
+
+func printGraph(wr io.Writer, config *GraphConfiguration) error {
+	graphURL, err := url.Parse(strings.TrimSuffix(config.URL, "/") + "/api/graph")
+	if err != nil {
+		return fmt.Errorf("Error parsing web provider URL %s: %v", config.URL, err)
+	}
+	graphURL.RawQuery = url.Values{"format": {config.Format}}.Encode()
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := fetchGraph(client, graphURL, config, "")
+	if err != nil {
+		return fmt.Errorf("Error fetching the routing graph: %v", err)
+	}
+	// the digest authentication requires the nonce of a first unauthenticated request
+	if resp.StatusCode == http.StatusUnauthorized && len(config.User) > 0 {
+		if challenge := auth.DigestAuthParams(resp.Header.Get("WWW-Authenticate")); challenge != nil {
+			resp.Body.Close()
+			resp, err = fetchGraph(client, graphURL, config, digestAuthorization(config, graphURL.RequestURI(), challenge))
+			if err != nil {
+				return fmt.Errorf("Error fetching the routing graph: %v", err)
+			}
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Error fetching the routing graph from %s: %s", graphURL, resp.Status)
+	}
+	_, err = io.Copy(wr, resp.Body)
+	return err
+}
+
+// fetchGraph requests the routing graph with the credentials of the configuration, or the given authorization
+func fetchGraph(client *http.Client, graphURL *url.URL, config *GraphConfiguration, authorization string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, graphURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(authorization) > 0:
+		req.Header.Set("Authorization", authorization)
+	case len(config.Token) > 0:
+		req.Header.Set("Authorization", "Bearer "+config.Token)
+	case len(config.User) > 0:
+		req.SetBasicAuth(config.User, config.Password)
+	}
+	return client.Do(req)
+}
+
+// digestAuthorization answers the digest authentication challenge of the web provider, as specified by RFC 2617
+func digestAuthorization(config *GraphConfiguration, uri string, challenge map[string]string) string {
+	const nc = "00000001"
+	cnonce := auth.RandomKey()
+	ha1 := auth.H(config.User + ":" + challenge["realm"] + ":" + config.Password)
+	ha2 := auth.H(http.MethodGet + ":" + uri)
+	response := auth.H(strings.Join([]string{ha1, challenge["nonce"], nc, cnonce, "auth", ha2}, ":"))
+	return fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", qop=auth, nc=%s, cnonce="%s", response="%s", opaque="%s", algorithm=MD5`,
+		config.User, challenge["realm"], challenge["nonce"], uri, nc, cnonce, response, challenge["opaque"])
+}
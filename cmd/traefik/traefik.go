@@ -102,20 +102,13 @@ Complete documentation is available at https://traefik.io`,
 	}
 
 	//init flaeg source
-	f := flaeg.New(traefikCmd, os.Args[1:])
-	//add custom parsers
-	f.AddParser(reflect.TypeOf(server.EntryPoints{}), &server.EntryPoints{})
-	f.AddParser(reflect.TypeOf(server.DefaultEntryPoints{}), &server.DefaultEntryPoints{})
-	f.AddParser(reflect.TypeOf(server.RootCAs{}), &server.RootCAs{})
-	f.AddParser(reflect.TypeOf(types.Constraints{}), &types.Constraints{})
-	f.AddParser(reflect.TypeOf(kubernetes.Namespaces{}), &kubernetes.Namespaces{})
-	f.AddParser(reflect.TypeOf([]acme.Domain{}), &acme.Domains{})
-	f.AddParser(reflect.TypeOf(types.Buckets{}), &types.Buckets{})
+	f := newFlaeg(traefikCmd)
 
 	//add commands
 	f.AddCommand(newVersionCmd())
 	f.AddCommand(newBugCmd(traefikConfiguration, traefikPointersConfiguration))
 	f.AddCommand(storeconfigCmd)
+	f.AddCommand(newGraphCmd())
 
 	usedCmd, err := f.GetCommand()
 	if err != nil {
@@ -172,6 +165,55 @@ Complete documentation is available at https://traefik.io`,
 	os.Exit(0)
 }
 
+// newFlaeg returns the flaeg source of the command line arguments, with the custom parsers
+func newFlaeg(traefikCmd *flaeg.Command) *flaeg.Flaeg {
+	f := flaeg.New(traefikCmd, os.Args[1:])
+	//add custom parsers
+	f.AddParser(reflect.TypeOf(server.EntryPoints{}), &server.EntryPoints{})
+	f.AddParser(reflect.TypeOf(server.DefaultEntryPoints{}), &server.DefaultEntryPoints{})
+	f.AddParser(reflect.TypeOf(server.RootCAs{}), &server.RootCAs{})
+	f.AddParser(reflect.TypeOf(types.Constraints{}), &types.Constraints{})
+	f.AddParser(reflect.TypeOf(kubernetes.Namespaces{}), &kubernetes.Namespaces{})
+	f.AddParser(reflect.TypeOf([]acme.Domain{}), &acme.Domains{})
+	f.AddParser(reflect.TypeOf(types.Buckets{}), &types.Buckets{})
+	f.AddParser(reflect.TypeOf(types.TrustedProxies{}), &types.TrustedProxies{})
+	return f
+}
+
+// reloadGlobalConfiguration reads the global configuration again from the TOML file, the command line
+// arguments and the KV store, like on startup
+func reloadGlobalConfiguration(configFile string) (server.GlobalConfiguration, error) {
+	traefikConfiguration := server.NewTraefikConfiguration()
+	traefikCmd := &flaeg.Command{
+		Name:                  "traefik",
+		Config:                traefikConfiguration,
+		DefaultPointersConfig: server.NewTraefikDefaultPointersConfiguration(),
+		Run:                   func() error { return nil },
+	}
+	f := newFlaeg(traefikCmd)
+	if _, err := f.Parse(traefikCmd); err != nil {
+		return server.GlobalConfiguration{}, err
+	}
+
+	s := staert.NewStaert(traefikCmd)
+	s.AddSource(staert.NewTomlSource("traefik", []string{configFile}))
+	s.AddSource(f)
+	if _, err := s.LoadConfig(); err != nil {
+		return server.GlobalConfiguration{}, err
+	}
+	kv, err := CreateKvSource(traefikConfiguration)
+	if err != nil {
+		return server.GlobalConfiguration{}, err
+	}
+	if kv != nil {
+		s.AddSource(kv)
+		if _, err := s.LoadConfig(); err != nil {
+			return server.GlobalConfiguration{}, err
+		}
+	}
+	return traefikConfiguration.GlobalConfiguration, nil
+}
+
 func run(traefikConfiguration *server.TraefikConfiguration) {
 	fmtlog.SetFlags(fmtlog.Lshortfile | fmtlog.LstdFlags)
 
@@ -290,6 +332,9 @@ func run(traefikConfiguration *server.TraefikConfiguration) {
 	}
 	log.Debugf("Global configuration loaded %s", string(jsonConf))
 	svr := server.NewServer(globalConfiguration)
+	svr.SetGlobalConfigurationLoader(func() (server.GlobalConfiguration, error) {
+		return reloadGlobalConfiguration(traefikConfiguration.ConfigFile)
+	})
 	svr.Start()
 	defer svr.Close()
 	sent, err := daemon.SdNotify(false, "READY=1")
@@ -27,15 +27,23 @@ func GetHealthCheck() *HealthCheck {
 }
 
 // Options are the public health check options.
+// A server is removed after UnhealthyThreshold consecutive failed checks, and added back after
+// HealthyThreshold consecutive successful ones. A server which changed state MaxFlaps times
+// within FlapWindow is kept out of the load balancer until its state changes age out of the window.
 type Options struct {
-	Path     string
-	Port     int
-	Interval time.Duration
-	LB       LoadBalancer
+	Path               string
+	Port               int
+	Interval           time.Duration
+	HealthyThreshold   int
+	UnhealthyThreshold int
+	MaxFlaps           int
+	FlapWindow         time.Duration
+	LB                 LoadBalancer
 }
 
 func (opt Options) String() string {
-	return fmt.Sprintf("[Path: %s Interval: %s]", opt.Path, opt.Interval)
+	return fmt.Sprintf("[Path: %s Interval: %s HealthyThreshold: %d UnhealthyThreshold: %d MaxFlaps: %d FlapWindow: %s]",
+		opt.Path, opt.Interval, opt.HealthyThreshold, opt.UnhealthyThreshold, opt.MaxFlaps, opt.FlapWindow)
 }
 
 // BackendHealthCheck HealthCheck configuration for a backend
@@ -43,12 +51,32 @@ type BackendHealthCheck struct {
 	Options
 	disabledURLs   []*url.URL
 	requestTimeout time.Duration
+	statuses       *serverStatuses
+}
+
+// serverStatuses are the statuses of the servers of a backend, by server URL.
+// They are kept across configuration reloads, so that the thresholds and the
+// flap damping keep applying to the servers of the reloaded backend.
+type serverStatuses struct {
+	lock  sync.Mutex
+	byURL map[string]*serverStatus
+}
+
+// serverStatus tracks the check results of a server since its last state change
+type serverStatus struct {
+	// disabled is true while the server is kept out of the load balancer
+	disabled bool
+	// consecutive is the number of consecutive results contradicting the current state
+	consecutive int
+	// transitions are the times of the recent state changes
+	transitions []time.Time
 }
 
 //HealthCheck struct
 type HealthCheck struct {
 	Backends map[string]*BackendHealthCheck
 	cancel   context.CancelFunc
+	statuses map[string]*serverStatuses
 }
 
 // LoadBalancer includes functionality for load-balancing management.
@@ -61,6 +89,7 @@ type LoadBalancer interface {
 func newHealthCheck() *HealthCheck {
 	return &HealthCheck{
 		Backends: make(map[string]*BackendHealthCheck),
+		statuses: make(map[string]*serverStatuses),
 	}
 }
 
@@ -69,6 +98,7 @@ func NewBackendHealthCheck(options Options) *BackendHealthCheck {
 	return &BackendHealthCheck{
 		Options:        options,
 		requestTimeout: 5 * time.Second,
+		statuses:       &serverStatuses{byURL: make(map[string]*serverStatus)},
 	}
 }
 
@@ -78,6 +108,16 @@ func (hc *HealthCheck) SetBackendsConfiguration(parentCtx context.Context, backe
 	if hc.cancel != nil {
 		hc.cancel()
 	}
+
+	statuses := make(map[string]*serverStatuses, len(backends))
+	for backendID, backend := range backends {
+		if previous, ok := hc.statuses[backendID]; ok {
+			backend.keepStatuses(previous)
+		}
+		statuses[backendID] = backend.statuses
+	}
+	hc.statuses = statuses
+
 	ctx, cancel := context.WithCancel(parentCtx)
 	hc.cancel = cancel
 
@@ -111,23 +151,118 @@ func checkBackend(currentBackend *BackendHealthCheck) {
 	enabledURLs := currentBackend.LB.Servers()
 	var newDisabledURLs []*url.URL
 	for _, url := range currentBackend.disabledURLs {
-		if checkHealth(url, currentBackend) {
-			log.Debugf("HealthCheck is up [%s]: Upsert in server list", url.String())
-			currentBackend.LB.UpsertServer(url, roundrobin.Weight(1))
-		} else {
-			log.Warnf("HealthCheck is still failing [%s]", url.String())
+		healthy := checkHealth(url, currentBackend)
+		if !currentBackend.shouldChangeState(url, healthy, currentBackend.HealthyThreshold) {
+			log.Debugf("HealthCheck of disabled server [%s] is healthy: %t", url.String(), healthy)
+			newDisabledURLs = append(newDisabledURLs, url)
+			continue
+		}
+		if currentBackend.isFlapping(url) {
+			log.Debugf("HealthCheck is up [%s] but the server is flapping: kept out of server list", url.String())
 			newDisabledURLs = append(newDisabledURLs, url)
+			continue
 		}
+		log.Infof("HealthCheck is up [%s]: Upsert in server list", url.String())
+		currentBackend.recordTransition(url, false)
+		currentBackend.LB.UpsertServer(url, roundrobin.Weight(1))
 	}
 	currentBackend.disabledURLs = newDisabledURLs
 
 	for _, url := range enabledURLs {
-		if !checkHealth(url, currentBackend) {
-			log.Warnf("HealthCheck has failed [%s]: Remove from server list", url.String())
-			currentBackend.LB.RemoveServer(url)
-			currentBackend.disabledURLs = append(currentBackend.disabledURLs, url)
+		healthy := checkHealth(url, currentBackend)
+		if !currentBackend.shouldChangeState(url, !healthy, currentBackend.UnhealthyThreshold) {
+			if !healthy {
+				log.Debugf("HealthCheck has failed [%s], below the unhealthy threshold", url.String())
+			}
+			continue
+		}
+		log.Warnf("HealthCheck has failed [%s]: Remove from server list", url.String())
+		currentBackend.recordTransition(url, true)
+		currentBackend.LB.RemoveServer(url)
+		currentBackend.disabledURLs = append(currentBackend.disabledURLs, url)
+	}
+}
+
+// keepStatuses takes over the statuses of the servers of the backend before the reload: the servers
+// which were disabled are removed from the load balancer again, and the statuses of the servers
+// no longer in the backend are dropped
+func (backend *BackendHealthCheck) keepStatuses(previous *serverStatuses) {
+	previous.lock.Lock()
+	defer previous.lock.Unlock()
+
+	servers := append([]*url.URL(nil), backend.LB.Servers()...)
+	byURL := make(map[string]*serverStatus, len(servers))
+	for _, serverURL := range servers {
+		status, ok := previous.byURL[serverURL.String()]
+		if !ok {
+			continue
+		}
+		byURL[serverURL.String()] = status
+		if status.disabled {
+			log.Debugf("HealthCheck keeps [%s] out of server list after reload", serverURL.String())
+			backend.LB.RemoveServer(serverURL)
+			backend.disabledURLs = append(backend.disabledURLs, serverURL)
+		}
+	}
+	previous.byURL = byURL
+	backend.statuses = previous
+}
+
+// status returns the status of the server, the caller holding the statuses lock
+func (backend *BackendHealthCheck) status(serverURL *url.URL) *serverStatus {
+	status, ok := backend.statuses.byURL[serverURL.String()]
+	if !ok {
+		status = &serverStatus{}
+		backend.statuses.byURL[serverURL.String()] = status
+	}
+	return status
+}
+
+// shouldChangeState counts the consecutive results contradicting the current state of the
+// server (changed is true) and returns true once threshold of them is reached
+func (backend *BackendHealthCheck) shouldChangeState(serverURL *url.URL, changed bool, threshold int) bool {
+	backend.statuses.lock.Lock()
+	defer backend.statuses.lock.Unlock()
+
+	status := backend.status(serverURL)
+	if !changed {
+		status.consecutive = 0
+		return false
+	}
+	status.consecutive++
+	return status.consecutive >= threshold
+}
+
+func (backend *BackendHealthCheck) recordTransition(serverURL *url.URL, disabled bool) {
+	backend.statuses.lock.Lock()
+	defer backend.statuses.lock.Unlock()
+
+	status := backend.status(serverURL)
+	status.disabled = disabled
+	status.consecutive = 0
+	if backend.MaxFlaps > 0 {
+		status.transitions = append(status.transitions, time.Now())
+	}
+}
+
+// isFlapping returns true when the server changed state MaxFlaps times within FlapWindow
+func (backend *BackendHealthCheck) isFlapping(serverURL *url.URL) bool {
+	if backend.MaxFlaps <= 0 {
+		return false
+	}
+	backend.statuses.lock.Lock()
+	defer backend.statuses.lock.Unlock()
+
+	status := backend.status(serverURL)
+	since := time.Now().Add(-backend.FlapWindow)
+	var recent []time.Time
+	for _, transition := range status.transitions {
+		if transition.After(since) {
+			recent = append(recent, transition)
 		}
 	}
+	status.transitions = recent
+	return len(recent) >= backend.MaxFlaps
 }
 
 func (backend *BackendHealthCheck) newRequest(serverURL *url.URL) (*http.Request, error) {
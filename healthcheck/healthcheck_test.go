@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -122,6 +123,142 @@ func TestSetBackendsConfiguration(t *testing.T) {
 	}
 }
 
+func TestCheckBackendHysteresis(t *testing.T) {
+	tests := []struct {
+		desc                   string
+		options                Options
+		startHealthy           bool
+		healthSequence         []bool
+		wantNumRemovedServers  int
+		wantNumUpsertedServers int
+	}{
+		{
+			desc:                  "single failure below the unhealthy threshold",
+			options:               Options{UnhealthyThreshold: 2},
+			startHealthy:          true,
+			healthSequence:        []bool{false, true, false},
+			wantNumRemovedServers: 0,
+		},
+		{
+			desc:                  "consecutive failures reaching the unhealthy threshold",
+			options:               Options{UnhealthyThreshold: 2},
+			startHealthy:          true,
+			healthSequence:        []bool{false, false},
+			wantNumRemovedServers: 1,
+		},
+		{
+			desc:                   "successes below the healthy threshold",
+			options:                Options{HealthyThreshold: 3},
+			startHealthy:           false,
+			healthSequence:         []bool{true, true, false, true},
+			wantNumUpsertedServers: 0,
+		},
+		{
+			desc:                   "consecutive successes reaching the healthy threshold",
+			options:                Options{HealthyThreshold: 3},
+			startHealthy:           false,
+			healthSequence:         []bool{true, true, true},
+			wantNumUpsertedServers: 1,
+		},
+		{
+			desc:                   "flapping server kept out",
+			options:                Options{MaxFlaps: 2, FlapWindow: time.Hour},
+			startHealthy:           true,
+			healthSequence:         []bool{false, true, false, true, true},
+			wantNumRemovedServers:  2,
+			wantNumUpsertedServers: 1,
+		},
+		{
+			desc:                   "state changes out of the flap window",
+			options:                Options{MaxFlaps: 2, FlapWindow: time.Nanosecond},
+			startHealthy:           true,
+			healthSequence:         []bool{false, true, false, true},
+			wantNumRemovedServers:  2,
+			wantNumUpsertedServers: 2,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			var healthy int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.LoadInt32(&healthy) == 1 {
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer ts.Close()
+
+			lb := &testLoadBalancer{RWMutex: &sync.RWMutex{}}
+			options := test.options
+			options.Path = "/path"
+			options.LB = lb
+			backend := NewBackendHealthCheck(options)
+			serverURL := testhelpers.MustParseURL(ts.URL)
+			if test.startHealthy {
+				lb.servers = append(lb.servers, serverURL)
+			} else {
+				backend.disabledURLs = append(backend.disabledURLs, serverURL)
+			}
+
+			for _, result := range test.healthSequence {
+				if result {
+					atomic.StoreInt32(&healthy, 1)
+				} else {
+					atomic.StoreInt32(&healthy, 0)
+				}
+				checkBackend(backend)
+			}
+
+			if lb.numRemovedServers != test.wantNumRemovedServers {
+				t.Errorf("got %d removed servers, wanted %d", lb.numRemovedServers, test.wantNumRemovedServers)
+			}
+			if lb.numUpsertedServers != test.wantNumUpsertedServers {
+				t.Errorf("got %d upserted servers, wanted %d", lb.numUpsertedServers, test.wantNumUpsertedServers)
+			}
+		})
+	}
+}
+
+func TestKeepStatusesAcrossReload(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	serverURL := testhelpers.MustParseURL(ts.URL)
+	removedURL := testhelpers.MustParseURL("http://removed:80")
+	options := Options{Path: "/path", UnhealthyThreshold: 1, HealthyThreshold: 2}
+
+	lb := &testLoadBalancer{RWMutex: &sync.RWMutex{}, servers: []*url.URL{serverURL}}
+	options.LB = lb
+	backend := NewBackendHealthCheck(options)
+	backend.statuses.byURL[removedURL.String()] = &serverStatus{}
+	checkBackend(backend)
+	if len(lb.servers) != 0 {
+		t.Fatalf("got %d servers before reload, wanted none", len(lb.servers))
+	}
+
+	// the reloaded load balancer has all the servers of the configuration again
+	reloadedLB := &testLoadBalancer{RWMutex: &sync.RWMutex{}, servers: []*url.URL{serverURL}}
+	options.LB = reloadedLB
+	reloaded := NewBackendHealthCheck(options)
+	reloaded.keepStatuses(backend.statuses)
+
+	if len(reloadedLB.servers) != 0 {
+		t.Errorf("got %d servers after reload, wanted the unhealthy server kept out", len(reloadedLB.servers))
+	}
+	if len(reloaded.disabledURLs) != 1 || *reloaded.disabledURLs[0] != *serverURL {
+		t.Errorf("got disabled servers %v, wanted %s", reloaded.disabledURLs, serverURL)
+	}
+	if _, ok := reloaded.statuses.byURL[removedURL.String()]; ok {
+		t.Errorf("got status of %s, which is no longer in the backend", removedURL)
+	}
+}
+
 func TestNewRequest(t *testing.T) {
 	tests := []struct {
 		desc     string
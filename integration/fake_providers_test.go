@@ -0,0 +1,111 @@
+package integration
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/containous/traefik/integration/fakes"
+	"github.com/containous/traefik/integration/try"
+	"github.com/containous/traefik/types"
+	"github.com/go-check/check"
+	checker "github.com/vdemeester/shakers"
+)
+
+// FakeProvidersSuite runs Traefik against the in-process fakes of the provider APIs,
+// so that it needs neither docker-compose nor amd64 images.
+type FakeProvidersSuite struct {
+	BaseSuite
+	whoami *httptest.Server
+	host   string
+	port   int
+}
+
+func (s *FakeProvidersSuite) SetUpSuite(c *check.C) {
+	s.whoami = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(rw, "Hostname: %s", req.Host)
+	}))
+
+	host, port, err := net.SplitHostPort(s.whoami.Listener.Addr().String())
+	c.Assert(err, checker.IsNil)
+	s.host = host
+	s.port, err = strconv.Atoi(port)
+	c.Assert(err, checker.IsNil)
+}
+
+func (s *FakeProvidersSuite) TearDownSuite(c *check.C) {
+	s.whoami.Close()
+}
+
+func (s *FakeProvidersSuite) TestMarathon(c *check.C) {
+	fake := fakes.NewMarathon()
+	defer fake.Close()
+
+	file := s.adaptFile(c, "fixtures/fakes/marathon.toml", struct{ Endpoint string }{fake.URL})
+	defer os.Remove(file)
+	cmd, output := s.cmdTraefik(withConfigFile(file))
+	err := cmd.Start()
+	c.Assert(err, checker.IsNil)
+	defer cmd.Process.Kill()
+
+	err = fake.WaitForSubscription(10 * time.Second)
+	c.Assert(err, checker.IsNil)
+
+	fake.SetApplications(fakes.NewApplication("/whoami", map[string]string{
+		types.LabelFrontendRule: "Host:whoami.marathon.localhost",
+	}, s.host, s.port))
+	err = fake.SendStatusUpdate("/whoami", "whoami.task", "TASK_RUNNING")
+	c.Assert(err, checker.IsNil)
+
+	err = s.waitForRoute("whoami.marathon.localhost", http.StatusOK)
+	if err != nil {
+		s.displayTraefikLog(c, output)
+	}
+	c.Assert(err, checker.IsNil)
+}
+
+func (s *FakeProvidersSuite) TestDocker(c *check.C) {
+	fake := fakes.NewDocker()
+	defer fake.Close()
+
+	file := s.adaptFile(c, "fixtures/fakes/docker.toml", struct{ Endpoint string }{fake.Endpoint()})
+	defer os.Remove(file)
+	cmd, output := s.cmdTraefik(withConfigFile(file))
+	err := cmd.Start()
+	c.Assert(err, checker.IsNil)
+	defer cmd.Process.Kill()
+
+	err = fake.WaitForSubscription(10 * time.Second)
+	c.Assert(err, checker.IsNil)
+
+	port := strconv.Itoa(s.port) + "/tcp"
+	err = fake.StartContainer(fakes.NewContainer("hidden-id", "hidden", nil, s.host, port))
+	c.Assert(err, checker.IsNil)
+	err = fake.StartContainer(fakes.NewContainer("whoami-id", "whoami", map[string]string{
+		types.LabelEnable: "true",
+	}, s.host, port))
+	c.Assert(err, checker.IsNil)
+
+	err = s.waitForRoute("whoami.docker.localhost", http.StatusOK)
+	if err != nil {
+		s.displayTraefikLog(c, output)
+	}
+	c.Assert(err, checker.IsNil)
+
+	// not exposed by default
+	err = s.waitForRoute("hidden.docker.localhost", http.StatusNotFound)
+	c.Assert(err, checker.IsNil)
+}
+
+func (s *FakeProvidersSuite) waitForRoute(host string, status int) error {
+	req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:8000/", nil)
+	if err != nil {
+		return err
+	}
+	req.Host = host
+	return try.Request(req, 10*time.Second, try.StatusCodeIs(status))
+}
@@ -0,0 +1,176 @@
+package fakes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	dockercontainertypes "github.com/docker/docker/api/types/container"
+	eventtypes "github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+)
+
+var dockerAPIVersionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+
+// Docker is a fake Docker API serving the containers and their events stream.
+// Its Endpoint is the endpoint of the Docker provider.
+type Docker struct {
+	*httptest.Server
+	streams *eventStreams
+
+	lock       sync.Mutex
+	containers map[string]dockertypes.ContainerJSON
+}
+
+// NewDocker starts a fake Docker API serving the given running containers
+func NewDocker(containers ...dockertypes.ContainerJSON) *Docker {
+	d := &Docker{
+		containers: make(map[string]dockertypes.ContainerJSON),
+		streams:    newEventStreams(),
+	}
+	for _, container := range containers {
+		d.containers[container.ID] = container
+	}
+	d.Server = httptest.NewServer(http.HandlerFunc(d.serveHTTP))
+	return d
+}
+
+// NewContainer returns a running container attached to the bridge network, exposing the given ports, e.g. "80/tcp"
+func NewContainer(id, name string, labels map[string]string, ipAddress string, ports ...string) dockertypes.ContainerJSON {
+	portMap := nat.PortMap{}
+	for _, port := range ports {
+		portMap[nat.Port(port)] = []nat.PortBinding{}
+	}
+	return dockertypes.ContainerJSON{
+		ContainerJSONBase: &dockertypes.ContainerJSONBase{
+			ID:         id,
+			Name:       "/" + name,
+			State:      &dockertypes.ContainerState{Status: "running", Running: true},
+			HostConfig: &dockercontainertypes.HostConfig{NetworkMode: "bridge"},
+		},
+		Config: &dockercontainertypes.Config{Labels: labels},
+		NetworkSettings: &dockertypes.NetworkSettings{
+			NetworkSettingsBase: dockertypes.NetworkSettingsBase{Ports: portMap},
+			Networks: map[string]*network.EndpointSettings{
+				"bridge": {IPAddress: ipAddress},
+			},
+		},
+	}
+}
+
+// Endpoint returns the endpoint of the Docker provider
+func (d *Docker) Endpoint() string {
+	return "tcp://" + d.Listener.Addr().String()
+}
+
+// Close terminates the open events streams before shutting the server down
+func (d *Docker) Close() {
+	d.streams.stop()
+	d.Server.Close()
+}
+
+// StartContainer adds the container and sends its start event
+func (d *Docker) StartContainer(container dockertypes.ContainerJSON) error {
+	d.lock.Lock()
+	d.containers[container.ID] = container
+	d.lock.Unlock()
+
+	return d.SendEvent(containerEvent(container.ID, "start"))
+}
+
+// StopContainer removes the container and sends its die event
+func (d *Docker) StopContainer(id string) error {
+	d.lock.Lock()
+	delete(d.containers, id)
+	d.lock.Unlock()
+
+	return d.SendEvent(containerEvent(id, "die"))
+}
+
+// Subscribers returns the number of open events streams
+func (d *Docker) Subscribers() int {
+	return d.streams.count()
+}
+
+// WaitForSubscription waits for a new events stream to be opened since the last call
+func (d *Docker) WaitForSubscription(timeout time.Duration) error {
+	return d.streams.waitForSubscription(timeout)
+}
+
+// CloseEventStreams closes the open events streams
+func (d *Docker) CloseEventStreams() {
+	d.streams.closeAll()
+}
+
+// SendEvent sends the event to the open events streams
+func (d *Docker) SendEvent(event eventtypes.Message) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return d.streams.send(append(data, '\n'))
+}
+
+func containerEvent(id, action string) eventtypes.Message {
+	now := time.Now()
+	return eventtypes.Message{
+		Status:   action,
+		ID:       id,
+		Type:     eventtypes.ContainerEventType,
+		Action:   action,
+		Actor:    eventtypes.Actor{ID: id},
+		Time:     now.Unix(),
+		TimeNano: now.UnixNano(),
+	}
+}
+
+func (d *Docker) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	path := dockerAPIVersionPrefix.ReplaceAllString(r.URL.Path, "")
+	switch {
+	case path == "/_ping":
+		w.Write([]byte("OK"))
+	case path == "/version":
+		writeJSON(w, dockertypes.Version{Version: "fake", APIVersion: "1.21"})
+	case path == "/events":
+		d.streams.serve(w, r, "application/json")
+	case path == "/containers/json":
+		d.serveContainers(w)
+	case strings.HasPrefix(path, "/containers/") && strings.HasSuffix(path, "/json"):
+		d.serveContainer(w, strings.TrimSuffix(strings.TrimPrefix(path, "/containers/"), "/json"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (d *Docker) serveContainers(w http.ResponseWriter) {
+	d.lock.Lock()
+	containers := make([]dockertypes.Container, 0, len(d.containers))
+	for _, container := range d.containers {
+		containers = append(containers, dockertypes.Container{ID: container.ID, Names: []string{container.Name}})
+	}
+	d.lock.Unlock()
+
+	sort.Slice(containers, func(i, j int) bool { return containers[i].ID < containers[j].ID })
+	writeJSON(w, containers)
+}
+
+func (d *Docker) serveContainer(w http.ResponseWriter, id string) {
+	d.lock.Lock()
+	container, ok := d.containers[id]
+	d.lock.Unlock()
+
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"No such container: ` + id + `"}`))
+		return
+	}
+	writeJSON(w, container)
+}
@@ -0,0 +1,49 @@
+package fakes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/provider/docker"
+	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerProviderWatchesContainers(t *testing.T) {
+	fake := NewDocker(
+		NewContainer("enabled-id", "enabled", map[string]string{types.LabelEnable: "true"}, "172.17.0.2", "80/tcp"),
+		NewContainer("default-id", "default", nil, "172.17.0.3", "80/tcp"),
+	)
+	defer fake.Close()
+
+	pool := safe.NewPool(context.Background())
+	defer pool.Stop()
+	provider := &docker.Provider{
+		Endpoint:         fake.Endpoint(),
+		Domain:           "docker.localhost",
+		ExposedByDefault: false,
+	}
+	provider.Watch = true
+	configurationChan := make(chan types.ConfigMessage, 10)
+	require.NoError(t, provider.Provide(configurationChan, pool, types.Constraints{}))
+
+	configuration := receiveConfiguration(t, configurationChan)
+	require.NotNil(t, configuration)
+	assert.Contains(t, configuration.Backends, "backend-enabled")
+	assert.NotContains(t, configuration.Backends, "backend-default")
+
+	require.NoError(t, fake.WaitForSubscription(5*time.Second))
+	require.NoError(t, fake.StartContainer(NewContainer("other-id", "other", map[string]string{types.LabelEnable: "true"}, "172.17.0.4", "80/tcp")))
+	configuration = receiveConfiguration(t, configurationChan)
+	require.NotNil(t, configuration)
+	assert.Contains(t, configuration.Backends, "backend-other")
+
+	require.NoError(t, fake.StopContainer("enabled-id"))
+	configuration = receiveConfiguration(t, configurationChan)
+	require.NotNil(t, configuration)
+	assert.NotContains(t, configuration.Backends, "backend-enabled")
+	assert.Contains(t, configuration.Backends, "backend-other")
+}
@@ -0,0 +1,133 @@
+// Package fakes provides in-process fakes of the APIs watched by the providers, so that their
+// reconnection, debounce and filtering logic, and custom templates, can be tested deterministically
+// without docker-compose and on any architecture.
+package fakes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// errNoSubscriber is returned when sending an event while no events stream is open
+var errNoSubscriber = errors.New("no subscriber to the events stream")
+
+// eventStreams are the open events streams of a fake API
+type eventStreams struct {
+	lock        sync.Mutex
+	subscribers map[*subscriber]bool
+	subscribed  chan struct{}
+	done        chan struct{}
+}
+
+// subscriber is an open events stream
+type subscriber struct {
+	events chan []byte
+	closed chan struct{}
+}
+
+func newEventStreams() *eventStreams {
+	return &eventStreams{
+		subscribers: make(map[*subscriber]bool),
+		subscribed:  make(chan struct{}, 100),
+		done:        make(chan struct{}),
+	}
+}
+
+// serve streams the events sent to the request until the stream is closed
+func (e *eventStreams) serve(w http.ResponseWriter, r *http.Request, contentType string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	s := &subscriber{events: make(chan []byte, 100), closed: make(chan struct{})}
+	e.lock.Lock()
+	e.subscribers[s] = true
+	e.lock.Unlock()
+	defer e.unsubscribe(s)
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	select {
+	case e.subscribed <- struct{}{}:
+	default:
+	}
+
+	for {
+		select {
+		case event := <-s.events:
+			w.Write(event)
+			flusher.Flush()
+		case <-s.closed:
+			return
+		case <-r.Context().Done():
+			return
+		case <-e.done:
+			return
+		}
+	}
+}
+
+func (e *eventStreams) unsubscribe(s *subscriber) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	delete(e.subscribers, s)
+}
+
+// send sends the event to the open events streams
+func (e *eventStreams) send(event []byte) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if len(e.subscribers) == 0 {
+		return errNoSubscriber
+	}
+	for s := range e.subscribers {
+		s.events <- event
+	}
+	return nil
+}
+
+func (e *eventStreams) count() int {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return len(e.subscribers)
+}
+
+// closeAll closes the open events streams
+func (e *eventStreams) closeAll() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for s := range e.subscribers {
+		close(s.closed)
+		delete(e.subscribers, s)
+	}
+}
+
+// stop terminates the open events streams for good
+func (e *eventStreams) stop() {
+	close(e.done)
+}
+
+// waitForSubscription waits for a new events stream to be opened since the last call
+func (e *eventStreams) waitForSubscription(timeout time.Duration) error {
+	select {
+	case <-e.subscribed:
+		return nil
+	case <-time.After(timeout):
+		return errNoSubscriber
+	}
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
@@ -0,0 +1,125 @@
+package fakes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gambol99/go-marathon"
+)
+
+// Marathon is a fake Marathon API serving the applications and the SSE events stream.
+// Its URL is the endpoint of the Marathon provider.
+type Marathon struct {
+	*httptest.Server
+	streams *eventStreams
+
+	lock         sync.Mutex
+	apps         []marathon.Application
+	appsRequests int
+}
+
+// NewMarathon starts a fake Marathon API serving the given applications
+func NewMarathon(apps ...marathon.Application) *Marathon {
+	m := &Marathon{
+		apps:    apps,
+		streams: newEventStreams(),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong"))
+	})
+	mux.HandleFunc("/v2/apps", m.serveApplications)
+	mux.HandleFunc("/v2/events", func(w http.ResponseWriter, r *http.Request) {
+		m.streams.serve(w, r, "text/event-stream")
+	})
+	m.Server = httptest.NewServer(mux)
+	return m
+}
+
+// NewApplication returns an application with a single running task listening on the host and port
+func NewApplication(id string, labels map[string]string, host string, port int) marathon.Application {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	return marathon.Application{
+		ID:     id,
+		Ports:  []int{port},
+		Labels: &labels,
+		Tasks: []*marathon.Task{{
+			ID:    strings.Replace(strings.TrimPrefix(id, "/"), "/", "_", -1) + ".task",
+			AppID: id,
+			Host:  host,
+			Ports: []int{port},
+			State: "TASK_RUNNING",
+		}},
+	}
+}
+
+// Close terminates the open events streams before shutting the server down
+func (m *Marathon) Close() {
+	m.streams.stop()
+	m.Server.Close()
+}
+
+// SetApplications replaces the applications returned by the next requests
+func (m *Marathon) SetApplications(apps ...marathon.Application) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.apps = apps
+}
+
+// ApplicationsRequests returns the number of applications requests received, i.e. of configuration loads
+func (m *Marathon) ApplicationsRequests() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.appsRequests
+}
+
+// Subscribers returns the number of open events streams
+func (m *Marathon) Subscribers() int {
+	return m.streams.count()
+}
+
+// WaitForSubscription waits for a new events stream to be opened since the last call
+func (m *Marathon) WaitForSubscription(timeout time.Duration) error {
+	return m.streams.waitForSubscription(timeout)
+}
+
+// CloseEventStreams closes the open events streams, e.g. to exercise the reconnection of the provider
+func (m *Marathon) CloseEventStreams() {
+	m.streams.closeAll()
+}
+
+// SendEvent sends the event, e.g. a marathon.EventStatusUpdate, to the open events streams
+func (m *Marathon) SendEvent(eventType string, event interface{}) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return m.streams.send([]byte(fmt.Sprintf("event: %s\ndata: %s\n\n", eventType, data)))
+}
+
+// SendStatusUpdate sends a status update event of a task to the open events streams
+func (m *Marathon) SendStatusUpdate(appID, taskID, taskStatus string) error {
+	return m.SendEvent("status_update_event", marathon.EventStatusUpdate{
+		EventType:  "status_update_event",
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		AppID:      appID,
+		TaskID:     taskID,
+		TaskStatus: taskStatus,
+	})
+}
+
+func (m *Marathon) serveApplications(w http.ResponseWriter, r *http.Request) {
+	m.lock.Lock()
+	m.appsRequests++
+	apps := marathon.Applications{Apps: m.apps}
+	m.lock.Unlock()
+
+	writeJSON(w, apps)
+}
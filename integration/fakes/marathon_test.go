@@ -0,0 +1,119 @@
+package fakes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/containous/flaeg"
+	"github.com/containous/traefik/provider/marathon"
+	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receiveConfiguration(t *testing.T, configurationChan <-chan types.ConfigMessage) *types.Configuration {
+	select {
+	case message := <-configurationChan:
+		return message.Configuration
+	case <-time.After(5 * time.Second):
+		t.Fatal("no configuration received")
+		return nil
+	}
+}
+
+func TestMarathonProviderReconnectsEventsStream(t *testing.T) {
+	fake := NewMarathon(NewApplication("/app", nil, "10.0.0.1", 80))
+	defer fake.Close()
+
+	pool := safe.NewPool(context.Background())
+	defer pool.Cleanup()
+	provider := &marathon.Provider{
+		Endpoint:                fake.URL,
+		Domain:                  "docker.localhost",
+		ExposedByDefault:        true,
+		EventsReconnectInterval: flaeg.Duration(10 * time.Millisecond),
+	}
+	provider.Watch = true
+	configurationChan := make(chan types.ConfigMessage, 10)
+	require.NoError(t, provider.Provide(configurationChan, pool, types.Constraints{}))
+	defer provider.Stop()
+
+	require.NotNil(t, receiveConfiguration(t, configurationChan))
+	require.NoError(t, fake.WaitForSubscription(5*time.Second))
+
+	fake.CloseEventStreams()
+	require.NoError(t, fake.WaitForSubscription(5*time.Second), "the events stream must be re-established")
+	drain(configurationChan)
+
+	fake.SetApplications(NewApplication("/app", nil, "10.0.0.1", 80), NewApplication("/other", nil, "10.0.0.2", 80))
+	require.NoError(t, fake.SendStatusUpdate("/other", "other.task", "TASK_RUNNING"))
+
+	configuration := receiveConfiguration(t, configurationChan)
+	require.NotNil(t, configuration)
+	assert.Contains(t, configuration.Backends, "backend-other")
+}
+
+func TestMarathonProviderCoalescesEvents(t *testing.T) {
+	fake := NewMarathon(NewApplication("/app", nil, "10.0.0.1", 80))
+	defer fake.Close()
+
+	pool := safe.NewPool(context.Background())
+	defer pool.Cleanup()
+	provider := &marathon.Provider{
+		Endpoint:           fake.URL,
+		Domain:             "docker.localhost",
+		ExposedByDefault:   true,
+		RefreshMinInterval: flaeg.Duration(300 * time.Millisecond),
+	}
+	provider.Watch = true
+	configurationChan := make(chan types.ConfigMessage, 10)
+	require.NoError(t, provider.Provide(configurationChan, pool, types.Constraints{}))
+	defer provider.Stop()
+
+	require.NotNil(t, receiveConfiguration(t, configurationChan))
+	require.NoError(t, fake.WaitForSubscription(5*time.Second))
+	requests := fake.ApplicationsRequests()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, fake.SendStatusUpdate("/app", "app.task", "TASK_RUNNING"))
+	}
+
+	// the first event is handled right away, the next ones by a single delayed refresh
+	time.Sleep(time.Second)
+	assert.Equal(t, requests+2, fake.ApplicationsRequests())
+}
+
+func TestMarathonProviderFiltersApplications(t *testing.T) {
+	fake := NewMarathon(
+		NewApplication("/enabled", map[string]string{types.LabelEnable: "true"}, "10.0.0.1", 80),
+		NewApplication("/default", nil, "10.0.0.2", 80),
+	)
+	defer fake.Close()
+
+	pool := safe.NewPool(context.Background())
+	defer pool.Cleanup()
+	provider := &marathon.Provider{
+		Endpoint: fake.URL,
+		Domain:   "docker.localhost",
+	}
+	configurationChan := make(chan types.ConfigMessage, 10)
+	require.NoError(t, provider.Provide(configurationChan, pool, types.Constraints{}))
+	defer provider.Stop()
+
+	configuration := receiveConfiguration(t, configurationChan)
+	require.NotNil(t, configuration)
+	assert.Contains(t, configuration.Backends, "backend-enabled")
+	assert.NotContains(t, configuration.Backends, "backend-default")
+}
+
+// drain discards the configurations already sent
+func drain(configurationChan <-chan types.ConfigMessage) {
+	for {
+		select {
+		case <-configurationChan:
+		case <-time.After(100 * time.Millisecond):
+			return
+		}
+	}
+}
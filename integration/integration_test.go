@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"testing"
 	"text/template"
 
@@ -25,6 +26,13 @@ func init() {
 	check.Suite(&SimpleSuite{})
 	check.Suite(&AccessLogSuite{})
 	check.Suite(&HTTPSSuite{})
+	check.Suite(&FakeProvidersSuite{})
+
+	// The suites below run the images of docker-compose projects or of the Docker daemon,
+	// which are only published for amd64. The fake providers cover the other architectures.
+	if runtime.GOARCH != "amd64" {
+		return
+	}
 	check.Suite(&FileSuite{})
 	check.Suite(&HealthCheckSuite{})
 	check.Suite(&DockerSuite{})
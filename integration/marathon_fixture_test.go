@@ -0,0 +1,117 @@
+package integration
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/containous/traefik/integration/try"
+	"github.com/gambol99/go-marathon"
+	"github.com/go-check/check"
+	checker "github.com/vdemeester/shakers"
+)
+
+const (
+	marathonURL           = "http://127.0.0.1:8080"
+	marathonLeaderTimeout = 2 * time.Minute
+	marathonDeployTimeout = 2 * time.Minute
+	whoamiImage           = "emilevauge/whoami"
+)
+
+// MarathonFixture deploys applications to the Marathon of the integration tests
+// and removes them on cleanup, so that each test starts from an empty Marathon.
+type MarathonFixture struct {
+	client marathon.Marathon
+	appIDs []string
+}
+
+// NewMarathonFixture creates a MarathonFixture once Marathon has elected a leader
+func NewMarathonFixture(c *check.C) *MarathonFixture {
+	config := marathon.NewDefaultConfig()
+	config.URL = marathonURL
+	client, err := marathon.NewClient(config)
+	c.Assert(err, checker.IsNil)
+
+	err = try.Do(marathonLeaderTimeout, func() error {
+		leader, err := client.Leader()
+		if err != nil {
+			return fmt.Errorf("leader not found: %v", err)
+		}
+		if len(leader) == 0 {
+			return errors.New("leader not elected yet")
+		}
+		return nil
+	})
+	c.Assert(err, checker.IsNil)
+
+	return &MarathonFixture{client: client}
+}
+
+// WhoamiApplication builds a whoami application exposing port 80, health checked by Marathon,
+// and labelled with the given labels
+func WhoamiApplication(c *check.C, id string, instances int, labels map[string]string) *marathon.Application {
+	app := marathon.NewDockerApplication().
+		Name(id).
+		CPU(0.1).
+		Memory(32).
+		Count(instances)
+	app.Container.Docker.Container(whoamiImage).Bridged().Expose(80)
+	for name, value := range labels {
+		app.AddLabel(name, value)
+	}
+	_, err := app.CheckHTTP("/", 80, 1)
+	c.Assert(err, checker.IsNil)
+	return app
+}
+
+// Deploy deploys the application and waits until all its tasks are running and healthy
+func (f *MarathonFixture) Deploy(c *check.C, app *marathon.Application) {
+	_, err := f.client.CreateApplication(app)
+	c.Assert(err, checker.IsNil)
+	f.appIDs = append(f.appIDs, app.ID)
+
+	err = f.client.WaitOnApplication(app.ID, marathonDeployTimeout)
+	c.Assert(err, checker.IsNil)
+
+	err = try.Do(marathonDeployTimeout, func() error {
+		healthy, err := f.client.ApplicationOK(app.ID)
+		if err != nil {
+			return err
+		}
+		if !healthy {
+			return fmt.Errorf("application %s is not healthy yet", app.ID)
+		}
+		return nil
+	})
+	c.Assert(err, checker.IsNil)
+}
+
+// DeployWhoami deploys a whoami application with the given labels
+func (f *MarathonFixture) DeployWhoami(c *check.C, id string, labels map[string]string) *marathon.Application {
+	app := WhoamiApplication(c, id, 1, labels)
+	f.Deploy(c, app)
+	return app
+}
+
+// WaitForRoute waits until Traefik answers the requests to the given URL and host with status
+func (f *MarathonFixture) WaitForRoute(url, host string, status int) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Host = host
+
+	return try.Request(req, marathonDeployTimeout, try.StatusCodeIs(status))
+}
+
+// Cleanup removes the deployed applications and waits for their removal
+func (f *MarathonFixture) Cleanup(c *check.C) {
+	for _, appID := range f.appIDs {
+		deployment, err := f.client.DeleteApplication(appID, true)
+		c.Assert(err, checker.IsNil)
+		err = f.client.WaitOnDeployment(deployment.DeploymentID, marathonDeployTimeout)
+		c.Assert(err, checker.IsNil)
+	}
+	f.appIDs = nil
+}
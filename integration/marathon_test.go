@@ -5,38 +5,27 @@ import (
 	"time"
 
 	"github.com/containous/traefik/integration/try"
+	"github.com/containous/traefik/types"
 	"github.com/go-check/check"
 	checker "github.com/vdemeester/shakers"
 )
 
 // Marathon test suites (using libcompose)
-type MarathonSuite struct{ BaseSuite }
+type MarathonSuite struct {
+	BaseSuite
+	fixture *MarathonFixture
+}
 
 func (s *MarathonSuite) SetUpSuite(c *check.C) {
 	s.createComposeProject(c, "marathon")
 	s.composeProject.Start(c)
 
-	// FIXME Doesn't work...
-	//// "github.com/gambol99/go-marathon"
-	//config := marathon.NewDefaultConfig()
-	//
-	//marathonClient, err := marathon.NewClient(config)
-	//if err != nil {
-	//	c.Fatalf("Error creating Marathon client. %v", err)
-	//}
-	//
-	//// Wait for Marathon to elect itself leader
-	//err = try.Do(30*time.Second, func() error {
-	//	leader, err := marathonClient.Leader()
-	//
-	//	if err != nil || len(leader) == 0 {
-	//		return fmt.Errorf("Leader not found. %v", err)
-	//	}
-	//
-	//	return nil
-	//})
-	//
-	//c.Assert(err, checker.IsNil)
+	// Wait for Marathon to elect itself leader
+	s.fixture = NewMarathonFixture(c)
+}
+
+func (s *MarathonSuite) TearDownTest(c *check.C) {
+	s.fixture.Cleanup(c)
 }
 
 func (s *MarathonSuite) TestSimpleConfiguration(c *check.C) {
@@ -51,3 +40,20 @@ func (s *MarathonSuite) TestSimpleConfiguration(c *check.C) {
 
 	c.Assert(err, checker.IsNil)
 }
+
+func (s *MarathonSuite) TestConfigurationUpdate(c *check.C) {
+	cmd, output := s.cmdTraefik(withConfigFile("fixtures/marathon/simple.toml"))
+	err := cmd.Start()
+	c.Assert(err, checker.IsNil)
+	defer cmd.Process.Kill()
+
+	s.fixture.DeployWhoami(c, "/whoami", map[string]string{
+		types.LabelFrontendRule: "Host:whoami.marathon.localhost",
+	})
+
+	err = s.fixture.WaitForRoute("http://127.0.0.1:8000/", "whoami.marathon.localhost", http.StatusOK)
+	if err != nil {
+		s.displayTraefikLog(c, output)
+	}
+	c.Assert(err, checker.IsNil)
+}
@@ -0,0 +1,102 @@
+package accesslog
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/containous/traefik/types"
+)
+
+// RedactedValue replaces the values of the redacted query parameters and headers
+const RedactedValue = "REDACTED"
+
+// SaveFrontendLogOptions sends the access log options of a frontend to the logger.
+type SaveFrontendLogOptions struct {
+	next    http.Handler
+	options *types.FrontendAccessLog
+}
+
+// NewSaveFrontendLogOptions creates a SaveFrontendLogOptions handler.
+func NewSaveFrontendLogOptions(next http.Handler, options *types.FrontendAccessLog) http.Handler {
+	return &SaveFrontendLogOptions{next, options}
+}
+
+func (s *SaveFrontendLogOptions) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if table, ok := r.Context().Value(DataTableKey).(*LogData); ok {
+		table.FrontendOptions = s.options
+	}
+	s.next.ServeHTTP(rw, r)
+}
+
+// redact replaces the values of the query parameters and headers to redact in the log fields
+func redact(logDataTable *LogData) {
+	options := logDataTable.FrontendOptions
+	if options == nil {
+		return
+	}
+
+	if len(options.RedactQueryParams) > 0 {
+		core := logDataTable.Core
+		if path, ok := core[RequestPath].(string); ok {
+			path = redactQueryParams(path, options.RedactQueryParams)
+			core[RequestPath] = path
+			core[RequestLine] = fmt.Sprintf("%s %s %s", core[RequestMethod], path, core[RequestProtocol])
+		}
+		if backendURL, ok := core[BackendURL].(*url.URL); ok {
+			redactedURL := *backendURL
+			redactedURL.RawQuery = redactQuery(backendURL.Query(), backendURL.RawQuery, options.RedactQueryParams)
+			core[BackendURL] = &redactedURL
+		}
+	}
+
+	if len(options.RedactHeaders) > 0 {
+		logDataTable.Request = redactHeaders(logDataTable.Request, options.RedactHeaders)
+		logDataTable.OriginResponse = redactHeaders(logDataTable.OriginResponse, options.RedactHeaders)
+		logDataTable.DownstreamResponse = redactHeaders(logDataTable.DownstreamResponse, options.RedactHeaders)
+	}
+}
+
+func redactQueryParams(requestPath string, params []string) string {
+	u, err := url.Parse(requestPath)
+	if err != nil {
+		return requestPath
+	}
+	u.RawQuery = redactQuery(u.Query(), u.RawQuery, params)
+	return u.String()
+}
+
+func redactQuery(query url.Values, rawQuery string, params []string) string {
+	redacted := false
+	for _, param := range params {
+		if values, ok := query[param]; ok {
+			for i := range values {
+				values[i] = RedactedValue
+			}
+			redacted = true
+		}
+	}
+	if !redacted {
+		return rawQuery
+	}
+	return query.Encode()
+}
+
+// redactHeaders returns a copy of headers with the values of the given headers redacted,
+// the original headers being used by the request and response
+func redactHeaders(headers http.Header, names []string) http.Header {
+	if headers == nil {
+		return nil
+	}
+	redacted := make(http.Header, len(headers))
+	for name, values := range headers {
+		redacted[name] = values
+	}
+	for _, name := range names {
+		name = http.CanonicalHeaderKey(name)
+		if _, ok := redacted[name]; ok {
+			redacted[name] = []string{RedactedValue}
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,87 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveFrontendLogOptions(t *testing.T) {
+	cases := []struct {
+		desc           string
+		options        *types.FrontendAccessLog
+		expectedLogged bool
+		expectedPath   string
+		expectedAuth   string
+		expectedCookie string
+	}{
+		{
+			desc:           "no options",
+			expectedLogged: true,
+			expectedPath:   "/login?email=user%40example.com&token=secret",
+			expectedAuth:   "Bearer secret",
+			expectedCookie: "session=secret",
+		},
+		{
+			desc:    "disabled",
+			options: &types.FrontendAccessLog{Disabled: true},
+		},
+		{
+			desc: "redacted query parameters and headers",
+			options: &types.FrontendAccessLog{
+				RedactQueryParams: []string{"email", "token", "missing"},
+				RedactHeaders:     []string{"authorization", "Set-Cookie"},
+			},
+			expectedLogged: true,
+			expectedPath:   "/login?email=" + RedactedValue + "&token=" + RedactedValue,
+			expectedAuth:   RedactedValue,
+			expectedCookie: RedactedValue,
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			tmpDir := createTempDir(t, JSONFormat)
+			defer os.RemoveAll(tmpDir)
+
+			logFilePath := filepath.Join(tmpDir, logFileNameSuffix)
+			logger, err := NewLogHandler(&types.AccessLog{FilePath: logFilePath, Format: JSONFormat}, nil)
+			require.NoError(t, err)
+			defer logger.Close()
+
+			handler := NewSaveFrontendLogOptions(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				rw.Header().Set("Set-Cookie", "session=secret")
+				rw.WriteHeader(http.StatusOK)
+			}), test.options)
+
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/login?email=user%40example.com&token=secret", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			logger.ServeHTTP(httptest.NewRecorder(), req, handler.ServeHTTP)
+
+			logData, err := ioutil.ReadFile(logFilePath)
+			require.NoError(t, err)
+			if !test.expectedLogged {
+				assert.Empty(t, logData)
+				return
+			}
+
+			jsonData := make(map[string]interface{})
+			require.NoError(t, json.Unmarshal(logData, &jsonData))
+			assert.Equal(t, test.expectedPath, jsonData[RequestPath])
+			assert.Equal(t, "GET "+test.expectedPath+" HTTP/1.1", jsonData[RequestLine])
+			assert.Equal(t, test.expectedAuth, jsonData["request_Authorization"])
+			assert.Equal(t, test.expectedCookie, jsonData["downstream_Set-Cookie"])
+			assert.Equal(t, "Bearer secret", req.Header.Get("Authorization"), "the request headers must not be modified")
+		})
+	}
+}
@@ -2,6 +2,8 @@ package accesslog
 
 import (
 	"net/http"
+
+	"github.com/containous/traefik/types"
 )
 
 const (
@@ -121,4 +123,6 @@ type LogData struct {
 	Request            http.Header
 	OriginResponse     http.Header
 	DownstreamResponse http.Header
+	// FrontendOptions holds the access log options of the frontend serving the request, if any
+	FrontendOptions *types.FrontendAccessLog
 }
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/middlewares"
 	"github.com/containous/traefik/types"
 )
 
@@ -31,12 +32,13 @@ const (
 
 // LogHandler will write each request and its response to the access log.
 type LogHandler struct {
-	logger *logrus.Logger
-	file   *os.File
+	logger   *logrus.Logger
+	file     *os.File
+	clientIP middlewares.ClientIPStrategy
 }
 
-// NewLogHandler creates a new LogHandler
-func NewLogHandler(config *types.AccessLog) (*LogHandler, error) {
+// NewLogHandler creates a new LogHandler, logging the client host determined by the client IP strategy
+func NewLogHandler(config *types.AccessLog, clientIP middlewares.ClientIPStrategy) (*LogHandler, error) {
 	file := os.Stdout
 	if len(config.FilePath) > 0 {
 		f, err := openAccessLogFile(config.FilePath)
@@ -63,7 +65,10 @@ func NewLogHandler(config *types.AccessLog) (*LogHandler, error) {
 		Hooks:     make(logrus.LevelHooks),
 		Level:     logrus.InfoLevel,
 	}
-	return &LogHandler{logger: logger, file: file}, nil
+	if clientIP == nil {
+		clientIP = middlewares.RemoteAddrStrategy{}
+	}
+	return &LogHandler{logger: logger, file: file, clientIP: clientIP}, nil
 }
 
 func openAccessLogFile(filePath string) (*os.File, error) {
@@ -124,6 +129,9 @@ func (l *LogHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request, next h
 
 	core[ClientAddr] = req.RemoteAddr
 	core[ClientHost], core[ClientPort] = silentSplitHostPort(req.RemoteAddr)
+	if clientIP := l.clientIP.ClientIP(req); clientIP != "" {
+		core[ClientHost] = clientIP
+	}
 	core[ClientUsername] = usernameIfPresent(req.URL)
 
 	crw := &captureResponseWriter{rw: rw}
@@ -159,6 +167,9 @@ func usernameIfPresent(theURL *url.URL) string {
 
 // Logging handler to log frontend name, backend name, and elapsed time
 func (l *LogHandler) logTheRoundTrip(logDataTable *LogData, crr *captureRequestReader, crw *captureResponseWriter) {
+	if logDataTable.FrontendOptions != nil && logDataTable.FrontendOptions.Disabled {
+		return
+	}
 
 	core := logDataTable.Core
 
@@ -185,6 +196,8 @@ func (l *LogHandler) logTheRoundTrip(logDataTable *LogData, crr *captureRequestR
 		core[Overhead] = total
 	}
 
+	redact(logDataTable)
+
 	fields := logrus.Fields{}
 
 	for k, v := range logDataTable.Core {
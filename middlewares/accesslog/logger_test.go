@@ -12,6 +12,7 @@ import (
 	"regexp"
 	"testing"
 
+	"github.com/containous/traefik/middlewares"
 	"github.com/containous/traefik/types"
 	shellwords "github.com/mattn/go-shellwords"
 	"github.com/stretchr/testify/assert"
@@ -160,6 +161,32 @@ func TestLoggerJSON(t *testing.T) {
 	assert.Equal(t, len(jsonData), assertCount, string(logData))
 }
 
+func TestLoggerClientIPStrategy(t *testing.T) {
+	tmpDir := createTempDir(t, JSONFormat)
+	defer os.RemoveAll(tmpDir)
+
+	logFilePath := filepath.Join(tmpDir, logFileNameSuffix)
+	strategy, err := middlewares.NewClientIPStrategy(&types.ClientIP{Depth: 1})
+	require.NoError(t, err)
+	logger, err := NewLogHandler(&types.AccessLog{FilePath: logFilePath, Format: JSONFormat}, strategy)
+	require.NoError(t, err)
+	defer logger.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "http://foo/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	logger.ServeHTTP(httptest.NewRecorder(), req, logWriterTestHandlerFunc)
+
+	logData, err := ioutil.ReadFile(logFilePath)
+	require.NoError(t, err)
+	jsonData := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(logData, &jsonData))
+
+	assert.Equal(t, "1.2.3.4", jsonData[ClientHost])
+	assert.Equal(t, "1234", jsonData[ClientPort])
+	assert.Equal(t, "10.0.0.1:1234", jsonData[ClientAddr])
+}
+
 func TestNewLogHandlerOutputStdout(t *testing.T) {
 	file, restoreStdout := captureStdout(t)
 	defer restoreStdout()
@@ -217,7 +244,7 @@ func createTempDir(t *testing.T, prefix string) string {
 }
 
 func doLogging(t *testing.T, config *types.AccessLog) {
-	logger, err := NewLogHandler(config)
+	logger, err := NewLogHandler(config, nil)
 	defer logger.Close()
 	require.NoError(t, err)
 
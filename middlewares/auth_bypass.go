@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/ryanuber/go-glob"
+)
+
+// AuthBypass is a middleware that skips the wrapped authentication middleware for
+// requests matching one of the configured paths or methods
+type AuthBypass struct {
+	auth    negroni.Handler
+	paths   []string
+	methods []string
+}
+
+// NewAuthBypass builds a new AuthBypass around the given authentication middleware.
+// Paths support glob patterns, e.g. /public/*
+func NewAuthBypass(auth negroni.Handler, bypass *types.AuthBypass) *AuthBypass {
+	authBypass := &AuthBypass{auth: auth}
+	if bypass != nil {
+		authBypass.paths = bypass.Paths
+		for _, method := range bypass.Methods {
+			authBypass.methods = append(authBypass.methods, strings.ToUpper(strings.TrimSpace(method)))
+		}
+	}
+	return authBypass
+}
+
+func (a *AuthBypass) bypass(r *http.Request) bool {
+	for _, method := range a.methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	// The router does not clean the paths, so that /public/../admin must not be bypassed
+	// as matching /public/*
+	if strings.Contains(r.URL.Path, "..") {
+		return false
+	}
+	cleanPath := path.Clean(r.URL.Path)
+	for _, pattern := range a.paths {
+		if glob.Glob(pattern, cleanPath) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *AuthBypass) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if a.bypass(r) {
+		log.Debugf("Bypassing authentication for %s %s", r.Method, r.URL.Path)
+		next.ServeHTTP(rw, r)
+		return
+	}
+	a.auth.ServeHTTP(rw, r, next)
+}
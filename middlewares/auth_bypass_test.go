@@ -0,0 +1,100 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthBypass(t *testing.T) {
+	authMiddleware, err := NewAuthenticator(&types.Auth{
+		Basic: &types.Basic{
+			Users: []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/"},
+		},
+	})
+	require.NoError(t, err)
+
+	bypass := &types.AuthBypass{
+		Paths:   []string{"/health", "/public/*"},
+		Methods: []string{"options"},
+	}
+
+	cases := []struct {
+		desc           string
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{
+			desc:           "exact path bypassed",
+			method:         http.MethodGet,
+			path:           "/health",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "glob path bypassed",
+			method:         http.MethodGet,
+			path:           "/public/css/app.css",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "method bypassed",
+			method:         http.MethodOptions,
+			path:           "/api",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "authentication required",
+			method:         http.MethodGet,
+			path:           "/api",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			desc:           "path prefix without glob is not bypassed",
+			method:         http.MethodGet,
+			path:           "/health/details",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			desc:           "path traversal out of a bypassed path is not bypassed",
+			method:         http.MethodGet,
+			path:           "/public/../admin",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			desc:           "encoded path traversal out of a bypassed path is not bypassed",
+			method:         http.MethodGet,
+			path:           "/public/%2e%2e/admin",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			desc:           "uncleaned path bypassed",
+			method:         http.MethodGet,
+			path:           "/public//css/./app.css",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			n := negroni.New(NewAuthBypass(authMiddleware, bypass))
+			n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := testhelpers.MustNewRequest(test.method, "http://example.com"+test.path, nil)
+			recorder := httptest.NewRecorder()
+			n.ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+		})
+	}
+}
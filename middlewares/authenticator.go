@@ -12,7 +12,8 @@ import (
 	"github.com/containous/traefik/types"
 )
 
-// Authenticator is a middleware that provides HTTP basic and digest authentication
+// Authenticator is a middleware that provides HTTP basic, digest and forward authentication,
+// optionally followed by the authorization of the authenticated requests
 type Authenticator struct {
 	handler negroni.Handler
 	users   map[string]string
@@ -40,7 +41,7 @@ func NewAuthenticator(authConfig *types.Auth) (*Authenticator, error) {
 				if authConfig.HeaderField != "" {
 					r.Header[authConfig.HeaderField] = []string{username}
 				}
-				next.ServeHTTP(w, r)
+				next.ServeHTTP(w, withPrincipal(r, username))
 			}
 		})
 	} else if authConfig.Digest != nil {
@@ -58,9 +59,24 @@ func NewAuthenticator(authConfig *types.Auth) (*Authenticator, error) {
 				if authConfig.HeaderField != "" {
 					r.Header[authConfig.HeaderField] = []string{username}
 				}
-				next.ServeHTTP(w, r)
+				next.ServeHTTP(w, withPrincipal(r, username))
 			}
 		})
+	} else if authConfig.Forward != nil {
+		authenticator.handler, err = newForwardAuthHandler(authConfig.Forward, authConfig.HeaderField)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if authConfig.Authorization != nil {
+		if authenticator.handler == nil {
+			return nil, fmt.Errorf("Error creating Authenticator: authorization requires an authentication")
+		}
+		authorizer, err := newAuthorizer(authConfig.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		authenticator.handler = authorizer.wrap(authenticator.handler)
 	}
 	return &authenticator, nil
 }
@@ -0,0 +1,236 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/ryanuber/go-glob"
+)
+
+const (
+	// DefaultAuthorizationCacheTTL is the duration of the cached decisions of the authorization policy server,
+	// when none is configured
+	DefaultAuthorizationCacheTTL = 30 * time.Second
+	// DefaultAuthorizationTimeout is the timeout of the requests to the authorization policy server,
+	// when none is configured
+	DefaultAuthorizationTimeout = 10 * time.Second
+	// maxAuthorizationCacheEntries bounds the memory used by the cached decisions
+	maxAuthorizationCacheEntries = 10000
+	xForwardedUser               = "X-Forwarded-User"
+)
+
+type principalKey struct{}
+
+// withPrincipal returns the request carrying the principal established by the authentication
+func withPrincipal(r *http.Request, principal string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, principal))
+}
+
+// principalFromRequest returns the principal established by the authentication of the request
+func principalFromRequest(r *http.Request) string {
+	principal, _ := r.Context().Value(principalKey{}).(string)
+	return principal
+}
+
+type authorizationDecision struct {
+	allowed bool
+	expires time.Time
+}
+
+// authorizer lets through the authenticated requests allowed by the rules or by the policy server
+type authorizer struct {
+	address  string
+	client   *http.Client
+	rules    []types.AuthorizationRule
+	cacheTTL time.Duration
+	lock     sync.Mutex
+	cache    map[string]authorizationDecision
+}
+
+func newAuthorizer(config *types.Authorization) (*authorizer, error) {
+	a := &authorizer{
+		address:  config.Address,
+		cacheTTL: DefaultAuthorizationCacheTTL,
+		cache:    make(map[string]authorizationDecision),
+	}
+	for _, rule := range config.Rules {
+		var methods []string
+		for _, method := range rule.Methods {
+			methods = append(methods, strings.ToUpper(strings.TrimSpace(method)))
+		}
+		rule.Methods = methods
+		a.rules = append(a.rules, rule)
+	}
+	if len(config.CacheTTL) > 0 {
+		cacheTTL, err := time.ParseDuration(config.CacheTTL)
+		if err != nil {
+			return nil, fmt.Errorf("Error creating authorizer: invalid cache TTL %q: %v", config.CacheTTL, err)
+		}
+		a.cacheTTL = cacheTTL
+	}
+	if a.address != "" {
+		timeout := DefaultAuthorizationTimeout
+		if len(config.Timeout) > 0 {
+			var err error
+			timeout, err = time.ParseDuration(config.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("Error creating authorizer: invalid timeout %q: %v", config.Timeout, err)
+			}
+		}
+		a.client = &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+		if config.TLS != nil {
+			tlsConfig, err := config.TLS.CreateTLSConfig()
+			if err != nil {
+				return nil, err
+			}
+			a.client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+		}
+	}
+	return a, nil
+}
+
+// wrap returns the authentication handler followed by the authorization of the authenticated requests
+func (a *authorizer) wrap(authentication negroni.Handler) negroni.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		authentication.ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+			a.ServeHTTP(w, r, next)
+		})
+	}
+}
+
+func (a *authorizer) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	principal := principalFromRequest(r)
+	allowed, err := a.allowed(principal, r)
+	if err != nil {
+		log.Errorf("Error authorizing %s %s for %q: %s", r.Method, r.URL.Path, principal, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		log.Debugf("Authorization denied %s %s for %q", r.Method, r.URL.Path, principal)
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	next.ServeHTTP(w, r)
+}
+
+func (a *authorizer) allowed(principal string, r *http.Request) (bool, error) {
+	for _, rule := range a.rules {
+		if ruleMatches(rule, principal, r) {
+			return true, nil
+		}
+	}
+	if a.address == "" {
+		return false, nil
+	}
+
+	key := strings.Join([]string{principal, r.Method, r.Host, r.URL.Path}, "\x00")
+	now := time.Now()
+	if allowed, ok := a.cached(key, now); ok {
+		return allowed, nil
+	}
+	allowed, err := a.askPolicyServer(principal, r)
+	if err != nil {
+		return false, err
+	}
+	a.store(key, allowed, now)
+	return allowed, nil
+}
+
+func ruleMatches(rule types.AuthorizationRule, principal string, r *http.Request) bool {
+	return matchesAny(rule.Principals, func(p string) bool { return p == principal }) &&
+		matchesAny(rule.Methods, func(method string) bool { return method == r.Method }) &&
+		(len(rule.Paths) == 0 || rulePathMatches(rule.Paths, r.URL.Path))
+}
+
+// rulePathMatches matches the cleaned request path, the router not cleaning the paths, and never
+// matches paths traversing up so that /public/../admin is not allowed as matching /public/*
+func rulePathMatches(patterns []string, requestPath string) bool {
+	if strings.Contains(requestPath, "..") {
+		return false
+	}
+	cleanPath := path.Clean(requestPath)
+	return matchesAny(patterns, func(pattern string) bool { return glob.Glob(pattern, cleanPath) })
+}
+
+// matchesAny returns whether one of the values matches, an empty list matching everything
+func matchesAny(values []string, match func(string) bool) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, value := range values {
+		if match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *authorizer) cached(key string, now time.Time) (bool, bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	decision, ok := a.cache[key]
+	if !ok || !now.Before(decision.expires) {
+		return false, false
+	}
+	return decision.allowed, true
+}
+
+func (a *authorizer) store(key string, allowed bool, now time.Time) {
+	if a.cacheTTL <= 0 {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if len(a.cache) >= maxAuthorizationCacheEntries {
+		for cachedKey, decision := range a.cache {
+			if !now.Before(decision.expires) {
+				delete(a.cache, cachedKey)
+			}
+		}
+		if len(a.cache) >= maxAuthorizationCacheEntries {
+			a.cache = make(map[string]authorizationDecision)
+		}
+	}
+	a.cache[key] = authorizationDecision{allowed: allowed, expires: now.Add(a.cacheTTL)}
+}
+
+// askPolicyServer describes the principal and the route of the request to the policy server with the
+// X-Forwarded-* headers, the policy server allowing the request with a 2XX status code and denying it
+// with a 401 or 403 status code
+func (a *authorizer) askPolicyServer(principal string, r *http.Request) (bool, error) {
+	policyReq, err := http.NewRequest(http.MethodGet, a.address, nil)
+	if err != nil {
+		return false, err
+	}
+	writeForwardedHeaders(policyReq, r, false)
+	policyReq.Header.Set(xForwardedUser, principal)
+
+	policyResponse, err := a.client.Do(policyReq)
+	if err != nil {
+		return false, err
+	}
+	policyResponse.Body.Close()
+
+	switch {
+	case policyResponse.StatusCode >= http.StatusOK && policyResponse.StatusCode < http.StatusMultipleChoices:
+		return true, nil
+	case policyResponse.StatusCode == http.StatusUnauthorized || policyResponse.StatusCode == http.StatusForbidden:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status %d from policy server %s", policyResponse.StatusCode, a.address)
+	}
+}
@@ -0,0 +1,233 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// test:test and admin:test
+var authorizationUsers = []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/", "admin:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/"}
+
+func newAuthorizedHandler(t *testing.T, authConfig *types.Auth) http.Handler {
+	authMiddleware, err := NewAuthenticator(authConfig)
+	require.NoError(t, err)
+	n := negroni.New(authMiddleware)
+	n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "traefik")
+	}))
+	return n
+}
+
+func serveAuthorized(handler http.Handler, method, path, user string) int {
+	req := testhelpers.MustNewRequest(method, "http://example.com"+path, nil)
+	if user != "" {
+		req.SetBasicAuth(user, "test")
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func TestAuthorizationRules(t *testing.T) {
+	handler := newAuthorizedHandler(t, &types.Auth{
+		Basic: &types.Basic{Users: authorizationUsers},
+		Authorization: &types.Authorization{
+			Rules: []types.AuthorizationRule{
+				{Principals: []string{"admin"}},
+				{Methods: []string{"get"}, Paths: []string{"/public/*"}},
+			},
+		},
+	})
+
+	testCases := []struct {
+		desc         string
+		method       string
+		path         string
+		user         string
+		expectedCode int
+	}{
+		{
+			desc:         "unauthenticated",
+			method:       http.MethodGet,
+			path:         "/public/index.html",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			desc:         "allowed principal",
+			method:       http.MethodDelete,
+			path:         "/admin",
+			user:         "admin",
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "allowed method and path",
+			method:       http.MethodGet,
+			path:         "/public/index.html",
+			user:         "test",
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "denied method",
+			method:       http.MethodPost,
+			path:         "/public/index.html",
+			user:         "test",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			desc:         "denied path",
+			method:       http.MethodGet,
+			path:         "/admin",
+			user:         "test",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			desc:         "denied path traversal out of an allowed path",
+			method:       http.MethodGet,
+			path:         "/public/../admin",
+			user:         "test",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			desc:         "allowed uncleaned path",
+			method:       http.MethodGet,
+			path:         "/public//./index.html",
+			user:         "test",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.expectedCode, serveAuthorized(handler, test.method, test.path, test.user))
+		})
+	}
+}
+
+func TestAuthorizationPolicyServer(t *testing.T) {
+	var calls int32
+	policyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		assert.Equal(t, "example.com", r.Header.Get("X-Forwarded-Host"))
+		assert.Empty(t, r.Header.Get("Authorization"), "credentials must not be sent to the policy server")
+		switch {
+		case r.Header.Get(xForwardedUser) == "admin":
+			w.WriteHeader(http.StatusNoContent)
+		case r.Header.Get(xForwardedMethod) == http.MethodGet && r.Header.Get(xForwardedURI) == "/":
+			w.WriteHeader(http.StatusOK)
+		case r.Header.Get(xForwardedURI) == "/broken":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer policyServer.Close()
+
+	handler := newAuthorizedHandler(t, &types.Auth{
+		Basic: &types.Basic{Users: authorizationUsers},
+		Authorization: &types.Authorization{
+			Address: policyServer.URL,
+			Rules:   []types.AuthorizationRule{{Paths: []string{"/health"}}},
+		},
+	})
+
+	assert.Equal(t, http.StatusOK, serveAuthorized(handler, http.MethodGet, "/health", "test"))
+	assert.EqualValues(t, 0, atomic.LoadInt32(&calls), "requests allowed by the rules must not reach the policy server")
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serveAuthorized(handler, http.MethodGet, "/", "test"))
+		assert.Equal(t, http.StatusForbidden, serveAuthorized(handler, http.MethodPost, "/", "test"))
+		assert.Equal(t, http.StatusOK, serveAuthorized(handler, http.MethodPost, "/", "admin"))
+	}
+	assert.EqualValues(t, 3, atomic.LoadInt32(&calls), "decisions must be cached by principal and route")
+
+	assert.Equal(t, http.StatusInternalServerError, serveAuthorized(handler, http.MethodGet, "/broken", "test"))
+	assert.Equal(t, http.StatusInternalServerError, serveAuthorized(handler, http.MethodGet, "/broken", "test"))
+	assert.EqualValues(t, 5, atomic.LoadInt32(&calls), "errors must not be cached")
+}
+
+func TestAuthorizationCacheDisabled(t *testing.T) {
+	var calls int32
+	policyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer policyServer.Close()
+
+	handler := newAuthorizedHandler(t, &types.Auth{
+		Basic:         &types.Basic{Users: authorizationUsers},
+		Authorization: &types.Authorization{Address: policyServer.URL, CacheTTL: "0s"},
+	})
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusOK, serveAuthorized(handler, http.MethodGet, "/", "test"))
+	}
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+}
+
+func TestAuthorizationForwardAuthPrincipal(t *testing.T) {
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Auth-User", r.Header.Get("X-Token"))
+	}))
+	defer authServer.Close()
+
+	handler := newAuthorizedHandler(t, &types.Auth{
+		Forward:     &types.Forward{Address: authServer.URL},
+		HeaderField: "X-Auth-User",
+		Authorization: &types.Authorization{
+			Rules: []types.AuthorizationRule{{Principals: []string{"admin"}}},
+		},
+	})
+
+	for token, expectedCode := range map[string]int{"admin": http.StatusOK, "test": http.StatusForbidden} {
+		req := testhelpers.MustNewRequest(http.MethodGet, "http://example.com/", nil)
+		req.Header.Set("X-Token", token)
+		req.Header.Set("X-Auth-User", "admin")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, expectedCode, recorder.Code, token)
+	}
+}
+
+func TestAuthorizationConfigurationErrors(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		authConfig *types.Auth
+	}{
+		{
+			desc:       "no authentication",
+			authConfig: &types.Auth{Authorization: &types.Authorization{}},
+		},
+		{
+			desc: "invalid cache TTL",
+			authConfig: &types.Auth{
+				Basic:         &types.Basic{Users: authorizationUsers},
+				Authorization: &types.Authorization{CacheTTL: "soon"},
+			},
+		},
+		{
+			desc: "invalid timeout",
+			authConfig: &types.Auth{
+				Basic:         &types.Basic{Users: authorizationUsers},
+				Authorization: &types.Authorization{Address: "http://policy.example.com", Timeout: "soon"},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewAuthenticator(test.authConfig)
+			assert.Error(t, err)
+		})
+	}
+}
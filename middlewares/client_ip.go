@@ -0,0 +1,138 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/utils"
+)
+
+// ClientIPStrategy determines the IP address of the client of a request.
+// It is shared by all the IP-based features so that they agree on who the client is.
+type ClientIPStrategy interface {
+	// ClientIP returns the IP address of the client, or an empty string when it cannot be determined
+	ClientIP(r *http.Request) string
+}
+
+// NewClientIPStrategy builds the client IP strategy described by the configuration:
+// the address of the peer by default, the X-Forwarded-For address at the given depth,
+// or the rightmost address which is not a trusted proxy. Setting both the depth and the
+// trusted proxies is rejected.
+func NewClientIPStrategy(config *types.ClientIP) (ClientIPStrategy, error) {
+	if config == nil || (config.Depth == 0 && len(config.TrustedProxies) == 0) {
+		return RemoteAddrStrategy{}, nil
+	}
+	if config.Depth < 0 {
+		return nil, fmt.Errorf("invalid client IP depth %d", config.Depth)
+	}
+	if config.Depth > 0 {
+		if len(config.TrustedProxies) > 0 {
+			return nil, fmt.Errorf("client IP depth %d and trusted proxies %v are exclusive", config.Depth, config.TrustedProxies)
+		}
+		return DepthStrategy{Depth: config.Depth}, nil
+	}
+
+	strategy := &TrustedProxiesStrategy{}
+	for _, cidr := range config.TrustedProxies {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("parsing trusted proxy CIDR %s: %v", cidr, err)
+		}
+		strategy.proxies = append(strategy.proxies, network)
+	}
+	return strategy, nil
+}
+
+// RemoteAddrStrategy uses the address of the peer
+type RemoteAddrStrategy struct{}
+
+// ClientIP returns the host part of the remote address
+func (RemoteAddrStrategy) ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// DepthStrategy uses the address at a given position from the right of the X-Forwarded-For header,
+// suited to a fixed number of proxies in front of traefik
+type DepthStrategy struct {
+	Depth int
+}
+
+// ClientIP returns the X-Forwarded-For address at the depth, or an empty string when there are not enough addresses
+func (s DepthStrategy) ClientIP(r *http.Request) string {
+	addresses := forwardedFor(r)
+	if s.Depth > len(addresses) {
+		return ""
+	}
+	return addresses[len(addresses)-s.Depth]
+}
+
+// TrustedProxiesStrategy uses the rightmost address, starting from the peer then going through
+// the X-Forwarded-For header, which is not a trusted proxy
+type TrustedProxiesStrategy struct {
+	proxies []*net.IPNet
+}
+
+// ClientIP returns the rightmost untrusted address, or the leftmost one when all of them are trusted
+func (s *TrustedProxiesStrategy) ClientIP(r *http.Request) string {
+	clientIP := RemoteAddrStrategy{}.ClientIP(r)
+	if !s.trusted(clientIP) {
+		return clientIP
+	}
+	addresses := forwardedFor(r)
+	for i := len(addresses) - 1; i >= 0; i-- {
+		clientIP = addresses[i]
+		if !s.trusted(clientIP) {
+			break
+		}
+	}
+	return clientIP
+}
+
+func (s *TrustedProxiesStrategy) trusted(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range s.proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor returns the addresses of all the X-Forwarded-For headers of the request
+func forwardedFor(r *http.Request) []string {
+	var addresses []string
+	for _, header := range r.Header["X-Forwarded-For"] {
+		for _, address := range strings.Split(header, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	return addresses
+}
+
+// NewSourceExtractor builds the source extractor of the rate limits and maximum connections,
+// the client.ip source being determined by the client IP strategy
+func NewSourceExtractor(variable string, strategy ClientIPStrategy) (utils.SourceExtractor, error) {
+	if variable != "client.ip" || strategy == nil {
+		return utils.NewExtractor(variable)
+	}
+	return utils.ExtractorFunc(func(r *http.Request) (string, int64, error) {
+		clientIP := strategy.ClientIP(r)
+		if clientIP == "" {
+			return "", 0, errors.New("unable to determine the client IP")
+		}
+		return clientIP, 1, nil
+	}), nil
+}
@@ -0,0 +1,189 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientIPStrategyError(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config *types.ClientIP
+	}{
+		{
+			desc:   "negative depth",
+			config: &types.ClientIP{Depth: -1},
+		},
+		{
+			desc:   "invalid trusted proxy",
+			config: &types.ClientIP{TrustedProxies: []string{"10.0.0.0/8", "foo"}},
+		},
+		{
+			desc:   "both depth and trusted proxies",
+			config: &types.ClientIP{Depth: 2, TrustedProxies: []string{"10.0.0.0/8"}},
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewClientIPStrategy(test.config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestClientIPStrategy(t *testing.T) {
+	cases := []struct {
+		desc         string
+		config       *types.ClientIP
+		remoteAddr   string
+		forwardedFor []string
+		expectedIP   string
+	}{
+		{
+			desc:         "no configuration uses the remote address",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"1.2.3.4"},
+			expectedIP:   "10.0.0.1",
+		},
+		{
+			desc:       "remote address without port",
+			config:     &types.ClientIP{},
+			remoteAddr: "10.0.0.1",
+			expectedIP: "10.0.0.1",
+		},
+		{
+			desc:         "depth 1 uses the rightmost forwarded address",
+			config:       &types.ClientIP{Depth: 1},
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"1.2.3.4, 5.6.7.8"},
+			expectedIP:   "5.6.7.8",
+		},
+		{
+			desc:         "depth spanning several headers",
+			config:       &types.ClientIP{Depth: 3},
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"1.2.3.4, 5.6.7.8", "9.9.9.9"},
+			expectedIP:   "1.2.3.4",
+		},
+		{
+			desc:         "depth beyond the forwarded addresses",
+			config:       &types.ClientIP{Depth: 2},
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"1.2.3.4"},
+			expectedIP:   "",
+		},
+		{
+			desc:         "untrusted peer ignores the forwarded addresses",
+			config:       &types.ClientIP{TrustedProxies: []string{"10.0.0.0/8"}},
+			remoteAddr:   "192.168.0.1:1234",
+			forwardedFor: []string{"1.2.3.4"},
+			expectedIP:   "192.168.0.1",
+		},
+		{
+			desc:         "trusted proxies are skipped from the right",
+			config:       &types.ClientIP{TrustedProxies: []string{"10.0.0.0/8", "172.16.0.0/12"}},
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"6.6.6.6, 1.2.3.4, 172.16.0.5, 10.0.0.3"},
+			expectedIP:   "1.2.3.4",
+		},
+		{
+			desc:         "all addresses trusted uses the leftmost forwarded address",
+			config:       &types.ClientIP{TrustedProxies: []string{"10.0.0.0/8"}},
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"10.0.0.3, 10.0.0.2"},
+			expectedIP:   "10.0.0.3",
+		},
+		{
+			desc:       "trusted peer without forwarded addresses",
+			config:     &types.ClientIP{TrustedProxies: []string{"10.0.0.0/8"}},
+			remoteAddr: "10.0.0.1:1234",
+			expectedIP: "10.0.0.1",
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			strategy, err := NewClientIPStrategy(test.config)
+			require.NoError(t, err)
+
+			req := testhelpers.MustNewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = test.remoteAddr
+			for _, forwardedFor := range test.forwardedFor {
+				req.Header.Add("X-Forwarded-For", forwardedFor)
+			}
+
+			assert.Equal(t, test.expectedIP, strategy.ClientIP(req))
+		})
+	}
+}
+
+func TestSourceExtractorClientIP(t *testing.T) {
+	strategy, err := NewClientIPStrategy(&types.ClientIP{Depth: 1})
+	require.NoError(t, err)
+	extractor, err := NewSourceExtractor("client.ip", strategy)
+	require.NoError(t, err)
+
+	req := testhelpers.MustNewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	_, _, err = extractor.Extract(req)
+	assert.Error(t, err, "no forwarded address")
+
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	source, amount, err := extractor.Extract(req)
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3.4", source)
+	assert.EqualValues(t, 1, amount)
+}
+
+func TestIPWhitelisterClientIPStrategy(t *testing.T) {
+	strategy, err := NewClientIPStrategy(&types.ClientIP{TrustedProxies: []string{"10.0.0.0/8"}})
+	require.NoError(t, err)
+	whitelister, err := NewIPWhitelister([]string{"1.2.3.0/24"}, strategy)
+	require.NoError(t, err)
+
+	n := negroni.New(whitelister)
+	n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	cases := []struct {
+		desc         string
+		forwardedFor string
+		expectedCode int
+	}{
+		{
+			desc:         "whitelisted client behind a trusted proxy",
+			forwardedFor: "1.2.3.4, 10.0.0.2",
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "spoofed whitelisted address",
+			forwardedFor: "1.2.3.4, 5.6.7.8",
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			req := testhelpers.MustNewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			req.Header.Set("X-Forwarded-For", test.forwardedFor)
+			recorder := httptest.NewRecorder()
+			n.ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedCode, recorder.Code)
+		})
+	}
+}
@@ -15,7 +15,8 @@ type Compress struct{}
 
 // ServerHTTP is a function used by Negroni
 func (c *Compress) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	if isEncoded(r.Header) {
+	// partial responses are not compressed, their Content-Range referring to the uncompressed representation
+	if isEncoded(r.Header) || r.Header.Get("Range") != "" {
 		next.ServeHTTP(rw, r)
 	} else {
 		newGzipHandler := gziphandler.GzipHandler(next)
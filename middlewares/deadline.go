@@ -0,0 +1,109 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/containous/traefik/log"
+)
+
+// DeadlineHeader is the request header telling the backends the remaining time budget of the request, in milliseconds
+const DeadlineHeader = "X-Request-Deadline"
+
+// grpcTimeoutMaxValue is the maximum value of the grpc-timeout header, whatever the unit
+const grpcTimeoutMaxValue = 99999999
+
+var grpcTimeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+// Deadline is a middleware bounding the time spent on the requests of a frontend: their context expires
+// after the timeout, cancelling the request to the backend.
+// It must be the innermost handler of the frontend, the route variables being bound to the original request.
+type Deadline struct {
+	handler http.Handler
+	timeout time.Duration
+}
+
+// NewDeadline builds a new Deadline middleware
+func NewDeadline(handler http.Handler, timeout time.Duration) *Deadline {
+	return &Deadline{handler: handler, timeout: timeout}
+}
+
+func (d *Deadline) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), d.timeout)
+	defer cancel()
+	d.handler.ServeHTTP(rw, r.WithContext(ctx))
+}
+
+// DeadlinePropagator forwards the remaining time budget of the requests having a deadline to the backends,
+// in the X-Request-Deadline header and in the grpc-timeout header of the gRPC requests, so that they can
+// give up on the requests nobody waits for anymore. The requests whose deadline is already exceeded are
+// not forwarded, and the X-Request-Deadline header sent by the clients is removed from the requests
+// without deadline.
+type DeadlinePropagator struct {
+	handler http.Handler
+}
+
+// NewDeadlinePropagator builds a new DeadlinePropagator forwarding the requests to the handler
+func NewDeadlinePropagator(handler http.Handler) *DeadlinePropagator {
+	return &DeadlinePropagator{handler: handler}
+}
+
+func (d *DeadlinePropagator) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	deadline, ok := r.Context().Deadline()
+	if !ok {
+		r.Header.Del(DeadlineHeader)
+		d.handler.ServeHTTP(rw, r)
+		return
+	}
+
+	remaining := deadline.Sub(time.Now())
+	if remaining <= 0 {
+		log.Debugf("Deadline of the request to %s exceeded, not forwarding it", r.URL)
+		http.Error(rw, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+		return
+	}
+
+	// rounded up, not to announce an exhausted budget
+	milliseconds := int64((remaining + time.Millisecond - 1) / time.Millisecond)
+	r.Header.Set(DeadlineHeader, strconv.FormatInt(milliseconds, 10))
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		if timeout, ok := parseGRPCTimeout(r.Header.Get("Grpc-Timeout")); !ok || timeout > remaining {
+			r.Header.Set("Grpc-Timeout", formatGRPCTimeout(milliseconds))
+		}
+	}
+	d.handler.ServeHTTP(rw, r)
+}
+
+// parseGRPCTimeout parses a grpc-timeout header value, e.g. 100m for 100 milliseconds
+func parseGRPCTimeout(value string) (time.Duration, bool) {
+	if len(value) < 2 {
+		return 0, false
+	}
+	unit, ok := grpcTimeoutUnits[value[len(value)-1]]
+	if !ok {
+		return 0, false
+	}
+	amount, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+	if err != nil || amount < 0 || amount > grpcTimeoutMaxValue {
+		return 0, false
+	}
+	return time.Duration(amount) * unit, true
+}
+
+// formatGRPCTimeout formats a grpc-timeout header value, in milliseconds or in seconds when too long
+func formatGRPCTimeout(milliseconds int64) string {
+	if milliseconds <= grpcTimeoutMaxValue {
+		return strconv.FormatInt(milliseconds, 10) + "m"
+	}
+	return strconv.FormatInt((milliseconds+999)/1000, 10) + "S"
+}
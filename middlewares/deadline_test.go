@@ -0,0 +1,168 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadline(t *testing.T) {
+	var deadline time.Time
+	var ok bool
+	handler := NewDeadline(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok = r.Context().Deadline()
+	}), time.Minute)
+
+	before := time.Now()
+	handler.ServeHTTP(httptest.NewRecorder(), testhelpers.MustNewRequest(http.MethodGet, "/", nil))
+
+	require.True(t, ok)
+	assert.WithinDuration(t, before.Add(time.Minute), deadline, time.Second)
+}
+
+func TestDeadlinePropagator(t *testing.T) {
+	cases := []struct {
+		desc                string
+		timeout             time.Duration
+		clientDeadline      string
+		contentType         string
+		grpcTimeout         string
+		expectedCode        int
+		expectedDeadline    bool
+		expectedGRPCTimeout string
+	}{
+		{
+			desc:         "no deadline",
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:           "no deadline with a client deadline header",
+			clientDeadline: "60000",
+			expectedCode:   http.StatusOK,
+		},
+		{
+			desc:             "remaining budget",
+			timeout:          time.Minute,
+			expectedCode:     http.StatusOK,
+			expectedDeadline: true,
+		},
+		{
+			desc:             "deadline exceeded",
+			timeout:          -time.Second,
+			expectedCode:     http.StatusGatewayTimeout,
+			expectedDeadline: false,
+		},
+		{
+			desc:                "gRPC request without timeout",
+			timeout:             time.Minute,
+			contentType:         "application/grpc+proto",
+			expectedCode:        http.StatusOK,
+			expectedDeadline:    true,
+			expectedGRPCTimeout: "set",
+		},
+		{
+			desc:                "gRPC request with a longer timeout",
+			timeout:             time.Minute,
+			contentType:         "application/grpc",
+			grpcTimeout:         "2M",
+			expectedCode:        http.StatusOK,
+			expectedDeadline:    true,
+			expectedGRPCTimeout: "set",
+		},
+		{
+			desc:                "gRPC request with a shorter timeout",
+			timeout:             time.Minute,
+			contentType:         "application/grpc",
+			grpcTimeout:         "100m",
+			expectedCode:        http.StatusOK,
+			expectedDeadline:    true,
+			expectedGRPCTimeout: "100m",
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			var header http.Header
+			handler := NewDeadlinePropagator(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header
+			}))
+
+			req := testhelpers.MustNewRequest(http.MethodGet, "/", nil)
+			if test.clientDeadline != "" {
+				req.Header.Set(DeadlineHeader, test.clientDeadline)
+			}
+			if test.contentType != "" {
+				req.Header.Set("Content-Type", test.contentType)
+			}
+			if test.grpcTimeout != "" {
+				req.Header.Set("Grpc-Timeout", test.grpcTimeout)
+			}
+			if test.timeout != 0 {
+				ctx, cancel := context.WithTimeout(req.Context(), test.timeout)
+				defer cancel()
+				req = req.WithContext(ctx)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedCode, recorder.Code)
+			if !test.expectedDeadline {
+				if header != nil {
+					assert.Empty(t, header.Get(DeadlineHeader))
+				}
+				return
+			}
+
+			milliseconds, err := strconv.ParseInt(header.Get(DeadlineHeader), 10, 64)
+			require.NoError(t, err)
+			assert.InDelta(t, int64(test.timeout/time.Millisecond), milliseconds, 1000)
+
+			switch test.expectedGRPCTimeout {
+			case "":
+				assert.Empty(t, header.Get("Grpc-Timeout"))
+			case "set":
+				assert.Equal(t, header.Get(DeadlineHeader)+"m", header.Get("Grpc-Timeout"))
+			default:
+				assert.Equal(t, test.expectedGRPCTimeout, header.Get("Grpc-Timeout"))
+			}
+		})
+	}
+}
+
+func TestGRPCTimeout(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected time.Duration
+		valid    bool
+	}{
+		{value: "100m", expected: 100 * time.Millisecond, valid: true},
+		{value: "2S", expected: 2 * time.Second, valid: true},
+		{value: "1H", expected: time.Hour, valid: true},
+		{value: "10", valid: false},
+		{value: "m", valid: false},
+		{value: "123456789n", valid: false},
+		{value: "-1S", valid: false},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.value, func(t *testing.T) {
+			t.Parallel()
+			timeout, ok := parseGRPCTimeout(test.value)
+			assert.Equal(t, test.valid, ok)
+			assert.Equal(t, test.expected, timeout)
+		})
+	}
+
+	assert.Equal(t, "1500m", formatGRPCTimeout(1500))
+	assert.Equal(t, "100001S", formatGRPCTimeout(100000001))
+}
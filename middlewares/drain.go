@@ -0,0 +1,217 @@
+package middlewares
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+// DrainHeader is the response header by which a backend server asks to be taken out of rotation
+const DrainHeader = "X-Traefik-Drain"
+
+// DrainStates keeps the servers being drained, by backend and server, across the reloads of the configuration
+// which rebuild the load balancers and the drainers
+type DrainStates struct {
+	lock     sync.Mutex
+	draining map[string]time.Time
+}
+
+// NewDrainStates builds a new DrainStates
+func NewDrainStates() *DrainStates {
+	return &DrainStates{draining: make(map[string]time.Time)}
+}
+
+func drainStateKey(backend string, server string) string {
+	return backend + "\x00" + server
+}
+
+// until returns the end of the drain of the server of the backend, if it is being drained
+func (s *DrainStates) until(backend string, server string, now time.Time) (time.Time, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := drainStateKey(backend, server)
+	until, ok := s.draining[key]
+	if ok && !now.Before(until) {
+		delete(s.draining, key)
+		return time.Time{}, false
+	}
+	return until, ok
+}
+
+func (s *DrainStates) set(backend string, server string, until time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.draining[drainStateKey(backend, server)] = until
+}
+
+// Drainer lets the servers of a backend drain themselves, e.g. when shutting down: a server answering
+// with the X-Traefik-Drain: true header is removed from the load balancer for the drain duration, the
+// requests in flight being completed. The header is not sent to the clients. The last server of the
+// load balancer is never drained.
+// It must wrap the forwarder, the load balancer setting the URL of the requests to the chosen server.
+type Drainer struct {
+	next     http.Handler
+	duration time.Duration
+	backend  string
+	states   *DrainStates
+	// servers are the servers of the backend, by scheme and host
+	servers map[string]types.Server
+
+	lock     sync.Mutex
+	pool     LocalityPool
+	draining map[string]bool
+}
+
+// NewDrainer builds a new Drainer of the backend, the weights of the drained servers being looked up in the
+// servers of the backend when putting them back into rotation. The drains are recorded in states, so that
+// they outlive the reloads of the configuration.
+func NewDrainer(next http.Handler, duration time.Duration, backend string, backendServers map[string]types.Server, states *DrainStates) *Drainer {
+	d := &Drainer{
+		next:     next,
+		duration: duration,
+		backend:  backend,
+		states:   states,
+		servers:  make(map[string]types.Server),
+		draining: make(map[string]bool),
+	}
+	for _, server := range backendServers {
+		u, err := url.Parse(server.URL)
+		if err != nil {
+			// reported when configuring the load balancer servers
+			continue
+		}
+		d.servers[u.Scheme+"://"+u.Host] = server
+	}
+	return d
+}
+
+// SetPool sets the load balancer the servers are drained from, removing from it the servers still being
+// drained since a previous configuration
+func (d *Drainer) SetPool(pool LocalityPool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.pool = pool
+
+	now := time.Now()
+	for key := range d.servers {
+		if until, ok := d.states.until(d.backend, key, now); ok {
+			d.remove(key, until)
+		}
+	}
+}
+
+func (d *Drainer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	d.next.ServeHTTP(&drainResponseWriter{ResponseWriter: rw, drainer: d, server: r.URL}, r)
+}
+
+func (d *Drainer) drain(u *url.URL) {
+	key := u.Scheme + "://" + u.Host
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.pool == nil || d.draining[key] {
+		return
+	}
+	if _, ok := d.servers[key]; !ok {
+		log.Debugf("Ignoring drain request of unknown server %s", key)
+		return
+	}
+	until := time.Now().Add(d.duration)
+	if d.remove(key, until) {
+		d.states.set(d.backend, key, until)
+	}
+}
+
+// remove takes the server out of the load balancer until the given time, unless it is the last one.
+// The lock of the drainer must be held.
+func (d *Drainer) remove(key string, until time.Time) bool {
+	server := d.servers[key]
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		return false
+	}
+	if len(d.pool.Servers()) <= 1 {
+		log.Warnf("Not draining server %s, the last server of backend %s", serverURL, d.backend)
+		return false
+	}
+	if err := d.pool.RemoveServer(serverURL); err != nil {
+		// already out of rotation, e.g. disabled by the health check
+		log.Debugf("Unable to drain server %s: %v", serverURL, err)
+		return false
+	}
+	log.Infof("Draining server %s of backend %s until %s", serverURL, d.backend, until.Format(time.RFC3339))
+	d.draining[key] = true
+	pool := d.pool
+	time.AfterFunc(until.Sub(time.Now()), func() {
+		d.restore(pool, key, serverURL, server.Weight)
+	})
+	return true
+}
+
+// restore puts the server back into the load balancer it was drained from, which is only used by
+// the requests in flight once the configuration has been reloaded
+func (d *Drainer) restore(pool LocalityPool, key string, serverURL *url.URL, weight int) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	delete(d.draining, key)
+	log.Debugf("Putting drained server %s of backend %s back into rotation", serverURL, d.backend)
+	if err := pool.UpsertServer(serverURL, roundrobin.Weight(weight)); err != nil {
+		log.Errorf("Error putting drained server %s back into rotation: %v", serverURL, err)
+	}
+}
+
+// drainResponseWriter drains the server of the request when its response carries the drain header
+type drainResponseWriter struct {
+	http.ResponseWriter
+	drainer       *Drainer
+	server        *url.URL
+	headerWritten bool
+}
+
+func (w *drainResponseWriter) WriteHeader(code int) {
+	if !w.headerWritten {
+		w.headerWritten = true
+		if value := w.Header().Get(DrainHeader); value != "" {
+			w.Header().Del(DrainHeader)
+			if strings.EqualFold(strings.TrimSpace(value), "true") {
+				w.drainer.drain(w.server)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *drainResponseWriter) Write(b []byte) (int, error) {
+	if !w.headerWritten {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *drainResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *drainResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("Not a hijacker: %T", w.ResponseWriter)
+}
+
+func (w *drainResponseWriter) CloseNotify() <-chan bool {
+	if c, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return c.CloseNotify()
+	}
+	return nil
+}
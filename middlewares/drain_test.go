@@ -0,0 +1,128 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+func TestDrainer(t *testing.T) {
+	backendServers := map[string]types.Server{
+		"server-0": {URL: "http://10.0.0.1:80", Weight: 1},
+		"server-1": {URL: "http://10.0.0.2:80", Weight: 1},
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host == "10.0.0.1:80" {
+			w.Header().Set(DrainHeader, "true")
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	drainer := NewDrainer(next, 100*time.Millisecond, "backend", backendServers, NewDrainStates())
+	rr, err := roundrobin.New(drainer)
+	require.NoError(t, err)
+	for _, server := range backendServers {
+		require.NoError(t, rr.UpsertServer(testhelpers.MustParseURL(server.URL), roundrobin.Weight(server.Weight)))
+	}
+	drainer.SetPool(rr)
+
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		rr.ServeHTTP(recorder, testhelpers.MustNewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Empty(t, recorder.Header().Get(DrainHeader), "drain header not sent to the client")
+	}
+	assert.Equal(t, []*url.URL{testhelpers.MustParseURL("http://10.0.0.2:80")}, rr.Servers())
+
+	time.Sleep(300 * time.Millisecond)
+	assert.Len(t, rr.Servers(), 2, "drained server back into rotation")
+}
+
+func TestDrainerIgnoresOtherValues(t *testing.T) {
+	backendServers := map[string]types.Server{
+		"server-0": {URL: "http://10.0.0.1:80"},
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(DrainHeader, "false")
+		w.Write([]byte("OK"))
+	})
+	drainer := NewDrainer(next, time.Minute, "backend", backendServers, NewDrainStates())
+	rr, err := roundrobin.New(drainer)
+	require.NoError(t, err)
+	require.NoError(t, rr.UpsertServer(testhelpers.MustParseURL("http://10.0.0.1:80")))
+	drainer.SetPool(rr)
+
+	recorder := httptest.NewRecorder()
+	rr.ServeHTTP(recorder, testhelpers.MustNewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, "OK", recorder.Body.String())
+	assert.Empty(t, recorder.Header().Get(DrainHeader))
+	assert.Len(t, rr.Servers(), 1)
+}
+
+func newDrainedPool(t *testing.T, next http.Handler, backendServers map[string]types.Server, states *DrainStates) *roundrobin.RoundRobin {
+	drainer := NewDrainer(next, time.Minute, "backend", backendServers, states)
+	rr, err := roundrobin.New(drainer)
+	require.NoError(t, err)
+	for _, server := range backendServers {
+		require.NoError(t, rr.UpsertServer(testhelpers.MustParseURL(server.URL), roundrobin.Weight(server.Weight)))
+	}
+	drainer.SetPool(rr)
+	return rr
+}
+
+func TestDrainerKeepsDrainsAcrossReloads(t *testing.T) {
+	backendServers := map[string]types.Server{
+		"server-0": {URL: "http://10.0.0.1:80", Weight: 1},
+		"server-1": {URL: "http://10.0.0.2:80", Weight: 1},
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host == "10.0.0.1:80" {
+			w.Header().Set(DrainHeader, "true")
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	states := NewDrainStates()
+
+	rr := newDrainedPool(t, next, backendServers, states)
+	for i := 0; i < 2; i++ {
+		rr.ServeHTTP(httptest.NewRecorder(), testhelpers.MustNewRequest(http.MethodGet, "/", nil))
+	}
+	assert.Equal(t, []*url.URL{testhelpers.MustParseURL("http://10.0.0.2:80")}, rr.Servers())
+
+	reloaded := newDrainedPool(t, next, backendServers, states)
+	assert.Equal(t, []*url.URL{testhelpers.MustParseURL("http://10.0.0.2:80")}, reloaded.Servers(), "drain lost on reload")
+
+	otherBackend := NewDrainer(next, time.Minute, "other", backendServers, states)
+	otherRR, err := roundrobin.New(otherBackend)
+	require.NoError(t, err)
+	for _, server := range backendServers {
+		require.NoError(t, otherRR.UpsertServer(testhelpers.MustParseURL(server.URL)))
+	}
+	otherBackend.SetPool(otherRR)
+	assert.Len(t, otherRR.Servers(), 2, "drains are kept by backend")
+}
+
+func TestDrainerKeepsLastServer(t *testing.T) {
+	backendServers := map[string]types.Server{
+		"server-0": {URL: "http://10.0.0.1:80", Weight: 1},
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(DrainHeader, "true")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	rr := newDrainedPool(t, next, backendServers, NewDrainStates())
+	recorder := httptest.NewRecorder()
+	rr.ServeHTTP(recorder, testhelpers.MustNewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Len(t, rr.Servers(), 1, "last server drained")
+}
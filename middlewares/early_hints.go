@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// statusEarlyHints is the status code of the 103 Early Hints informational responses
+const statusEarlyHints = 103
+
+// earlyHintsSupported reports whether the 103 Early Hints of backends can be passed through:
+// this requires the client trace of 1xx responses and writing 1xx responses, available since Go 1.19.
+// Both are detected at run time, so that traefik builds with any Go version.
+var earlyHintsSupported = goVersionAtLeast(runtime.Version(), 1, 19) && got1xxResponseTraceSupported()
+
+// got1xxResponseTraceSupported reports whether httptrace.ClientTrace has the Got1xxResponse hook
+func got1xxResponseTraceSupported() bool {
+	_, ok := reflect.TypeOf(httptrace.ClientTrace{}).FieldByName("Got1xxResponse")
+	return ok
+}
+
+// goVersionAtLeast reports whether the Go release of version, as returned by runtime.Version,
+// is major.minor or later. Development versions are considered recent.
+func goVersionAtLeast(version string, major, minor int) bool {
+	if !strings.HasPrefix(version, "go") {
+		return true
+	}
+	parts := strings.SplitN(strings.TrimPrefix(version, "go"), ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	versionMajor, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	// the minor version of a pre-release is followed by its suffix, e.g. go1.19rc1
+	minorDigits := strings.IndexFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' })
+	if minorDigits >= 0 {
+		parts[1] = parts[1][:minorDigits]
+	}
+	versionMinor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	return versionMajor > major || versionMajor == major && versionMinor >= minor
+}
+
+// withEarlyHintsPassthrough returns the request to forward, writing the 103 Early Hints received
+// from the backend to the client as soon as they arrive.
+func withEarlyHintsPassthrough(rw http.ResponseWriter, r *http.Request) *http.Request {
+	got1xxResponse := func(code int, header textproto.MIMEHeader) error {
+		if code == statusEarlyHints {
+			writeEarlyHints(rw, http.Header(header))
+		}
+		return nil
+	}
+
+	trace := &httptrace.ClientTrace{}
+	// the hook is set by reflection, httptrace.ClientTrace having no Got1xxResponse field before Go 1.11
+	reflect.ValueOf(trace).Elem().FieldByName("Got1xxResponse").Set(reflect.ValueOf(got1xxResponse))
+	return r.WithContext(httptrace.WithClientTrace(r.Context(), trace))
+}
+
+// writeEarlyHints writes the 103 Early Hints with the hinted headers, the headers of the final
+// response being restored afterwards.
+func writeEarlyHints(rw http.ResponseWriter, hints http.Header) {
+	responseHeader := rw.Header()
+	saved := make(http.Header, len(hints))
+	for name := range hints {
+		if values, ok := responseHeader[name]; ok {
+			saved[name] = values
+		}
+	}
+
+	for name, values := range hints {
+		responseHeader[name] = append([]string(nil), values...)
+	}
+	rw.WriteHeader(statusEarlyHints)
+
+	for name := range hints {
+		if values, ok := saved[name]; ok {
+			responseHeader[name] = values
+		} else {
+			delete(responseHeader, name)
+		}
+	}
+}
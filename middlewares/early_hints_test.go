@@ -0,0 +1,110 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"reflect"
+	"testing"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoVersionAtLeast(t *testing.T) {
+	cases := []struct {
+		version  string
+		expected bool
+	}{
+		{version: "go1.8.3", expected: false},
+		{version: "go1.18", expected: false},
+		{version: "go1.19", expected: true},
+		{version: "go1.19rc1", expected: true},
+		{version: "go1.21.0", expected: true},
+		{version: "go2.0", expected: true},
+		{version: "devel +b7a7b5f Thu Oct 1 2026", expected: true},
+		{version: "go1", expected: false},
+	}
+
+	for _, test := range cases {
+		assert.Equal(t, test.expected, goVersionAtLeast(test.version, 1, 19), test.version)
+	}
+}
+
+func TestResponseHintsEarlyHints(t *testing.T) {
+	if !earlyHintsSupported {
+		t.Skip("early hints passthrough requires Go 1.19 or later")
+	}
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(statusEarlyHints)
+		w.Header().Del("Link")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	cases := []struct {
+		desc       string
+		earlyHints string
+		expected   []string
+	}{
+		{
+			desc:     "default",
+			expected: nil,
+		},
+		{
+			desc:       "strip",
+			earlyHints: EarlyHintsStrip,
+			expected:   nil,
+		},
+		{
+			desc:       "passthrough",
+			earlyHints: EarlyHintsPassthrough,
+			expected:   []string{"</style.css>; rel=preload; as=style"},
+		},
+		{
+			desc:       "unknown behavior",
+			earlyHints: "forward",
+			expected:   nil,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.desc, func(t *testing.T) {
+			proxy := httptest.NewServer(NewResponseHints(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// the headers of the final response are kept when the early hints are written
+				w.Header().Set("Link", "</app.js>; rel=preload; as=script")
+				outReq := testhelpers.MustNewRequest(http.MethodGet, backend.URL, nil).WithContext(r.Context())
+				resp, err := http.DefaultTransport.RoundTrip(outReq)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadGateway)
+					return
+				}
+				resp.Body.Close()
+				w.WriteHeader(resp.StatusCode)
+			}), test.earlyHints, false))
+			defer proxy.Close()
+
+			var links []string
+			got1xxResponse := func(code int, header textproto.MIMEHeader) error {
+				if code == statusEarlyHints {
+					links = append(links, header["Link"]...)
+				}
+				return nil
+			}
+			trace := &httptrace.ClientTrace{}
+			reflect.ValueOf(trace).Elem().FieldByName("Got1xxResponse").Set(reflect.ValueOf(got1xxResponse))
+			req := testhelpers.MustNewRequest(http.MethodGet, proxy.URL, nil)
+			resp, err := http.DefaultClient.Do(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, []string{"</app.js>; rel=preload; as=script"}, resp.Header["Link"])
+			assert.Equal(t, test.expected, links)
+		})
+	}
+}
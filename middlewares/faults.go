@@ -0,0 +1,105 @@
+package middlewares
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+)
+
+// FaultHeader is the response header telling which fault was injected into the response
+const FaultHeader = "X-Traefik-Fault"
+
+// FaultInjector is a middleware injecting faults into a percentage of the requests of a frontend, so that
+// the resilience of the clients can be tested through the real edge path. Each fault is drawn independently:
+// a delayed request can still be answered with an error or aborted.
+type FaultInjector struct {
+	next            http.Handler
+	delay           time.Duration
+	delayPercentage float64
+	errorStatus     int
+	errorPercentage float64
+	abortPercentage float64
+	// random returns a number in [0, 100)
+	random func() float64
+}
+
+// NewFaultInjector builds a new FaultInjector forwarding the requests spared by the faults to next
+func NewFaultInjector(next http.Handler, faults *types.Faults) (*FaultInjector, error) {
+	for name, percentage := range map[string]float64{
+		"delay": faults.DelayPercentage,
+		"error": faults.ErrorPercentage,
+		"abort": faults.AbortPercentage,
+	} {
+		if percentage < 0 || percentage > 100 {
+			return nil, fmt.Errorf("%s percentage %v is not between 0 and 100", name, percentage)
+		}
+	}
+
+	injector := &FaultInjector{
+		next:            next,
+		delayPercentage: faults.DelayPercentage,
+		errorStatus:     faults.ErrorStatus,
+		errorPercentage: faults.ErrorPercentage,
+		abortPercentage: faults.AbortPercentage,
+		random:          func() float64 { return rand.Float64() * 100 },
+	}
+	if injector.delayPercentage > 0 {
+		delay, err := time.ParseDuration(faults.Delay)
+		if err != nil || delay <= 0 {
+			return nil, fmt.Errorf("invalid delay %q", faults.Delay)
+		}
+		injector.delay = delay
+	}
+	if injector.errorStatus == 0 {
+		injector.errorStatus = http.StatusServiceUnavailable
+	}
+	if injector.errorStatus < 400 || injector.errorStatus > 599 {
+		return nil, fmt.Errorf("error status %d is not an error", injector.errorStatus)
+	}
+	return injector, nil
+}
+
+func (f *FaultInjector) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if f.draw(f.delayPercentage) {
+		timer := time.NewTimer(f.delay)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			timer.Stop()
+			return
+		}
+	}
+	if f.draw(f.abortPercentage) {
+		f.abort(rw)
+		return
+	}
+	if f.draw(f.errorPercentage) {
+		rw.Header().Set(FaultHeader, "error")
+		http.Error(rw, http.StatusText(f.errorStatus), f.errorStatus)
+		return
+	}
+	f.next.ServeHTTP(rw, r)
+}
+
+func (f *FaultInjector) draw(percentage float64) bool {
+	return percentage > 0 && f.random() < percentage
+}
+
+// abort closes the client connection without any response, or answers with a 502 Bad Gateway when the
+// connection cannot be taken over, e.g. with HTTP/2
+func (f *FaultInjector) abort(rw http.ResponseWriter) {
+	if hijacker, ok := rw.(http.Hijacker); ok {
+		conn, _, err := hijacker.Hijack()
+		if err == nil {
+			conn.Close()
+			return
+		}
+		log.Debugf("Unable to abort connection, answering with an error instead: %v", err)
+	}
+	rw.Header().Set(FaultHeader, "abort")
+	http.Error(rw, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+}
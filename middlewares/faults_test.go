@@ -0,0 +1,128 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFaultInjectorInvalid(t *testing.T) {
+	cases := []struct {
+		desc   string
+		faults *types.Faults
+	}{
+		{
+			desc:   "negative percentage",
+			faults: &types.Faults{ErrorPercentage: -1},
+		},
+		{
+			desc:   "percentage above 100",
+			faults: &types.Faults{AbortPercentage: 101},
+		},
+		{
+			desc:   "missing delay",
+			faults: &types.Faults{DelayPercentage: 10},
+		},
+		{
+			desc:   "invalid delay",
+			faults: &types.Faults{Delay: "soon", DelayPercentage: 10},
+		},
+		{
+			desc:   "status not an error",
+			faults: &types.Faults{ErrorStatus: 302, ErrorPercentage: 10},
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewFaultInjector(http.NotFoundHandler(), test.faults)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFaultInjector(t *testing.T) {
+	cases := []struct {
+		desc           string
+		faults         *types.Faults
+		draw           float64
+		expectedStatus int
+		expectedFault  string
+		expectedDelay  bool
+	}{
+		{
+			desc:           "no fault drawn",
+			faults:         &types.Faults{Delay: "50ms", DelayPercentage: 10, ErrorPercentage: 10, AbortPercentage: 10},
+			draw:           50,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "error with the default status",
+			faults:         &types.Faults{ErrorPercentage: 60},
+			draw:           50,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedFault:  "error",
+		},
+		{
+			desc:           "error with a custom status",
+			faults:         &types.Faults{ErrorStatus: 500, ErrorPercentage: 100},
+			draw:           99,
+			expectedStatus: http.StatusInternalServerError,
+			expectedFault:  "error",
+		},
+		{
+			desc:           "delay",
+			faults:         &types.Faults{Delay: "50ms", DelayPercentage: 60},
+			draw:           50,
+			expectedStatus: http.StatusOK,
+			expectedDelay:  true,
+		},
+		{
+			desc:           "abort without hijackable connection",
+			faults:         &types.Faults{AbortPercentage: 100},
+			draw:           0,
+			expectedStatus: http.StatusBadGateway,
+			expectedFault:  "abort",
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			injector, err := NewFaultInjector(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}), test.faults)
+			require.NoError(t, err)
+			injector.random = func() float64 { return test.draw }
+
+			recorder := httptest.NewRecorder()
+			start := time.Now()
+			injector.ServeHTTP(recorder, testhelpers.MustNewRequest(http.MethodGet, "http://localhost/", nil))
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedFault, recorder.Header().Get(FaultHeader))
+			assert.Equal(t, test.expectedDelay, time.Since(start) >= 50*time.Millisecond)
+		})
+	}
+}
+
+func TestFaultInjectorAbortsConnection(t *testing.T) {
+	injector, err := NewFaultInjector(http.NotFoundHandler(), &types.Faults{AbortPercentage: 100})
+	require.NoError(t, err)
+	server := httptest.NewServer(injector)
+	defer server.Close()
+
+	_, err = http.Get(server.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), io.EOF.Error())
+}
@@ -0,0 +1,124 @@
+package middlewares
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/forward"
+	"github.com/vulcand/oxy/utils"
+)
+
+const (
+	// DefaultForwardAuthTimeout is the timeout of the requests to the authentication server,
+	// when none is configured
+	DefaultForwardAuthTimeout = 10 * time.Second
+	xForwardedMethod          = "X-Forwarded-Method"
+	xForwardedURI             = "X-Forwarded-Uri"
+)
+
+// newForwardAuthHandler creates a handler delegating authentication to the
+// server at config.Address. The server receives the headers of the request along
+// with its X-Forwarded-* headers; requests are let through when it answers with
+// a 2XX status code, otherwise its response is returned to the client, e.g. a
+// redirection to a login page. When headerField is set, the principal of the
+// request is the value of this header in the response of the server, the header
+// sent by the client being removed.
+// Requests to the server not answered within config.Timeout fail with a 500.
+func newForwardAuthHandler(config *types.Forward, headerField string) (negroni.HandlerFunc, error) {
+	if config.Address == "" {
+		return nil, fmt.Errorf("Error creating forward authenticator: address is empty")
+	}
+	timeout := DefaultForwardAuthTimeout
+	if len(config.Timeout) > 0 {
+		var err error
+		timeout, err = time.ParseDuration(config.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("Error creating forward authenticator: invalid timeout %q: %v", config.Timeout, err)
+		}
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	if config.TLS != nil {
+		tlsConfig, err := config.TLS.CreateTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		forwardReq, err := http.NewRequest(http.MethodGet, config.Address, nil)
+		if err != nil {
+			log.Errorf("Error creating forward authentication request to %s: %s", config.Address, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		utils.CopyHeaders(forwardReq.Header, r.Header)
+		utils.RemoveHeaders(forwardReq.Header, forward.HopHeaders...)
+		forwardReq.Header.Del("Content-Length")
+		writeForwardedHeaders(forwardReq, r, config.TrustForwardHeader)
+
+		forwardResponse, err := client.Do(forwardReq)
+		if err != nil {
+			log.Errorf("Error calling forward authentication server %s: %s", config.Address, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer forwardResponse.Body.Close()
+
+		if forwardResponse.StatusCode < http.StatusOK || forwardResponse.StatusCode >= http.StatusMultipleChoices {
+			log.Debugf("Forward auth failed with status %d", forwardResponse.StatusCode)
+			utils.CopyHeaders(w.Header(), forwardResponse.Header)
+			w.WriteHeader(forwardResponse.StatusCode)
+			io.Copy(w, forwardResponse.Body)
+			return
+		}
+		log.Debug("Forward auth success...")
+		if headerField != "" {
+			// only the principal given by the server reaches the backend
+			r.Header.Del(headerField)
+			if principal := forwardResponse.Header.Get(headerField); principal != "" {
+				r.Header.Set(headerField, principal)
+				r = withPrincipal(r, principal)
+			}
+		}
+		next.ServeHTTP(w, r)
+	}, nil
+}
+
+// writeForwardedHeaders describes the original request to the authentication server
+func writeForwardedHeaders(forwardReq, r *http.Request, trustForwardHeader bool) {
+	setHeader := func(name, value string) {
+		if trustForwardHeader && r.Header.Get(name) != "" {
+			return
+		}
+		forwardReq.Header.Set(name, value)
+	}
+
+	if clientIP, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		if trustForwardHeader {
+			if prior := r.Header.Get("X-Forwarded-For"); prior != "" {
+				clientIP = prior + ", " + clientIP
+			}
+		}
+		forwardReq.Header.Set("X-Forwarded-For", clientIP)
+	}
+	setHeader(xForwardedMethod, r.Method)
+	if r.TLS != nil {
+		setHeader("X-Forwarded-Proto", "https")
+	} else {
+		setHeader("X-Forwarded-Proto", "http")
+	}
+	setHeader("X-Forwarded-Host", r.Host)
+	setHeader(xForwardedURI, r.URL.RequestURI())
+}
@@ -0,0 +1,211 @@
+package middlewares
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwardAuthFail(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="traefik"`)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	authMiddleware, err := NewAuthenticator(&types.Auth{
+		Forward: &types.Forward{Address: server.URL},
+	})
+	require.NoError(t, err)
+
+	n := negroni.New(authMiddleware)
+	n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "traefik")
+	}))
+	ts := httptest.NewServer(n)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	assert.Equal(t, `Bearer realm="traefik"`, res.Header.Get("WWW-Authenticate"))
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "Forbidden\n", string(body))
+}
+
+func TestForwardAuthRedirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://auth.example.com/login", http.StatusFound)
+	}))
+	defer server.Close()
+
+	authMiddleware, err := NewAuthenticator(&types.Auth{
+		Forward: &types.Forward{Address: server.URL},
+	})
+	require.NoError(t, err)
+
+	n := negroni.New(authMiddleware)
+	n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "traefik")
+	}))
+
+	req := testhelpers.MustNewRequest(http.MethodGet, "http://example.com/", nil)
+	recorder := httptest.NewRecorder()
+	n.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusFound, recorder.Code)
+	assert.Equal(t, "http://auth.example.com/login", recorder.Header().Get("Location"))
+}
+
+func TestForwardAuthSuccess(t *testing.T) {
+	cases := []struct {
+		desc               string
+		trustForwardHeader bool
+		expectedHost       string
+		expectedFor        string
+	}{
+		{
+			desc:         "forward headers not trusted",
+			expectedHost: "example.com",
+			expectedFor:  "10.0.0.1",
+		},
+		{
+			desc:               "forward headers trusted",
+			trustForwardHeader: true,
+			expectedHost:       "public.example.com",
+			expectedFor:        "192.168.0.1, 10.0.0.1",
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+				assert.Equal(t, http.MethodPost, r.Header.Get(xForwardedMethod))
+				assert.Equal(t, "/api?q=1", r.Header.Get(xForwardedURI))
+				assert.Equal(t, "http", r.Header.Get("X-Forwarded-Proto"))
+				assert.Equal(t, test.expectedHost, r.Header.Get("X-Forwarded-Host"))
+				assert.Equal(t, test.expectedFor, r.Header.Get("X-Forwarded-For"))
+				fmt.Fprintln(w, "Success")
+			}))
+			defer server.Close()
+
+			authMiddleware, err := NewAuthenticator(&types.Auth{
+				Forward: &types.Forward{Address: server.URL, TrustForwardHeader: test.trustForwardHeader},
+			})
+			require.NoError(t, err)
+
+			n := negroni.New(authMiddleware)
+			n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintln(w, "traefik")
+			}))
+
+			req := testhelpers.MustNewRequest(http.MethodPost, "http://example.com/api?q=1", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			req.Header.Set("Authorization", "Bearer token")
+			req.Header.Set("X-Forwarded-Host", "public.example.com")
+			req.Header.Set("X-Forwarded-For", "192.168.0.1")
+			recorder := httptest.NewRecorder()
+			n.ServeHTTP(recorder, req)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, "traefik\n", recorder.Body.String())
+		})
+	}
+}
+
+func TestForwardAuthHeaderField(t *testing.T) {
+	cases := []struct {
+		desc              string
+		principal         string
+		expectedPrincipal string
+	}{
+		{
+			desc:              "principal given by the server",
+			principal:         "alice",
+			expectedPrincipal: "alice",
+		},
+		{
+			desc:              "no principal given by the server",
+			expectedPrincipal: "",
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if test.principal != "" {
+					w.Header().Set("X-Auth-User", test.principal)
+				}
+				fmt.Fprintln(w, "Success")
+			}))
+			defer server.Close()
+
+			authMiddleware, err := NewAuthenticator(&types.Auth{
+				HeaderField: "X-Auth-User",
+				Forward:     &types.Forward{Address: server.URL},
+			})
+			require.NoError(t, err)
+
+			var principal []string
+			n := negroni.New(authMiddleware)
+			n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal = r.Header["X-Auth-User"]
+			}))
+
+			// the client claims another identity
+			req := testhelpers.MustNewRequest(http.MethodGet, "http://example.com/", nil)
+			req.Header.Set("X-Auth-User", "admin")
+			recorder := httptest.NewRecorder()
+			n.ServeHTTP(recorder, req)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			if test.expectedPrincipal == "" {
+				assert.Empty(t, principal)
+			} else {
+				assert.Equal(t, []string{test.expectedPrincipal}, principal)
+			}
+		})
+	}
+}
+
+func TestForwardAuthTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	authMiddleware, err := NewAuthenticator(&types.Auth{
+		Forward: &types.Forward{Address: server.URL, Timeout: "50ms"},
+	})
+	require.NoError(t, err)
+
+	n := negroni.New(authMiddleware)
+	n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "traefik")
+	}))
+	recorder := httptest.NewRecorder()
+	n.ServeHTTP(recorder, testhelpers.MustNewRequest(http.MethodGet, "http://app.localhost/", nil))
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestNewForwardAuthError(t *testing.T) {
+	_, err := NewAuthenticator(&types.Auth{Forward: &types.Forward{}})
+	assert.Error(t, err)
+
+	_, err = NewAuthenticator(&types.Auth{Forward: &types.Forward{Address: "http://auth.localhost", Timeout: "soon"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,618 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+)
+
+// encodeGRPCMessage encodes a JSON object, decoded with json.Decoder.UseNumber, to the protobuf message type,
+// following the proto3 JSON mapping
+func encodeGRPCMessage(message *grpcMessage, object map[string]interface{}) ([]byte, error) {
+	buf := proto.NewBuffer(nil)
+	for name, value := range object {
+		field, ok := message.byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q of message %s", name, message.name)
+		}
+		if value == nil && field.typeName != ".google.protobuf.Value" {
+			continue
+		}
+		var err error
+		switch {
+		case field.isMap():
+			err = encodeGRPCMap(buf, field, value)
+		case field.repeated:
+			values, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("field %q of message %s must be an array", name, message.name)
+			}
+			for _, value := range values {
+				if err = encodeGRPCField(buf, field, value); err != nil {
+					break
+				}
+			}
+		default:
+			err = encodeGRPCField(buf, field, value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid field %q of message %s: %v", name, message.name, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeGRPCMap(buf *proto.Buffer, field *grpcField, value interface{}) error {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("must be an object")
+	}
+	keyField, valueField := field.message.byNumber[1], field.message.byNumber[2]
+	if keyField == nil || valueField == nil {
+		return fmt.Errorf("invalid map entry %s", field.message.name)
+	}
+	for key, value := range object {
+		entry := proto.NewBuffer(nil)
+		var keyValue interface{} = key
+		if keyField.kind != protoString {
+			keyValue = json.Number(key)
+		}
+		if err := encodeGRPCField(entry, keyField, keyValue); err != nil {
+			return fmt.Errorf("invalid key %q: %v", key, err)
+		}
+		if value != nil {
+			if err := encodeGRPCField(entry, valueField, value); err != nil {
+				return fmt.Errorf("invalid value of key %q: %v", key, err)
+			}
+		}
+		buf.EncodeVarint(field.number<<3 | wireBytes)
+		buf.EncodeRawBytes(entry.Bytes())
+	}
+	return nil
+}
+
+func encodeGRPCField(buf *proto.Buffer, field *grpcField, value interface{}) error {
+	if field.wellKnown != nil {
+		return encodeGRPCWellKnown(buf, field, value)
+	}
+	switch field.kind {
+	case protoDouble, protoFloat:
+		f, err := jsonFloat(value)
+		if err != nil {
+			return err
+		}
+		if field.kind == protoFloat {
+			buf.EncodeVarint(field.number<<3 | wireFixed32)
+			return buf.EncodeFixed32(uint64(math.Float32bits(float32(f))))
+		}
+		buf.EncodeVarint(field.number<<3 | wireFixed64)
+		return buf.EncodeFixed64(math.Float64bits(f))
+	case protoInt32, protoInt64, protoSint32, protoSint64, protoSfixed32, protoSfixed64:
+		bitSize := 64
+		if field.kind == protoInt32 || field.kind == protoSint32 || field.kind == protoSfixed32 {
+			bitSize = 32
+		}
+		i, err := jsonInt(value, bitSize)
+		if err != nil {
+			return err
+		}
+		switch field.kind {
+		case protoSint32:
+			buf.EncodeVarint(field.number<<3 | wireVarint)
+			return buf.EncodeZigzag32(uint64(i))
+		case protoSint64:
+			buf.EncodeVarint(field.number<<3 | wireVarint)
+			return buf.EncodeZigzag64(uint64(i))
+		case protoSfixed32:
+			buf.EncodeVarint(field.number<<3 | wireFixed32)
+			return buf.EncodeFixed32(uint64(uint32(i)))
+		case protoSfixed64:
+			buf.EncodeVarint(field.number<<3 | wireFixed64)
+			return buf.EncodeFixed64(uint64(i))
+		}
+		buf.EncodeVarint(field.number<<3 | wireVarint)
+		return buf.EncodeVarint(uint64(i))
+	case protoUint32, protoUint64, protoFixed32, protoFixed64:
+		bitSize := 64
+		if field.kind == protoUint32 || field.kind == protoFixed32 {
+			bitSize = 32
+		}
+		u, err := jsonUint(value, bitSize)
+		if err != nil {
+			return err
+		}
+		switch field.kind {
+		case protoFixed32:
+			buf.EncodeVarint(field.number<<3 | wireFixed32)
+			return buf.EncodeFixed32(u)
+		case protoFixed64:
+			buf.EncodeVarint(field.number<<3 | wireFixed64)
+			return buf.EncodeFixed64(u)
+		}
+		buf.EncodeVarint(field.number<<3 | wireVarint)
+		return buf.EncodeVarint(u)
+	case protoBool:
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("expected a boolean, got %v", value)
+		}
+		var v uint64
+		if b {
+			v = 1
+		}
+		buf.EncodeVarint(field.number<<3 | wireVarint)
+		return buf.EncodeVarint(v)
+	case protoEnum:
+		var number int32
+		switch v := value.(type) {
+		case string:
+			n, ok := field.enum.numbers[v]
+			if !ok {
+				return fmt.Errorf("unknown enum value %q", v)
+			}
+			number = n
+		default:
+			i, err := jsonInt(value, 32)
+			if err != nil {
+				return err
+			}
+			number = int32(i)
+		}
+		buf.EncodeVarint(field.number<<3 | wireVarint)
+		return buf.EncodeVarint(uint64(number))
+	case protoString:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected a string, got %v", value)
+		}
+		buf.EncodeVarint(field.number<<3 | wireBytes)
+		return buf.EncodeStringBytes(s)
+	case protoBytes:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected a base64 string, got %v", value)
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			if b, err = base64.URLEncoding.DecodeString(s); err != nil {
+				return err
+			}
+		}
+		buf.EncodeVarint(field.number<<3 | wireBytes)
+		return buf.EncodeRawBytes(b)
+	case protoMessage:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected an object, got %v", value)
+		}
+		b, err := encodeGRPCMessage(field.message, object)
+		if err != nil {
+			return err
+		}
+		buf.EncodeVarint(field.number<<3 | wireBytes)
+		return buf.EncodeRawBytes(b)
+	default:
+		return fmt.Errorf("unsupported field type %d", field.kind)
+	}
+}
+
+// encodeGRPCWellKnown encodes the JSON value of a field of a well-known type with jsonpb, which implements
+// their special JSON mapping, e.g. RFC 3339 strings for the timestamps
+func encodeGRPCWellKnown(buf *proto.Buffer, field *grpcField, value interface{}) error {
+	var message proto.Message
+	switch field.typeName {
+	case ".google.protobuf.Struct", ".google.protobuf.Value", ".google.protobuf.ListValue":
+		// the vendored jsonpb does not unmarshal the struct types yet, and marshals the nested lists as objects
+		structValue, err := structpbValue(value)
+		if err != nil {
+			return err
+		}
+		switch message = structValue; field.typeName {
+		case ".google.protobuf.Struct":
+			if message = structValue.GetStructValue(); message == (*structpb.Struct)(nil) {
+				return fmt.Errorf("expected an object, got %v", value)
+			}
+		case ".google.protobuf.ListValue":
+			if message = structValue.GetListValue(); message == (*structpb.ListValue)(nil) {
+				return fmt.Errorf("expected an array, got %v", value)
+			}
+		}
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		message = field.wellKnown()
+		if err := jsonpb.Unmarshal(bytes.NewReader(data), message); err != nil {
+			return err
+		}
+	}
+	b, err := proto.Marshal(message)
+	if err != nil {
+		return err
+	}
+	buf.EncodeVarint(field.number<<3 | wireBytes)
+	return buf.EncodeRawBytes(b)
+}
+
+// structpbValue converts a JSON value, decoded with json.Decoder.UseNumber, to a google.protobuf.Value
+func structpbValue(value interface{}) (*structpb.Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return &structpb.Value{Kind: &structpb.Value_NullValue{}}, nil
+	case bool:
+		return &structpb.Value{Kind: &structpb.Value_BoolValue{BoolValue: v}}, nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return &structpb.Value{Kind: &structpb.Value_NumberValue{NumberValue: f}}, nil
+	case string:
+		return &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: v}}, nil
+	case []interface{}:
+		list := &structpb.ListValue{}
+		for _, value := range v {
+			listValue, err := structpbValue(value)
+			if err != nil {
+				return nil, err
+			}
+			list.Values = append(list.Values, listValue)
+		}
+		return &structpb.Value{Kind: &structpb.Value_ListValue{ListValue: list}}, nil
+	case map[string]interface{}:
+		object := &structpb.Struct{Fields: make(map[string]*structpb.Value, len(v))}
+		for name, value := range v {
+			fieldValue, err := structpbValue(value)
+			if err != nil {
+				return nil, err
+			}
+			object.Fields[name] = fieldValue
+		}
+		return &structpb.Value{Kind: &structpb.Value_StructValue{StructValue: object}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported JSON value %v", value)
+	}
+}
+
+// jsonFloat converts a JSON number, or a string holding a number, NaN, Infinity or -Infinity, to a float
+func jsonFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case json.Number:
+		return v.Float64()
+	case string:
+		switch v {
+		case "NaN":
+			return math.NaN(), nil
+		case "Infinity":
+			return math.Inf(1), nil
+		case "-Infinity":
+			return math.Inf(-1), nil
+		}
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("expected a number, got %v", value)
+	}
+}
+
+// jsonInt converts a JSON number, or a string holding a number, to an integer
+func jsonInt(value interface{}, bitSize int) (int64, error) {
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return 0, fmt.Errorf("expected an integer, got %v", value)
+	}
+	return strconv.ParseInt(s, 10, bitSize)
+}
+
+// jsonUint converts a JSON number, or a string holding a number, to an unsigned integer
+func jsonUint(value interface{}, bitSize int) (uint64, error) {
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return 0, fmt.Errorf("expected an unsigned integer, got %v", value)
+	}
+	return strconv.ParseUint(s, 10, bitSize)
+}
+
+// decodeGRPCMessage decodes an encoded protobuf message to a JSON object, following the proto3 JSON mapping:
+// the fields are named by their JSON name, the 64-bit integers are strings, the bytes base64 strings and the
+// enums the names of their values, the unknown fields being ignored
+func decodeGRPCMessage(message *grpcMessage, data []byte) ([]byte, error) {
+	values := make(map[*grpcField][]interface{})
+	entries := make(map[*grpcField]map[string]interface{})
+	err := forEachProtoField(data, func(number uint64, wireType int, value uint64, data []byte) error {
+		field, ok := message.byNumber[number]
+		if !ok {
+			return nil
+		}
+		if field.isMap() {
+			key, value, err := decodeGRPCMapEntry(field.message, data)
+			if err != nil {
+				return err
+			}
+			if entries[field] == nil {
+				entries[field] = make(map[string]interface{})
+			}
+			entries[field][key] = value
+			return nil
+		}
+		if wireType == wireBytes && field.repeated && isPackableProtoKind(field.kind) {
+			return forEachPackedValue(field.kind, data, func(value uint64) error {
+				v, err := decodeGRPCValue(field, value, nil)
+				values[field] = append(values[field], v)
+				return err
+			})
+		}
+		if wireType != protoWireType(field.kind) {
+			return fmt.Errorf("invalid wire type %d of field %s of message %s", wireType, field.name, message.name)
+		}
+		v, err := decodeGRPCValue(field, value, data)
+		if err != nil {
+			return err
+		}
+		values[field] = append(values[field], v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for _, field := range message.fields {
+		var value interface{}
+		switch {
+		case field.isMap():
+			if entries[field] == nil {
+				continue
+			}
+			value = entries[field]
+		case field.repeated:
+			if values[field] == nil {
+				continue
+			}
+			value = values[field]
+		default:
+			if values[field] == nil {
+				continue
+			}
+			// the last value of a singular field wins
+			value = values[field][len(values[field])-1]
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(field.jsonName)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(b)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func decodeGRPCMapEntry(entry *grpcMessage, data []byte) (string, interface{}, error) {
+	keyField, valueField := entry.byNumber[1], entry.byNumber[2]
+	if keyField == nil || valueField == nil {
+		return "", nil, fmt.Errorf("invalid map entry %s", entry.name)
+	}
+	var key, value interface{}
+	err := forEachProtoField(data, func(number uint64, wireType int, v uint64, data []byte) error {
+		field := entry.byNumber[number]
+		if field == nil {
+			return nil
+		}
+		if wireType != protoWireType(field.kind) {
+			return fmt.Errorf("invalid wire type %d of map entry %s", wireType, entry.name)
+		}
+		decoded, err := decodeGRPCValue(field, v, data)
+		if number == 1 {
+			key = decoded
+		} else {
+			value = decoded
+		}
+		return err
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if value == nil {
+		value = defaultGRPCValue(valueField)
+	}
+	if key == nil {
+		key = defaultGRPCValue(keyField)
+	}
+	return fmt.Sprint(key), value, nil
+}
+
+// defaultGRPCValue returns the JSON value of a field absent from the encoded message
+func defaultGRPCValue(field *grpcField) interface{} {
+	switch field.kind {
+	case protoMessage:
+		if field.wellKnown != nil {
+			value, _ := decodeGRPCWellKnown(field, nil)
+			return value
+		}
+		return json.RawMessage("{}")
+	case protoString, protoBytes:
+		return ""
+	case protoBool:
+		return false
+	case protoInt64, protoUint64, protoFixed64, protoSfixed64, protoSint64:
+		return "0"
+	case protoEnum:
+		if name, ok := field.enum.names[0]; ok {
+			return name
+		}
+	}
+	return 0
+}
+
+func decodeGRPCValue(field *grpcField, value uint64, data []byte) (interface{}, error) {
+	switch field.kind {
+	case protoDouble:
+		return jsonFloatValue(math.Float64frombits(value)), nil
+	case protoFloat:
+		return jsonFloatValue(float64(math.Float32frombits(uint32(value)))), nil
+	case protoInt64, protoSfixed64:
+		return strconv.FormatInt(int64(value), 10), nil
+	case protoUint64, protoFixed64:
+		return strconv.FormatUint(value, 10), nil
+	case protoSint64:
+		return strconv.FormatInt(int64(value>>1)^-int64(value&1), 10), nil
+	case protoInt32, protoSfixed32:
+		return int32(value), nil
+	case protoUint32, protoFixed32:
+		return uint32(value), nil
+	case protoSint32:
+		return int32(uint32(value)>>1) ^ -int32(value&1), nil
+	case protoBool:
+		return value != 0, nil
+	case protoEnum:
+		if name, ok := field.enum.names[int32(value)]; ok {
+			return name, nil
+		}
+		return int32(value), nil
+	case protoString:
+		return string(data), nil
+	case protoBytes:
+		return base64.StdEncoding.EncodeToString(data), nil
+	case protoMessage:
+		if field.wellKnown != nil {
+			return decodeGRPCWellKnown(field, data)
+		}
+		b, err := decodeGRPCMessage(field.message, data)
+		return json.RawMessage(b), err
+	default:
+		return nil, fmt.Errorf("unsupported field type %d", field.kind)
+	}
+}
+
+// decodeGRPCWellKnown decodes an encoded message of a well-known type to its JSON value with jsonpb
+func decodeGRPCWellKnown(field *grpcField, data []byte) (interface{}, error) {
+	message := field.wellKnown()
+	if err := proto.Unmarshal(data, message); err != nil {
+		return nil, err
+	}
+	switch m := message.(type) {
+	case *structpb.Value:
+		return structpbJSON(m), nil
+	case *structpb.Struct:
+		return structpbJSON(&structpb.Value{Kind: &structpb.Value_StructValue{StructValue: m}}), nil
+	case *structpb.ListValue:
+		return structpbJSON(&structpb.Value{Kind: &structpb.Value_ListValue{ListValue: m}}), nil
+	}
+	s, err := (&jsonpb.Marshaler{}).MarshalToString(message)
+	return json.RawMessage(s), err
+}
+
+// structpbJSON converts a google.protobuf.Value to a JSON value
+func structpbJSON(value *structpb.Value) interface{} {
+	switch kind := value.GetKind().(type) {
+	case *structpb.Value_BoolValue:
+		return kind.BoolValue
+	case *structpb.Value_NumberValue:
+		return jsonFloatValue(kind.NumberValue)
+	case *structpb.Value_StringValue:
+		return kind.StringValue
+	case *structpb.Value_ListValue:
+		values := make([]interface{}, 0, len(kind.ListValue.GetValues()))
+		for _, value := range kind.ListValue.GetValues() {
+			values = append(values, structpbJSON(value))
+		}
+		return values
+	case *structpb.Value_StructValue:
+		object := make(map[string]interface{}, len(kind.StructValue.GetFields()))
+		for name, value := range kind.StructValue.GetFields() {
+			object[name] = structpbJSON(value)
+		}
+		return object
+	default:
+		return nil
+	}
+}
+
+// jsonFloatValue returns the JSON value of a float, NaN and the infinities being strings
+func jsonFloatValue(f float64) interface{} {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return f
+}
+
+// protoWireType returns the wire type of the unpacked values of a field type
+func protoWireType(kind descriptor.FieldDescriptorProto_Type) int {
+	switch kind {
+	case protoDouble, protoFixed64, protoSfixed64:
+		return wireFixed64
+	case protoFloat, protoFixed32, protoSfixed32:
+		return wireFixed32
+	case protoString, protoBytes, protoMessage:
+		return wireBytes
+	default:
+		return wireVarint
+	}
+}
+
+// isPackableProtoKind returns whether the repeated values of a field type may be packed
+func isPackableProtoKind(kind descriptor.FieldDescriptorProto_Type) bool {
+	return protoWireType(kind) != wireBytes
+}
+
+func forEachPackedValue(kind descriptor.FieldDescriptorProto_Type, data []byte, fn func(value uint64) error) error {
+	for len(data) > 0 {
+		var value uint64
+		switch protoWireType(kind) {
+		case wireFixed64:
+			if len(data) < 8 {
+				return errTruncatedProto
+			}
+			value = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errTruncatedProto
+			}
+			value = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		default:
+			var n int
+			value, n = proto.DecodeVarint(data)
+			if n == 0 {
+				return errTruncatedProto
+			}
+			data = data[n:]
+		}
+		if err := fn(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
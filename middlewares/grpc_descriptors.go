@@ -0,0 +1,320 @@
+package middlewares
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/golang/protobuf/ptypes/empty"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// protobuf field types
+const (
+	protoDouble   = descriptor.FieldDescriptorProto_TYPE_DOUBLE
+	protoFloat    = descriptor.FieldDescriptorProto_TYPE_FLOAT
+	protoInt64    = descriptor.FieldDescriptorProto_TYPE_INT64
+	protoUint64   = descriptor.FieldDescriptorProto_TYPE_UINT64
+	protoInt32    = descriptor.FieldDescriptorProto_TYPE_INT32
+	protoFixed64  = descriptor.FieldDescriptorProto_TYPE_FIXED64
+	protoFixed32  = descriptor.FieldDescriptorProto_TYPE_FIXED32
+	protoBool     = descriptor.FieldDescriptorProto_TYPE_BOOL
+	protoString   = descriptor.FieldDescriptorProto_TYPE_STRING
+	protoGroup    = descriptor.FieldDescriptorProto_TYPE_GROUP
+	protoMessage  = descriptor.FieldDescriptorProto_TYPE_MESSAGE
+	protoBytes    = descriptor.FieldDescriptorProto_TYPE_BYTES
+	protoUint32   = descriptor.FieldDescriptorProto_TYPE_UINT32
+	protoEnum     = descriptor.FieldDescriptorProto_TYPE_ENUM
+	protoSfixed32 = descriptor.FieldDescriptorProto_TYPE_SFIXED32
+	protoSfixed64 = descriptor.FieldDescriptorProto_TYPE_SFIXED64
+	protoSint32   = descriptor.FieldDescriptorProto_TYPE_SINT32
+	protoSint64   = descriptor.FieldDescriptorProto_TYPE_SINT64
+)
+
+var errTruncatedProto = errors.New("truncated protobuf message")
+
+// grpcWellKnownTypes builds the messages of the well-known types, which have a special JSON mapping,
+// by fully qualified name. Any is transcoded as a regular message, its JSON mapping requiring the
+// Go types of the messages it holds.
+var grpcWellKnownTypes = map[string]func() proto.Message{
+	".google.protobuf.Duration":    func() proto.Message { return &duration.Duration{} },
+	".google.protobuf.Empty":       func() proto.Message { return &empty.Empty{} },
+	".google.protobuf.Struct":      func() proto.Message { return &structpb.Struct{} },
+	".google.protobuf.Value":       func() proto.Message { return &structpb.Value{} },
+	".google.protobuf.ListValue":   func() proto.Message { return &structpb.ListValue{} },
+	".google.protobuf.Timestamp":   func() proto.Message { return &timestamp.Timestamp{} },
+	".google.protobuf.DoubleValue": func() proto.Message { return &wrappers.DoubleValue{} },
+	".google.protobuf.FloatValue":  func() proto.Message { return &wrappers.FloatValue{} },
+	".google.protobuf.Int64Value":  func() proto.Message { return &wrappers.Int64Value{} },
+	".google.protobuf.UInt64Value": func() proto.Message { return &wrappers.UInt64Value{} },
+	".google.protobuf.Int32Value":  func() proto.Message { return &wrappers.Int32Value{} },
+	".google.protobuf.UInt32Value": func() proto.Message { return &wrappers.UInt32Value{} },
+	".google.protobuf.BoolValue":   func() proto.Message { return &wrappers.BoolValue{} },
+	".google.protobuf.StringValue": func() proto.Message { return &wrappers.StringValue{} },
+	".google.protobuf.BytesValue":  func() proto.Message { return &wrappers.BytesValue{} },
+}
+
+// grpcMessage is a protobuf message type of a descriptor set
+type grpcMessage struct {
+	name     string
+	fields   []*grpcField
+	byName   map[string]*grpcField
+	byNumber map[uint64]*grpcField
+	mapEntry bool
+}
+
+// grpcField is a field of a protobuf message type
+type grpcField struct {
+	name     string
+	jsonName string
+	number   uint64
+	kind     descriptor.FieldDescriptorProto_Type
+	repeated bool
+	typeName string
+	message  *grpcMessage
+	enum     *grpcEnum
+	// wellKnown builds the message of a field of a well-known type
+	wellKnown func() proto.Message
+}
+
+// isMap returns whether the field is a map, i.e. a repeated field of map entries
+func (f *grpcField) isMap() bool {
+	return f.repeated && f.message != nil && f.message.mapEntry
+}
+
+// grpcEnum is a protobuf enum type of a descriptor set
+type grpcEnum struct {
+	names   map[int32]string
+	numbers map[string]int32
+}
+
+// grpcMethod is a method of a gRPC service of a descriptor set
+type grpcMethod struct {
+	path            string
+	input           *grpcMessage
+	output          *grpcMessage
+	clientStreaming bool
+	serverStreaming bool
+}
+
+// forEachProtoField calls fn for each field of an encoded protobuf message, with the value of the varint
+// and fixed fields, or the data of the length-delimited ones
+func forEachProtoField(buf []byte, fn func(number uint64, wireType int, value uint64, data []byte) error) error {
+	for len(buf) > 0 {
+		key, n := proto.DecodeVarint(buf)
+		if n == 0 {
+			return errTruncatedProto
+		}
+		buf = buf[n:]
+		number, wireType := key>>3, int(key&7)
+		var value uint64
+		var data []byte
+		switch wireType {
+		case wireVarint:
+			value, n = proto.DecodeVarint(buf)
+			if n == 0 {
+				return errTruncatedProto
+			}
+			buf = buf[n:]
+		case wireFixed64:
+			if len(buf) < 8 {
+				return errTruncatedProto
+			}
+			value = binary.LittleEndian.Uint64(buf)
+			buf = buf[8:]
+		case wireFixed32:
+			if len(buf) < 4 {
+				return errTruncatedProto
+			}
+			value = uint64(binary.LittleEndian.Uint32(buf))
+			buf = buf[4:]
+		case wireBytes:
+			length, n := proto.DecodeVarint(buf)
+			if n == 0 || uint64(len(buf)-n) < length {
+				return errTruncatedProto
+			}
+			data = buf[n : n+int(length)]
+			buf = buf[n+int(length):]
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d", wireType)
+		}
+		if err := fn(number, wireType, value, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// grpcDescriptors holds the message and enum types, by fully qualified name, and the methods,
+// by gRPC path, of a descriptor set
+type grpcDescriptors struct {
+	messages map[string]*grpcMessage
+	enums    map[string]*grpcEnum
+	methods  map[string]*grpcMethod
+}
+
+// parseGRPCDescriptorSet parses a binary google.protobuf.FileDescriptorSet, as written by
+// protoc --include_imports --descriptor_set_out
+func parseGRPCDescriptorSet(data []byte) (*grpcDescriptors, error) {
+	set := &descriptor.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, set); err != nil {
+		return nil, err
+	}
+
+	d := &grpcDescriptors{
+		messages: make(map[string]*grpcMessage),
+		enums:    make(map[string]*grpcEnum),
+		methods:  make(map[string]*grpcMethod),
+	}
+	// the types are collected before resolving the fields and the methods, which may reference the
+	// types of any file
+	for _, file := range set.File {
+		scope := "."
+		if file.GetPackage() != "" {
+			scope = "." + file.GetPackage() + "."
+		}
+		for _, enum := range file.EnumType {
+			d.addEnum(scope, enum)
+		}
+		for _, message := range file.MessageType {
+			d.addMessage(scope, message)
+		}
+	}
+	for _, message := range d.messages {
+		for _, field := range message.fields {
+			if err := d.resolveField(message, field); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, file := range set.File {
+		for _, service := range file.Service {
+			if err := d.addService(file.GetPackage(), service); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return d, nil
+}
+
+func (d *grpcDescriptors) addEnum(scope string, enumDescriptor *descriptor.EnumDescriptorProto) {
+	enum := &grpcEnum{names: make(map[int32]string), numbers: make(map[string]int32)}
+	for _, value := range enumDescriptor.Value {
+		if _, ok := enum.names[value.GetNumber()]; !ok {
+			enum.names[value.GetNumber()] = value.GetName()
+		}
+		enum.numbers[value.GetName()] = value.GetNumber()
+	}
+	d.enums[scope+enumDescriptor.GetName()] = enum
+}
+
+func (d *grpcDescriptors) addMessage(scope string, messageDescriptor *descriptor.DescriptorProto) {
+	fullName := scope + messageDescriptor.GetName()
+	message := &grpcMessage{
+		name:     strings.TrimPrefix(fullName, "."),
+		byName:   make(map[string]*grpcField),
+		byNumber: make(map[uint64]*grpcField),
+		mapEntry: messageDescriptor.GetOptions().GetMapEntry(),
+	}
+	for _, fieldDescriptor := range messageDescriptor.Field {
+		field := &grpcField{
+			name:     fieldDescriptor.GetName(),
+			jsonName: fieldDescriptor.GetJsonName(),
+			number:   uint64(fieldDescriptor.GetNumber()),
+			kind:     fieldDescriptor.GetType(),
+			repeated: fieldDescriptor.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED,
+			typeName: fieldDescriptor.GetTypeName(),
+		}
+		if field.jsonName == "" {
+			field.jsonName = protoJSONName(field.name)
+		}
+		message.fields = append(message.fields, field)
+		message.byName[field.name] = field
+		message.byName[field.jsonName] = field
+		message.byNumber[field.number] = field
+	}
+	d.messages[fullName] = message
+	for _, enum := range messageDescriptor.EnumType {
+		d.addEnum(fullName+".", enum)
+	}
+	for _, nested := range messageDescriptor.NestedType {
+		d.addMessage(fullName+".", nested)
+	}
+}
+
+// resolveField resolves the message or enum type of a field, the well-known types being transcoded
+// with their Go types whether the descriptor set includes them or not
+func (d *grpcDescriptors) resolveField(message *grpcMessage, field *grpcField) error {
+	switch field.kind {
+	case protoMessage:
+		if wellKnown, ok := grpcWellKnownTypes[field.typeName]; ok {
+			field.wellKnown = wellKnown
+			return nil
+		}
+		if field.message = d.messages[field.typeName]; field.message == nil {
+			return fmt.Errorf("unknown message type %s of field %s.%s", field.typeName, message.name, field.name)
+		}
+	case protoEnum:
+		if field.enum = d.enums[field.typeName]; field.enum == nil {
+			return fmt.Errorf("unknown enum type %s of field %s.%s", field.typeName, message.name, field.name)
+		}
+	case protoGroup:
+		return fmt.Errorf("unsupported group field %s.%s", message.name, field.name)
+	}
+	return nil
+}
+
+// protoJSONName returns the lowerCamelCase JSON name of a field, as protoc does when no json_name is set
+func protoJSONName(name string) string {
+	var jsonName []byte
+	upper := false
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; {
+		case c == '_':
+			upper = true
+		case upper && 'a' <= c && c <= 'z':
+			jsonName = append(jsonName, c-'a'+'A')
+			upper = false
+		default:
+			jsonName = append(jsonName, c)
+			upper = false
+		}
+	}
+	return string(jsonName)
+}
+
+func (d *grpcDescriptors) addService(pkg string, service *descriptor.ServiceDescriptorProto) error {
+	name := service.GetName()
+	if pkg != "" {
+		name = pkg + "." + name
+	}
+	for _, methodDescriptor := range service.Method {
+		method := &grpcMethod{
+			path:            "/" + name + "/" + methodDescriptor.GetName(),
+			clientStreaming: methodDescriptor.GetClientStreaming(),
+			serverStreaming: methodDescriptor.GetServerStreaming(),
+		}
+		if method.input = d.messages[methodDescriptor.GetInputType()]; method.input == nil {
+			return fmt.Errorf("unknown input type %s of method %s", methodDescriptor.GetInputType(), method.path)
+		}
+		if method.output = d.messages[methodDescriptor.GetOutputType()]; method.output == nil {
+			return fmt.Errorf("unknown output type %s of method %s", methodDescriptor.GetOutputType(), method.path)
+		}
+		d.methods[method.path] = method
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+)
+
+// DefaultGRPCTranscodingMaxMessageSize is the maximum size of the JSON requests and of the gRPC responses
+// transcoded, as the default gRPC maximum message size
+const DefaultGRPCTranscodingMaxMessageSize = 4 << 20
+
+// grpcHTTPStatuses maps the gRPC status codes to HTTP status codes
+var grpcHTTPStatuses = map[int]int{
+	0:  http.StatusOK,
+	1:  http.StatusRequestTimeout,
+	2:  http.StatusInternalServerError,
+	3:  http.StatusBadRequest,
+	4:  http.StatusGatewayTimeout,
+	5:  http.StatusNotFound,
+	6:  http.StatusConflict,
+	7:  http.StatusForbidden,
+	8:  http.StatusTooManyRequests,
+	9:  http.StatusBadRequest,
+	10: http.StatusConflict,
+	11: http.StatusBadRequest,
+	12: http.StatusNotImplemented,
+	13: http.StatusInternalServerError,
+	14: http.StatusServiceUnavailable,
+	15: http.StatusInternalServerError,
+	16: http.StatusUnauthorized,
+}
+
+// GRPCTranscoder is a middleware transcoding the JSON requests POSTed to the path of a gRPC method,
+// i.e. /<package>.<Service>/<Method>, to gRPC calls to a backend, and their gRPC responses to JSON.
+// The server streaming methods answer with a JSON array of the streamed messages, the client streaming
+// ones are not supported. The gRPC requests and the requests to other paths are forwarded untouched.
+// The backend must be reachable with HTTP/2, like for proxying gRPC requests.
+type GRPCTranscoder struct {
+	next    http.Handler
+	methods map[string]*grpcMethod
+}
+
+// NewGRPCTranscoder builds a new GRPCTranscoder middleware given a config
+func NewGRPCTranscoder(next http.Handler, config *types.GRPCTranscoding) (*GRPCTranscoder, error) {
+	data, err := ioutil.ReadFile(config.DescriptorSet)
+	if err != nil {
+		return nil, fmt.Errorf("error reading descriptor set: %v", err)
+	}
+	descriptors, err := parseGRPCDescriptorSet(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing descriptor set %s: %v", config.DescriptorSet, err)
+	}
+	methods := descriptors.methods
+	if len(config.Services) > 0 {
+		methods = make(map[string]*grpcMethod)
+		for _, service := range config.Services {
+			prefix := "/" + service + "/"
+			found := false
+			for path, method := range descriptors.methods {
+				if strings.HasPrefix(path, prefix) {
+					methods[path] = method
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unknown service %s in descriptor set %s", service, config.DescriptorSet)
+			}
+		}
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("no service in descriptor set %s", config.DescriptorSet)
+	}
+
+	paths := make([]string, 0, len(methods))
+	for path := range methods {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	log.Debugf("Transcoding JSON requests to the gRPC methods %s", strings.Join(paths, ", "))
+	return &GRPCTranscoder{next: next, methods: methods}, nil
+}
+
+func (t *GRPCTranscoder) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	method, ok := t.methods[r.URL.Path]
+	if !ok || strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		t.next.ServeHTTP(rw, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		writeGRPCTranscodingError(rw, http.StatusMethodNotAllowed, 12, "method not allowed")
+		return
+	}
+	if method.clientStreaming {
+		writeGRPCTranscodingError(rw, http.StatusNotImplemented, 12, "client streaming methods are not supported")
+		return
+	}
+
+	message, err := t.transcodeRequest(method, r.Body)
+	if err != nil {
+		writeGRPCTranscodingError(rw, http.StatusBadRequest, 3, err.Error())
+		return
+	}
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	frame = append(frame, message...)
+
+	outReq := r.WithContext(r.Context())
+	outReq.Header = make(http.Header, len(r.Header))
+	for name, values := range r.Header {
+		outReq.Header[name] = values
+	}
+	outReq.Header.Del("Content-Length")
+	outReq.Header.Del("Accept")
+	outReq.Header.Del("Accept-Encoding")
+	outReq.Header.Set("Content-Type", "application/grpc")
+	outReq.Header.Set("Te", "trailers")
+	outReq.Body = ioutil.NopCloser(bytes.NewReader(frame))
+	outReq.ContentLength = int64(len(frame))
+
+	recorder := newGRPCResponseRecorder(rw, DefaultGRPCTranscodingMaxMessageSize)
+	t.next.ServeHTTP(recorder, outReq)
+
+	if recorder.tooLarge {
+		writeGRPCTranscodingError(rw, http.StatusBadGateway, 8, fmt.Sprintf("response larger than %d bytes", DefaultGRPCTranscodingMaxMessageSize))
+		return
+	}
+	if recorder.Code != http.StatusOK || !strings.HasPrefix(recorder.HeaderMap.Get("Content-Type"), "application/grpc") {
+		// not a gRPC response, e.g. an error of the load balancer
+		for name, values := range recorder.HeaderMap {
+			rw.Header()[name] = values
+		}
+		rw.WriteHeader(recorder.Code)
+		rw.Write(recorder.Body.Bytes())
+		return
+	}
+
+	status, statusMessage := grpcStatus(recorder.HeaderMap)
+	if status != 0 {
+		httpStatus, ok := grpcHTTPStatuses[status]
+		if !ok {
+			httpStatus = http.StatusInternalServerError
+		}
+		writeGRPCTranscodingError(rw, httpStatus, status, statusMessage)
+		return
+	}
+
+	body, err := transcodeGRPCResponse(method, recorder.Body.Bytes())
+	if err != nil {
+		log.Errorf("Error transcoding the gRPC response of %s: %v", method.path, err)
+		writeGRPCTranscodingError(rw, http.StatusBadGateway, 13, err.Error())
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	rw.Write(body)
+}
+
+// grpcResponseRecorder buffers the response of the backend to transcode it, failing the writes beyond
+// a maximum size to abort the responses too large to transcode
+type grpcResponseRecorder struct {
+	Code      int
+	HeaderMap http.Header
+	Body      *bytes.Buffer
+
+	responseWriter http.ResponseWriter
+	maxSize        int
+	tooLarge       bool
+}
+
+func newGRPCResponseRecorder(rw http.ResponseWriter, maxSize int) *grpcResponseRecorder {
+	return &grpcResponseRecorder{
+		Code:           http.StatusOK,
+		HeaderMap:      make(http.Header),
+		Body:           new(bytes.Buffer),
+		responseWriter: rw,
+		maxSize:        maxSize,
+	}
+}
+
+// Header returns the response headers
+func (rw *grpcResponseRecorder) Header() http.Header {
+	return rw.HeaderMap
+}
+
+// WriteHeader sets rw.Code
+func (rw *grpcResponseRecorder) WriteHeader(code int) {
+	rw.Code = code
+}
+
+// Write appends the data to rw.Body, unless it would grow larger than the maximum size
+func (rw *grpcResponseRecorder) Write(buf []byte) (int, error) {
+	if rw.tooLarge || rw.Body.Len()+len(buf) > rw.maxSize {
+		rw.tooLarge = true
+		return 0, fmt.Errorf("response larger than %d bytes", rw.maxSize)
+	}
+	return rw.Body.Write(buf)
+}
+
+// Flush does nothing, the response being sent once transcoded
+func (rw *grpcResponseRecorder) Flush() {}
+
+// CloseNotify returns a channel that receives at most a single value (true) when the client connection
+// has gone away
+func (rw *grpcResponseRecorder) CloseNotify() <-chan bool {
+	if closeNotifier, ok := rw.responseWriter.(http.CloseNotifier); ok {
+		return closeNotifier.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// transcodeRequest encodes the JSON request body to the input message of the method
+func (t *GRPCTranscoder) transcodeRequest(method *grpcMethod, body io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(body, DefaultGRPCTranscodingMaxMessageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > DefaultGRPCTranscodingMaxMessageSize {
+		return nil, fmt.Errorf("request larger than %d bytes", DefaultGRPCTranscodingMaxMessageSize)
+	}
+	object := make(map[string]interface{})
+	if len(bytes.TrimSpace(data)) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&object); err != nil {
+			return nil, fmt.Errorf("invalid JSON request: %v", err)
+		}
+	}
+	return encodeGRPCMessage(method.input, object)
+}
+
+// transcodeGRPCResponse decodes the messages of a gRPC response body to a JSON object, or to a JSON array
+// for the server streaming methods
+func transcodeGRPCResponse(method *grpcMethod, body []byte) ([]byte, error) {
+	var messages [][]byte
+	for len(body) > 0 {
+		if len(body) < 5 {
+			return nil, errTruncatedProto
+		}
+		if body[0] != 0 {
+			return nil, fmt.Errorf("unsupported compressed gRPC message")
+		}
+		length := binary.BigEndian.Uint32(body[1:5])
+		if uint64(len(body)-5) < uint64(length) {
+			return nil, errTruncatedProto
+		}
+		message, err := decodeGRPCMessage(method.output, body[5:5+length])
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+		body = body[5+length:]
+	}
+	if !method.serverStreaming {
+		if len(messages) != 1 {
+			return nil, fmt.Errorf("expected 1 response message, got %d", len(messages))
+		}
+		return messages[0], nil
+	}
+	return append(append([]byte("["), bytes.Join(messages, []byte(","))...), ']'), nil
+}
+
+// grpcStatus returns the status code and message of a gRPC response, from its trailers or,
+// for the trailers-only responses, from its headers
+func grpcStatus(header http.Header) (int, string) {
+	get := func(name string) string {
+		if value := header.Get(name); value != "" {
+			return value
+		}
+		return header.Get(http.TrailerPrefix + name)
+	}
+	value := get("Grpc-Status")
+	if value == "" {
+		return 2, "missing gRPC status"
+	}
+	status, err := strconv.Atoi(value)
+	if err != nil {
+		return 2, fmt.Sprintf("invalid gRPC status %q", value)
+	}
+	message := get("Grpc-Message")
+	if unescaped, err := url.PathUnescape(message); err == nil {
+		message = unescaped
+	}
+	return status, message
+}
+
+func writeGRPCTranscodingError(rw http.ResponseWriter, httpStatus int, status int, message string) {
+	body, _ := json.Marshal(struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}{Code: status, Message: message})
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(httpStatus)
+	rw.Write(body)
+}
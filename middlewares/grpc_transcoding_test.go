@@ -0,0 +1,352 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func protoFieldDescriptor(name string, number int32, kind descriptor.FieldDescriptorProto_Type, typeName string, repeated bool) *descriptor.FieldDescriptorProto {
+	field := &descriptor.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Type:   kind.Enum(),
+		Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+	if repeated {
+		field.Label = descriptor.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	}
+	if typeName != "" {
+		field.TypeName = proto.String(typeName)
+	}
+	return field
+}
+
+func protoMethodDescriptor(name string, clientStreaming, serverStreaming bool) *descriptor.MethodDescriptorProto {
+	return &descriptor.MethodDescriptorProto{
+		Name:            proto.String(name),
+		InputType:       proto.String(".helloworld.HelloRequest"),
+		OutputType:      proto.String(".helloworld.HelloRequest"),
+		ClientStreaming: proto.Bool(clientStreaming),
+		ServerStreaming: proto.Bool(serverStreaming),
+	}
+}
+
+// helloWorldDescriptorSet returns the descriptor set of:
+//
+//	package helloworld;
+//	enum Mood { NEUTRAL = 0; HAPPY = 1; }
+//	message HelloRequest {
+//	  message Nested { bytes data = 1; }
+//	  string name = 1; int64 count = 2; repeated int32 ids = 3; Mood mood = 4;
+//	  map<string, int32> tags = 5; Nested nested = 6; string first_name = 7; double score = 8;
+//	  google.protobuf.Timestamp created = 9; google.protobuf.Duration ttl = 10;
+//	  google.protobuf.Int64Value limit = 11; google.protobuf.Struct meta = 12;
+//	}
+//	service Greeter {
+//	  rpc SayHello (HelloRequest) returns (HelloRequest);
+//	  rpc StreamHello (HelloRequest) returns (stream HelloRequest);
+//	  rpc UploadHello (stream HelloRequest) returns (HelloRequest);
+//	}
+//
+// without the descriptors of the well-known types, which are built in
+func helloWorldDescriptorSet() []byte {
+	set := &descriptor.FileDescriptorSet{
+		File: []*descriptor.FileDescriptorProto{{
+			Name:    proto.String("helloworld.proto"),
+			Package: proto.String("helloworld"),
+			MessageType: []*descriptor.DescriptorProto{{
+				Name: proto.String("HelloRequest"),
+				Field: []*descriptor.FieldDescriptorProto{
+					protoFieldDescriptor("name", 1, protoString, "", false),
+					protoFieldDescriptor("count", 2, protoInt64, "", false),
+					protoFieldDescriptor("ids", 3, protoInt32, "", true),
+					protoFieldDescriptor("mood", 4, protoEnum, ".helloworld.Mood", false),
+					protoFieldDescriptor("tags", 5, protoMessage, ".helloworld.HelloRequest.TagsEntry", true),
+					protoFieldDescriptor("nested", 6, protoMessage, ".helloworld.HelloRequest.Nested", false),
+					protoFieldDescriptor("first_name", 7, protoString, "", false),
+					protoFieldDescriptor("score", 8, protoDouble, "", false),
+					protoFieldDescriptor("created", 9, protoMessage, ".google.protobuf.Timestamp", false),
+					protoFieldDescriptor("ttl", 10, protoMessage, ".google.protobuf.Duration", false),
+					protoFieldDescriptor("limit", 11, protoMessage, ".google.protobuf.Int64Value", false),
+					protoFieldDescriptor("meta", 12, protoMessage, ".google.protobuf.Struct", false),
+				},
+				NestedType: []*descriptor.DescriptorProto{
+					{
+						Name: proto.String("TagsEntry"),
+						Field: []*descriptor.FieldDescriptorProto{
+							protoFieldDescriptor("key", 1, protoString, "", false),
+							protoFieldDescriptor("value", 2, protoInt32, "", false),
+						},
+						Options: &descriptor.MessageOptions{MapEntry: proto.Bool(true)},
+					},
+					{
+						Name:  proto.String("Nested"),
+						Field: []*descriptor.FieldDescriptorProto{protoFieldDescriptor("data", 1, protoBytes, "", false)},
+					},
+				},
+			}},
+			EnumType: []*descriptor.EnumDescriptorProto{{
+				Name: proto.String("Mood"),
+				Value: []*descriptor.EnumValueDescriptorProto{
+					{Name: proto.String("NEUTRAL"), Number: proto.Int32(0)},
+					{Name: proto.String("HAPPY"), Number: proto.Int32(1)},
+				},
+			}},
+			Service: []*descriptor.ServiceDescriptorProto{{
+				Name: proto.String("Greeter"),
+				Method: []*descriptor.MethodDescriptorProto{
+					protoMethodDescriptor("SayHello", false, false),
+					protoMethodDescriptor("StreamHello", false, true),
+					protoMethodDescriptor("UploadHello", true, false),
+				},
+			}},
+		}},
+	}
+	data, err := proto.Marshal(set)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func writeDescriptorSet(t *testing.T) string {
+	file, err := ioutil.TempFile("", "descriptors")
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = file.Write(helloWorldDescriptorSet())
+	require.NoError(t, err)
+	return file.Name()
+}
+
+// grpcEchoBackend answers the gRPC requests with their message, twice for StreamHello,
+// with a NOT_FOUND status for the requests naming "nobody", and with a response larger than the maximum
+// size for the requests naming "huge"
+func grpcEchoBackend(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/grpc" {
+			rw.Write([]byte("not gRPC " + r.URL.Path))
+			return
+		}
+		assert.Equal(t, "trailers", r.Header.Get("Te"))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.True(t, len(body) >= 5)
+		assert.EqualValues(t, len(body)-5, binary.BigEndian.Uint32(body[1:5]))
+
+		rw.Header().Set("Content-Type", "application/grpc")
+		if bytes.Contains(body, []byte("nobody")) {
+			rw.Header().Set("Grpc-Status", "5")
+			rw.Header().Set("Grpc-Message", "nobody%20here")
+			return
+		}
+		if bytes.Contains(body, []byte("huge")) {
+			rw.Write(make([]byte, DefaultGRPCTranscodingMaxMessageSize))
+		}
+		rw.Write(body)
+		if r.URL.Path == "/helloworld.Greeter/StreamHello" {
+			rw.Write(body)
+		}
+		rw.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+	})
+}
+
+func TestGRPCTranscoder(t *testing.T) {
+	descriptorSet := writeDescriptorSet(t)
+	defer os.Remove(descriptorSet)
+	transcoder, err := NewGRPCTranscoder(grpcEchoBackend(t), &types.GRPCTranscoding{DescriptorSet: descriptorSet})
+	require.NoError(t, err)
+
+	const message = `{"name":"traefik","count":"42","ids":[1,2],"mood":"HAPPY","tags":{"a":1,"b":2},"nested":{"data":"aGk="},"firstName":"T","score":1.5}`
+
+	testCases := []struct {
+		desc         string
+		method       string
+		path         string
+		contentType  string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			desc:         "unary",
+			method:       http.MethodPost,
+			path:         "/helloworld.Greeter/SayHello",
+			body:         message,
+			expectedCode: http.StatusOK,
+			expectedBody: message,
+		},
+		{
+			desc:         "unary with proto field names and numbers",
+			method:       http.MethodPost,
+			path:         "/helloworld.Greeter/SayHello",
+			body:         `{"first_name":"T","count":42,"mood":1}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"firstName":"T","count":"42","mood":"HAPPY"}`,
+		},
+		{
+			desc:         "well-known types",
+			method:       http.MethodPost,
+			path:         "/helloworld.Greeter/SayHello",
+			body:         `{"created":"2017-07-01T10:00:00Z","ttl":"30s","limit":5,"meta":{"a":[1,"b",null]}}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"created":"2017-07-01T10:00:00.000Z","ttl":"30.000s","limit":"5","meta":{"a":[1,"b",null]}}`,
+		},
+		{
+			desc:         "invalid well-known type",
+			method:       http.MethodPost,
+			path:         "/helloworld.Greeter/SayHello",
+			body:         `{"created":"yesterday"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "response too large",
+			method:       http.MethodPost,
+			path:         "/helloworld.Greeter/SayHello",
+			body:         `{"name":"huge"}`,
+			expectedCode: http.StatusBadGateway,
+			expectedBody: `{"code":8,"message":"response larger than 4194304 bytes"}`,
+		},
+		{
+			desc:         "empty request",
+			method:       http.MethodPost,
+			path:         "/helloworld.Greeter/SayHello",
+			expectedCode: http.StatusOK,
+			expectedBody: `{}`,
+		},
+		{
+			desc:         "server streaming",
+			method:       http.MethodPost,
+			path:         "/helloworld.Greeter/StreamHello",
+			body:         `{"name":"traefik"}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `[{"name":"traefik"},{"name":"traefik"}]`,
+		},
+		{
+			desc:         "gRPC error",
+			method:       http.MethodPost,
+			path:         "/helloworld.Greeter/SayHello",
+			body:         `{"name":"nobody"}`,
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"code":5,"message":"nobody here"}`,
+		},
+		{
+			desc:         "unknown field",
+			method:       http.MethodPost,
+			path:         "/helloworld.Greeter/SayHello",
+			body:         `{"surname":"traefik"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"code":3,"message":"unknown field \"surname\" of message helloworld.HelloRequest"}`,
+		},
+		{
+			desc:         "invalid JSON",
+			method:       http.MethodPost,
+			path:         "/helloworld.Greeter/SayHello",
+			body:         `[]`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "client streaming",
+			method:       http.MethodPost,
+			path:         "/helloworld.Greeter/UploadHello",
+			body:         `{}`,
+			expectedCode: http.StatusNotImplemented,
+			expectedBody: `{"code":12,"message":"client streaming methods are not supported"}`,
+		},
+		{
+			desc:         "not POST",
+			method:       http.MethodGet,
+			path:         "/helloworld.Greeter/SayHello",
+			expectedCode: http.StatusMethodNotAllowed,
+			expectedBody: `{"code":12,"message":"method not allowed"}`,
+		},
+		{
+			desc:         "other path",
+			method:       http.MethodGet,
+			path:         "/health",
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "gRPC request",
+			method:       http.MethodPost,
+			path:         "/helloworld.Greeter/SayHello",
+			contentType:  "application/grpc+proto",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			req := testhelpers.MustNewRequest(test.method, "http://localhost"+test.path, bytes.NewReader([]byte(test.body)))
+			req.Header.Set("Content-Type", "application/json")
+			if test.contentType != "" {
+				req.Header.Set("Content-Type", test.contentType)
+			}
+			recorder := httptest.NewRecorder()
+			transcoder.ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedCode, recorder.Code)
+			if test.expectedBody != "" {
+				assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+				assert.JSONEq(t, test.expectedBody, recorder.Body.String())
+			}
+			if test.path == "/health" || test.contentType != "" {
+				assert.Equal(t, "not gRPC "+test.path, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestGRPCTranscoderServices(t *testing.T) {
+	descriptorSet := writeDescriptorSet(t)
+	defer os.Remove(descriptorSet)
+
+	_, err := NewGRPCTranscoder(http.NotFoundHandler(), &types.GRPCTranscoding{DescriptorSet: descriptorSet, Services: []string{"helloworld.Greeter"}})
+	assert.NoError(t, err)
+	_, err = NewGRPCTranscoder(http.NotFoundHandler(), &types.GRPCTranscoding{DescriptorSet: descriptorSet, Services: []string{"helloworld.Farewell"}})
+	assert.Error(t, err)
+	_, err = NewGRPCTranscoder(http.NotFoundHandler(), &types.GRPCTranscoding{DescriptorSet: descriptorSet + ".missing"})
+	assert.Error(t, err)
+}
+
+func TestDecodeGRPCMessagePacked(t *testing.T) {
+	descriptors, err := parseGRPCDescriptorSet(helloWorldDescriptorSet())
+	require.NoError(t, err)
+	message := descriptors.messages[".helloworld.HelloRequest"]
+	require.NotNil(t, message)
+
+	packed := proto.NewBuffer(nil)
+	packed.EncodeVarint(1)
+	packed.EncodeVarint(300)
+	buf := proto.NewBuffer(nil)
+	buf.EncodeVarint(3<<3 | wireBytes)
+	buf.EncodeRawBytes(packed.Bytes())
+	buf.EncodeVarint(3<<3 | wireVarint)
+	buf.EncodeVarint(7)
+	buf.EncodeVarint(99<<3 | wireVarint)
+	buf.EncodeVarint(1)
+
+	actual, err := decodeGRPCMessage(message, buf.Bytes())
+
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"ids":[1,300,7]}`, string(actual))
+}
+
+func TestProtoJSONName(t *testing.T) {
+	assert.Equal(t, "firstName", protoJSONName("first_name"))
+	assert.Equal(t, "name", protoJSONName("name"))
+	assert.Equal(t, "a1B", protoJSONName("a1_b"))
+}
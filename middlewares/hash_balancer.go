@@ -0,0 +1,152 @@
+package middlewares
+
+import (
+	"errors"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/roundrobin"
+	"github.com/vulcand/oxy/utils"
+)
+
+// hashReplicas is the number of points of a server of weight 1 on the hash ring
+const hashReplicas = 100
+
+// HashBalancer is a consistent hashing load balancer: the requests of a client go to the same
+// server as long as it is available, and only the clients of the servers added or removed move.
+// The servers are placed on the hash ring by name rather than by URL, so that the clients stick
+// to a server replaced at another address under the same name, e.g. a redeployed Marathon task
+// identified by its instance index.
+type HashBalancer struct {
+	next     http.Handler
+	strategy ClientIPStrategy
+	// backendServers are the servers of the backend, by URL
+	backendServers map[string]types.Server
+	names          map[string]string
+
+	lock    sync.RWMutex
+	servers map[string]*url.URL
+	ring    []hashRingPoint
+}
+
+type hashRingPoint struct {
+	hash uint32
+	url  *url.URL
+}
+
+// NewHashBalancer builds a new HashBalancer hashing the IP of the clients determined by the strategy,
+// the names and weights of the servers being looked up in the servers of the backend
+func NewHashBalancer(next http.Handler, strategy ClientIPStrategy, backendServers map[string]types.Server) *HashBalancer {
+	if strategy == nil {
+		strategy = RemoteAddrStrategy{}
+	}
+	h := &HashBalancer{
+		next:           next,
+		strategy:       strategy,
+		backendServers: make(map[string]types.Server),
+		names:          make(map[string]string),
+		servers:        make(map[string]*url.URL),
+	}
+	for name, server := range backendServers {
+		h.backendServers[server.URL] = server
+		h.names[server.URL] = name
+	}
+	return h
+}
+
+// UpsertServer adds a server to the hash ring, the server options being ignored
+func (h *HashBalancer) UpsertServer(u *url.URL, options ...roundrobin.ServerOption) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.servers[u.String()] = utils.CopyURL(u)
+	h.buildRing()
+	return nil
+}
+
+// RemoveServer removes a server from the hash ring
+func (h *HashBalancer) RemoveServer(u *url.URL) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if _, ok := h.servers[u.String()]; !ok {
+		return errors.New("server not found")
+	}
+	delete(h.servers, u.String())
+	h.buildRing()
+	return nil
+}
+
+// Servers returns the servers on the hash ring
+func (h *HashBalancer) Servers() []*url.URL {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	servers := make([]*url.URL, 0, len(h.servers))
+	for _, u := range h.servers {
+		servers = append(servers, utils.CopyURL(u))
+	}
+	return servers
+}
+
+func (h *HashBalancer) buildRing() {
+	h.ring = h.ring[:0]
+	for key, u := range h.servers {
+		name, ok := h.names[key]
+		if !ok {
+			name = key
+		}
+		weight := h.backendServers[key].Weight
+		if weight < 1 {
+			weight = 1
+		}
+		for i := 0; i < hashReplicas*weight; i++ {
+			h.ring = append(h.ring, hashRingPoint{hash: hash(name + "#" + strconv.Itoa(i)), url: u})
+		}
+	}
+	sort.Slice(h.ring, func(i, j int) bool {
+		if h.ring[i].hash == h.ring[j].hash {
+			return h.ring[i].url.String() < h.ring[j].url.String()
+		}
+		return h.ring[i].hash < h.ring[j].hash
+	})
+}
+
+// lookup returns the server of the key, the first one clockwise from the key on the hash ring
+func (h *HashBalancer) lookup(key string) *url.URL {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	if len(h.ring) == 0 {
+		return nil
+	}
+	keyHash := hash(key)
+	i := sort.Search(len(h.ring), func(i int) bool {
+		return h.ring[i].hash >= keyHash
+	})
+	if i == len(h.ring) {
+		i = 0
+	}
+	return utils.CopyURL(h.ring[i].url)
+}
+
+func (h *HashBalancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u := h.lookup(h.strategy.ClientIP(r))
+	if u == nil {
+		log.Debugf("No server available for %s", r.URL)
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	// make shallow copy of request before changing anything to avoid side effects
+	newReq := *r
+	newReq.URL = u
+	h.next.ServeHTTP(w, &newReq)
+}
+
+func hash(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
@@ -0,0 +1,111 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hashTarget returns the host of the server the balancer sends the requests of the client to
+func hashTarget(t *testing.T, balancer *HashBalancer, clientIP string) string {
+	req := testhelpers.MustNewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = clientIP + ":1234"
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	return recorder.Body.String()
+}
+
+func newTestHashBalancer(t *testing.T, servers map[string]types.Server) *HashBalancer {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Host)
+	})
+	balancer := NewHashBalancer(next, nil, servers)
+	for _, server := range servers {
+		require.NoError(t, balancer.UpsertServer(testhelpers.MustParseURL(server.URL)))
+	}
+	return balancer
+}
+
+func TestHashBalancerNoServer(t *testing.T) {
+	balancer := NewHashBalancer(http.NotFoundHandler(), nil, nil)
+
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, testhelpers.MustNewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
+
+func TestHashBalancerSticksToServerName(t *testing.T) {
+	balancer := newTestHashBalancer(t, map[string]types.Server{
+		"server-0": {URL: "http://10.0.0.1:80"},
+		"server-1": {URL: "http://10.0.0.2:80"},
+		"server-2": {URL: "http://10.0.0.3:80"},
+	})
+	redeployed := newTestHashBalancer(t, map[string]types.Server{
+		"server-0": {URL: "http://10.0.1.1:31000"},
+		"server-1": {URL: "http://10.0.1.2:31000"},
+		"server-2": {URL: "http://10.0.1.3:31000"},
+	})
+	redeployedHosts := map[string]string{
+		"10.0.0.1:80": "10.0.1.1:31000",
+		"10.0.0.2:80": "10.0.1.2:31000",
+		"10.0.0.3:80": "10.0.1.3:31000",
+	}
+
+	targets := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		clientIP := fmt.Sprintf("192.168.0.%d", i)
+		target := hashTarget(t, balancer, clientIP)
+		targets[target] = true
+
+		assert.Equal(t, target, hashTarget(t, balancer, clientIP), "same client")
+		assert.Equal(t, redeployedHosts[target], hashTarget(t, redeployed, clientIP), "same server name")
+	}
+	assert.Len(t, targets, 3, "clients spread across the servers")
+}
+
+func TestHashBalancerRemoveServer(t *testing.T) {
+	balancer := newTestHashBalancer(t, map[string]types.Server{
+		"server-0": {URL: "http://10.0.0.1:80"},
+		"server-1": {URL: "http://10.0.0.2:80"},
+		"server-2": {URL: "http://10.0.0.3:80"},
+	})
+
+	before := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		clientIP := fmt.Sprintf("192.168.0.%d", i)
+		before[clientIP] = hashTarget(t, balancer, clientIP)
+	}
+
+	removed := testhelpers.MustParseURL("http://10.0.0.2:80")
+	require.NoError(t, balancer.RemoveServer(removed))
+	assert.Error(t, balancer.RemoveServer(removed))
+	assert.Len(t, balancer.Servers(), 2)
+
+	for clientIP, target := range before {
+		after := hashTarget(t, balancer, clientIP)
+		if target == removed.Host {
+			assert.NotEqual(t, removed.Host, after)
+		} else {
+			assert.Equal(t, target, after, "only the clients of the removed server move")
+		}
+	}
+}
+
+func TestHashBalancerServers(t *testing.T) {
+	balancer := newTestHashBalancer(t, map[string]types.Server{
+		"server-0": {URL: "http://10.0.0.1:80", Weight: 2},
+	})
+	require.NoError(t, balancer.UpsertServer(&url.URL{Scheme: "http", Host: "10.0.0.1:80"}))
+
+	assert.Equal(t, []*url.URL{testhelpers.MustParseURL("http://10.0.0.1:80")}, balancer.Servers())
+	assert.Len(t, balancer.ring, 2*hashReplicas)
+}
@@ -14,16 +14,21 @@ import (
 type IPWhitelister struct {
 	handler    negroni.Handler
 	whitelists []*net.IPNet
+	strategy   ClientIPStrategy
 }
 
-// NewIPWhitelister builds a new IPWhitelister given a list of CIDR-Strings to whitelist
-func NewIPWhitelister(whitelistStrings []string) (*IPWhitelister, error) {
+// NewIPWhitelister builds a new IPWhitelister given a list of CIDR-Strings to whitelist,
+// the IP of the clients being determined by the strategy, the remote address by default
+func NewIPWhitelister(whitelistStrings []string, strategy ClientIPStrategy) (*IPWhitelister, error) {
 
 	if len(whitelistStrings) == 0 {
 		return nil, errors.New("no whitelists provided")
 	}
 
-	whitelister := IPWhitelister{}
+	if strategy == nil {
+		strategy = RemoteAddrStrategy{}
+	}
+	whitelister := IPWhitelister{strategy: strategy}
 
 	for _, whitelistString := range whitelistStrings {
 		_, whitelist, err := net.ParseCIDR(whitelistString)
@@ -40,9 +45,9 @@ func NewIPWhitelister(whitelistStrings []string) (*IPWhitelister, error) {
 }
 
 func (whitelister *IPWhitelister) handle(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	remoteIP, err := ipFromRemoteAddr(r.RemoteAddr)
+	remoteIP, err := parseClientIP(whitelister.strategy.ClientIP(r))
 	if err != nil {
-		log.Warnf("unable to parse remote-address from header: %s - rejecting", r.RemoteAddr)
+		log.Warnf("unable to determine the source-IP of the request from %s: %s - rejecting", r.RemoteAddr, err)
 		reject(w)
 		return
 	}
@@ -67,10 +72,9 @@ func reject(w http.ResponseWriter) {
 	w.Write([]byte(http.StatusText(statusCode)))
 }
 
-func ipFromRemoteAddr(addr string) (*net.IP, error) {
-	ip, _, err := net.SplitHostPort(addr)
-	if err != nil {
-		return nil, fmt.Errorf("can't extract IP/Port from address %s: %s", addr, err)
+func parseClientIP(ip string) (*net.IP, error) {
+	if ip == "" {
+		return nil, errors.New("no client IP")
 	}
 
 	userIP := net.ParseIP(ip)
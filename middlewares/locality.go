@@ -0,0 +1,109 @@
+package middlewares
+
+import (
+	"math/rand"
+	"net/http"
+	"net/url"
+
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+// LocalityPool is a load balancer used by the LocalityBalancer for the servers of one locality
+type LocalityPool interface {
+	http.Handler
+	UpsertServer(u *url.URL, options ...roundrobin.ServerOption) error
+	RemoveServer(u *url.URL) error
+	Servers() []*url.URL
+}
+
+// LocalityBalancer is a load balancer that prefers the servers located in the same zone
+// as traefik. Requests spill over to remote servers only when fewer than minLocalServers
+// local servers are available (e.g. removed by the health check).
+// With sticky sessions, the requests of a client stuck to an available server are sent to
+// the pool of this server, wherever it is located.
+type LocalityBalancer struct {
+	local           LocalityPool
+	remote          LocalityPool
+	localServers    map[string]bool
+	minLocalServers int
+	sticky          *roundrobin.StickySession
+}
+
+// NewLocalityBalancer builds a new LocalityBalancer, servers listed in localServers are
+// added to the local pool while the other ones go to the remote pool.
+// sticky is the sticky session of both pools, nil without sticky sessions.
+func NewLocalityBalancer(local, remote LocalityPool, localServers []*url.URL, minLocalServers int, sticky *roundrobin.StickySession) *LocalityBalancer {
+	if minLocalServers < 1 {
+		minLocalServers = 1
+	}
+	lb := &LocalityBalancer{
+		local:           local,
+		remote:          remote,
+		localServers:    make(map[string]bool),
+		minLocalServers: minLocalServers,
+		sticky:          sticky,
+	}
+	for _, u := range localServers {
+		lb.localServers[u.String()] = true
+	}
+	return lb
+}
+
+func (lb *LocalityBalancer) pool(u *url.URL) LocalityPool {
+	if lb.localServers[u.String()] {
+		return lb.local
+	}
+	return lb.remote
+}
+
+// UpsertServer adds or updates a server in the pool of its locality
+func (lb *LocalityBalancer) UpsertServer(u *url.URL, options ...roundrobin.ServerOption) error {
+	return lb.pool(u).UpsertServer(u, options...)
+}
+
+// RemoveServer removes a server from the pool of its locality
+func (lb *LocalityBalancer) RemoveServer(u *url.URL) error {
+	return lb.pool(u).RemoveServer(u)
+}
+
+// Servers returns the servers of every locality
+func (lb *LocalityBalancer) Servers() []*url.URL {
+	return append(lb.local.Servers(), lb.remote.Servers()...)
+}
+
+func (lb *LocalityBalancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if pool := lb.stickyPool(r); pool != nil {
+		pool.ServeHTTP(w, r)
+		return
+	}
+
+	localCount := len(lb.local.Servers())
+	remoteCount := len(lb.remote.Servers())
+
+	switch {
+	case localCount >= lb.minLocalServers || remoteCount == 0:
+		lb.local.ServeHTTP(w, r)
+	case localCount == 0:
+		lb.remote.ServeHTTP(w, r)
+	default:
+		// degraded local capacity: spread the load across every available server
+		if rand.Intn(localCount+remoteCount) < localCount {
+			lb.local.ServeHTTP(w, r)
+		} else {
+			lb.remote.ServeHTTP(w, r)
+		}
+	}
+}
+
+// stickyPool returns the pool of the available server the client is stuck to, if any
+func (lb *LocalityBalancer) stickyPool(r *http.Request) LocalityPool {
+	if lb.sticky == nil {
+		return nil
+	}
+	for _, pool := range []LocalityPool{lb.local, lb.remote} {
+		if _, ok, _ := lb.sticky.GetBackend(r, pool.Servers()); ok {
+			return pool
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+func newLocalityTestPool(t *testing.T, name string) *roundrobin.RoundRobin {
+	rr, err := roundrobin.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name))
+	}))
+	require.NoError(t, err)
+	return rr
+}
+
+func TestLocalityBalancer(t *testing.T) {
+	localServer := testhelpers.MustParseURL("http://10.0.0.1:80")
+	otherLocalServer := testhelpers.MustParseURL("http://10.0.0.2:80")
+	remoteServer := testhelpers.MustParseURL("http://10.1.0.1:80")
+
+	cases := []struct {
+		desc            string
+		servers         []*url.URL
+		minLocalServers int
+		expected        []string
+	}{
+		{
+			desc:            "local servers available",
+			servers:         []*url.URL{localServer, otherLocalServer, remoteServer},
+			minLocalServers: 1,
+			expected:        []string{"local"},
+		},
+		{
+			desc:            "no local server available",
+			servers:         []*url.URL{remoteServer},
+			minLocalServers: 1,
+			expected:        []string{"remote"},
+		},
+		{
+			desc:            "degraded local capacity",
+			servers:         []*url.URL{localServer, remoteServer},
+			minLocalServers: 2,
+			expected:        []string{"local", "remote"},
+		},
+		{
+			desc:            "no remote server to spill over to",
+			servers:         []*url.URL{localServer},
+			minLocalServers: 2,
+			expected:        []string{"local"},
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			lb := NewLocalityBalancer(newLocalityTestPool(t, "local"), newLocalityTestPool(t, "remote"),
+				[]*url.URL{localServer, otherLocalServer}, test.minLocalServers, nil)
+			for _, server := range test.servers {
+				require.NoError(t, lb.UpsertServer(server))
+			}
+			assert.Len(t, lb.Servers(), len(test.servers))
+
+			seen := map[string]bool{}
+			for i := 0; i < 100; i++ {
+				recorder := httptest.NewRecorder()
+				lb.ServeHTTP(recorder, testhelpers.MustNewRequest(http.MethodGet, "http://example.com/", nil))
+				seen[recorder.Body.String()] = true
+			}
+			for _, pool := range test.expected {
+				assert.True(t, seen[pool], "pool %s should have been used", pool)
+			}
+			assert.Len(t, seen, len(test.expected))
+		})
+	}
+}
+
+func TestLocalityBalancerRemoveServer(t *testing.T) {
+	localServer := testhelpers.MustParseURL("http://10.0.0.1:80")
+	remoteServer := testhelpers.MustParseURL("http://10.1.0.1:80")
+
+	lb := NewLocalityBalancer(newLocalityTestPool(t, "local"), newLocalityTestPool(t, "remote"), []*url.URL{localServer}, 1, nil)
+	require.NoError(t, lb.UpsertServer(localServer))
+	require.NoError(t, lb.UpsertServer(remoteServer))
+	require.NoError(t, lb.RemoveServer(localServer))
+
+	recorder := httptest.NewRecorder()
+	lb.ServeHTTP(recorder, testhelpers.MustNewRequest(http.MethodGet, "http://example.com/", nil))
+	assert.Equal(t, "remote", recorder.Body.String())
+}
+
+func TestLocalityBalancerStickySession(t *testing.T) {
+	localServer := testhelpers.MustParseURL("http://10.0.0.1:80")
+	remoteServer := testhelpers.MustParseURL("http://10.1.0.1:80")
+	removedServer := testhelpers.MustParseURL("http://10.1.0.2:80")
+
+	cases := []struct {
+		desc     string
+		cookie   *http.Cookie
+		expected string
+	}{
+		{
+			desc:     "no cookie",
+			expected: "local",
+		},
+		{
+			desc:     "stuck to a remote server",
+			cookie:   &http.Cookie{Name: "_TRAEFIK_BACKEND_test", Value: remoteServer.String()},
+			expected: "remote",
+		},
+		{
+			desc:     "stuck to a local server",
+			cookie:   &http.Cookie{Name: "_TRAEFIK_BACKEND_test", Value: localServer.String()},
+			expected: "local",
+		},
+		{
+			desc:     "stuck to a removed server",
+			cookie:   &http.Cookie{Name: "_TRAEFIK_BACKEND_test", Value: removedServer.String()},
+			expected: "local",
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			lb := NewLocalityBalancer(newLocalityTestPool(t, "local"), newLocalityTestPool(t, "remote"),
+				[]*url.URL{localServer}, 1, roundrobin.NewStickySession("_TRAEFIK_BACKEND_test"))
+			require.NoError(t, lb.UpsertServer(localServer))
+			require.NoError(t, lb.UpsertServer(remoteServer))
+
+			req := testhelpers.MustNewRequest(http.MethodGet, "http://example.com/", nil)
+			if test.cookie != nil {
+				req.AddCookie(test.cookie)
+			}
+			recorder := httptest.NewRecorder()
+			lb.ServeHTTP(recorder, req)
+			assert.Equal(t, test.expected, recorder.Body.String())
+		})
+	}
+}
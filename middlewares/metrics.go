@@ -26,6 +26,8 @@ type RetryMetrics interface {
 // given Metrics implementation to expose and monitor Traefik Metrics.
 type MetricsWrapper struct {
 	Impl Metrics
+	// Enabled, when set, tells whether the metrics of a request are collected
+	Enabled func(r *http.Request) bool
 }
 
 // NewMetricsWrapper return a MetricsWrapper struct with
@@ -39,6 +41,11 @@ func NewMetricsWrapper(impl Metrics) *MetricsWrapper {
 }
 
 func (m *MetricsWrapper) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if m.Enabled != nil && !m.Enabled(r) {
+		next(rw, r)
+		return
+	}
+
 	start := time.Now()
 	prw := &responseRecorder{rw, http.StatusOK}
 	next(prw, r)
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/containous/mux"
+	"github.com/containous/traefik/types"
+)
+
+// DefaultPathParamsHeaderPrefix is the prefix of the headers forwarding the path parameters when none is configured
+const DefaultPathParamsHeaderPrefix = "X-Path-Param-"
+
+// PathParams is a middleware forwarding the named segments matched by the route of a frontend
+// to the backend as request headers, e.g. {id} in Path:/tenants/{id} as X-Path-Param-Id.
+// It must wrap the route handler, where the matched segments are available.
+type PathParams struct {
+	handler http.Handler
+	prefix  string
+	names   map[string]bool
+}
+
+// NewPathParams builds a new PathParams middleware
+func NewPathParams(handler http.Handler, config *types.PathParams) *PathParams {
+	pathParams := &PathParams{
+		handler: handler,
+		prefix:  DefaultPathParamsHeaderPrefix,
+	}
+	if config != nil {
+		if len(config.HeaderPrefix) > 0 {
+			pathParams.prefix = http.CanonicalHeaderKey(config.HeaderPrefix)
+		}
+		if len(config.Names) > 0 {
+			pathParams.names = make(map[string]bool)
+			for _, name := range config.Names {
+				pathParams.names[strings.TrimSpace(name)] = true
+			}
+		}
+	}
+	return pathParams
+}
+
+func (p *PathParams) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// headers sent by the client must not be mistaken for matched segments
+	for name := range r.Header {
+		if strings.HasPrefix(name, p.prefix) {
+			r.Header.Del(name)
+		}
+	}
+
+	for name, value := range mux.Vars(r) {
+		if p.names != nil && !p.names[name] {
+			continue
+		}
+		r.Header.Set(p.prefix+name, value)
+	}
+	p.handler.ServeHTTP(w, r)
+}
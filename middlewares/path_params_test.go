@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/mux"
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathParams(t *testing.T) {
+	cases := []struct {
+		desc           string
+		config         *types.PathParams
+		requestHeaders map[string]string
+		expected       http.Header
+	}{
+		{
+			desc:   "all segments with the default prefix",
+			config: &types.PathParams{},
+			expected: http.Header{
+				"X-Path-Param-Id":      {"42"},
+				"X-Path-Param-Section": {"orders"},
+			},
+		},
+		{
+			desc:   "selected segments",
+			config: &types.PathParams{Names: []string{"id"}},
+			expected: http.Header{
+				"X-Path-Param-Id": {"42"},
+			},
+		},
+		{
+			desc:   "custom prefix",
+			config: &types.PathParams{Names: []string{"id"}, HeaderPrefix: "x-tenant-"},
+			expected: http.Header{
+				"X-Tenant-Id": {"42"},
+			},
+		},
+		{
+			desc:   "client headers are not forwarded",
+			config: &types.PathParams{Names: []string{"id"}},
+			requestHeaders: map[string]string{
+				"X-Path-Param-Id":      "1",
+				"X-Path-Param-Section": "admin",
+				"X-Other":              "kept",
+			},
+			expected: http.Header{
+				"X-Path-Param-Id": {"42"},
+				"X-Other":         {"kept"},
+			},
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			var forwarded http.Header
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				forwarded = r.Header
+			})
+			router := mux.NewRouter()
+			router.Path("/tenants/{id:[0-9]+}/{section}").Handler(NewPathParams(next, test.config))
+
+			req := testhelpers.MustNewRequest(http.MethodGet, "http://localhost/tenants/42/orders", nil)
+			for name, value := range test.requestHeaders {
+				req.Header.Set(name, value)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, test.expected, forwarded)
+		})
+	}
+}
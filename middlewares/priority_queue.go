@@ -0,0 +1,196 @@
+package middlewares
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+)
+
+const (
+	// PriorityHigh is the priority class of the requests forwarded first
+	PriorityHigh = "high"
+	// PriorityBulk is the priority class of the other requests
+	PriorityBulk = "bulk"
+	// PriorityHeader trusts the priority header of the backend to set the priority class of the requests
+	PriorityHeader = "header"
+	// DefaultPriorityQueueMaxQueued is the maximum number of queued requests of a backend, when none is configured
+	DefaultPriorityQueueMaxQueued = 1000
+)
+
+var (
+	errPriorityQueueFull    = errors.New("queue full")
+	errPriorityQueueTimeout = errors.New("queue timeout")
+)
+
+type requestPriorityKey struct{}
+
+// RequestPriority is a middleware setting the priority class of the requests of a frontend, or letting
+// the priority header of the backend set it. The priority header sent by the clients is ignored unless
+// their frontend trusts it.
+// It must be the innermost handler of the frontend, the route variables being bound to the original request.
+type RequestPriority struct {
+	handler  http.Handler
+	priority string
+}
+
+// NewRequestPriority builds a new RequestPriority middleware for the "high" or "bulk" priority class,
+// or trusting the priority header with "header"
+func NewRequestPriority(handler http.Handler, priority string) (*RequestPriority, error) {
+	switch priority {
+	case PriorityHigh, PriorityBulk, PriorityHeader:
+		return &RequestPriority{handler: handler, priority: priority}, nil
+	default:
+		return nil, fmt.Errorf("invalid request priority %q, expected %q, %q or %q", priority, PriorityHigh, PriorityBulk, PriorityHeader)
+	}
+}
+
+func (p *RequestPriority) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	p.handler.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), requestPriorityKey{}, p.priority)))
+}
+
+// priorityWaiter is a request waiting in the queue, granted a slot by a completed request
+type priorityWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// PriorityQueue is a middleware limiting the requests in flight to a backend, the requests beyond the limit
+// waiting in a queue from which the high priority requests are dequeued before the bulk ones
+type PriorityQueue struct {
+	next           http.Handler
+	maxConcurrency int64
+	maxQueued      int
+	timeout        time.Duration
+	header         string
+	lock           sync.Mutex
+	inFlight       int64
+	high           *list.List
+	bulk           *list.List
+}
+
+// NewPriorityQueue builds a new PriorityQueue middleware given a config
+func NewPriorityQueue(next http.Handler, config *types.PriorityQueue) (*PriorityQueue, error) {
+	if config.MaxConcurrency <= 0 {
+		return nil, fmt.Errorf("invalid priority queue max concurrency %d", config.MaxConcurrency)
+	}
+	q := &PriorityQueue{
+		next:           next,
+		maxConcurrency: config.MaxConcurrency,
+		maxQueued:      DefaultPriorityQueueMaxQueued,
+		header:         config.Header,
+		high:           list.New(),
+		bulk:           list.New(),
+	}
+	if config.MaxQueued > 0 {
+		q.maxQueued = config.MaxQueued
+	}
+	if len(config.Timeout) > 0 {
+		timeout, err := time.ParseDuration(config.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid priority queue timeout %q: %v", config.Timeout, err)
+		}
+		q.timeout = timeout
+	}
+	return q, nil
+}
+
+func (q *PriorityQueue) isHigh(r *http.Request) bool {
+	switch r.Context().Value(requestPriorityKey{}) {
+	case PriorityHigh:
+		return true
+	case PriorityHeader:
+		return q.header != "" && r.Header.Get(q.header) == PriorityHigh
+	default:
+		return false
+	}
+}
+
+func (q *PriorityQueue) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	high := q.isHigh(r)
+	if err := q.acquire(r.Context(), high); err != nil {
+		if r.Context().Err() != nil {
+			return
+		}
+		log.Debugf("Rejecting %s %s, priority high=%t: %s", r.Method, r.URL.Path, high, err)
+		http.Error(rw, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	defer q.release()
+	q.next.ServeHTTP(rw, r)
+}
+
+// acquire waits for a slot, returning an error when the queue is full, on timeout, or when the request is cancelled
+func (q *PriorityQueue) acquire(ctx context.Context, high bool) error {
+	q.lock.Lock()
+	if q.inFlight < q.maxConcurrency && q.high.Len() == 0 && q.bulk.Len() == 0 {
+		q.inFlight++
+		q.lock.Unlock()
+		return nil
+	}
+	if q.high.Len()+q.bulk.Len() >= q.maxQueued {
+		q.lock.Unlock()
+		return errPriorityQueueFull
+	}
+	queue := q.bulk
+	if high {
+		queue = q.high
+	}
+	waiter := &priorityWaiter{ready: make(chan struct{})}
+	element := queue.PushBack(waiter)
+	q.lock.Unlock()
+
+	var timeout <-chan time.Time
+	if q.timeout > 0 {
+		timer := time.NewTimer(q.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-waiter.ready:
+		return nil
+	case <-timeout:
+		err = errPriorityQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if waiter.granted {
+		// granted meanwhile, the slot is given back
+		q.releaseLocked()
+		return err
+	}
+	queue.Remove(element)
+	return err
+}
+
+func (q *PriorityQueue) release() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.releaseLocked()
+}
+
+// releaseLocked hands the slot of a completed request over to the next queued request, if any
+func (q *PriorityQueue) releaseLocked() {
+	queue := q.high
+	if queue.Len() == 0 {
+		queue = q.bulk
+	}
+	if queue.Len() == 0 {
+		q.inFlight--
+		return
+	}
+	waiter := queue.Remove(queue.Front()).(*priorityWaiter)
+	waiter.granted = true
+	close(waiter.ready)
+}
@@ -0,0 +1,185 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitQueued waits until the queue holds the given numbers of high and bulk requests
+func waitQueued(t *testing.T, queue *PriorityQueue, high, bulk int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		queue.lock.Lock()
+		queued := queue.high.Len() == high && queue.bulk.Len() == bulk
+		queue.lock.Unlock()
+		if queued {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d high and %d bulk queued requests", high, bulk)
+}
+
+// waitInFlight waits until the given number of requests are in flight
+func waitInFlight(t *testing.T, queue *PriorityQueue, inFlight int64) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		queue.lock.Lock()
+		current := queue.inFlight
+		queue.lock.Unlock()
+		if current == inFlight {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d requests in flight", inFlight)
+}
+
+func newPriorityRequest(name, priority string) *http.Request {
+	req := testhelpers.MustNewRequest(http.MethodGet, "http://example.com/"+name, nil)
+	if priority != "" {
+		req.Header.Set("X-Priority", priority)
+	}
+	return req
+}
+
+func TestPriorityQueueOrder(t *testing.T) {
+	release := make(chan struct{})
+	var lock sync.Mutex
+	var served []string
+	queue, err := NewPriorityQueue(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/blocking" {
+			<-release
+		}
+		lock.Lock()
+		served = append(served, r.URL.Path)
+		lock.Unlock()
+	}), &types.PriorityQueue{MaxConcurrency: 1, Header: "X-Priority"})
+	require.NoError(t, err)
+	bulkFrontend, err := NewRequestPriority(queue, PriorityBulk)
+	require.NoError(t, err)
+	headerFrontend, err := NewRequestPriority(queue, PriorityHeader)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	serve := func(handler http.Handler, req *http.Request) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			assert.Equal(t, http.StatusOK, recorder.Code, req.URL.Path)
+		}()
+	}
+
+	serve(queue, newPriorityRequest("blocking", ""))
+	waitInFlight(t, queue, 1)
+	serve(queue, newPriorityRequest("bulk", ""))
+	waitQueued(t, queue, 0, 1)
+	serve(bulkFrontend, newPriorityRequest("bulk-frontend", PriorityHigh))
+	waitQueued(t, queue, 0, 2)
+	// the priority header is ignored unless the frontend trusts it
+	serve(queue, newPriorityRequest("untrusted", PriorityHigh))
+	waitQueued(t, queue, 0, 3)
+	serve(headerFrontend, newPriorityRequest("high", PriorityHigh))
+	waitQueued(t, queue, 1, 3)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, []string{"/blocking", "/high", "/bulk", "/bulk-frontend", "/untrusted"}, served)
+	assert.EqualValues(t, 0, queue.inFlight)
+}
+
+func TestPriorityQueueRejections(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.PriorityQueue
+	}{
+		{
+			desc:   "queue full",
+			config: &types.PriorityQueue{MaxConcurrency: 1, MaxQueued: 1},
+		},
+		{
+			desc:   "queue timeout",
+			config: &types.PriorityQueue{MaxConcurrency: 1, MaxQueued: 10, Timeout: "10ms"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			release := make(chan struct{})
+			queue, err := NewPriorityQueue(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/blocking" {
+					<-release
+				}
+			}), test.config)
+			require.NoError(t, err)
+
+			done := make(chan struct{})
+			go func() {
+				queue.ServeHTTP(httptest.NewRecorder(), newPriorityRequest("blocking", ""))
+				close(done)
+			}()
+			waitInFlight(t, queue, 1)
+			queued := make(chan int)
+			go func() {
+				recorder := httptest.NewRecorder()
+				queue.ServeHTTP(recorder, newPriorityRequest("queued", ""))
+				queued <- recorder.Code
+			}()
+
+			if test.config.Timeout == "" {
+				waitQueued(t, queue, 0, 1)
+				recorder := httptest.NewRecorder()
+				queue.ServeHTTP(recorder, newPriorityRequest("rejected", PriorityHigh))
+				assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				close(release)
+				assert.Equal(t, http.StatusOK, <-queued)
+			} else {
+				assert.Equal(t, http.StatusServiceUnavailable, <-queued)
+				close(release)
+			}
+			<-done
+			waitQueued(t, queue, 0, 0)
+			waitInFlight(t, queue, 0)
+		})
+	}
+}
+
+func TestPriorityQueueInvalidConfig(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.PriorityQueue
+	}{
+		{
+			desc:   "no max concurrency",
+			config: &types.PriorityQueue{},
+		},
+		{
+			desc:   "invalid timeout",
+			config: &types.PriorityQueue{MaxConcurrency: 1, Timeout: "later"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewPriorityQueue(http.NotFoundHandler(), test.config)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := NewRequestPriority(http.NotFoundHandler(), "urgent")
+	assert.Error(t, err)
+}
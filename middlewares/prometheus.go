@@ -1,7 +1,9 @@
 package middlewares
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/containous/traefik/types"
 	"github.com/go-kit/kit/metrics"
@@ -10,19 +12,75 @@ import (
 )
 
 const (
-	reqsTotalName    = "traefik_requests_total"
-	reqDurationName  = "traefik_request_duration_seconds"
-	retriesTotalName = "traefik_backend_retries_total"
+	reqsTotalName     = "traefik_requests_total"
+	reqDurationName   = "traefik_request_duration_seconds"
+	retriesTotalName  = "traefik_backend_retries_total"
+	providerStaleName = "traefik_provider_stale"
+	idleConnsName     = "traefik_entrypoint_idle_connections"
+	reapedConnsName   = "traefik_entrypoint_idle_connections_reaped_total"
 )
 
+// idleConnsFrontends holds the frontends reported with idle connections, by entrypoint,
+// so that their gauge is reset once they have none, and the idle connections vectors,
+// registered on the first report
+var idleConnsFrontends = struct {
+	sync.Mutex
+	entryPoints map[string]map[string]bool
+	gauge       *stdprometheus.GaugeVec
+	counter     *stdprometheus.CounterVec
+}{entryPoints: make(map[string]map[string]bool)}
+
+var defaultBuckets = []float64{0.1, 0.3, 1.2, 5}
+
+// reqDurationHistograms holds the request duration histograms of every service,
+// so that their buckets can be changed at runtime
+var reqDurationHistograms = &histogramStore{vecs: make(map[string]*histogramVecCollector)}
+
+type histogramStore struct {
+	lock sync.RWMutex
+	vecs map[string]*histogramVecCollector
+	// buckets set at runtime, overriding the configured ones
+	buckets []float64
+}
+
+// histogramVecCollector is the collector registered for the request duration histograms of a service.
+// It collects the current HistogramVec, which is swapped when the buckets change: a HistogramVec with
+// other buckets has the same descriptors, and could not be registered alongside the current one.
+type histogramVecCollector struct {
+	lock sync.RWMutex
+	vec  *stdprometheus.HistogramVec
+}
+
+func (c *histogramVecCollector) get() *stdprometheus.HistogramVec {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.vec
+}
+
+func (c *histogramVecCollector) set(vec *stdprometheus.HistogramVec) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.vec = vec
+}
+
+// Describe implements prometheus.Collector
+func (c *histogramVecCollector) Describe(ch chan<- *stdprometheus.Desc) {
+	c.get().Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *histogramVecCollector) Collect(ch chan<- stdprometheus.Metric) {
+	c.get().Collect(ch)
+}
+
 // Prometheus is an Implementation for Metrics that exposes the following Prometheus metrics:
 // - number of requests partitioned by status code and method
 // - request durations partitioned by status code
 // - amount of retries happened
 type Prometheus struct {
-	reqsCounter          metrics.Counter
-	reqDurationHistogram metrics.Histogram
-	retryCounter         metrics.Counter
+	name         string
+	reqsCounter  metrics.Counter
+	retryCounter metrics.Counter
 }
 
 func (p *Prometheus) getReqsCounter() metrics.Counter {
@@ -30,7 +88,9 @@ func (p *Prometheus) getReqsCounter() metrics.Counter {
 }
 
 func (p *Prometheus) getReqDurationHistogram() metrics.Histogram {
-	return p.reqDurationHistogram
+	reqDurationHistograms.lock.RLock()
+	defer reqDurationHistograms.lock.RUnlock()
+	return prometheus.NewHistogram(reqDurationHistograms.vecs[p.name].get())
 }
 
 func (p *Prometheus) getRetryCounter() metrics.Counter {
@@ -42,7 +102,7 @@ func (p *Prometheus) getRetryCounter() metrics.Counter {
 // This is for example useful while testing the Prometheus implementation.
 // If any of the Prometheus Metrics can not be registered an error will be returned and the returned Metrics implementation will be nil.
 func NewPrometheus(name string, config *types.Prometheus) (*Prometheus, []stdprometheus.Collector, error) {
-	var prom Prometheus
+	prom := Prometheus{name: name}
 	var collectors []stdprometheus.Collector
 
 	cv := stdprometheus.NewCounterVec(
@@ -60,26 +120,21 @@ func NewPrometheus(name string, config *types.Prometheus) (*Prometheus, []stdpro
 	prom.reqsCounter = prometheus.NewCounter(cv)
 	collectors = append(collectors, cv)
 
-	var buckets []float64
-	if config.Buckets != nil {
+	reqDurationHistograms.lock.Lock()
+	buckets := reqDurationHistograms.buckets
+	if buckets == nil && config.Buckets != nil {
 		buckets = config.Buckets
-	} else {
-		buckets = []float64{0.1, 0.3, 1.2, 5}
+	} else if buckets == nil {
+		buckets = defaultBuckets
 	}
-	hv := stdprometheus.NewHistogramVec(
-		stdprometheus.HistogramOpts{
-			Name:        reqDurationName,
-			Help:        "How long it took to process the request.",
-			ConstLabels: stdprometheus.Labels{"service": name},
-			Buckets:     buckets,
-		},
-		[]string{"code"},
-	)
-	hv, err = registerHistogramVec(hv)
+	hv, err := registerHistogramVecCollector(&histogramVecCollector{vec: newReqDurationHistogramVec(name, buckets)})
+	if err == nil {
+		reqDurationHistograms.vecs[name] = hv
+	}
+	reqDurationHistograms.lock.Unlock()
 	if err != nil {
 		return nil, collectors, err
 	}
-	prom.reqDurationHistogram = prometheus.NewHistogram(hv)
 	collectors = append(collectors, hv)
 
 	cv = stdprometheus.NewCounterVec(
@@ -100,6 +155,111 @@ func NewPrometheus(name string, config *types.Prometheus) (*Prometheus, []stdpro
 	return &prom, collectors, nil
 }
 
+func newReqDurationHistogramVec(name string, buckets []float64) *stdprometheus.HistogramVec {
+	return stdprometheus.NewHistogramVec(
+		stdprometheus.HistogramOpts{
+			Name:        reqDurationName,
+			Help:        "How long it took to process the request.",
+			ConstLabels: stdprometheus.Labels{"service": name},
+			Buckets:     buckets,
+		},
+		[]string{"code"},
+	)
+}
+
+// SetPrometheusBuckets replaces the buckets of the request duration histograms of every service,
+// including the ones created afterwards. The histograms are reset, the new ones replacing the current
+// ones in the registered collectors, so that the histograms are never unregistered.
+func SetPrometheusBuckets(buckets []float64) error {
+	if len(buckets) == 0 {
+		return errors.New("at least one bucket is required")
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			return fmt.Errorf("buckets must be in increasing order: %v", buckets)
+		}
+	}
+
+	reqDurationHistograms.lock.Lock()
+	defer reqDurationHistograms.lock.Unlock()
+	reqDurationHistograms.buckets = append([]float64(nil), buckets...)
+	for name, collector := range reqDurationHistograms.vecs {
+		collector.set(newReqDurationHistogramVec(name, reqDurationHistograms.buckets))
+	}
+	return nil
+}
+
+// SetPrometheusProviderStale reports whether the provider failed to synchronize since its last
+// successful synchronization, its last configuration being kept active
+func SetPrometheusProviderStale(provider string, stale bool) error {
+	gv, err := registerGaugeVec(stdprometheus.NewGaugeVec(
+		stdprometheus.GaugeOpts{
+			Name: providerStaleName,
+			Help: "Whether the provider failed to synchronize since its last successful synchronization.",
+		},
+		[]string{"provider"},
+	))
+	if err != nil {
+		return err
+	}
+	value := 0.0
+	if stale {
+		value = 1
+	}
+	gv.WithLabelValues(provider).Set(value)
+	return nil
+}
+
+// SetPrometheusIdleConnections reports the idle keep-alive connections of the entrypoint and the idle
+// connections closed since the last report, by frontend of their last request
+func SetPrometheusIdleConnections(entryPoint string, idle map[string]int, reaped map[string]int) error {
+	idleConnsFrontends.Lock()
+	defer idleConnsFrontends.Unlock()
+	if idleConnsFrontends.gauge == nil {
+		gv, err := registerGaugeVec(stdprometheus.NewGaugeVec(
+			stdprometheus.GaugeOpts{
+				Name: idleConnsName,
+				Help: "How many client connections are idle, waiting for a keep-alive request, by frontend of their last request.",
+			},
+			[]string{"entrypoint", "frontend"},
+		))
+		if err != nil {
+			return err
+		}
+		cv, err := registerCounterVec(stdprometheus.NewCounterVec(
+			stdprometheus.CounterOpts{
+				Name: reapedConnsName,
+				Help: "How many idle client connections were closed after the idle timeout of the frontend of their last request.",
+			},
+			[]string{"entrypoint", "frontend"},
+		))
+		if err != nil {
+			return err
+		}
+		idleConnsFrontends.gauge, idleConnsFrontends.counter = gv, cv
+	}
+	gv, cv := idleConnsFrontends.gauge, idleConnsFrontends.counter
+
+	frontends := idleConnsFrontends.entryPoints[entryPoint]
+	if frontends == nil {
+		frontends = make(map[string]bool)
+		idleConnsFrontends.entryPoints[entryPoint] = frontends
+	}
+	for frontend := range frontends {
+		if _, ok := idle[frontend]; !ok {
+			gv.WithLabelValues(entryPoint, frontend).Set(0)
+		}
+	}
+	for frontend, count := range idle {
+		frontends[frontend] = true
+		gv.WithLabelValues(entryPoint, frontend).Set(float64(count))
+	}
+	for frontend, count := range reaped {
+		cv.WithLabelValues(entryPoint, frontend).Add(float64(count))
+	}
+	return nil
+}
+
 func registerCounterVec(cv *stdprometheus.CounterVec) (*stdprometheus.CounterVec, error) {
 	err := stdprometheus.Register(cv)
 
@@ -114,16 +274,34 @@ func registerCounterVec(cv *stdprometheus.CounterVec) (*stdprometheus.CounterVec
 	return cv, nil
 }
 
-func registerHistogramVec(hv *stdprometheus.HistogramVec) (*stdprometheus.HistogramVec, error) {
-	err := stdprometheus.Register(hv)
+func registerHistogramVecCollector(c *histogramVecCollector) (*histogramVecCollector, error) {
+	err := stdprometheus.Register(c)
 
 	if err != nil {
 		e, ok := err.(stdprometheus.AlreadyRegisteredError)
 		if !ok {
 			return nil, fmt.Errorf("error registering HistogramVec: %s", e)
 		}
-		hv = e.ExistingCollector.(*stdprometheus.HistogramVec)
+		existing, ok := e.ExistingCollector.(*histogramVecCollector)
+		if !ok {
+			return nil, fmt.Errorf("error registering HistogramVec: %s is registered by another collector", reqDurationName)
+		}
+		c = existing
+	}
+
+	return c, nil
+}
+
+func registerGaugeVec(gv *stdprometheus.GaugeVec) (*stdprometheus.GaugeVec, error) {
+	err := stdprometheus.Register(gv)
+
+	if err != nil {
+		e, ok := err.(stdprometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, fmt.Errorf("error registering GaugeVec: %s", err)
+		}
+		gv = e.ExistingCollector.(*stdprometheus.GaugeVec)
 	}
 
-	return hv, nil
+	return gv, nil
 }
@@ -15,6 +15,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPrometheus(t *testing.T) {
@@ -149,6 +150,63 @@ func TestPrometheusRegisterMetricsMultipleTimes(t *testing.T) {
 	}
 }
 
+func TestSetPrometheusBuckets(t *testing.T) {
+	defer resetPrometheusValues()
+	defer func() {
+		reqDurationHistograms.buckets = nil
+	}()
+
+	assert.Error(t, SetPrometheusBuckets(nil))
+	assert.Error(t, SetPrometheusBuckets([]float64{1, 0.5}))
+
+	metrics, _ := newPrometheusMetrics()
+	collector := reqDurationHistograms.vecs["test"]
+	require.NoError(t, SetPrometheusBuckets([]float64{0.25, 0.5}))
+	// the histograms are swapped in the registered collector, never unregistered
+	assert.True(t, collector == reqDurationHistograms.vecs["test"])
+	metrics.getReqDurationHistogram().With("code", "200").Observe(0.1)
+
+	metricsFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	family := findMetricFamily(reqDurationName, metricsFamilies)
+	require.NotNil(t, family)
+	var upperBounds []float64
+	for _, bucket := range family.Metric[0].Histogram.Bucket {
+		upperBounds = append(upperBounds, bucket.GetUpperBound())
+	}
+	assert.Equal(t, []float64{0.25, 0.5}, upperBounds)
+	assert.Equal(t, uint64(1), family.Metric[0].Histogram.GetSampleCount())
+}
+
+func TestSetPrometheusIdleConnections(t *testing.T) {
+	require.NoError(t, SetPrometheusIdleConnections("http", map[string]int{"bots": 3, "users": 1}, map[string]int{"bots": 2}))
+	require.NoError(t, SetPrometheusIdleConnections("http", map[string]int{"bots": 1}, map[string]int{"bots": 1}))
+
+	metricsFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	values := func(name string) map[string]float64 {
+		family := findMetricFamily(name, metricsFamilies)
+		require.NotNil(t, family, name)
+		values := make(map[string]float64)
+		for _, metric := range family.Metric {
+			for _, label := range metric.Label {
+				if label.GetName() != "frontend" {
+					continue
+				}
+				if metric.Gauge != nil {
+					values[label.GetValue()] = metric.Gauge.GetValue()
+				} else {
+					values[label.GetValue()] = metric.Counter.GetValue()
+				}
+			}
+		}
+		return values
+	}
+	assert.Equal(t, map[string]float64{"bots": 1, "users": 0}, values(idleConnsName))
+	assert.Equal(t, map[string]float64{"bots": 3}, values(reapedConnsName))
+}
+
 func setupTestHTTPHandler() http.Handler {
 	serveMux := http.NewServeMux()
 	serveMux.Handle("/metrics", promhttp.Handler())
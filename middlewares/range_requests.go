@@ -0,0 +1,144 @@
+package middlewares
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/containous/traefik/log"
+)
+
+const (
+	// RangeRequestsPassthrough forwards the range requests with their Range and If-Range headers,
+	// streaming the partial responses of the backend without buffering nor retrying them
+	RangeRequestsPassthrough = "passthrough"
+	// RangeRequestsBuffer buffers and retries the range requests like the other requests, the default
+	RangeRequestsBuffer = "buffer"
+	// RangeRequestsSynthesize forwards the range requests without their Range and If-Range headers,
+	// and serves the requested ranges from the complete response of the backend. The responses being
+	// streamed when they are not synthesized, the range requests are not retried.
+	RangeRequestsSynthesize = "synthesize"
+	// DefaultMaxSynthesizedRangeSize is the maximum size of the complete responses buffered to synthesize
+	// a range response, when none is configured
+	DefaultMaxSynthesizedRangeSize = 16 << 20
+)
+
+// SetRangeRequests sets how the requests with a Range header are handled, and the maximum size of the
+// complete responses buffered to synthesize their range responses
+func (retry *Retry) SetRangeRequests(mode string, maxSynthesizedSize int64) error {
+	switch mode {
+	case "", RangeRequestsPassthrough, RangeRequestsBuffer, RangeRequestsSynthesize:
+	default:
+		return fmt.Errorf("invalid range requests mode %q, expected %q, %q or %q", mode, RangeRequestsPassthrough, RangeRequestsBuffer, RangeRequestsSynthesize)
+	}
+	retry.rangeRequests = mode
+	retry.maxSynthesizedRangeSize = DefaultMaxSynthesizedRangeSize
+	if maxSynthesizedSize > 0 {
+		retry.maxSynthesizedRangeSize = maxSynthesizedSize
+	}
+	return nil
+}
+
+// serveRange serves a request with a Range header, returning false when it must be buffered and retried
+func (retry *Retry) serveRange(rw http.ResponseWriter, r *http.Request) bool {
+	switch retry.rangeRequests {
+	case RangeRequestsPassthrough:
+		retry.next.ServeHTTP(rw, r)
+	case RangeRequestsSynthesize:
+		outReq := r.WithContext(r.Context())
+		outReq.Header = make(http.Header, len(r.Header))
+		for name, values := range r.Header {
+			if name != "Range" && name != "If-Range" {
+				outReq.Header[name] = values
+			}
+		}
+		writer := &rangeSynthesisWriter{responseWriter: rw, max: retry.maxSynthesizedRangeSize}
+		retry.next.ServeHTTP(writer, outReq)
+		writer.serveRange(r)
+	default:
+		return false
+	}
+	return true
+}
+
+// rangeSynthesisWriter buffers a complete 200 response to serve the requested range from it,
+// streaming the other responses and the ones larger than max
+type rangeSynthesisWriter struct {
+	responseWriter http.ResponseWriter
+	max            int64
+	code           int
+	body           bytes.Buffer
+	streaming      bool
+}
+
+func (w *rangeSynthesisWriter) Header() http.Header {
+	return w.responseWriter.Header()
+}
+
+func (w *rangeSynthesisWriter) WriteHeader(code int) {
+	if w.code != 0 {
+		return
+	}
+	w.code = code
+	if code != http.StatusOK {
+		w.stream()
+		return
+	}
+	if length, err := strconv.ParseInt(w.Header().Get("Content-Length"), 10, 64); err == nil && length > w.max {
+		w.stream()
+	}
+}
+
+func (w *rangeSynthesisWriter) Write(buf []byte) (int, error) {
+	if w.code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.streaming {
+		return w.responseWriter.Write(buf)
+	}
+	if int64(w.body.Len()+len(buf)) > w.max {
+		if err := w.stream(); err != nil {
+			return 0, err
+		}
+		return w.responseWriter.Write(buf)
+	}
+	return w.body.Write(buf)
+}
+
+// stream writes the response header and the buffered body, the rest of the body being written through
+func (w *rangeSynthesisWriter) stream() error {
+	w.streaming = true
+	w.responseWriter.WriteHeader(w.code)
+	_, err := w.responseWriter.Write(w.body.Bytes())
+	w.body.Reset()
+	return err
+}
+
+// Flush flushes the streamed responses
+func (w *rangeSynthesisWriter) Flush() {
+	if !w.streaming {
+		return
+	}
+	if flusher, ok := w.responseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// CloseNotify returns a channel that receives at most a
+// single value (true) when the client connection has gone
+// away.
+func (w *rangeSynthesisWriter) CloseNotify() <-chan bool {
+	return w.responseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+// serveRange serves the range of the original request from the buffered response, if not streamed
+func (w *rangeSynthesisWriter) serveRange(r *http.Request) {
+	if w.streaming {
+		log.Debugf("Not synthesizing the range of %s %s, the response being streamed", r.Method, r.URL)
+		return
+	}
+	modTime, _ := http.ParseTime(w.Header().Get("Last-Modified"))
+	w.Header().Del("Content-Length")
+	http.ServeContent(w.responseWriter, r, "", modTime, bytes.NewReader(w.body.Bytes()))
+}
@@ -0,0 +1,148 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rangeContent = "0123456789"
+
+// rangeBackend serves rangeContent, answering the range requests when supportsRange is set
+type rangeBackend struct {
+	supportsRange bool
+	calls         int
+	rangeHeaders  []string
+}
+
+func (b *rangeBackend) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	b.calls++
+	b.rangeHeaders = append(b.rangeHeaders, r.Header.Get("Range"))
+	rw.Header().Set("Etag", `"v1"`)
+	rw.Header().Set("Content-Type", "text/plain")
+	if b.supportsRange && r.Header.Get("Range") == "bytes=2-4" {
+		rw.Header().Set("Content-Range", "bytes 2-4/10")
+		rw.WriteHeader(http.StatusPartialContent)
+		rw.Write([]byte(rangeContent[2:5]))
+		return
+	}
+	rw.Write([]byte(rangeContent))
+}
+
+func TestRetryRangeRequests(t *testing.T) {
+	testCases := []struct {
+		desc                 string
+		mode                 string
+		maxSize              int64
+		supportsRange        bool
+		ifRange              string
+		expectedCode         int
+		expectedBody         string
+		expectedRangeHeader  string
+		expectedContentRange string
+	}{
+		{
+			desc:                 "default",
+			supportsRange:        true,
+			expectedCode:         http.StatusPartialContent,
+			expectedBody:         "234",
+			expectedRangeHeader:  "bytes=2-4",
+			expectedContentRange: "bytes 2-4/10",
+		},
+		{
+			desc:                 "passthrough",
+			mode:                 RangeRequestsPassthrough,
+			supportsRange:        true,
+			expectedCode:         http.StatusPartialContent,
+			expectedBody:         "234",
+			expectedRangeHeader:  "bytes=2-4",
+			expectedContentRange: "bytes 2-4/10",
+		},
+		{
+			desc:                 "buffer",
+			mode:                 RangeRequestsBuffer,
+			supportsRange:        true,
+			expectedCode:         http.StatusPartialContent,
+			expectedBody:         "234",
+			expectedRangeHeader:  "bytes=2-4",
+			expectedContentRange: "bytes 2-4/10",
+		},
+		{
+			desc:                 "synthesize",
+			mode:                 RangeRequestsSynthesize,
+			expectedCode:         http.StatusPartialContent,
+			expectedBody:         "234",
+			expectedContentRange: "bytes 2-4/10",
+		},
+		{
+			desc:                 "synthesize with matching If-Range",
+			mode:                 RangeRequestsSynthesize,
+			ifRange:              `"v1"`,
+			expectedCode:         http.StatusPartialContent,
+			expectedBody:         "234",
+			expectedContentRange: "bytes 2-4/10",
+		},
+		{
+			desc:         "synthesize with stale If-Range",
+			mode:         RangeRequestsSynthesize,
+			ifRange:      `"v0"`,
+			expectedCode: http.StatusOK,
+			expectedBody: rangeContent,
+		},
+		{
+			desc:         "synthesize beyond max size",
+			mode:         RangeRequestsSynthesize,
+			maxSize:      4,
+			expectedCode: http.StatusOK,
+			expectedBody: rangeContent,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			backend := &rangeBackend{supportsRange: test.supportsRange}
+			retry := NewRetry(2, backend, &countingRetryListener{})
+			require.NoError(t, retry.SetRangeRequests(test.mode, test.maxSize))
+
+			req := testhelpers.MustNewRequest(http.MethodGet, "http://localhost/video", http.NoBody)
+			req.Header.Set("Range", "bytes=2-4")
+			if test.ifRange != "" {
+				req.Header.Set("If-Range", test.ifRange)
+			}
+			recorder := httptest.NewRecorder()
+			retry.ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedCode, recorder.Code)
+			assert.Equal(t, test.expectedBody, recorder.Body.String())
+			assert.Equal(t, test.expectedContentRange, recorder.Header().Get("Content-Range"))
+			assert.Equal(t, []string{test.expectedRangeHeader}, backend.rangeHeaders)
+		})
+	}
+}
+
+func TestRetryRangeRequestsStreamingErrors(t *testing.T) {
+	retry := NewRetry(2, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		http.Error(rw, "not found", http.StatusNotFound)
+	}), &countingRetryListener{})
+	require.NoError(t, retry.SetRangeRequests(RangeRequestsSynthesize, 0))
+
+	req := testhelpers.MustNewRequest(http.MethodGet, "http://localhost/video", http.NoBody)
+	req.Header.Set("Range", "bytes=2-4")
+	recorder := httptest.NewRecorder()
+	retry.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, "not found", strings.TrimSpace(recorder.Body.String()))
+}
+
+func TestRetryRangeRequestsInvalidMode(t *testing.T) {
+	retry := NewRetry(2, http.NotFoundHandler(), &countingRetryListener{})
+	assert.Error(t, retry.SetRangeRequests("seek", 0))
+}
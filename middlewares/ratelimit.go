@@ -0,0 +1,325 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/docker/libkv/store"
+	"github.com/vulcand/oxy/utils"
+)
+
+const (
+	kvTokenBucketMaxAttempts  = 5
+	kvTokenBucketSyncInterval = 500 * time.Millisecond
+	localTokenBucketSweep     = time.Minute
+)
+
+// TokenBucketStore takes tokens out of keyed token buckets
+type TokenBucketStore interface {
+	// Take takes a token out of the bucket identified by key, refilled with rate
+	// tokens per second up to burst tokens, and reports whether one was available.
+	Take(key string, rate float64, burst int64, now time.Time) (bool, error)
+}
+
+type tokenBucket struct {
+	Tokens float64 `json:"tokens"`
+	// Last is the time of the last refill, in nanoseconds since the epoch
+	Last int64 `json:"last"`
+}
+
+func newTokenBucket(burst int64, now time.Time) *tokenBucket {
+	return &tokenBucket{Tokens: float64(burst), Last: now.UnixNano()}
+}
+
+func (b *tokenBucket) refill(rate float64, burst int64, now time.Time) {
+	// clocks of the traefik instances sharing a bucket may drift apart
+	if elapsed := now.UnixNano() - b.Last; elapsed > 0 {
+		b.Tokens += rate * time.Duration(elapsed).Seconds()
+		b.Last = now.UnixNano()
+	}
+	if b.Tokens > float64(burst) {
+		b.Tokens = float64(burst)
+	}
+}
+
+func (b *tokenBucket) take(rate float64, burst int64, now time.Time) bool {
+	b.refill(rate, burst, now)
+	if b.Tokens < 1 {
+		return false
+	}
+	b.Tokens--
+	return true
+}
+
+// LocalTokenBucketStore keeps token buckets in memory
+type LocalTokenBucketStore struct {
+	lock      sync.Mutex
+	buckets   map[string]*localTokenBucket
+	lastSweep time.Time
+}
+
+type localTokenBucket struct {
+	tokenBucket
+	rate  float64
+	burst int64
+}
+
+// NewLocalTokenBucketStore creates an empty LocalTokenBucketStore
+func NewLocalTokenBucketStore() *LocalTokenBucketStore {
+	return &LocalTokenBucketStore{buckets: make(map[string]*localTokenBucket)}
+}
+
+// Take implements TokenBucketStore
+func (s *LocalTokenBucketStore) Take(key string, rate float64, burst int64, now time.Time) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if now.Sub(s.lastSweep) > localTokenBucketSweep {
+		s.sweep(now)
+	}
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &localTokenBucket{tokenBucket: *newTokenBucket(burst, now)}
+		s.buckets[key] = bucket
+	}
+	bucket.rate, bucket.burst = rate, burst
+	return bucket.take(rate, burst, now), nil
+}
+
+// sweep drops the buckets that are full again, they are recreated on demand
+func (s *LocalTokenBucketStore) sweep(now time.Time) {
+	for key, bucket := range s.buckets {
+		bucket.refill(bucket.rate, bucket.burst, now)
+		if bucket.Tokens >= float64(bucket.burst) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// KVTokenBucketStore keeps token buckets in a KV store, so that they are shared
+// by all the traefik instances using the store.
+// The tokens are taken out of local copies of the buckets, and the tokens taken are deducted from the
+// buckets of the KV store every sync interval, the local copies being replaced by the updated buckets.
+// The requests are thus never delayed by the KV store, and keep being limited locally while it is
+// unreachable, each instance letting through up to rate * sync interval more requests than the limit.
+// Buckets are updated with compare-and-swap operations.
+type KVTokenBucketStore struct {
+	store    store.Store
+	prefix   string
+	lock     sync.Mutex
+	buckets  map[string]*kvTokenBucket
+	degraded bool
+}
+
+type kvTokenBucket struct {
+	localTokenBucket
+	// taken is the number of tokens taken since the last sync
+	taken int64
+}
+
+// NewKVTokenBucketStore creates a KVTokenBucketStore keeping buckets under prefix
+func NewKVTokenBucketStore(kv store.Store, prefix string) *KVTokenBucketStore {
+	return &KVTokenBucketStore{store: kv, prefix: prefix, buckets: make(map[string]*kvTokenBucket)}
+}
+
+// Take implements TokenBucketStore
+func (s *KVTokenBucketStore) Take(key string, rate float64, burst int64, now time.Time) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &kvTokenBucket{localTokenBucket: localTokenBucket{tokenBucket: *newTokenBucket(burst, now)}}
+		s.buckets[key] = bucket
+	}
+	bucket.rate, bucket.burst = rate, burst
+	if !bucket.take(rate, burst, now) {
+		return false, nil
+	}
+	bucket.taken++
+	return true, nil
+}
+
+// Run syncs the buckets with the KV store every sync interval until stop is closed
+func (s *KVTokenBucketStore) Run(stop chan bool) {
+	ticker := time.NewTicker(kvTokenBucketSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			s.sync(now)
+		}
+	}
+}
+
+// sync deducts the tokens taken locally from the buckets of the KV store, and replaces the local
+// buckets by the updated ones. The buckets full again and no longer used are dropped.
+func (s *KVTokenBucketStore) sync(now time.Time) {
+	type pendingBucket struct {
+		rate  float64
+		burst int64
+		taken int64
+	}
+	s.lock.Lock()
+	pending := make(map[string]pendingBucket, len(s.buckets))
+	for key, bucket := range s.buckets {
+		pending[key] = pendingBucket{rate: bucket.rate, burst: bucket.burst, taken: bucket.taken}
+		bucket.taken = 0
+	}
+	s.lock.Unlock()
+
+	var syncErr error
+	for key, p := range pending {
+		shared, err := s.deduct(key, p.taken, p.rate, p.burst, now)
+
+		s.lock.Lock()
+		bucket := s.buckets[key]
+		if err != nil {
+			// the tokens are deducted on the next sync
+			bucket.taken += p.taken
+			syncErr = err
+		} else {
+			bucket.tokenBucket = *shared
+			bucket.Tokens -= float64(bucket.taken)
+			if bucket.taken == 0 && bucket.Tokens >= float64(bucket.burst) {
+				delete(s.buckets, key)
+			}
+		}
+		s.lock.Unlock()
+	}
+
+	if syncErr != nil && !s.degraded {
+		log.Warnf("Unable to sync the shared rate limits with the KV store, limiting requests locally: %s", syncErr)
+	} else if syncErr == nil && s.degraded {
+		log.Info("Shared rate limits synced with the KV store again")
+	}
+	s.degraded = syncErr != nil
+}
+
+// deduct takes the tokens out of the bucket of the KV store identified by key, and returns the updated bucket
+func (s *KVTokenBucketStore) deduct(key string, taken int64, rate float64, burst int64, now time.Time) (*tokenBucket, error) {
+	kvKey := s.prefix + "/" + url.PathEscape(key)
+	for attempt := 0; attempt < kvTokenBucketMaxAttempts; attempt++ {
+		bucket := newTokenBucket(burst, now)
+		pair, err := s.store.Get(kvKey)
+		if err == store.ErrKeyNotFound {
+			pair = nil
+		} else if err != nil {
+			return nil, err
+		} else if err := json.Unmarshal(pair.Value, bucket); err != nil {
+			log.Warnf("Resetting invalid rate limit bucket %s: %s", kvKey, err)
+			bucket = newTokenBucket(burst, now)
+		}
+
+		bucket.refill(rate, burst, now)
+		if taken == 0 {
+			// nothing to deduct, a missing bucket being full
+			return bucket, nil
+		}
+		bucket.Tokens = math.Max(bucket.Tokens-float64(taken), 0)
+		value, err := json.Marshal(bucket)
+		if err != nil {
+			return nil, err
+		}
+		// the bucket expires once it would have been refilled
+		ttl := time.Duration(float64(burst)/rate*float64(time.Second)) + time.Second
+		ok, _, err := s.store.AtomicPut(kvKey, value, pair, &store.WriteOptions{TTL: ttl})
+		if err == store.ErrKeyModified || err == store.ErrKeyExists || err == nil && !ok {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return bucket, nil
+	}
+	return nil, fmt.Errorf("too many concurrent updates of rate limit bucket %s", kvKey)
+}
+
+// RateLimiter is a middleware limiting the rate of requests with token buckets.
+// When the store fails, for instance because the shared store is unreachable,
+// the requests are limited by the fallback store instead.
+type RateLimiter struct {
+	name      string
+	rate      float64
+	burst     int64
+	extractor utils.SourceExtractor
+	store     TokenBucketStore
+	fallback  TokenBucketStore
+	degraded  int32
+}
+
+// NewRateLimiter creates a RateLimiter identified by name, as buckets are
+// shared by the rate limiters of the same name, the client.ip source being determined by the strategy
+func NewRateLimiter(name string, rateLimit *types.RateLimit, bucketStore, fallback TokenBucketStore, strategy ClientIPStrategy) (*RateLimiter, error) {
+	if rateLimit.Average <= 0 {
+		return nil, errors.New("rate limit average must be positive")
+	}
+	burst := rateLimit.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	extractorFunc := rateLimit.ExtractorFunc
+	if extractorFunc == "" {
+		extractorFunc = "client.ip"
+	}
+	extractor, err := NewSourceExtractor(extractorFunc, strategy)
+	if err != nil {
+		return nil, err
+	}
+	return &RateLimiter{
+		name:      name,
+		rate:      float64(rateLimit.Average),
+		burst:     burst,
+		extractor: extractor,
+		store:     bucketStore,
+		fallback:  fallback,
+	}, nil
+}
+
+func (rl *RateLimiter) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	source, _, err := rl.extractor.Extract(r)
+	if err != nil {
+		log.Errorf("Unable to extract rate limit source of request: %s", err)
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if !rl.take(rl.name+"/"+source, time.Now()) {
+		rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(1/rl.rate))))
+		http.Error(rw, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+	next(rw, r)
+}
+
+func (rl *RateLimiter) take(key string, now time.Time) bool {
+	allowed, err := rl.store.Take(key, rl.rate, rl.burst, now)
+	if err == nil {
+		if atomic.CompareAndSwapInt32(&rl.degraded, 1, 0) {
+			log.Infof("Rate limit store of %s is available again", rl.name)
+		}
+		return allowed
+	}
+	if rl.fallback == nil {
+		log.Errorf("Unable to apply rate limit of %s, letting request through: %s", rl.name, err)
+		return true
+	}
+	if atomic.CompareAndSwapInt32(&rl.degraded, 0, 1) {
+		log.Warnf("Rate limit store of %s is unavailable, falling back to local rate limiting: %s", rl.name, err)
+	}
+	allowed, _ = rl.fallback.Take(key, rl.rate, rl.burst, now)
+	return allowed
+}
@@ -0,0 +1,187 @@
+package middlewares
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/docker/libkv/store"
+	"github.com/docker/libkv/store/boltdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingTokenBucketStore struct{}
+
+func (failingTokenBucketStore) Take(key string, rate float64, burst int64, now time.Time) (bool, error) {
+	return false, errors.New("store unreachable")
+}
+
+func assertTakes(t *testing.T, bucketStore TokenBucketStore, key string, now time.Time, expected ...bool) {
+	for i, allowed := range expected {
+		actual, err := bucketStore.Take(key, 2, 3, now)
+		require.NoError(t, err)
+		assert.Equal(t, allowed, actual, "take %d", i)
+	}
+}
+
+func testTokenBucketStore(t *testing.T, bucketStore TokenBucketStore) {
+	now := time.Now()
+	assertTakes(t, bucketStore, "a", now, true, true, true, false)
+	assertTakes(t, bucketStore, "b", now, true)
+	// 2 tokens per second
+	assertTakes(t, bucketStore, "a", now.Add(500*time.Millisecond), true, false)
+	// refill is capped by the burst
+	assertTakes(t, bucketStore, "a", now.Add(time.Minute), true, true, true, false)
+}
+
+func TestLocalTokenBucketStore(t *testing.T) {
+	testTokenBucketStore(t, NewLocalTokenBucketStore())
+}
+
+func TestLocalTokenBucketStoreSweep(t *testing.T) {
+	bucketStore := NewLocalTokenBucketStore()
+	now := time.Now()
+	assertTakes(t, bucketStore, "a", now, true)
+	assertTakes(t, bucketStore, "b", now.Add(localTokenBucketSweep+time.Second), true, true, true, false)
+	assert.Len(t, bucketStore.buckets, 1)
+}
+
+func TestKVTokenBucketStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ratelimit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	kv, err := boltdb.New([]string{filepath.Join(dir, "ratelimit.db")}, &store.Config{Bucket: "traefik"})
+	require.NoError(t, err)
+	defer kv.Close()
+
+	bucketStore := NewKVTokenBucketStore(kv, "traefik/ratelimits")
+	testTokenBucketStore(t, bucketStore)
+
+	// buckets are shared by the stores using the same KV store once synced
+	now := time.Now().Add(2 * time.Minute)
+	other := NewKVTokenBucketStore(kv, "traefik/ratelimits")
+	assertTakes(t, bucketStore, "c", now, true)
+	bucketStore.sync(now)
+	assertTakes(t, other, "c", now, true)
+	other.sync(now)
+	assertTakes(t, other, "c", now, true, false)
+	other.sync(now)
+	bucketStore.sync(now)
+	assertTakes(t, bucketStore, "c", now, false)
+
+	// the buckets full again are dropped
+	bucketStore.sync(now.Add(time.Minute))
+	assert.Empty(t, bucketStore.buckets)
+}
+
+func TestKVTokenBucketStoreUnreachable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ratelimit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	kv, err := boltdb.New([]string{filepath.Join(dir, "ratelimit.db")}, &store.Config{Bucket: "traefik"})
+	require.NoError(t, err)
+	bucketStore := NewKVTokenBucketStore(kv, "traefik/ratelimits")
+	kv.Close()
+
+	// the requests are limited locally, the tokens taken being deducted once the store is reachable
+	now := time.Now()
+	assertTakes(t, bucketStore, "a", now, true, true)
+	bucketStore.sync(now)
+	assert.True(t, bucketStore.degraded)
+	assertTakes(t, bucketStore, "a", now, true, false)
+	assert.EqualValues(t, 3, bucketStore.buckets["a"].taken)
+}
+
+func TestNewRateLimiterError(t *testing.T) {
+	cases := []struct {
+		desc      string
+		rateLimit *types.RateLimit
+	}{
+		{
+			desc:      "no average",
+			rateLimit: &types.RateLimit{Burst: 10},
+		},
+		{
+			desc:      "invalid extractor",
+			rateLimit: &types.RateLimit{Average: 10, ExtractorFunc: "foo"},
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewRateLimiter("frontend", test.rateLimit, NewLocalTokenBucketStore(), nil, nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	cases := []struct {
+		desc     string
+		store    TokenBucketStore
+		fallback TokenBucketStore
+		expected []int
+	}{
+		{
+			desc:     "local store",
+			store:    NewLocalTokenBucketStore(),
+			expected: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			desc:     "fallback on store failure",
+			store:    failingTokenBucketStore{},
+			fallback: NewLocalTokenBucketStore(),
+			expected: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			desc:     "requests let through without fallback",
+			store:    failingTokenBucketStore{},
+			expected: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			rateLimiter, err := NewRateLimiter("frontend", &types.RateLimit{Average: 1, Burst: 2}, test.store, test.fallback, nil)
+			require.NoError(t, err)
+
+			n := negroni.New(rateLimiter)
+			n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			for i, expected := range test.expected {
+				req := testhelpers.MustNewRequest(http.MethodGet, "http://example.com/", nil)
+				req.RemoteAddr = "10.0.0.1:1234"
+				recorder := httptest.NewRecorder()
+				n.ServeHTTP(recorder, req)
+				assert.Equal(t, expected, recorder.Code, "request %d", i)
+				if expected == http.StatusTooManyRequests {
+					assert.Equal(t, "1", recorder.Header().Get("Retry-After"))
+				}
+			}
+
+			// requests of other clients are limited separately
+			req := testhelpers.MustNewRequest(http.MethodGet, "http://example.com/", nil)
+			req.RemoteAddr = "10.0.0.2:1234"
+			recorder := httptest.NewRecorder()
+			n.ServeHTTP(recorder, req)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+		})
+	}
+}
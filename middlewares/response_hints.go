@@ -0,0 +1,166 @@
+package middlewares
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/containous/traefik/log"
+)
+
+const (
+	// EarlyHintsStrip drops the 103 Early Hints sent by backends
+	EarlyHintsStrip = "strip"
+	// EarlyHintsPassthrough forwards the 103 Early Hints sent by backends to the clients
+	EarlyHintsPassthrough = "passthrough"
+)
+
+// pushedRequestHeaders are the request headers copied to the requests of pushed resources
+var pushedRequestHeaders = []string{"Accept-Encoding", "Accept-Language", "Cookie", "User-Agent"}
+
+// ResponseHints is a middleware controlling the hints sent by backends ahead of their responses:
+// the 103 Early Hints informational responses are stripped or passed through, and the resources
+// announced in "Link: <...>; rel=preload" headers can be pushed to HTTP/2 clients.
+// It must wrap the whole entry point handler, the response writers of the other middlewares
+// not exposing http.Pusher.
+type ResponseHints struct {
+	handler     http.Handler
+	passthrough bool
+	push        bool
+}
+
+// NewResponseHints builds a new ResponseHints middleware
+func NewResponseHints(handler http.Handler, earlyHints string, push bool) *ResponseHints {
+	hints := &ResponseHints{handler: handler, push: push}
+	switch earlyHints {
+	case "", EarlyHintsStrip:
+	case EarlyHintsPassthrough:
+		if earlyHintsSupported {
+			hints.passthrough = true
+		} else {
+			log.Warnf("Early hints passthrough requires traefik to be built with Go 1.19 or later, early hints are stripped")
+		}
+	default:
+		log.Errorf("Unknown early hints behavior %q, early hints are stripped", earlyHints)
+	}
+	return hints
+}
+
+func (h *ResponseHints) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if h.passthrough {
+		r = withEarlyHintsPassthrough(rw, r)
+	}
+	if pusher, ok := rw.(http.Pusher); ok && h.push {
+		rw = &pushResponseWriter{ResponseWriter: rw, pusher: pusher, request: r}
+	}
+	h.handler.ServeHTTP(rw, r)
+}
+
+// pushResponseWriter pushes the resources preloaded by a successful response before writing its headers
+type pushResponseWriter struct {
+	http.ResponseWriter
+	pusher        http.Pusher
+	request       *http.Request
+	headerWritten bool
+}
+
+func (w *pushResponseWriter) WriteHeader(code int) {
+	if !w.headerWritten {
+		w.headerWritten = true
+		if code >= http.StatusOK && code < http.StatusMultipleChoices {
+			w.pushPreloads()
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *pushResponseWriter) Write(b []byte) (int, error) {
+	if !w.headerWritten {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *pushResponseWriter) pushPreloads() {
+	var options *http.PushOptions
+	for _, target := range preloadTargets(w.Header()["Link"]) {
+		if options == nil {
+			options = &http.PushOptions{Header: http.Header{}}
+			for _, name := range pushedRequestHeaders {
+				if value := w.request.Header.Get(name); value != "" {
+					options.Header.Set(name, value)
+				}
+			}
+		}
+		if err := w.pusher.Push(target, options); err != nil {
+			log.Debugf("Unable to push %s: %v", target, err)
+			// the client disabled push, or the stream does not allow it anymore
+			return
+		}
+	}
+}
+
+func (w *pushResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *pushResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("Not a hijacker: %T", w.ResponseWriter)
+}
+
+func (w *pushResponseWriter) CloseNotify() <-chan bool {
+	if c, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return c.CloseNotify()
+	}
+	return nil
+}
+
+// preloadTargets returns the same origin paths of the Link header values with the preload relation
+// and without the nopush parameter
+func preloadTargets(links []string) []string {
+	var targets []string
+	for _, header := range links {
+		for _, link := range strings.Split(header, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = target[1 : len(target)-1]
+			if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") {
+				continue
+			}
+
+			preload, nopush := false, false
+			for _, param := range parts[1:] {
+				key, value := param, ""
+				if i := strings.Index(param, "="); i >= 0 {
+					key, value = param[:i], param[i+1:]
+				}
+				key = strings.ToLower(strings.TrimSpace(key))
+				value = strings.ToLower(strings.Trim(strings.TrimSpace(value), `"`))
+				switch key {
+				case "rel":
+					for _, rel := range strings.Fields(value) {
+						if rel == "preload" {
+							preload = true
+						}
+					}
+				case "nopush":
+					nopush = true
+				}
+			}
+			if preload && !nopush {
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets
+}
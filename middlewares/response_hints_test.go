@@ -0,0 +1,130 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreloadTargets(t *testing.T) {
+	cases := []struct {
+		desc     string
+		links    []string
+		expected []string
+	}{
+		{
+			desc:     "no link",
+			expected: nil,
+		},
+		{
+			desc:     "preload",
+			links:    []string{"</style.css>; rel=preload; as=style"},
+			expected: []string{"/style.css"},
+		},
+		{
+			desc:     "several links and headers",
+			links:    []string{`</app.js>; rel="preload"; as=script, </style.css>; rel=preload`, "</font.woff2>; rel=PRELOAD"},
+			expected: []string{"/app.js", "/style.css", "/font.woff2"},
+		},
+		{
+			desc:     "several relations",
+			links:    []string{`</app.js>; rel="preload prefetch"`},
+			expected: []string{"/app.js"},
+		},
+		{
+			desc:     "nopush",
+			links:    []string{"</style.css>; rel=preload; nopush"},
+			expected: nil,
+		},
+		{
+			desc:     "other relation",
+			links:    []string{"</next.html>; rel=prefetch"},
+			expected: nil,
+		},
+		{
+			desc:     "other origin",
+			links:    []string{"<https://cdn.example.com/style.css>; rel=preload", "<//cdn.example.com/app.js>; rel=preload"},
+			expected: nil,
+		},
+		{
+			desc:     "malformed",
+			links:    []string{"/style.css; rel=preload"},
+			expected: nil,
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.expected, preloadTargets(test.links))
+		})
+	}
+}
+
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed  []string
+	options []*http.PushOptions
+}
+
+func (r *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	r.pushed = append(r.pushed, target)
+	r.options = append(r.options, opts)
+	return nil
+}
+
+func TestResponseHintsPush(t *testing.T) {
+	cases := []struct {
+		desc     string
+		push     bool
+		status   int
+		expected []string
+	}{
+		{
+			desc:     "push disabled",
+			status:   http.StatusOK,
+			expected: nil,
+		},
+		{
+			desc:     "push enabled",
+			push:     true,
+			status:   http.StatusOK,
+			expected: []string{"/style.css", "/app.js"},
+		},
+		{
+			desc:     "push enabled, error response",
+			push:     true,
+			status:   http.StatusNotFound,
+			expected: nil,
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			handler := NewResponseHints(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Link", "</style.css>; rel=preload; as=style")
+				w.Header().Add("Link", "</app.js>; rel=preload; as=script")
+				w.WriteHeader(test.status)
+			}), "", test.push)
+
+			recorder := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+			req := testhelpers.MustNewRequest(http.MethodGet, "http://localhost/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			req.Header.Set("Authorization", "Basic dGVzdDp0ZXN0")
+			handler.ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.status, recorder.Code)
+			assert.Equal(t, test.expected, recorder.pushed)
+			for _, opts := range recorder.options {
+				assert.Equal(t, "gzip", opts.Header.Get("Accept-Encoding"))
+				assert.Empty(t, opts.Header.Get("Authorization"))
+			}
+		})
+	}
+}
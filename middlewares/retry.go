@@ -19,9 +19,11 @@ var (
 
 // Retry is a middleware that retries requests
 type Retry struct {
-	attempts int
-	next     http.Handler
-	listener RetryListener
+	attempts                int
+	next                    http.Handler
+	listener                RetryListener
+	rangeRequests           string
+	maxSynthesizedRangeSize int64
 }
 
 // NewRetry returns a new Retry instance
@@ -34,6 +36,9 @@ func NewRetry(attempts int, next http.Handler, listener RetryListener) *Retry {
 }
 
 func (retry *Retry) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Range") != "" && retry.serveRange(rw, r) {
+		return
+	}
 	// if we might make multiple attempts, swap the body for an ioutil.NopCloser
 	// cf https://github.com/containous/traefik/issues/1008
 	if retry.attempts > 1 {
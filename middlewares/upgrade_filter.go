@@ -0,0 +1,98 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/traefik/log"
+	"github.com/pkg/errors"
+)
+
+// AnyUpgrade allows every protocol upgrade when listed in the allowed upgrades
+const AnyUpgrade = "*"
+
+// UpgradeFilter is a middleware that rejects protocol upgrade requests (websocket, h2c, ...)
+// whose protocol is not explicitly allowed
+type UpgradeFilter struct {
+	handler   negroni.Handler
+	allowAll  bool
+	protocols map[string]bool
+}
+
+// NewUpgradeFilter builds a new UpgradeFilter given a list of allowed upgrade protocols
+func NewUpgradeFilter(allowedUpgrades []string) (*UpgradeFilter, error) {
+	if len(allowedUpgrades) == 0 {
+		return nil, errors.New("no allowed upgrades provided")
+	}
+
+	filter := UpgradeFilter{protocols: make(map[string]bool)}
+	for _, upgrade := range allowedUpgrades {
+		upgrade = strings.TrimSpace(upgrade)
+		switch upgrade {
+		case "":
+			return nil, errors.New("empty upgrade protocol")
+		case AnyUpgrade:
+			filter.allowAll = true
+		default:
+			filter.protocols[strings.ToLower(upgrade)] = true
+		}
+	}
+
+	filter.handler = negroni.HandlerFunc(filter.handle)
+	return &filter, nil
+}
+
+func (filter *UpgradeFilter) handle(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if filter.allowAll || !isUpgradeRequest(r) {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	for _, protocol := range upgradeProtocols(r) {
+		if !filter.protocols[protocol] {
+			log.Debugf("upgrade to %s is not allowed - rejecting", protocol)
+			reject(w)
+			return
+		}
+	}
+
+	next.ServeHTTP(w, r)
+}
+
+func (filter *UpgradeFilter) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	filter.handler.ServeHTTP(rw, r, next)
+}
+
+// isUpgradeRequest returns true if the request asks for a protocol upgrade
+func isUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, value := range r.Header["Connection"] {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// upgradeProtocols returns the lower-cased protocol names (without version) requested
+// through the Upgrade header
+func upgradeProtocols(r *http.Request) []string {
+	var protocols []string
+	for _, value := range r.Header["Upgrade"] {
+		for _, protocol := range strings.Split(value, ",") {
+			protocol = strings.TrimSpace(protocol)
+			if i := strings.Index(protocol, "/"); i >= 0 {
+				protocol = protocol[:i]
+			}
+			if protocol != "" {
+				protocols = append(protocols, strings.ToLower(protocol))
+			}
+		}
+	}
+	return protocols
+}
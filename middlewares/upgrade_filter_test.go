@@ -0,0 +1,131 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/traefik/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUpgradeFilter(t *testing.T) {
+	cases := []struct {
+		desc            string
+		allowedUpgrades []string
+		errMessage      string
+	}{
+		{
+			desc:       "nil allowed upgrades",
+			errMessage: "no allowed upgrades provided",
+		},
+		{
+			desc:            "empty protocol",
+			allowedUpgrades: []string{"websocket", " "},
+			errMessage:      "empty upgrade protocol",
+		},
+		{
+			desc:            "websocket only",
+			allowedUpgrades: []string{"websocket"},
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			filter, err := NewUpgradeFilter(test.allowedUpgrades)
+			if test.errMessage != "" {
+				require.EqualError(t, err, test.errMessage)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, filter)
+		})
+	}
+}
+
+func TestUpgradeFilter(t *testing.T) {
+	cases := []struct {
+		desc            string
+		allowedUpgrades []string
+		connection      string
+		upgrade         string
+		expectedStatus  int
+	}{
+		{
+			desc:            "plain request",
+			allowedUpgrades: []string{"websocket"},
+			expectedStatus:  http.StatusOK,
+		},
+		{
+			desc:            "upgrade header without connection upgrade",
+			allowedUpgrades: []string{"websocket"},
+			upgrade:         "h2c",
+			expectedStatus:  http.StatusOK,
+		},
+		{
+			desc:            "allowed websocket upgrade",
+			allowedUpgrades: []string{"websocket"},
+			connection:      "keep-alive, Upgrade",
+			upgrade:         "WebSocket",
+			expectedStatus:  http.StatusOK,
+		},
+		{
+			desc:            "rejected h2c upgrade",
+			allowedUpgrades: []string{"websocket"},
+			connection:      "Upgrade",
+			upgrade:         "h2c",
+			expectedStatus:  http.StatusForbidden,
+		},
+		{
+			desc:            "rejected when one of several protocols is not allowed",
+			allowedUpgrades: []string{"websocket"},
+			connection:      "Upgrade",
+			upgrade:         "websocket, TLS/1.2",
+			expectedStatus:  http.StatusForbidden,
+		},
+		{
+			desc:            "versioned protocol allowed",
+			allowedUpgrades: []string{"tls"},
+			connection:      "Upgrade",
+			upgrade:         "TLS/1.2",
+			expectedStatus:  http.StatusOK,
+		},
+		{
+			desc:            "arbitrary upgrades allowed",
+			allowedUpgrades: []string{AnyUpgrade},
+			connection:      "Upgrade",
+			upgrade:         "h2c",
+			expectedStatus:  http.StatusOK,
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			filter, err := NewUpgradeFilter(test.allowedUpgrades)
+			require.NoError(t, err)
+
+			n := negroni.New(filter)
+			n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := testhelpers.MustNewRequest(http.MethodGet, "http://example.com/", nil)
+			if test.connection != "" {
+				req.Header.Set("Connection", test.connection)
+			}
+			if test.upgrade != "" {
+				req.Header.Set("Upgrade", test.upgrade)
+			}
+			recorder := httptest.NewRecorder()
+			n.ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+		})
+	}
+}
@@ -271,6 +271,7 @@ func (p *Provider) loadDockerConfig(containersInspected []dockerData) *types.Con
 		"hasMaxConnLabels":            p.hasMaxConnLabels,
 		"getMaxConnAmount":            p.getMaxConnAmount,
 		"getMaxConnExtractorFunc":     p.getMaxConnExtractorFunc,
+		"getServersTransport":         p.getServersTransport,
 		"getSticky":                   p.getSticky,
 		"getIsBackendLBSwarm":         p.getIsBackendLBSwarm,
 		"hasServices":                 p.hasServices,
@@ -511,6 +512,13 @@ func (p *Provider) getMaxConnExtractorFunc(container dockerData) string {
 	return "request.host"
 }
 
+func (p *Provider) getServersTransport(container dockerData) string {
+	if label, err := getLabel(container, types.LabelBackendServersTransport); err == nil {
+		return label
+	}
+	return ""
+}
+
 func (p *Provider) containerFilter(container dockerData) bool {
 	_, err := strconv.Atoi(container.Labels[types.LabelPort])
 	if len(container.NetworkSettings.Ports) == 0 && err != nil {
@@ -983,6 +983,7 @@ func TestDockerLoadDockerConfig(t *testing.T) {
 						types.LabelBackendMaxconnExtractorfunc:     "somethingelse",
 						types.LabelBackendLoadbalancerMethod:       "drr",
 						types.LabelBackendCircuitbreakerExpression: "NetworkErrorRatio() > 0.5",
+						types.LabelBackendServersTransport:         "internal-tls",
 					}),
 					ports(nat.PortMap{
 						"80/tcp": {},
@@ -1021,6 +1022,7 @@ func TestDockerLoadDockerConfig(t *testing.T) {
 						Amount:        1000,
 						ExtractorFunc: "somethingelse",
 					},
+					ServersTransport: "internal-tls",
 				},
 			},
 		},
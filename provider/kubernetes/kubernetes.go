@@ -248,6 +248,10 @@ func (p *Provider) loadIngresses(k8sClient Client) (*types.Configuration, error)
 					templateObjects.Backends[r.Host+pa.Path].LoadBalancer.Sticky = true
 				}
 
+				if serversTransport := service.Annotations[types.LabelBackendServersTransport]; serversTransport != "" {
+					templateObjects.Backends[r.Host+pa.Path].ServersTransport = serversTransport
+				}
+
 				protocol := "http"
 				for _, port := range service.Spec.Ports {
 					if equalPorts(port, pa.Backend.ServicePort) {
@@ -1226,6 +1226,7 @@ func TestServiceAnnotations(t *testing.T) {
 				Annotations: map[string]string{
 					types.LabelTraefikBackendCircuitbreaker: "NetworkErrorRatio() > 0.5",
 					types.LabelBackendLoadbalancerMethod:    "drr",
+					types.LabelBackendServersTransport:      "internal-tls",
 				},
 			},
 			Spec: v1.ServiceSpec{
@@ -1360,6 +1361,7 @@ func TestServiceAnnotations(t *testing.T) {
 					Method: "drr",
 					Sticky: false,
 				},
+				ServersTransport: "internal-tls",
 			},
 			"bar": {
 				Servers: map[string]types.Server{
@@ -0,0 +1,124 @@
+package marathon
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/provider"
+	"github.com/gambol99/go-marathon"
+)
+
+const (
+	// backendCollisionMerge lets applications resolving to the same backend name share it
+	backendCollisionMerge = "merge"
+	// backendCollisionSuffix appends a numeric suffix to the backend name of colliding applications
+	backendCollisionSuffix = "suffix"
+	// backendCollisionReject drops colliding applications
+	backendCollisionReject = "reject"
+)
+
+// backendNameData is the data given to the backend name template.
+type backendNameData struct {
+	// ID is the application ID, e.g. /group/app
+	ID string
+	// Name is the last segment of the application ID, e.g. app
+	Name string
+	// Group is the group path of the application with "/" replaced by "-", e.g. group
+	Group  string
+	Labels map[string]string
+}
+
+func parseBackendNameTemplate(text string) (*template.Template, error) {
+	return template.New("backendName").Option("missingkey=zero").Parse(text)
+}
+
+func newBackendNameData(application marathon.Application) backendNameData {
+	data := backendNameData{
+		ID:     application.ID,
+		Name:   path.Base(application.ID),
+		Group:  strings.Replace(strings.Trim(path.Dir(application.ID), "/"), "/", "-", -1),
+		Labels: map[string]string{},
+	}
+	if application.Labels != nil {
+		data.Labels = *application.Labels
+	}
+	return data
+}
+
+// executeBackendNameTemplate renders the backend name of the application with
+// the configured template.
+func (p *Provider) executeBackendNameTemplate(application marathon.Application) (string, error) {
+	var buffer bytes.Buffer
+	if err := p.backendNameTemplate.Execute(&buffer, newBackendNameData(application)); err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(provider.Replace("/", "-", buffer.String()))
+	if name == "" {
+		return "", fmt.Errorf("empty backend name")
+	}
+	return name, nil
+}
+
+// resolveBackendNames computes the backend name of every application and detects
+// collisions between them. Applications are processed in ID order so that the
+// outcome does not depend on the order returned by Marathon; the first application
+// keeps the name, the following ones are handled according to BackendCollision,
+// and share the backend unless suffix or reject is set.
+// It returns the applications to expose along with their backend names by ID.
+func (p *Provider) resolveBackendNames(applications []marathon.Application) ([]marathon.Application, map[string]string) {
+	sorted := make([]marathon.Application, len(applications))
+	copy(sorted, applications)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	names := make(map[string]string)
+	owners := make(map[string]string)
+	for _, application := range sorted {
+		names[application.ID] = p.getBackend(application)
+		// names computed for other applications must not be taken by a suffixed name
+		owners[names[application.ID]] = ""
+	}
+
+	rejected := make(map[string]bool)
+	for _, application := range sorted {
+		name := names[application.ID]
+		owner := owners[name]
+		if owner == "" {
+			owners[name] = application.ID
+			continue
+		}
+
+		switch p.BackendCollision {
+		case backendCollisionReject:
+			log.Warnf("Rejecting Marathon application %s: backend %s is already used by application %s", application.ID, name, owner)
+			rejected[application.ID] = true
+		case backendCollisionSuffix:
+			suffixed := name
+			for i := 2; ; i++ {
+				suffixed = fmt.Sprintf("%s-%d", name, i)
+				if _, used := owners[suffixed]; !used {
+					break
+				}
+			}
+			log.Warnf("Backend %s of Marathon application %s is already used by application %s, using %s instead", name, application.ID, owner, suffixed)
+			names[application.ID] = suffixed
+			owners[suffixed] = application.ID
+		default:
+			// merged into the backend of the first application
+		}
+	}
+
+	var resolved []marathon.Application
+	for _, application := range applications {
+		if !rejected[application.ID] {
+			resolved = append(resolved, application)
+		}
+	}
+	return resolved, names
+}
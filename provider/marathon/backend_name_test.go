@@ -0,0 +1,148 @@
+package marathon
+
+import (
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/gambol99/go-marathon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarathonGetBackendWithTemplate(t *testing.T) {
+	cases := []struct {
+		desc        string
+		template    string
+		application marathon.Application
+		expected    string
+	}{
+		{
+			desc:     "label takes precedence",
+			template: "{{.Name}}",
+			application: marathon.Application{
+				ID:     "/group/app",
+				Labels: &map[string]string{types.LabelBackend: "bar"},
+			},
+			expected: "bar",
+		},
+		{
+			desc:     "group and name",
+			template: "{{.Name}}.{{.Group}}",
+			application: marathon.Application{
+				ID:     "/team/sub/app",
+				Labels: &map[string]string{},
+			},
+			expected: "app.team-sub",
+		},
+		{
+			desc:     "label value",
+			template: `{{index .Labels "team"}}-{{.Name}}`,
+			application: marathon.Application{
+				ID:     "/app",
+				Labels: &map[string]string{"team": "payments"},
+			},
+			expected: "payments-app",
+		},
+		{
+			desc:     "empty rendering falls back to the application ID",
+			template: `{{index .Labels "team"}}`,
+			application: marathon.Application{
+				ID:     "/group/app",
+				Labels: &map[string]string{},
+			},
+			expected: "-group-app",
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			backendNameTemplate, err := parseBackendNameTemplate(test.template)
+			require.NoError(t, err)
+			provider := &Provider{backendNameTemplate: backendNameTemplate}
+			assert.Equal(t, test.expected, provider.getBackend(test.application))
+		})
+	}
+}
+
+func TestParseBackendNameTemplateError(t *testing.T) {
+	_, err := parseBackendNameTemplate("{{.Name")
+	assert.Error(t, err)
+}
+
+func TestMarathonResolveBackendNames(t *testing.T) {
+	applications := []marathon.Application{
+		{ID: "/web-2", Labels: &map[string]string{}},
+		{ID: "/b", Labels: &map[string]string{types.LabelBackend: "web"}},
+		{ID: "/a", Labels: &map[string]string{types.LabelBackend: "web"}},
+		{ID: "/c", Labels: &map[string]string{types.LabelBackend: "web"}},
+	}
+
+	cases := []struct {
+		desc          string
+		collision     string
+		expectedIDs   []string
+		expectedNames map[string]string
+	}{
+		{
+			desc:        "merge by default",
+			expectedIDs: []string{"/web-2", "/b", "/a", "/c"},
+			expectedNames: map[string]string{
+				"/web-2": "-web-2",
+				"/a":     "web",
+				"/b":     "web",
+				"/c":     "web",
+			},
+		},
+		{
+			desc:        "suffix",
+			collision:   backendCollisionSuffix,
+			expectedIDs: []string{"/web-2", "/b", "/a", "/c"},
+			expectedNames: map[string]string{
+				"/web-2": "-web-2",
+				"/a":     "web",
+				"/b":     "web-2",
+				"/c":     "web-3",
+			},
+		},
+		{
+			desc:        "reject",
+			collision:   backendCollisionReject,
+			expectedIDs: []string{"/web-2", "/a"},
+			expectedNames: map[string]string{
+				"/web-2": "-web-2",
+				"/a":     "web",
+				"/b":     "web",
+				"/c":     "web",
+			},
+		},
+		{
+			desc:        "merge",
+			collision:   backendCollisionMerge,
+			expectedIDs: []string{"/web-2", "/b", "/a", "/c"},
+			expectedNames: map[string]string{
+				"/web-2": "-web-2",
+				"/a":     "web",
+				"/b":     "web",
+				"/c":     "web",
+			},
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			provider := &Provider{BackendCollision: test.collision}
+			resolved, names := provider.resolveBackendNames(applications)
+
+			var ids []string
+			for _, application := range resolved {
+				ids = append(ids, application.ID)
+			}
+			assert.Equal(t, test.expectedIDs, ids)
+			assert.Equal(t, test.expectedNames, names)
+		})
+	}
+}
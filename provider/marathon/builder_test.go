@@ -0,0 +1,149 @@
+package marathon
+
+import (
+	"github.com/gambol99/go-marathon"
+)
+
+// Builders of Marathon applications and tasks used to set up test scenarios concisely, e.g.
+//
+//	application(appID("/app"), appPorts(80), withLabel(types.LabelPort, "80"),
+//		withTasks(task(taskID("task1"), taskHost("10.0.0.1"), taskPorts(80), taskState(taskStateRunning))))
+
+func application(ops ...func(*marathon.Application)) marathon.Application {
+	app := marathon.Application{
+		Labels: &map[string]string{},
+	}
+	for _, op := range ops {
+		op(&app)
+	}
+	return app
+}
+
+func appID(id string) func(*marathon.Application) {
+	return func(app *marathon.Application) {
+		app.ID = id
+	}
+}
+
+func appPorts(ports ...int) func(*marathon.Application) {
+	return func(app *marathon.Application) {
+		app.Ports = append(app.Ports, ports...)
+	}
+}
+
+func withLabel(key, value string) func(*marathon.Application) {
+	return func(app *marathon.Application) {
+		app.AddLabel(key, value)
+	}
+}
+
+func withLabels(labels map[string]string) func(*marathon.Application) {
+	return func(app *marathon.Application) {
+		for key, value := range labels {
+			app.AddLabel(key, value)
+		}
+	}
+}
+
+// ipAddrPerTask declares a single port in the IP-per-task discovery information of the application
+func ipAddrPerTask(port int) func(*marathon.Application) {
+	return func(app *marathon.Application) {
+		app.IPAddressPerTask = &marathon.IPAddressPerTask{
+			Discovery: &marathon.Discovery{
+				Ports: &[]marathon.Port{
+					{
+						Number: port,
+						Name:   "port",
+					},
+				},
+			},
+		}
+	}
+}
+
+// withHealthCheck declares a health check on the application, so that the health results of its tasks are considered
+func withHealthCheck() func(*marathon.Application) {
+	return func(app *marathon.Application) {
+		app.AddHealthCheck(*marathon.NewDefaultHealthCheck())
+	}
+}
+
+// withTasks adds the tasks to the application, setting their AppID
+func withTasks(tasks ...marathon.Task) func(*marathon.Application) {
+	return func(app *marathon.Application) {
+		for _, task := range tasks {
+			task := task
+			task.AppID = app.ID
+			app.Tasks = append(app.Tasks, &task)
+		}
+	}
+}
+
+// withEnv sets an environment variable of the application
+func withEnv(name, value string) func(*marathon.Application) {
+	return func(app *marathon.Application) {
+		app.AddEnv(name, value)
+	}
+}
+
+func task(ops ...func(*marathon.Task)) marathon.Task {
+	t := marathon.Task{}
+	for _, op := range ops {
+		op(&t)
+	}
+	return t
+}
+
+func taskID(id string) func(*marathon.Task) {
+	return func(t *marathon.Task) {
+		t.ID = id
+	}
+}
+
+func taskHost(host string) func(*marathon.Task) {
+	return func(t *marathon.Task) {
+		t.Host = host
+	}
+}
+
+func taskPorts(ports ...int) func(*marathon.Task) {
+	return func(t *marathon.Task) {
+		t.Ports = append(t.Ports, ports...)
+	}
+}
+
+func taskState(state string) func(*marathon.Task) {
+	return func(t *marathon.Task) {
+		t.State = state
+	}
+}
+
+func taskIPAddresses(addresses ...string) func(*marathon.Task) {
+	return func(t *marathon.Task) {
+		for _, address := range addresses {
+			t.IPAddresses = append(t.IPAddresses, &marathon.IPAddress{
+				IPAddress: address,
+				Protocol:  "tcp",
+			})
+		}
+	}
+}
+
+func taskStartedAt(startedAt string) func(*marathon.Task) {
+	return func(t *marathon.Task) {
+		t.StartedAt = startedAt
+	}
+}
+
+// healthResults appends one health check result per given state
+func healthResults(alive ...bool) func(*marathon.Task) {
+	return func(t *marathon.Task) {
+		for _, a := range alive {
+			t.HealthCheckResults = append(t.HealthCheckResults, &marathon.HealthCheckResult{Alive: a})
+		}
+	}
+}
+
+func applications(apps ...marathon.Application) marathon.Applications {
+	return marathon.Applications{Apps: apps}
+}
@@ -0,0 +1,64 @@
+package marathon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/containous/traefik/types"
+)
+
+// configurationSnapshot is the content of the cache file
+type configurationSnapshot struct {
+	Time          time.Time            `json:"time"`
+	Configuration *types.Configuration `json:"configuration"`
+}
+
+// loadCachedConfiguration returns the configuration snapshot of the cache file, or nil if there is none
+func (p *Provider) loadCachedConfiguration() (*configurationSnapshot, error) {
+	data, err := ioutil.ReadFile(p.CacheFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshot := new(configurationSnapshot)
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, err
+	}
+	if snapshot.Configuration == nil {
+		return nil, nil
+	}
+	return snapshot, nil
+}
+
+// cacheConfiguration replaces the snapshot of the cache file with the configuration.
+// The snapshot is written to a temporary file renamed over the cache file, so that
+// the cache file is never left partially written.
+func (p *Provider) cacheConfiguration(configuration *types.Configuration) error {
+	data, err := json.Marshal(configurationSnapshot{Time: time.Now().UTC(), Configuration: configuration})
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(p.CacheFile), "."+filepath.Base(p.CacheFile)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), p.CacheFile)
+}
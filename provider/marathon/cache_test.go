@@ -0,0 +1,115 @@
+package marathon
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarathonCacheConfiguration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "traefik-marathon-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	provider := &Provider{CacheFile: filepath.Join(dir, "marathon.json")}
+	snapshot, err := provider.loadCachedConfiguration()
+	require.NoError(t, err)
+	assert.Nil(t, snapshot, "a missing cache file must not be an error")
+
+	configuration := &types.Configuration{
+		Backends: map[string]*types.Backend{
+			"backend-app": {Servers: map[string]types.Server{"server-task": {URL: "http://10.0.0.1:80", Weight: 1}}},
+		},
+		Frontends: map[string]*types.Frontend{
+			"frontend-app": {Backend: "backend-app", Routes: map[string]types.Route{"route-host-app": {Rule: "Host:app.docker.localhost"}}},
+		},
+	}
+	require.NoError(t, provider.cacheConfiguration(configuration))
+	require.NoError(t, provider.cacheConfiguration(configuration))
+
+	snapshot, err = provider.loadCachedConfiguration()
+	require.NoError(t, err)
+	require.NotNil(t, snapshot)
+	assert.Equal(t, configuration, snapshot.Configuration)
+	assert.WithinDuration(t, time.Now(), snapshot.Time, time.Minute)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1, "temporary files must not be left behind")
+	assert.Equal(t, "marathon.json", files[0].Name())
+
+	require.NoError(t, ioutil.WriteFile(provider.CacheFile, []byte("{"), 0600))
+	_, err = provider.loadCachedConfiguration()
+	assert.Error(t, err)
+}
+
+func TestMarathonLoadConfigCachesConfiguration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "traefik-marathon-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	provider := &Provider{
+		Domain:           "docker.localhost",
+		ExposedByDefault: true,
+		CacheFile:        filepath.Join(dir, "marathon.json"),
+		marathonClient: newScriptedClient(
+			respond(application(appID("/app"), appPorts(80),
+				withTasks(task(taskID("task"), taskHost("10.0.0.1"), taskPorts(80), taskState(taskStateRunning))))),
+			respondError("marathon unavailable"),
+		),
+	}
+
+	configuration := provider.loadMarathonConfig()
+	require.NotNil(t, configuration)
+	assert.Nil(t, provider.loadMarathonConfig())
+
+	snapshot, err := provider.loadCachedConfiguration()
+	require.NoError(t, err)
+	require.NotNil(t, snapshot, "a failed sync must not remove the cached configuration")
+	expected, err := json.Marshal(configuration)
+	require.NoError(t, err)
+	actual, err := json.Marshal(snapshot.Configuration)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+}
+
+func TestMarathonProvideServesCachedConfiguration(t *testing.T) {
+	server, requested := newBlockingMarathonServer()
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "traefik-marathon-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cached := &types.Configuration{
+		Backends: map[string]*types.Backend{
+			"backend-app": {Servers: map[string]types.Server{"server-task": {URL: "http://10.0.0.1:80"}}},
+		},
+	}
+	provider := &Provider{Endpoint: server.URL, CacheFile: filepath.Join(dir, "marathon.json")}
+	require.NoError(t, provider.cacheConfiguration(cached))
+
+	pool := safe.NewPool(context.Background())
+	defer pool.Cleanup()
+	configurationChan := make(chan types.ConfigMessage, 10)
+	go provider.Provide(configurationChan, pool, types.Constraints{})
+	defer provider.Stop()
+
+	select {
+	case configMsg := <-configurationChan:
+		assert.Equal(t, "marathon", configMsg.ProviderName)
+		assert.Equal(t, cached, configMsg.Configuration)
+		assert.Equal(t, []string{"configuration cache"}, configMsg.Causes)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the cached configuration was not served")
+	}
+	<-requested
+}
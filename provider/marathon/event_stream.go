@@ -0,0 +1,271 @@
+package marathon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/donovanhide/eventsource"
+	"github.com/gambol99/go-marathon"
+)
+
+const defaultEventsReconnectInterval = time.Second
+
+var errEventsHeartbeatTimeout = errors.New("no data received on the Marathon events stream before the heartbeat timeout")
+
+// eventStreamRoundTripper supervises the SSE events stream opened by subscribeEvents, which resubscribes
+// whenever the stream fails but cannot notice a connection dropped silently, e.g. by the idle timeout
+// of a load balancer:
+//   - the stream is closed when nothing, not even the keepalives of Marathon, is received within the heartbeat timeout,
+//   - the resubscriptions are delayed by the reconnect interval,
+//   - every successful resubscription is notified on reconnected, the events sent while disconnected being lost,
+//   - every failed subscription and heartbeat timeout is reported to failed, if set, so that a Marathon
+//     unreachable while no event is expected is noticed.
+type eventStreamRoundTripper struct {
+	next              http.RoundTripper
+	ctx               context.Context
+	heartbeatTimeout  time.Duration
+	reconnectInterval time.Duration
+	reconnected       chan struct{}
+	failed            func(error)
+	lock              sync.Mutex
+	subscribed        bool
+	attempted         bool
+}
+
+func newEventStreamRoundTripper(ctx context.Context, next http.RoundTripper, heartbeatTimeout, reconnectInterval time.Duration) *eventStreamRoundTripper {
+	if reconnectInterval <= 0 {
+		reconnectInterval = defaultEventsReconnectInterval
+	}
+	return &eventStreamRoundTripper{
+		next:              next,
+		ctx:               ctx,
+		heartbeatTimeout:  heartbeatTimeout,
+		reconnectInterval: reconnectInterval,
+		reconnected:       make(chan struct{}, 1),
+	}
+}
+
+func (rt *eventStreamRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept") != "text/event-stream" {
+		return rt.next.RoundTrip(req)
+	}
+
+	rt.lock.Lock()
+	retry := rt.attempted
+	rt.attempted = true
+	rt.lock.Unlock()
+	if retry {
+		log.Debugf("Reconnecting to the Marathon events stream in %s", rt.reconnectInterval)
+		timer := time.NewTimer(rt.reconnectInterval)
+		select {
+		case <-timer.C:
+		case <-rt.ctx.Done():
+			timer.Stop()
+			return nil, rt.ctx.Err()
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+
+	resp, err := rt.next.RoundTrip(req)
+	if err != nil {
+		rt.fail(fmt.Errorf("subscribing to the Marathon events stream: %v", err))
+		return resp, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		rt.fail(fmt.Errorf("subscribing to the Marathon events stream: unexpected status %d", resp.StatusCode))
+		return resp, err
+	}
+
+	rt.lock.Lock()
+	resubscribed := rt.subscribed
+	rt.subscribed = true
+	rt.lock.Unlock()
+	if resubscribed {
+		log.Infof("Marathon events stream re-established")
+		select {
+		case rt.reconnected <- struct{}{}:
+		default:
+			// a refresh is already pending
+		}
+	}
+
+	if rt.heartbeatTimeout > 0 {
+		resp.Body = newHeartbeatBody(resp.Body, rt.heartbeatTimeout, func() {
+			rt.fail(errEventsHeartbeatTimeout)
+		})
+	}
+	return resp, nil
+}
+
+func (rt *eventStreamRoundTripper) fail(err error) {
+	if rt.failed != nil && rt.ctx.Err() == nil {
+		rt.failed(err)
+	}
+}
+
+// heartbeatBody closes the events stream when no data is read within the timeout,
+// making the pending and next reads fail with errEventsHeartbeatTimeout.
+// The expiration of the timeout is notified to onExpire, if set.
+type heartbeatBody struct {
+	io.ReadCloser
+	timeout  time.Duration
+	timer    *time.Timer
+	onExpire func()
+	lock     sync.Mutex
+	expired  bool
+}
+
+func newHeartbeatBody(body io.ReadCloser, timeout time.Duration, onExpire func()) *heartbeatBody {
+	b := &heartbeatBody{ReadCloser: body, timeout: timeout, onExpire: onExpire}
+	b.timer = time.AfterFunc(timeout, b.expire)
+	return b
+}
+
+func (b *heartbeatBody) expire() {
+	b.lock.Lock()
+	b.expired = true
+	b.lock.Unlock()
+	log.Warnf("No data received on the Marathon events stream for %s, reconnecting", b.timeout)
+	if b.onExpire != nil {
+		b.onExpire()
+	}
+	b.ReadCloser.Close()
+}
+
+func (b *heartbeatBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.expired {
+		return n, errEventsHeartbeatTimeout
+	}
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	return n, err
+}
+
+func (b *heartbeatBody) Close() error {
+	b.timer.Stop()
+	return b.ReadCloser.Close()
+}
+
+// subscribeEvents reads the Marathon events stream until the context is done, sending the events matching
+// the filter to the returned channel. Whenever the stream fails, it is opened again on the next Marathon
+// endpoint, the client delaying the resubscriptions. Unlike the subscription of go-marathon, whose goroutine
+// retries forever, nothing is left running once the context is done.
+func (p *Provider) subscribeEvents(ctx context.Context, client *http.Client, filter int) <-chan *marathon.Event {
+	events := make(chan *marathon.Event)
+	endpoints := strings.Split(p.Endpoint, ",")
+	p.goCtx(ctx, func(ctx context.Context) {
+		for i := 0; ctx.Err() == nil; i = (i + 1) % len(endpoints) {
+			err := p.readEvents(ctx, client, endpoints[i], filter, events)
+			if ctx.Err() == nil {
+				log.Debugf("Marathon events stream of %s interrupted: %v", endpoints[i], err)
+			}
+		}
+	})
+	return events
+}
+
+// readEvents reads the events stream of a Marathon endpoint until it fails
+func (p *Provider) readEvents(ctx context.Context, client *http.Client, endpoint string, filter int, events chan<- *marathon.Event) error {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(endpoint, "/")+"/v2/events", nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	setMarathonCredentials(req, p.Basic, p.DCOSToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	decoder := eventsource.NewDecoder(resp.Body)
+	for {
+		publication, err := decoder.Decode()
+		if err != nil {
+			return err
+		}
+		event, err := decodeEvent(publication.Data())
+		if err != nil {
+			log.Debugf("Ignoring Marathon event: %s", err)
+			continue
+		}
+		if event.ID&filter == 0 {
+			continue
+		}
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// decodeEvent decodes the data of a Marathon event, from the type given by its eventType field
+func decodeEvent(data string) (*marathon.Event, error) {
+	eventType := struct {
+		EventType string `json:"eventType"`
+	}{}
+	if err := json.Unmarshal([]byte(data), &eventType); err != nil {
+		return nil, fmt.Errorf("failed to decode the event type of %s: %v", data, err)
+	}
+	event, err := marathon.GetEvent(eventType.EventType)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(data), event.Event); err != nil {
+		return nil, fmt.Errorf("failed to decode the %s event: %v", event.Name, err)
+	}
+	return event, nil
+}
+
+// describeEvent returns a short description of a Marathon event, naming the application and task it is about
+func describeEvent(event *marathon.Event) string {
+	switch e := event.Event.(type) {
+	case *marathon.EventStatusUpdate:
+		return fmt.Sprintf("%s: task %s of application %s is %s", event.Name, e.TaskID, e.AppID, e.TaskStatus)
+	case *marathon.EventHealthCheckChanged:
+		return fmt.Sprintf("%s: task %s of application %s alive: %t", event.Name, e.TaskID, e.AppID, e.Alive)
+	case *marathon.EventFailedHealthCheck:
+		return fmt.Sprintf("%s: application %s", event.Name, e.AppID)
+	case *marathon.EventAppTerminated:
+		return fmt.Sprintf("%s: application %s", event.Name, e.AppID)
+	default:
+		return event.Name
+	}
+}
+
+// serverChangeReason returns the name of the server of the task a Marathon event is about,
+// and the reason of the change of the server, if the event is about a task
+func serverChangeReason(event *marathon.Event) (string, string, bool) {
+	switch e := event.Event.(type) {
+	case *marathon.EventStatusUpdate:
+		return taskServerName(e.TaskID), "task " + e.TaskStatus, true
+	case *marathon.EventHealthCheckChanged:
+		if e.Alive {
+			return taskServerName(e.TaskID), "task healthy", true
+		}
+		return taskServerName(e.TaskID), "task unhealthy", true
+	default:
+		return "", "", false
+	}
+}
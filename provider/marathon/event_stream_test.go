@@ -0,0 +1,202 @@
+package marathon
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gambol99/go-marathon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeartbeatBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		for i := 0; i < 3; i++ {
+			w.Write([]byte("\r\n"))
+			flusher.Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+		// the connection is then silently kept open
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	var expired bool
+	body := newHeartbeatBody(resp.Body, 100*time.Millisecond, func() {
+		expired = true
+	})
+	defer body.Close()
+
+	start := time.Now()
+	data, err := ioutil.ReadAll(body)
+	assert.Equal(t, errEventsHeartbeatTimeout, err)
+	assert.Equal(t, "\r\n\r\n\r\n", string(data))
+	assert.True(t, time.Since(start) >= 140*time.Millisecond, "the keepalives must postpone the timeout")
+	assert.True(t, expired, "the timeout must be reported")
+}
+
+func TestEventStreamRoundTripper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	rt := newEventStreamRoundTripper(context.Background(), http.DefaultTransport, 0, 50*time.Millisecond)
+	var failures int
+	rt.failed = func(error) {
+		failures++
+	}
+	roundTrip := func(path string, stream bool) (time.Duration, bool) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		if stream {
+			req.Header.Set("Accept", "text/event-stream")
+		}
+		start := time.Now()
+		resp, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		select {
+		case <-rt.reconnected:
+			return time.Since(start), true
+		default:
+			return time.Since(start), false
+		}
+	}
+
+	elapsed, reconnected := roundTrip("/", true)
+	assert.True(t, elapsed < 50*time.Millisecond, "first subscription must not be delayed")
+	assert.False(t, reconnected, "first subscription is not a reconnection")
+
+	elapsed, reconnected = roundTrip("/", false)
+	assert.True(t, elapsed < 50*time.Millisecond, "API calls must not be delayed")
+	assert.False(t, reconnected)
+
+	elapsed, reconnected = roundTrip("/fail", true)
+	assert.True(t, elapsed >= 50*time.Millisecond, "resubscription must wait for the reconnect interval")
+	assert.False(t, reconnected, "failed resubscription is not a reconnection")
+	assert.Equal(t, 1, failures, "failed resubscription must be reported")
+
+	elapsed, reconnected = roundTrip("/", true)
+	assert.True(t, elapsed >= 50*time.Millisecond, "resubscription must wait for the reconnect interval")
+	assert.True(t, reconnected)
+}
+
+func TestEventStreamRoundTripperStopsWaiting(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rt := newEventStreamRoundTripper(ctx, http.DefaultTransport, 0, time.Hour)
+	rt.attempted = true
+	cancel()
+
+	req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:1/", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "text/event-stream")
+	_, err = rt.RoundTrip(req)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestMarathonDescribeEvent(t *testing.T) {
+	cases := []struct {
+		desc     string
+		event    *marathon.Event
+		expected string
+	}{
+		{
+			desc: "status update",
+			event: &marathon.Event{
+				Name:  "status_update_event",
+				Event: &marathon.EventStatusUpdate{AppID: "/app", TaskID: "task1", TaskStatus: "TASK_KILLED"},
+			},
+			expected: "status_update_event: task task1 of application /app is TASK_KILLED",
+		},
+		{
+			desc: "health status changed",
+			event: &marathon.Event{
+				Name:  "health_status_changed_event",
+				Event: &marathon.EventHealthCheckChanged{AppID: "/app", TaskID: "task1", Alive: false},
+			},
+			expected: "health_status_changed_event: task task1 of application /app alive: false",
+		},
+		{
+			desc: "application terminated",
+			event: &marathon.Event{
+				Name:  "app_terminated_event",
+				Event: &marathon.EventAppTerminated{AppID: "/app"},
+			},
+			expected: "app_terminated_event: application /app",
+		},
+		{
+			desc:     "other event",
+			event:    &marathon.Event{Name: "deployment_success"},
+			expected: "deployment_success",
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.expected, describeEvent(test.event))
+		})
+	}
+}
+
+func TestMarathonServerChangeReason(t *testing.T) {
+	cases := []struct {
+		desc               string
+		event              *marathon.Event
+		expectedServerName string
+		expectedReason     string
+		expectedOk         bool
+	}{
+		{
+			desc: "status update",
+			event: &marathon.Event{
+				Name:  "status_update_event",
+				Event: &marathon.EventStatusUpdate{AppID: "/app", TaskID: "app.task1", TaskStatus: "TASK_KILLED"},
+			},
+			expectedServerName: "server-app-task1",
+			expectedReason:     "task TASK_KILLED",
+			expectedOk:         true,
+		},
+		{
+			desc: "health status changed",
+			event: &marathon.Event{
+				Name:  "health_status_changed_event",
+				Event: &marathon.EventHealthCheckChanged{AppID: "/app", TaskID: "app.task1", Alive: false},
+			},
+			expectedServerName: "server-app-task1",
+			expectedReason:     "task unhealthy",
+			expectedOk:         true,
+		},
+		{
+			desc: "application terminated",
+			event: &marathon.Event{
+				Name:  "app_terminated_event",
+				Event: &marathon.EventAppTerminated{AppID: "/app"},
+			},
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			serverName, reason, ok := serverChangeReason(test.event)
+			assert.Equal(t, test.expectedServerName, serverName)
+			assert.Equal(t, test.expectedReason, reason)
+			assert.Equal(t, test.expectedOk, ok)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package marathon
+
+import (
+	"errors"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/gambol99/go-marathon"
+)
+
+var errNoHealthyTask = errors.New("no healthy task")
+
+// resolveFailovers substitutes the servers of the fallback application named by the failover label to the
+// servers of the applications without any healthy task, giving active/passive failover.
+// The fallback applications are looked up among the exposed applications only, so that an application
+// cannot route its traffic to an application not meant to be exposed. It returns the applications, those failing over having their tasks removed, and the
+// fallback application of each of them, with its healthy tasks only, indexed by application ID.
+func (p *Provider) resolveFailovers(filteredApps []marathon.Application) ([]marathon.Application, map[string]*marathon.Application) {
+	failovers := make(map[string]*marathon.Application)
+	resolved := make([]marathon.Application, 0, len(filteredApps))
+	for _, app := range filteredApps {
+		fallbackID, ok := p.getLabel(app, types.LabelBackendFailoverApp)
+		if !ok || len(fallbackID) == 0 || p.hasHealthyTasks(app) {
+			resolved = append(resolved, app)
+			continue
+		}
+
+		fallback, err := p.healthyFallback(fallbackID, filteredApps)
+		if err != nil {
+			log.Warnf("Unable to fail application %s over to %s: %v", app.ID, fallbackID, err)
+			resolved = append(resolved, app)
+			continue
+		}
+
+		log.Infof("Application %s has no healthy task, failing over to %s", app.ID, fallbackID)
+		app.Tasks = nil
+		failovers[app.ID] = fallback
+		resolved = append(resolved, app)
+	}
+	return resolved, failovers
+}
+
+func (p *Provider) hasHealthyTasks(application marathon.Application) bool {
+	for _, task := range application.Tasks {
+		if p.taskFilter(*task, application) {
+			return true
+		}
+	}
+	return false
+}
+
+// healthyFallback returns the fallback application with its healthy tasks only
+func (p *Provider) healthyFallback(fallbackID string, apps []marathon.Application) (*marathon.Application, error) {
+	fallback, err := getApplication(marathon.Task{AppID: fallbackID}, apps)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []*marathon.Task
+	for _, task := range fallback.Tasks {
+		if p.taskFilter(*task, fallback) {
+			tasks = append(tasks, task)
+		}
+	}
+	if len(tasks) == 0 {
+		return nil, errNoHealthyTask
+	}
+	fallback.Tasks = tasks
+	return &fallback, nil
+}
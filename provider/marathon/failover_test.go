@@ -0,0 +1,83 @@
+package marathon
+
+import (
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/gambol99/go-marathon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarathonLoadConfigFailover(t *testing.T) {
+	fallbackTasks := withTasks(
+		task(taskID("fallback-task"), taskHost("10.0.1.1"), taskPorts(80), taskState(taskStateRunning)),
+		task(taskID("fallback-staging"), taskHost("10.0.1.2"), taskPorts(80), taskState("TASK_STAGING")),
+	)
+	fallback := application(appID("/fallback"), appPorts(80), fallbackTasks)
+
+	cases := []struct {
+		desc            string
+		application     marathon.Application
+		fallbackHidden  bool
+		expectedServers map[string]types.Server
+	}{
+		{
+			desc: "healthy primary",
+			application: application(appID("/app"), appPorts(80), withHealthCheck(), withLabel(types.LabelBackendFailoverApp, "/fallback"),
+				withTasks(task(taskID("task"), taskHost("10.0.0.1"), taskPorts(80), taskState(taskStateRunning), healthResults(true)))),
+			expectedServers: map[string]types.Server{
+				"server-task": {URL: "http://10.0.0.1:80"},
+			},
+		},
+		{
+			desc: "unhealthy primary",
+			application: application(appID("/app"), appPorts(80), withHealthCheck(), withLabel(types.LabelBackendFailoverApp, "/fallback"),
+				withTasks(task(taskID("task"), taskHost("10.0.0.1"), taskPorts(80), taskState(taskStateRunning), healthResults(false)))),
+			expectedServers: map[string]types.Server{
+				"server-fallback-task": {URL: "http://10.0.1.1:80"},
+			},
+		},
+		{
+			desc:        "primary without task",
+			application: application(appID("/app"), appPorts(80), withLabel(types.LabelBackendFailoverApp, "/fallback")),
+			expectedServers: map[string]types.Server{
+				"server-fallback-task": {URL: "http://10.0.1.1:80"},
+			},
+		},
+		{
+			desc:        "unknown fallback",
+			application: application(appID("/app"), appPorts(80), withLabel(types.LabelBackendFailoverApp, "/unknown")),
+		},
+		{
+			desc:           "fallback not exposed",
+			application:    application(appID("/app"), appPorts(80), withLabel(types.LabelBackendFailoverApp, "/fallback")),
+			fallbackHidden: true,
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			fallbackApp := fallback
+			if test.fallbackHidden {
+				fallbackApp = application(appID("/fallback"), appPorts(80), withLabel(types.LabelEnable, "false"), fallbackTasks)
+			}
+			provider := &Provider{
+				Domain:           "docker.localhost",
+				ExposedByDefault: true,
+				marathonClient:   newScriptedClient(respond(test.application, fallbackApp)),
+			}
+
+			configuration := provider.loadMarathonConfig()
+			require.NotNil(t, configuration)
+			if test.expectedServers == nil {
+				assert.NotContains(t, configuration.Backends, "backend-app")
+			} else {
+				require.Contains(t, configuration.Backends, "backend-app")
+				assert.Equal(t, test.expectedServers, configuration.Backends["backend-app"].Servers)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package marathon
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/gambol99/go-marathon"
+)
+
+// frontendRuleData is the data given to the frontend rule template.
+// It holds the backend name template data along with the domain of the application.
+type frontendRuleData struct {
+	backendNameData
+	// Domain is the domain of the application, from its traefik.domain label or the provider domain
+	Domain string
+	// SubDomain is the sub domain used by the default rule, e.g. group-app
+	SubDomain string
+}
+
+func parseFrontendRuleTemplate(text string) (*template.Template, error) {
+	return template.New("frontendRule").Option("missingkey=zero").Parse(text)
+}
+
+// executeFrontendRuleTemplate renders the default frontend rule of the application
+// with the configured template.
+func (p *Provider) executeFrontendRuleTemplate(application marathon.Application) (string, error) {
+	data := frontendRuleData{
+		backendNameData: newBackendNameData(application),
+		Domain:          p.getDomain(application),
+		SubDomain:       p.getSubDomain(application.ID),
+	}
+
+	var buffer bytes.Buffer
+	if err := p.frontendRuleTemplate.Execute(&buffer, data); err != nil {
+		return "", err
+	}
+	rule := strings.TrimSpace(buffer.String())
+	if rule == "" {
+		return "", fmt.Errorf("empty frontend rule")
+	}
+	return rule, nil
+}
@@ -0,0 +1,75 @@
+package marathon
+
+import (
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/gambol99/go-marathon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarathonGetFrontendRuleWithTemplate(t *testing.T) {
+	cases := []struct {
+		desc        string
+		template    string
+		application marathon.Application
+		expected    string
+	}{
+		{
+			desc:        "label takes precedence",
+			template:    "PathPrefix:/{{.Name}}",
+			application: application(appID("/group/app"), withLabel(types.LabelFrontendRule, "Host:foo.bar")),
+			expected:    "Host:foo.bar",
+		},
+		{
+			desc:        "path routing",
+			template:    "PathPrefix:/{{.Group}}/{{.Name}}",
+			application: application(appID("/team/sub/app")),
+			expected:    "PathPrefix:/team-sub/app",
+		},
+		{
+			desc:        "sub domain and domain",
+			template:    "Host:{{.SubDomain}}.{{.Domain}};PathPrefix:/api",
+			application: application(appID("/group/app")),
+			expected:    "Host:group-app.docker.localhost;PathPrefix:/api",
+		},
+		{
+			desc:        "domain label",
+			template:    "Host:{{.Name}}.{{.Domain}}",
+			application: application(appID("/group/app"), withLabel(types.LabelDomain, "example.com")),
+			expected:    "Host:app.example.com",
+		},
+		{
+			desc:        "label value",
+			template:    `Headers:X-Tenant,{{index .Labels "tenant"}}`,
+			application: application(appID("/app"), withLabel("tenant", "acme")),
+			expected:    "Headers:X-Tenant,acme",
+		},
+		{
+			desc:        "empty rendering falls back to the default rule",
+			template:    `{{index .Labels "rule"}}`,
+			application: application(appID("/group/app")),
+			expected:    "Host:group-app.docker.localhost",
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			frontendRuleTemplate, err := parseFrontendRuleTemplate(test.template)
+			require.NoError(t, err)
+			provider := &Provider{
+				Domain:               "docker.localhost",
+				frontendRuleTemplate: frontendRuleTemplate,
+			}
+			assert.Equal(t, test.expected, provider.getFrontendRule(test.application))
+		})
+	}
+}
+
+func TestParseFrontendRuleTemplateError(t *testing.T) {
+	_, err := parseFrontendRuleTemplate("{{.Name")
+	assert.Error(t, err)
+}
@@ -0,0 +1,66 @@
+package marathon
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gambol99/go-marathon"
+)
+
+// GroupLabels holds the labels of Marathon groups, indexed by group ID.
+// Marathon groups having no labels of their own, they are set in the provider configuration.
+type GroupLabels map[string]map[string]string
+
+// labelledGroup is a group of an application, with its labels
+type labelledGroup struct {
+	// ID is the normalized group ID, e.g. /prod/payments, or / for the root group
+	ID     string
+	Labels map[string]string
+}
+
+// getApplicationGroupLabels returns the labels configured for the groups containing the application,
+// from the outermost group to the innermost one
+func (p *Provider) getApplicationGroupLabels(application marathon.Application) []labelledGroup {
+	var groups []labelledGroup
+	for id, labels := range p.GroupLabels {
+		id = "/" + strings.Trim(id, "/")
+		if id == "/" || strings.HasPrefix(application.ID, id+"/") {
+			groups = append(groups, labelledGroup{ID: id, Labels: labels})
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groupDepth(groups[i].ID) < groupDepth(groups[j].ID)
+	})
+	return groups
+}
+
+func groupDepth(id string) int {
+	if id == "/" {
+		return 0
+	}
+	return strings.Count(id, "/")
+}
+
+// withGroupLabels returns the application with the labels of its groups it does not set itself,
+// the labels of the innermost groups taking precedence.
+// The labels of the given application are left untouched.
+func (p *Provider) withGroupLabels(application marathon.Application) marathon.Application {
+	groups := p.getApplicationGroupLabels(application)
+	if len(groups) == 0 {
+		return application
+	}
+
+	labels := make(map[string]string)
+	for _, group := range groups {
+		for key, value := range group.Labels {
+			labels[key] = value
+		}
+	}
+	if application.Labels != nil {
+		for key, value := range *application.Labels {
+			labels[key] = value
+		}
+	}
+	application.Labels = &labels
+	return application
+}
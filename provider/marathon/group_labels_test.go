@@ -0,0 +1,123 @@
+package marathon
+
+import (
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/gambol99/go-marathon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarathonWithGroupLabels(t *testing.T) {
+	provider := &Provider{
+		GroupLabels: GroupLabels{
+			"/":                  {types.LabelTags: "public"},
+			"/prod":              {types.LabelEnable: "false", types.LabelFrontendPriority: "10"},
+			"prod/payments/":     {types.LabelEnable: "true"},
+			"/prod/payments/api": {types.LabelWeight: "5"},
+		},
+	}
+
+	cases := []struct {
+		desc        string
+		application marathon.Application
+		expected    map[string]string
+	}{
+		{
+			desc:        "root group only",
+			application: application(appID("/dev/app")),
+			expected:    map[string]string{types.LabelTags: "public"},
+		},
+		{
+			desc:        "nested groups",
+			application: application(appID("/prod/payments/app")),
+			expected: map[string]string{
+				types.LabelTags:             "public",
+				types.LabelEnable:           "true",
+				types.LabelFrontendPriority: "10",
+			},
+		},
+		{
+			desc:        "application label overrides group label",
+			application: application(appID("/prod/app"), withLabel(types.LabelEnable, "true")),
+			expected: map[string]string{
+				types.LabelTags:             "public",
+				types.LabelEnable:           "true",
+				types.LabelFrontendPriority: "10",
+			},
+		},
+		{
+			desc:        "group prefix is not a parent group",
+			application: application(appID("/production/app")),
+			expected:    map[string]string{types.LabelTags: "public"},
+		},
+		{
+			desc:        "application named after a group",
+			application: application(appID("/prod/payments/api")),
+			expected: map[string]string{
+				types.LabelTags:             "public",
+				types.LabelEnable:           "true",
+				types.LabelFrontendPriority: "10",
+			},
+		},
+		{
+			desc:        "application without labels",
+			application: marathon.Application{ID: "/prod/app"},
+			expected: map[string]string{
+				types.LabelTags:             "public",
+				types.LabelEnable:           "false",
+				types.LabelFrontendPriority: "10",
+			},
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			original := test.application.Labels
+			var originalLabels map[string]string
+			if original != nil {
+				originalLabels = copyLabels(*original)
+			}
+
+			actual := provider.withGroupLabels(test.application)
+			require.NotNil(t, actual.Labels)
+			assert.Equal(t, test.expected, *actual.Labels)
+			if original != nil {
+				assert.Equal(t, originalLabels, *original, "the application labels must be left untouched")
+			}
+		})
+	}
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	copied := make(map[string]string, len(labels))
+	for key, value := range labels {
+		copied[key] = value
+	}
+	return copied
+}
+
+func TestMarathonLoadConfigGroupLabels(t *testing.T) {
+	running := withTasks(task(taskID("task"), taskHost("10.0.0.1"), taskPorts(80), taskState(taskStateRunning)))
+	provider := &Provider{
+		Domain:           "docker.localhost",
+		ExposedByDefault: true,
+		GroupLabels: GroupLabels{
+			"/internal": {types.LabelEnable: "false"},
+		},
+		marathonClient: newScriptedClient(respond(
+			application(appID("/public/app"), appPorts(80), running),
+			application(appID("/internal/app"), appPorts(80), running),
+			application(appID("/internal/exposed"), appPorts(80), withLabel(types.LabelEnable, "true"), running),
+		)),
+	}
+
+	configuration := provider.loadMarathonConfig()
+	require.NotNil(t, configuration)
+	assert.Contains(t, configuration.Backends, "backend-public-app")
+	assert.NotContains(t, configuration.Backends, "backend-internal-app")
+	assert.Contains(t, configuration.Backends, "backend-internal-exposed")
+}
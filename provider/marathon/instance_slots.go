@@ -0,0 +1,81 @@
+package marathon
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/gambol99/go-marathon"
+)
+
+// instanceSlots assigns the tasks of the applications to stable instance slots: a task keeps its slot
+// as long as it runs, and a new task takes the lowest free slot, e.g. the slot of the task it replaces
+// when the application is redeployed, so that the other instances keep their index. The tasks without
+// a slot yet are assigned one in the order of their start time, the tasks not started yet coming last.
+type instanceSlots struct {
+	lock sync.Mutex
+	// slots are the slots of the tasks, by application ID and task ID
+	slots map[string]map[string]int
+}
+
+// update assigns the slots of the tasks of the applications, forgetting the applications and tasks gone
+func (s *instanceSlots) update(applications []marathon.Application) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	slots := make(map[string]map[string]int, len(applications))
+	for _, application := range applications {
+		slots[application.ID] = s.assign(application)
+	}
+	s.slots = slots
+}
+
+// slot returns the slot of the task of the application
+func (s *instanceSlots) slot(task marathon.Task, application marathon.Application) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if slot, ok := s.slots[application.ID][task.ID]; ok {
+		return slot
+	}
+	if s.slots == nil {
+		s.slots = make(map[string]map[string]int)
+	}
+	s.slots[application.ID] = s.assign(application)
+	if slot, ok := s.slots[application.ID][task.ID]; ok {
+		return slot
+	}
+	return len(application.Tasks)
+}
+
+// assign returns the slots of the tasks of the application, keeping the slots of the known tasks
+func (s *instanceSlots) assign(application marathon.Application) map[string]int {
+	known := s.slots[application.ID]
+	slots := make(map[string]int, len(application.Tasks))
+	used := make(map[int]bool)
+	var newTasks []*marathon.Task
+	for _, task := range application.Tasks {
+		if slot, ok := known[task.ID]; ok {
+			slots[task.ID] = slot
+			used[slot] = true
+		} else {
+			newTasks = append(newTasks, task)
+		}
+	}
+
+	sort.Slice(newTasks, func(i, j int) bool {
+		if newTasks[i].StartedAt != newTasks[j].StartedAt {
+			if newTasks[i].StartedAt == "" || newTasks[j].StartedAt == "" {
+				return newTasks[j].StartedAt == ""
+			}
+			return newTasks[i].StartedAt < newTasks[j].StartedAt
+		}
+		return newTasks[i].ID < newTasks[j].ID
+	})
+	next := 0
+	for _, task := range newTasks {
+		for used[next] {
+			next++
+		}
+		slots[task.ID] = next
+		used[next] = true
+	}
+	return slots
+}
@@ -0,0 +1,36 @@
+package marathon
+
+import (
+	"testing"
+
+	"github.com/gambol99/go-marathon"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstanceSlots(t *testing.T) {
+	slots := &instanceSlots{}
+
+	app := application(appID("/app"), withTasks(
+		task(taskID("app.2"), taskStartedAt("2017-01-02T00:00:00.000Z")),
+		task(taskID("app.3")),
+		task(taskID("app.1"), taskStartedAt("2017-01-01T00:00:00.000Z")),
+	))
+	slots.update([]marathon.Application{app})
+	assert.Equal(t, 0, slots.slot(task(taskID("app.1")), app))
+	assert.Equal(t, 1, slots.slot(task(taskID("app.2")), app))
+	assert.Equal(t, 2, slots.slot(task(taskID("app.3")), app))
+
+	// app.1 is replaced by app.4, started after the other tasks
+	redeployed := application(appID("/app"), withTasks(
+		task(taskID("app.2"), taskStartedAt("2017-01-02T00:00:00.000Z")),
+		task(taskID("app.3"), taskStartedAt("2017-01-03T00:00:00.000Z")),
+		task(taskID("app.4"), taskStartedAt("2017-01-04T00:00:00.000Z")),
+	))
+	slots.update([]marathon.Application{redeployed})
+	assert.Equal(t, 1, slots.slot(task(taskID("app.2")), redeployed), "running task must keep its slot")
+	assert.Equal(t, 2, slots.slot(task(taskID("app.3")), redeployed), "running task must keep its slot")
+	assert.Equal(t, 0, slots.slot(task(taskID("app.4")), redeployed), "new task must take the free slot")
+
+	slots.update(nil)
+	assert.Empty(t, slots.slots, "applications gone must be forgotten")
+}
@@ -0,0 +1,51 @@
+package marathon
+
+import (
+	"regexp"
+
+	"github.com/containous/traefik/log"
+	"github.com/gambol99/go-marathon"
+)
+
+// labelEnvReference matches the references to environment variables in label values, e.g. {env.PUBLIC_HOSTNAME}
+var labelEnvReference = regexp.MustCompile(`\{env\.([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// withEnvLabels returns the application with the {env.NAME} references of its label values expanded
+// with its environment variables, so that the same application definition can be promoted across
+// environments. The references to undefined variables are left as is.
+// The labels of the given application are left untouched.
+func (p *Provider) withEnvLabels(application marathon.Application) marathon.Application {
+	if application.Labels == nil {
+		return application
+	}
+
+	var env map[string]string
+	if application.Env != nil {
+		env = *application.Env
+	}
+	var labels map[string]string
+	for key, value := range *application.Labels {
+		expanded := labelEnvReference.ReplaceAllStringFunc(value, func(reference string) string {
+			name := labelEnvReference.FindStringSubmatch(reference)[1]
+			if variable, ok := env[name]; ok {
+				return variable
+			}
+			log.Warnf("Unable to expand %s in label %s of application %s: undefined environment variable", reference, key, application.ID)
+			return reference
+		})
+		if expanded == value {
+			continue
+		}
+		if labels == nil {
+			labels = make(map[string]string, len(*application.Labels))
+			for key, value := range *application.Labels {
+				labels[key] = value
+			}
+		}
+		labels[key] = expanded
+	}
+	if labels != nil {
+		application.Labels = &labels
+	}
+	return application
+}
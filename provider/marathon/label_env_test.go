@@ -0,0 +1,76 @@
+package marathon
+
+import (
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/gambol99/go-marathon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarathonWithEnvLabels(t *testing.T) {
+	cases := []struct {
+		desc        string
+		application marathon.Application
+		expected    map[string]string
+	}{
+		{
+			desc: "expanded reference",
+			application: application(withEnv("PUBLIC_HOSTNAME", "app.example.com"),
+				withLabel(types.LabelFrontendRule, "Host:{env.PUBLIC_HOSTNAME}")),
+			expected: map[string]string{types.LabelFrontendRule: "Host:app.example.com"},
+		},
+		{
+			desc: "several references",
+			application: application(withEnv("HOST", "app.example.com"), withEnv("PREFIX", "/v1"),
+				withLabel(types.LabelFrontendRule, "Host:{env.HOST};PathPrefix:{env.PREFIX}")),
+			expected: map[string]string{types.LabelFrontendRule: "Host:app.example.com;PathPrefix:/v1"},
+		},
+		{
+			desc:        "undefined variable",
+			application: application(withLabel(types.LabelFrontendRule, "Host:{env.PUBLIC_HOSTNAME}")),
+			expected:    map[string]string{types.LabelFrontendRule: "Host:{env.PUBLIC_HOSTNAME}"},
+		},
+		{
+			desc:        "no reference",
+			application: application(withEnv("HOST", "app.example.com"), withLabel(types.LabelFrontendRule, "PathPrefix:/{id}")),
+			expected:    map[string]string{types.LabelFrontendRule: "PathPrefix:/{id}"},
+		},
+	}
+
+	provider := &Provider{}
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			labels := *test.application.Labels
+			original := make(map[string]string)
+			for key, value := range labels {
+				original[key] = value
+			}
+
+			application := provider.withEnvLabels(test.application)
+			require.NotNil(t, application.Labels)
+			assert.Equal(t, test.expected, *application.Labels)
+			assert.Equal(t, original, labels, "the labels of the given application must be left untouched")
+		})
+	}
+}
+
+func TestMarathonLoadConfigEnvLabels(t *testing.T) {
+	provider := &Provider{
+		Domain:           "docker.localhost",
+		ExposedByDefault: true,
+		marathonClient: newScriptedClient(respond(
+			application(appID("/app"), appPorts(80), withEnv("PUBLIC_HOSTNAME", "app.example.com"),
+				withLabel(types.LabelFrontendRule, "Host:{env.PUBLIC_HOSTNAME}"),
+				withTasks(task(taskID("task"), taskHost("10.0.0.1"), taskPorts(80), taskState(taskStateRunning)))),
+		)),
+	}
+
+	configuration := provider.loadMarathonConfig()
+	require.NotNil(t, configuration)
+	require.Contains(t, configuration.Frontends, "frontend-app")
+	assert.Equal(t, "Host:app.example.com", configuration.Frontends["frontend-app"].Routes["route-host-app"].Rule)
+}
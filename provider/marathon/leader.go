@@ -0,0 +1,152 @@
+package marathon
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+const defaultLeaderCheckInterval = 30 * time.Second
+
+var leaderChangesCounter = stdprometheus.NewCounter(stdprometheus.CounterOpts{
+	Name: "traefik_marathon_leader_changes_total",
+	Help: "How many times the Marathon provider re-targeted a new Marathon leader.",
+})
+
+func init() {
+	stdprometheus.MustRegister(leaderChangesCounter)
+}
+
+type leaderClient interface {
+	Leader() (string, error)
+}
+
+// leaderRoundTripper sends every request (API calls and events stream) to the current
+// Marathon leader when it is known, instead of the configured endpoints.
+// Requests fall back to the configured endpoints when the leader is unreachable.
+type leaderRoundTripper struct {
+	next    http.RoundTripper
+	lock    sync.Mutex
+	leader  string
+	streams map[*leaderStreamBody]bool
+}
+
+func newLeaderRoundTripper(next http.RoundTripper) *leaderRoundTripper {
+	return &leaderRoundTripper{
+		next:    next,
+		streams: make(map[*leaderStreamBody]bool),
+	}
+}
+
+func (rt *leaderRoundTripper) getLeader() string {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	return rt.leader
+}
+
+// setLeader records the current leader (host:port) and returns true if it changed.
+// On change, the opened events streams are closed so that they reconnect to the new leader.
+func (rt *leaderRoundTripper) setLeader(leader string) bool {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	if rt.leader == leader {
+		return false
+	}
+	rt.leader = leader
+	for stream := range rt.streams {
+		stream.ReadCloser.Close()
+		delete(rt.streams, stream)
+	}
+	return true
+}
+
+func (rt *leaderRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	leader := rt.getLeader()
+	if leader == "" || leader == req.URL.Host {
+		return rt.track(rt.next.RoundTrip(req))
+	}
+
+	leaderReq := new(http.Request)
+	*leaderReq = *req
+	leaderURL := *req.URL
+	leaderURL.Host = leader
+	leaderReq.URL = &leaderURL
+	leaderReq.Host = leader
+
+	resp, err := rt.next.RoundTrip(leaderReq)
+	if err != nil && req.Body == nil {
+		log.Warnf("Marathon leader %s unreachable, falling back to %s: %s", leader, req.URL.Host, err)
+		rt.lock.Lock()
+		if rt.leader == leader {
+			rt.leader = ""
+		}
+		rt.lock.Unlock()
+		return rt.track(rt.next.RoundTrip(req))
+	}
+	return rt.track(resp, err)
+}
+
+// track keeps a reference on the events streams, to be able to close them on leader change
+func (rt *leaderRoundTripper) track(resp *http.Response, err error) (*http.Response, error) {
+	if err != nil || resp.Request == nil || resp.Request.Header.Get("Accept") != "text/event-stream" {
+		return resp, err
+	}
+
+	stream := &leaderStreamBody{ReadCloser: resp.Body, rt: rt}
+	rt.lock.Lock()
+	rt.streams[stream] = true
+	rt.lock.Unlock()
+	resp.Body = stream
+	return resp, nil
+}
+
+type leaderStreamBody struct {
+	io.ReadCloser
+	rt *leaderRoundTripper
+}
+
+func (b *leaderStreamBody) Close() error {
+	b.rt.lock.Lock()
+	delete(b.rt.streams, b)
+	b.rt.lock.Unlock()
+	return b.ReadCloser.Close()
+}
+
+// followLeader resolves the Marathon leader every interval until stop is closed.
+func followLeader(client leaderClient, rt *leaderRoundTripper, interval time.Duration, stop <-chan struct{}) {
+	if interval <= 0 {
+		interval = defaultLeaderCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			checkLeader(client, rt)
+		}
+	}
+}
+
+func checkLeader(client leaderClient, rt *leaderRoundTripper) {
+	leader, err := client.Leader()
+	if err != nil {
+		log.Warnf("Unable to resolve Marathon leader: %s", err)
+		return
+	}
+	previous := rt.getLeader()
+	if !rt.setLeader(leader) {
+		return
+	}
+	if previous == "" {
+		log.Infof("Following Marathon leader %s", leader)
+		return
+	}
+	log.Infof("Marathon leader changed from %s to %s, re-targeting API calls and events stream", previous, leader)
+	leaderChangesCounter.Inc()
+}
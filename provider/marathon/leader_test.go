@@ -0,0 +1,88 @@
+package marathon
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLeaderClient struct {
+	leader string
+	err    error
+}
+
+func (c *fakeLeaderClient) Leader() (string, error) {
+	return c.leader, c.err
+}
+
+func newNamedServer(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name))
+	}))
+}
+
+func getBody(t *testing.T, client *http.Client, rawURL string) string {
+	resp, err := client.Get(rawURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestLeaderRoundTripper(t *testing.T) {
+	endpoint := newNamedServer("endpoint")
+	defer endpoint.Close()
+	leader := newNamedServer("leader")
+	defer leader.Close()
+	leaderURL, err := url.Parse(leader.URL)
+	require.NoError(t, err)
+
+	rt := newLeaderRoundTripper(http.DefaultTransport)
+	client := &http.Client{Transport: rt}
+
+	assert.Equal(t, "endpoint", getBody(t, client, endpoint.URL+"/v2/apps"))
+
+	checkLeader(&fakeLeaderClient{leader: leaderURL.Host}, rt)
+	assert.Equal(t, leaderURL.Host, rt.getLeader())
+	assert.Equal(t, "leader", getBody(t, client, endpoint.URL+"/v2/apps"))
+
+	// a failing leader resolution keeps the current leader
+	checkLeader(&fakeLeaderClient{err: errors.New("leader election in progress")}, rt)
+	assert.Equal(t, leaderURL.Host, rt.getLeader())
+
+	// an unreachable leader makes requests fall back to the configured endpoint
+	leader.Close()
+	assert.Equal(t, "endpoint", getBody(t, client, endpoint.URL+"/v2/apps"))
+	assert.Equal(t, "", rt.getLeader())
+}
+
+func TestLeaderRoundTripperClosesStreamsOnLeaderChange(t *testing.T) {
+	stream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer stream.Close()
+
+	rt := newLeaderRoundTripper(http.DefaultTransport)
+	req, err := http.NewRequest(http.MethodGet, stream.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := (&http.Client{Transport: rt}).Do(req)
+	require.NoError(t, err)
+	assert.Len(t, rt.streams, 1)
+
+	assert.True(t, rt.setLeader("10.0.0.1:8080"))
+	assert.Len(t, rt.streams, 0)
+	_, err = ioutil.ReadAll(resp.Body)
+	assert.Error(t, err)
+	assert.False(t, rt.setLeader("10.0.0.1:8080"))
+}
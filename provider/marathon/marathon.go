@@ -1,6 +1,7 @@
 package marathon
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -9,10 +10,10 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
-	"github.com/BurntSushi/ty/fun"
 	"github.com/Sirupsen/logrus"
 	"github.com/cenk/backoff"
 	"github.com/containous/flaeg"
@@ -30,6 +31,7 @@ const (
 )
 
 var _ provider.Provider = (*Provider)(nil)
+var _ provider.SyncStatusReporter = (*Provider)(nil)
 
 // Provider holds configuration of the provider.
 type Provider struct {
@@ -38,14 +40,42 @@ type Provider struct {
 	Domain                  string              `description:"Default domain used"`
 	ExposedByDefault        bool                `description:"Expose Marathon apps by default"`
 	GroupsAsSubDomains      bool                `description:"Convert Marathon groups to subdomains"`
+	GroupFrontends          bool                `description:"Route the applications of groups set with the traefik.frontend.group label by path prefix under the group domain"`
 	DCOSToken               string              `description:"DCOSToken for DCOS environment, This will override the Authorization header"`
+	SecretsEndpoint         string              `description:"DC/OS secrets API endpoint used to resolve the traefik.frontend.auth.basic.usersSecret labels"`
+	MesosEndpoint           string              `description:"Mesos master endpoint the labels of the tasks are read from"`
 	MarathonLBCompatibility bool                `description:"Add compatibility with marathon-lb labels"`
 	TLS                     *provider.ClientTLS `description:"Enable Docker TLS support"`
 	DialerTimeout           flaeg.Duration      `description:"Set a non-default connection timeout for Marathon"`
 	KeepAlive               flaeg.Duration      `description:"Set a non-default TCP Keep Alive time in seconds"`
+	ResponseHeaderTimeout   flaeg.Duration      `description:"Set the maximum time to wait for Marathon response headers"`
+	TLSHandshakeTimeout     flaeg.Duration      `description:"Set the maximum time to wait for a TLS handshake with Marathon"`
+	ClientTimeout           flaeg.Duration      `description:"Set the maximum duration of a Marathon API call (the events stream is not affected)"`
+	FollowLeader            bool                `description:"Send API calls and the events stream to the current Marathon leader"`
+	LeaderCheckInterval     flaeg.Duration      `description:"Interval between two resolutions of the Marathon leader"`
+	EventsHeartbeatTimeout  flaeg.Duration      `description:"Reconnect the Marathon events stream when nothing, keepalives included, is received within this duration"`
+	EventsReconnectInterval flaeg.Duration      `description:"Time to wait before reconnecting the Marathon events stream"`
+	RefreshMinInterval      flaeg.Duration      `description:"Minimum time between two loads of the Marathon applications, the events received meanwhile being coalesced"`
 	ForceTaskHostname       bool                `description:"Force to use the task's hostname."`
+	BackendNameTemplate     string              `description:"Template used to name the backend of applications without traefik.backend label"`
+	FrontendRuleTemplate    string              `description:"Template used to build the frontend rule of applications without traefik.frontend.rule label"`
+	BackendCollision        string              `description:"Handling of applications sharing a backend name: merge, suffix or reject"`
 	Basic                   *Basic              `description:"Enable basic authentication"`
-	marathonClient          marathon.Marathon
+	CacheFile               string              `description:"File keeping the last Marathon configuration, served at startup until Marathon is reachable"`
+	GroupLabels             GroupLabels         // configured in the configuration file only, labels inherited by the applications of the groups
+	marathonClient          lightMarathonClient
+	backendNameTemplate     *template.Template
+	frontendRuleTemplate    *template.Template
+	secretsClient           secretsClient
+	taskMetadataClient      taskMetadataClient
+	lastTaskMetadata        map[string]taskMetadata
+	configuredConstraints   types.Constraints
+	lock                    sync.Mutex
+	cancel                  context.CancelFunc
+	leaderTransport         *leaderRoundTripper
+	syncTracker             syncTracker
+	instanceSlots           instanceSlots
+	routines                sync.WaitGroup
 }
 
 // Basic holds basic authentication specific configurations
@@ -60,12 +90,98 @@ type lightMarathonClient interface {
 
 // Provide allows the marathon provider to provide configurations to traefik
 // using the given configuration channel.
+// The outstanding Marathon calls and connection retries are cancelled when the pool is stopped.
+// Calling Provide on a running provider restarts it, e.g. to apply an updated endpoint.
 func (p *Provider) Provide(configurationChan chan<- types.ConfigMessage, pool *safe.Pool, constraints types.Constraints) error {
-	p.Constraints = append(p.Constraints, constraints...)
+	p.Stop()
+
+	if p.configuredConstraints == nil {
+		p.configuredConstraints = append(types.Constraints{}, p.Constraints...)
+	}
+	p.Constraints = append(append(types.Constraints{}, p.configuredConstraints...), constraints...)
+	p.backendNameTemplate = nil
+	if len(p.BackendNameTemplate) > 0 {
+		backendNameTemplate, err := parseBackendNameTemplate(p.BackendNameTemplate)
+		if err != nil {
+			return fmt.Errorf("invalid Marathon backend name template: %s", err)
+		}
+		p.backendNameTemplate = backendNameTemplate
+	}
+	p.frontendRuleTemplate = nil
+	if len(p.FrontendRuleTemplate) > 0 {
+		frontendRuleTemplate, err := parseFrontendRuleTemplate(p.FrontendRuleTemplate)
+		if err != nil {
+			return fmt.Errorf("invalid Marathon frontend rule template: %s", err)
+		}
+		p.frontendRuleTemplate = frontendRuleTemplate
+	}
+
+	ctx, cancel := context.WithCancel(pool.Ctx())
+	p.lock.Lock()
+	p.cancel = cancel
+	p.lock.Unlock()
+	pool.Go(func(stop chan bool) {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	})
+
+	p.routines.Add(1)
+	defer p.routines.Done()
+	return p.provide(ctx, configurationChan)
+}
+
+// Stop cancels the outstanding Marathon calls and connection retries of the provider,
+// and waits for its goroutines to terminate. The provider can then be started again with Provide.
+func (p *Provider) Stop() {
+	p.lock.Lock()
+	cancel := p.cancel
+	p.cancel = nil
+	p.lock.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	p.routines.Wait()
+}
+
+// goCtx starts a recoverable goroutine tracked by Stop
+func (p *Provider) goCtx(ctx context.Context, goroutine func(ctx context.Context)) {
+	p.routines.Add(1)
+	safe.Go(func() {
+		defer p.routines.Done()
+		goroutine(ctx)
+	})
+}
+
+func (p *Provider) provide(ctx context.Context, configurationChan chan<- types.ConfigMessage) error {
+	sendConfiguration := func(configuration *types.Configuration, causes []string, serverReasons map[string]string) {
+		select {
+		case configurationChan <- types.ConfigMessage{
+			ProviderName:  "marathon",
+			Configuration: configuration,
+			Causes:        causes,
+			ServerReasons: serverReasons,
+		}:
+		case <-ctx.Done():
+		}
+	}
+
+	if len(p.CacheFile) > 0 {
+		snapshot, err := p.loadCachedConfiguration()
+		if err != nil {
+			log.Errorf("Unable to load the cached Marathon configuration from %s: %s", p.CacheFile, err)
+		} else if snapshot != nil {
+			log.Infof("Serving the Marathon configuration cached at %s until Marathon is reachable", snapshot.Time.Format(time.RFC3339))
+			sendConfiguration(snapshot.Configuration, []string{"configuration cache"}, nil)
+		}
+	}
+
 	operation := func() error {
 		config := marathon.NewDefaultConfig()
 		config.URL = p.Endpoint
-		config.EventsTransport = marathon.EventsTransportSSE
 		if p.Trace {
 			config.LogOutput = log.CustomWriterLevel(logrus.DebugLevel, traceMaxScanTokenSize)
 		}
@@ -76,64 +192,150 @@ func (p *Provider) Provide(configurationChan chan<- types.ConfigMessage, pool *s
 		if len(p.DCOSToken) > 0 {
 			config.DCOSToken = p.DCOSToken
 		}
-		TLSConfig, err := p.TLS.CreateTLSConfig()
-		if err != nil {
-			return err
+		dialer := &net.Dialer{
+			KeepAlive: time.Duration(p.KeepAlive),
+			Timeout:   time.Duration(p.DialerTimeout),
+		}
+		transport := &http.Transport{
+			DialContext:           dialer.DialContext,
+			ResponseHeaderTimeout: time.Duration(p.ResponseHeaderTimeout),
+			TLSHandshakeTimeout:   time.Duration(p.TLSHandshakeTimeout),
 		}
+		if p.TLS != nil {
+			// The TLS configuration is re-read on every new connection so that
+			// rotated certificates are used without restarting the provider.
+			tlsReloader, err := provider.NewTLSConfigReloader(p.TLS)
+			if err != nil {
+				return err
+			}
+			transport.DialTLS = func(network, addr string) (net.Conn, error) {
+				return dialTLS(dialer, tlsReloader, time.Duration(p.TLSHandshakeTimeout), network, addr)
+			}
+		}
+		var roundTripper http.RoundTripper = transport
+		var leaderTransport *leaderRoundTripper
+		if p.FollowLeader {
+			if len(p.DCOSToken) > 0 {
+				log.Warn("Marathon leader following is not supported with DC/OS, ignoring followLeader")
+			} else {
+				leaderTransport = newLeaderRoundTripper(transport)
+				roundTripper = leaderTransport
+			}
+		}
+		p.lock.Lock()
+		p.leaderTransport = leaderTransport
+		p.lock.Unlock()
+		eventStream := newEventStreamRoundTripper(ctx, newETagRoundTripper(&timeoutRoundTripper{
+			next:    roundTripper,
+			timeout: time.Duration(p.ClientTimeout),
+		}), time.Duration(p.EventsHeartbeatTimeout), time.Duration(p.EventsReconnectInterval))
+		eventStream.failed = p.syncTracker.failure
 		config.HTTPClient = &http.Client{
-			Transport: &http.Transport{
-				DialContext: (&net.Dialer{
-					KeepAlive: time.Duration(p.KeepAlive),
-					Timeout:   time.Duration(p.DialerTimeout),
-				}).DialContext,
-				TLSClientConfig: TLSConfig,
+			Transport: &contextRoundTripper{
+				ctx:  ctx,
+				next: eventStream,
 			},
 		}
 		client, err := marathon.NewClient(config)
 		if err != nil {
 			log.Errorf("Failed to create a client for marathon, error: %s", err)
+			p.syncTracker.failure(err)
 			return err
 		}
 		p.marathonClient = client
+		p.secretsClient = nil
+		if len(p.SecretsEndpoint) > 0 {
+			p.secretsClient = newSecretsCache(&dcosSecretsClient{
+				endpoint:   p.SecretsEndpoint,
+				token:      p.DCOSToken,
+				httpClient: config.HTTPClient,
+			})
+		}
+		p.taskMetadataClient = &httpTaskMetadataClient{
+			marathonEndpoints: strings.Split(p.Endpoint, ","),
+			mesosEndpoint:     p.MesosEndpoint,
+			basic:             p.Basic,
+			token:             p.DCOSToken,
+			httpClient:        config.HTTPClient,
+		}
+
+		if leaderTransport != nil {
+			checkLeader(client, leaderTransport)
+		}
 
 		if p.Watch {
-			update, err := client.AddEventsListener(marathon.EventIDApplications)
-			if err != nil {
-				log.Errorf("Failed to register for events, %s", err)
-				return err
-			}
-			pool.Go(func(stop chan bool) {
-				defer close(update)
+			update := p.subscribeEvents(ctx, config.HTTPClient, marathon.EventIDApplications)
+			p.goCtx(ctx, func(ctx context.Context) {
+				var lastRefresh time.Time
+				var pendingRefresh <-chan time.Time
+				// causes describe the events received since the last refresh, and serverReasons the
+				// task changes they report, by server name
+				var causes []string
+				var serverReasons map[string]string
+				refresh := func() {
+					lastRefresh = time.Now()
+					pendingRefresh = nil
+					configuration := p.loadMarathonConfig()
+					if configuration != nil {
+						sendConfiguration(configuration, causes, serverReasons)
+					}
+					causes = nil
+					serverReasons = nil
+				}
+				// throttledRefresh delays the refresh until the minimum interval since the last one elapses,
+				// a single refresh covering all the events received meanwhile
+				throttledRefresh := func() {
+					if pendingRefresh != nil {
+						return
+					}
+					wait := time.Duration(p.RefreshMinInterval) - time.Since(lastRefresh)
+					if wait <= 0 {
+						refresh()
+						return
+					}
+					log.Debugf("Delaying the Marathon configuration refresh by %s", wait)
+					pendingRefresh = time.After(wait)
+				}
 				for {
 					select {
-					case <-stop:
+					case <-ctx.Done():
 						return
 					case event := <-update:
 						log.Debug("Provider event received", event)
-						configuration := p.loadMarathonConfig()
-						if configuration != nil {
-							configurationChan <- types.ConfigMessage{
-								ProviderName:  "marathon",
-								Configuration: configuration,
+						causes = append(causes, describeEvent(event))
+						if serverName, reason, ok := serverChangeReason(event); ok {
+							if serverReasons == nil {
+								serverReasons = make(map[string]string)
 							}
+							serverReasons[serverName] = reason
 						}
+						throttledRefresh()
+					case <-eventStream.reconnected:
+						// the events sent while the stream was down are lost
+						log.Debug("Refreshing Marathon configuration after events stream reconnection")
+						causes = append(causes, "events stream reconnection")
+						throttledRefresh()
+					case <-pendingRefresh:
+						refresh()
 					}
 				}
 			})
 		}
-		configuration := p.loadMarathonConfig()
-		configurationChan <- types.ConfigMessage{
-			ProviderName:  "marathon",
-			Configuration: configuration,
+		if leaderTransport != nil {
+			p.goCtx(ctx, func(ctx context.Context) {
+				followLeader(client, leaderTransport, time.Duration(p.LeaderCheckInterval), ctx.Done())
+			})
 		}
+		configuration := p.loadMarathonConfig()
+		sendConfiguration(configuration, nil, nil)
 		return nil
 	}
 
 	notify := func(err error, time time.Duration) {
 		log.Errorf("Provider connection error %+v, retrying in %s", err, time)
 	}
-	err := backoff.RetryNotify(safe.OperationWithRecover(operation), job.NewBackOff(backoff.NewExponentialBackOff()), notify)
-	if err != nil {
+	err := backoff.RetryNotify(safe.OperationWithRecover(operation), backoff.WithContext(job.NewBackOff(backoff.NewExponentialBackOff()), ctx), notify)
+	if err != nil && ctx.Err() == nil {
 		log.Errorf("Cannot connect to Provider server %+v", err)
 	}
 	return nil
@@ -145,11 +347,15 @@ func (p *Provider) loadMarathonConfig() *types.Configuration {
 		"getBackendServer":            p.getBackendServer,
 		"getPort":                     p.getPort,
 		"getWeight":                   p.getWeight,
+		"getServerName":               p.getServerName,
+		"getServersTransport":         p.getServersTransport,
 		"getDomain":                   p.getDomain,
 		"getSubDomain":                p.getSubDomain,
 		"getProtocol":                 p.getProtocol,
 		"getPassHostHeader":           p.getPassHostHeader,
 		"getPriority":                 p.getPriority,
+		"getTimeout":                  p.getTimeout,
+		"getProfile":                  p.getProfile,
 		"getEntryPoints":              p.getEntryPoints,
 		"getFrontendRule":             p.getFrontendRule,
 		"hasCircuitBreakerLabels":     p.hasCircuitBreakerLabels,
@@ -163,7 +369,16 @@ func (p *Provider) loadMarathonConfig() *types.Configuration {
 		"hasHealthCheckLabels":        p.hasHealthCheckLabels,
 		"getHealthCheckPath":          p.getHealthCheckPath,
 		"getHealthCheckInterval":      p.getHealthCheckInterval,
+		"getDrainDuration":            p.getDrainDuration,
 		"getBasicAuth":                p.getBasicAuth,
+		"getAllowedUpgrades":          p.getAllowedUpgrades,
+		"hasAuthBypassLabels":         p.hasAuthBypassLabels,
+		"getAuthBypassPaths":          p.getAuthBypassPaths,
+		"getAuthBypassMethods":        p.getAuthBypassMethods,
+		"getForwardAuth":              p.getForwardAuth,
+		"getAccessLog":                p.getAccessLog,
+		"getPathParams":               p.getPathParams,
+		"getFaults":                   p.getFaults,
 	}
 
 	v := url.Values{}
@@ -171,27 +386,59 @@ func (p *Provider) loadMarathonConfig() *types.Configuration {
 	applications, err := p.marathonClient.Applications(v)
 	if err != nil {
 		log.Errorf("Failed to retrieve Marathon applications: %s", err)
+		p.syncTracker.failure(err)
 		return nil
 	}
 
-	filteredApps := fun.Filter(p.applicationFilter, applications.Apps).([]marathon.Application)
-	for _, app := range filteredApps {
-		app.Tasks = fun.Filter(func(task *marathon.Task) bool {
-			return p.taskFilter(*task, app)
-		}, app.Tasks).([]*marathon.Task)
+	apps := make([]marathon.Application, 0, len(applications.Apps))
+	for _, app := range applications.Apps {
+		if len(p.GroupLabels) > 0 {
+			app = p.withGroupLabels(app)
+		}
+		apps = append(apps, p.withEnvLabels(app))
+	}
+
+	filteredApps := p.filterApplications(apps)
+
+	filteredApps, basicAuthUsers := p.resolveBasicAuthSecrets(filteredApps)
+	MarathonFuncMap["getBasicAuth"] = func(application marathon.Application) []string {
+		return append(p.getBasicAuth(application), basicAuthUsers[application.ID]...)
+	}
+
+	filteredApps, backendNames := p.resolveBackendNames(filteredApps)
+	MarathonFuncMap["getBackend"] = func(application marathon.Application) string {
+		return backendNames[application.ID]
 	}
 
-	templateObjects := struct {
-		Applications []marathon.Application
-		Domain       string
-	}{
-		filteredApps,
-		p.Domain,
+	p.instanceSlots.update(filteredApps)
+
+	taskMetadata := p.loadTaskMetadata(filteredApps)
+	MarathonFuncMap["getZone"] = func(task marathon.Task, application marathon.Application) string {
+		return p.getZone(task, application, taskMetadata[task.ID])
+	}
+	MarathonFuncMap["getTaskLabel"] = func(task marathon.Task, label string) string {
+		return taskMetadata[task.ID].Labels[label]
+	}
+	MarathonFuncMap["getServerWeight"] = func(task marathon.Task, application marathon.Application) string {
+		return p.getServerWeight(task, application, taskMetadata[task.ID])
 	}
 
-	configuration, err := p.GetConfiguration("templates/marathon.tmpl", MarathonFuncMap, templateObjects)
+	filteredApps, failovers := p.resolveFailovers(filteredApps)
+	MarathonFuncMap["getFailoverApplication"] = func(application marathon.Application) *marathon.Application {
+		return failovers[application.ID]
+	}
+
+	configuration, err := p.renderConfiguration(MarathonFuncMap, filteredApps)
 	if err != nil {
 		log.Errorf("failed to render Marathon configuration template: %s", err)
+		p.syncTracker.failure(err)
+		return configuration
+	}
+	p.syncTracker.success()
+	if len(p.CacheFile) > 0 {
+		if err := p.cacheConfiguration(configuration); err != nil {
+			log.Errorf("Unable to cache the Marathon configuration in %s: %s", p.CacheFile, err)
+		}
 	}
 	return configuration
 }
@@ -293,6 +540,54 @@ func (p *Provider) getWeight(application marathon.Application) string {
 	return "0"
 }
 
+// getServerWeight returns the weight of the server of a task: the weight label of the Mesos task when valid,
+// otherwise the one of its application. Unlike the labels of the application, the labels of the Mesos tasks
+// can differ per instance.
+func (p *Provider) getServerWeight(task marathon.Task, application marathon.Application, metadata taskMetadata) string {
+	if label := metadata.Labels[types.LabelWeight]; label != "" {
+		if _, err := strconv.Atoi(label); err == nil {
+			return label
+		}
+		log.Warnf("Invalid weight %q for task %s, using the weight of application %s", label, task.ID, application.ID)
+	}
+	return p.getWeight(application)
+}
+
+// getServerName returns the name of the server of a task: by default its task ID, or its instance
+// index when the application identifies its servers by instance, so that the server keeps its name,
+// e.g. its position on the hash ring of the consistent hashing load balancer, when it is redeployed.
+// The instance index is the stable slot of the task among the tasks of the application.
+func (p *Provider) getServerName(task marathon.Task, application marathon.Application) string {
+	if label, ok := p.getLabel(application, types.LabelBackendServerIdentity); ok && label == "instance" {
+		return fmt.Sprintf("server-%s-%d", strings.Replace(strings.TrimPrefix(application.ID, "/"), "/", "-", -1), p.instanceSlots.slot(task, application))
+	}
+	return taskServerName(task.ID)
+}
+
+// taskServerName returns the default name of the server of a task
+func taskServerName(taskID string) string {
+	return "server-" + strings.Replace(taskID, ".", "-", -1)
+}
+
+// getZone returns the zone of a task server: the fault domain zone reported by Marathon
+// when the application prefers its local zone, the zone label otherwise
+func (p *Provider) getZone(task marathon.Task, application marathon.Application, metadata taskMetadata) string {
+	if label, ok := p.getLabel(application, types.LabelBackendPreferLocalZone); ok && label == "true" && len(metadata.Zone) > 0 {
+		return metadata.Zone
+	}
+	if label, ok := p.getLabel(application, types.LabelBackendZone); ok {
+		return label
+	}
+	return ""
+}
+
+func (p *Provider) getServersTransport(application marathon.Application) string {
+	if label, ok := p.getLabel(application, types.LabelBackendServersTransport); ok {
+		return label
+	}
+	return ""
+}
+
 func (p *Provider) getDomain(application marathon.Application) string {
 	if label, ok := p.getLabel(application, types.LabelDomain); ok {
 		return label
@@ -328,6 +623,20 @@ func (p *Provider) getPriority(application marathon.Application) string {
 	return "0"
 }
 
+func (p *Provider) getTimeout(application marathon.Application) string {
+	if timeout, ok := p.getLabel(application, types.LabelFrontendTimeout); ok {
+		return timeout
+	}
+	return ""
+}
+
+func (p *Provider) getProfile(application marathon.Application) string {
+	if profile, ok := p.getLabel(application, types.LabelFrontendProfile); ok {
+		return profile
+	}
+	return ""
+}
+
 func (p *Provider) getEntryPoints(application marathon.Application) []string {
 	if entryPoints, ok := p.getLabel(application, types.LabelFrontendEntryPoints); ok {
 		return strings.Split(entryPoints, ",")
@@ -346,13 +655,47 @@ func (p *Provider) getFrontendRule(application marathon.Application) string {
 			return "Host:" + label
 		}
 	}
+	if p.GroupFrontends {
+		if group, ok := p.getFrontendGroup(application); ok {
+			return "Host:" + p.getSubDomain(group) + "." + p.Domain + ";PathPrefix:" + strings.TrimPrefix(application.ID, group)
+		}
+	}
+	if p.frontendRuleTemplate != nil {
+		rule, err := p.executeFrontendRuleTemplate(application)
+		if err == nil {
+			return rule
+		}
+		log.Errorf("Unable to render the frontend rule of Marathon application %s, using the default one: %s", application.ID, err)
+	}
 	return "Host:" + p.getSubDomain(application.ID) + "." + p.Domain
 }
 
+// getFrontendGroup returns the group the application is routed under, from its
+// label. The group must be one of the groups the application belongs to.
+func (p *Provider) getFrontendGroup(application marathon.Application) (string, bool) {
+	label, ok := p.getLabel(application, types.LabelFrontendGroup)
+	if !ok {
+		return "", false
+	}
+	group := "/" + strings.Trim(label, "/")
+	if group == "/" || !strings.HasPrefix(application.ID, group+"/") {
+		log.Errorf("Ignoring group %s of Marathon application %s: the application does not belong to it", label, application.ID)
+		return "", false
+	}
+	return group, true
+}
+
 func (p *Provider) getBackend(application marathon.Application) string {
 	if label, ok := p.getLabel(application, types.LabelBackend); ok {
 		return label
 	}
+	if p.backendNameTemplate != nil {
+		name, err := p.executeBackendNameTemplate(application)
+		if err == nil {
+			return name
+		}
+		log.Errorf("Unable to render the backend name of Marathon application %s, using the default one: %s", application.ID, err)
+	}
 	return provider.Replace("/", "-", application.ID)
 }
 
@@ -378,14 +721,38 @@ func (p *Provider) hasLoadBalancerLabels(application marathon.Application) bool
 }
 
 func (p *Provider) hasMaxConnLabels(application marathon.Application) bool {
-	if _, ok := p.getLabel(application, types.LabelBackendMaxconnAmount); !ok {
+	_, hasAmount := p.getLabel(application, types.LabelBackendMaxconnAmount)
+	_, hasPerTask := p.getLabel(application, types.LabelBackendMaxconnPerTask)
+	if !hasAmount && !hasPerTask {
 		return false
 	}
 	_, ok := p.getLabel(application, types.LabelBackendMaxconnExtractorfunc)
 	return ok
 }
 
+// getMaxConnAmount returns the maximum number of connections to the backend.
+// When traefik.backend.maxconn.perTask is set, it takes precedence over
+// traefik.backend.maxconn.amount and the limit scales with the number of
+// healthy tasks of the application.
 func (p *Provider) getMaxConnAmount(application marathon.Application) int64 {
+	if label, ok := p.getLabel(application, types.LabelBackendMaxconnPerTask); ok {
+		perTask, errConv := strconv.ParseInt(label, 10, 64)
+		if errConv != nil {
+			log.Errorf("Unable to parse traefik.backend.maxconn.perTask %s", label)
+			return math.MaxInt64
+		}
+		var healthyTasks int64
+		for _, task := range application.Tasks {
+			if p.taskFilter(*task, application) {
+				healthyTasks++
+			}
+		}
+		// a zero amount would disable the limit
+		if healthyTasks == 0 {
+			healthyTasks = 1
+		}
+		return perTask * healthyTasks
+	}
 	if label, ok := p.getLabel(application, types.LabelBackendMaxconnAmount); ok {
 		i, errConv := strconv.ParseInt(label, 10, 64)
 		if errConv != nil {
@@ -436,6 +803,15 @@ func (p *Provider) getHealthCheckInterval(application marathon.Application) stri
 	return ""
 }
 
+// getDrainDuration returns the time the servers asking to be drained are kept out of rotation,
+// the servers of the applications without the label not being able to drain themselves.
+func (p *Provider) getDrainDuration(application marathon.Application) string {
+	if label, ok := p.getLabel(application, types.LabelBackendDrainDuration); ok {
+		return label
+	}
+	return ""
+}
+
 func (p *Provider) getBasicAuth(application marathon.Application) []string {
 	if basicAuth, ok := p.getLabel(application, types.LabelFrontendAuthBasic); ok {
 		return strings.Split(basicAuth, ",")
@@ -444,6 +820,132 @@ func (p *Provider) getBasicAuth(application marathon.Application) []string {
 	return []string{}
 }
 
+func (p *Provider) getAllowedUpgrades(application marathon.Application) []string {
+	if allowedUpgrades, ok := p.getLabel(application, types.LabelFrontendAllowedUpgrades); ok {
+		return provider.SplitAndTrimString(allowedUpgrades)
+	}
+
+	return nil
+}
+
+func (p *Provider) hasAuthBypassLabels(application marathon.Application) bool {
+	_, hasPaths := p.getLabel(application, types.LabelFrontendAuthBypassPaths)
+	_, hasMethods := p.getLabel(application, types.LabelFrontendAuthBypassMethods)
+	return hasPaths || hasMethods
+}
+
+func (p *Provider) getAuthBypassPaths(application marathon.Application) []string {
+	if paths, ok := p.getLabel(application, types.LabelFrontendAuthBypassPaths); ok {
+		return provider.SplitAndTrimString(paths)
+	}
+	return []string{}
+}
+
+func (p *Provider) getAuthBypassMethods(application marathon.Application) []string {
+	if methods, ok := p.getLabel(application, types.LabelFrontendAuthBypassMethods); ok {
+		return provider.SplitAndTrimString(methods)
+	}
+	return []string{}
+}
+
+// getForwardAuth returns the forward authentication configuration of the
+// application, or nil if it does not delegate authentication.
+func (p *Provider) getForwardAuth(application marathon.Application) *types.Forward {
+	address, ok := p.getLabel(application, types.LabelFrontendAuthForwardAddress)
+	if !ok {
+		return nil
+	}
+	forward := &types.Forward{Address: address}
+	if trust, ok := p.getLabel(application, types.LabelFrontendAuthForwardTrustForwardHeader); ok {
+		forward.TrustForwardHeader = trust == "true"
+	}
+	if timeout, ok := p.getLabel(application, types.LabelFrontendAuthForwardTimeout); ok {
+		forward.Timeout = timeout
+	}
+
+	ca, hasCA := p.getLabel(application, types.LabelFrontendAuthForwardTLSCA)
+	cert, hasCert := p.getLabel(application, types.LabelFrontendAuthForwardTLSCert)
+	key, hasKey := p.getLabel(application, types.LabelFrontendAuthForwardTLSKey)
+	insecureSkipVerify, hasInsecureSkipVerify := p.getLabel(application, types.LabelFrontendAuthForwardTLSInsecureSkipVerify)
+	if hasCA || hasCert || hasKey || hasInsecureSkipVerify {
+		forward.TLS = &types.ClientTLS{
+			CA:                 ca,
+			Cert:               cert,
+			Key:                key,
+			InsecureSkipVerify: insecureSkipVerify == "true",
+		}
+	}
+	return forward
+}
+
+func (p *Provider) getAccessLog(application marathon.Application) *types.FrontendAccessLog {
+	disabled, hasDisabled := p.getLabel(application, types.LabelFrontendAccessLogDisabled)
+	queryParams, hasQueryParams := p.getLabel(application, types.LabelFrontendAccessLogRedactQueryParams)
+	headers, hasHeaders := p.getLabel(application, types.LabelFrontendAccessLogRedactHeaders)
+	if !hasDisabled && !hasQueryParams && !hasHeaders {
+		return nil
+	}
+	accessLog := &types.FrontendAccessLog{Disabled: disabled == "true"}
+	if hasQueryParams {
+		accessLog.RedactQueryParams = provider.SplitAndTrimString(queryParams)
+	}
+	if hasHeaders {
+		accessLog.RedactHeaders = provider.SplitAndTrimString(headers)
+	}
+	return accessLog
+}
+
+func (p *Provider) getPathParams(application marathon.Application) *types.PathParams {
+	names, hasNames := p.getLabel(application, types.LabelFrontendPathParamsNames)
+	headerPrefix, hasHeaderPrefix := p.getLabel(application, types.LabelFrontendPathParamsHeaderPrefix)
+	if !hasNames && !hasHeaderPrefix {
+		return nil
+	}
+	pathParams := &types.PathParams{HeaderPrefix: headerPrefix}
+	if hasNames {
+		pathParams.Names = provider.SplitAndTrimString(names)
+	}
+	return pathParams
+}
+
+// getFaults returns the faults injected into the requests of the application, the invalid values being ignored
+func (p *Provider) getFaults(application marathon.Application) *types.Faults {
+	faults := &types.Faults{}
+	configured := false
+	percentages := map[string]*float64{
+		types.LabelFrontendFaultsDelayPercentage: &faults.DelayPercentage,
+		types.LabelFrontendFaultsErrorPercentage: &faults.ErrorPercentage,
+		types.LabelFrontendFaultsAbortPercentage: &faults.AbortPercentage,
+	}
+	for label, percentage := range percentages {
+		value, ok := p.getLabel(application, label)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			log.Errorf("Unable to parse %s label of application %s: %s", label, application.ID, err)
+			continue
+		}
+		*percentage = parsed
+		configured = true
+	}
+	if !configured {
+		return nil
+	}
+
+	faults.Delay, _ = p.getLabel(application, types.LabelFrontendFaultsDelay)
+	if status, ok := p.getLabel(application, types.LabelFrontendFaultsErrorStatus); ok {
+		parsed, err := strconv.Atoi(status)
+		if err != nil {
+			log.Errorf("Unable to parse %s label of application %s: %s", types.LabelFrontendFaultsErrorStatus, application.ID, err)
+		} else {
+			faults.ErrorStatus = parsed
+		}
+	}
+	return faults
+}
+
 func processPorts(application marathon.Application, task marathon.Task) (int, error) {
 	if portLabel, ok := (*application.Labels)[types.LabelPort]; ok {
 		port, err := strconv.Atoi(portLabel)
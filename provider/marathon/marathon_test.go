@@ -1,17 +1,26 @@
 package marathon
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"runtime"
 	"testing"
+	"time"
 
+	"github.com/containous/flaeg"
 	"github.com/containous/traefik/provider/marathon/mocks"
+	"github.com/containous/traefik/safe"
 	"github.com/containous/traefik/testhelpers"
 	"github.com/containous/traefik/types"
 	"github.com/gambol99/go-marathon"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type fakeClient struct {
@@ -46,6 +55,7 @@ func TestMarathonLoadConfigNonAPIErrors(t *testing.T) {
 		desc              string
 		application       marathon.Application
 		task              marathon.Task
+		taskMetadata      *taskMetadata
 		expectedFrontends map[string]*types.Frontend
 		expectedBackends  map[string]*types.Backend
 	}{
@@ -320,6 +330,521 @@ func TestMarathonLoadConfigNonAPIErrors(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc: "allowed upgrades label",
+			application: marathon.Application{
+				Ports: []int{80},
+				Labels: &map[string]string{
+					types.LabelFrontendAllowedUpgrades: "websocket",
+				},
+			},
+			task: marathon.Task{
+				Host:  "127.0.0.1",
+				Ports: []int{80},
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-app": {
+					Backend:         "backend-app",
+					PassHostHeader:  true,
+					BasicAuth:       []string{},
+					EntryPoints:     []string{},
+					AllowedUpgrades: []string{"websocket"},
+					Routes: map[string]types.Route{
+						"route-host-app": {
+							Rule: "Host:app.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-app": {
+					Servers: map[string]types.Server{
+						"server-task": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "auth bypass labels",
+			application: marathon.Application{
+				Ports: []int{80},
+				Labels: &map[string]string{
+					types.LabelFrontendAuthBasic:         "test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/",
+					types.LabelFrontendAuthBypassPaths:   "/health, /public/*",
+					types.LabelFrontendAuthBypassMethods: "OPTIONS",
+				},
+			},
+			task: marathon.Task{
+				Host:  "127.0.0.1",
+				Ports: []int{80},
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-app": {
+					Backend:        "backend-app",
+					PassHostHeader: true,
+					BasicAuth:      []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/"},
+					AuthBypass: &types.AuthBypass{
+						Paths:   []string{"/health", "/public/*"},
+						Methods: []string{"OPTIONS"},
+					},
+					EntryPoints: []string{},
+					Routes: map[string]types.Route{
+						"route-host-app": {
+							Rule: "Host:app.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-app": {
+					Servers: map[string]types.Server{
+						"server-task": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "zone label",
+			application: marathon.Application{
+				Ports: []int{80},
+				Labels: &map[string]string{
+					types.LabelBackendZone: "us-east-1a",
+				},
+			},
+			task: marathon.Task{
+				Host:  "127.0.0.1",
+				Ports: []int{80},
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-app": {
+					Backend:        "backend-app",
+					PassHostHeader: true,
+					BasicAuth:      []string{},
+					EntryPoints:    []string{},
+					Routes: map[string]types.Route{
+						"route-host-app": {
+							Rule: "Host:app.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-app": {
+					Servers: map[string]types.Server{
+						"server-task": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+							Zone:   "us-east-1a",
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "task zone preferred",
+			application: marathon.Application{
+				Ports: []int{80},
+				Labels: &map[string]string{
+					types.LabelBackendPreferLocalZone: "true",
+				},
+			},
+			task: marathon.Task{
+				Host:  "127.0.0.1",
+				Ports: []int{80},
+			},
+			taskMetadata: &taskMetadata{Region: "us-east-1", Zone: "us-east-1b"},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-app": {
+					Backend:        "backend-app",
+					PassHostHeader: true,
+					BasicAuth:      []string{},
+					EntryPoints:    []string{},
+					Routes: map[string]types.Route{
+						"route-host-app": {
+							Rule: "Host:app.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-app": {
+					Servers: map[string]types.Server{
+						"server-task": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+							Zone:   "us-east-1b",
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "forward auth labels",
+			application: marathon.Application{
+				Ports: []int{80},
+				Labels: &map[string]string{
+					types.LabelFrontendAuthForwardAddress:               "https://auth.example.com/verify",
+					types.LabelFrontendAuthForwardTrustForwardHeader:    "true",
+					types.LabelFrontendAuthForwardTLSCA:                 "/etc/ssl/auth-ca.crt",
+					types.LabelFrontendAuthForwardTLSInsecureSkipVerify: "true",
+				},
+			},
+			task: marathon.Task{
+				Host:  "127.0.0.1",
+				Ports: []int{80},
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-app": {
+					Backend:        "backend-app",
+					PassHostHeader: true,
+					BasicAuth:      []string{},
+					Auth: &types.Auth{
+						Forward: &types.Forward{
+							Address:            "https://auth.example.com/verify",
+							TrustForwardHeader: true,
+							TLS: &types.ClientTLS{
+								CA:                 "/etc/ssl/auth-ca.crt",
+								InsecureSkipVerify: true,
+							},
+						},
+					},
+					EntryPoints: []string{},
+					Routes: map[string]types.Route{
+						"route-host-app": {
+							Rule: "Host:app.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-app": {
+					Servers: map[string]types.Server{
+						"server-task": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "access log labels",
+			application: marathon.Application{
+				Ports: []int{80},
+				Labels: &map[string]string{
+					types.LabelFrontendAccessLogRedactQueryParams: "token, email",
+					types.LabelFrontendAccessLogRedactHeaders:     "Authorization",
+				},
+			},
+			task: marathon.Task{
+				Host:  "127.0.0.1",
+				Ports: []int{80},
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-app": {
+					Backend:        "backend-app",
+					PassHostHeader: true,
+					BasicAuth:      []string{},
+					AccessLog: &types.FrontendAccessLog{
+						RedactQueryParams: []string{"token", "email"},
+						RedactHeaders:     []string{"Authorization"},
+					},
+					EntryPoints: []string{},
+					Routes: map[string]types.Route{
+						"route-host-app": {
+							Rule: "Host:app.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-app": {
+					Servers: map[string]types.Server{
+						"server-task": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "servers transport label",
+			application: marathon.Application{
+				Ports: []int{80},
+				Labels: &map[string]string{
+					types.LabelBackendServersTransport: "internal-tls",
+				},
+			},
+			task: marathon.Task{
+				Host:  "127.0.0.1",
+				Ports: []int{80},
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-app": {
+					Backend:        "backend-app",
+					PassHostHeader: true,
+					BasicAuth:      []string{},
+					EntryPoints:    []string{},
+					Routes: map[string]types.Route{
+						"route-host-app": {
+							Rule: "Host:app.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-app": {
+					Servers: map[string]types.Server{
+						"server-task": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					ServersTransport: "internal-tls",
+				},
+			},
+		},
+		{
+			desc: "path params labels",
+			application: marathon.Application{
+				Ports: []int{80},
+				Labels: &map[string]string{
+					types.LabelFrontendPathParamsNames: "tenant",
+				},
+			},
+			task: marathon.Task{
+				Host:  "127.0.0.1",
+				Ports: []int{80},
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-app": {
+					Backend:        "backend-app",
+					PassHostHeader: true,
+					BasicAuth:      []string{},
+					EntryPoints:    []string{},
+					PathParams: &types.PathParams{
+						Names: []string{"tenant"},
+					},
+					Routes: map[string]types.Route{
+						"route-host-app": {
+							Rule: "Host:app.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-app": {
+					Servers: map[string]types.Server{
+						"server-task": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "frontend timeout",
+			application: marathon.Application{
+				Ports: []int{80},
+				Labels: &map[string]string{
+					types.LabelFrontendTimeout: "1500ms",
+				},
+			},
+			task: marathon.Task{
+				Host:  "127.0.0.1",
+				Ports: []int{80},
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-app": {
+					Backend:        "backend-app",
+					PassHostHeader: true,
+					BasicAuth:      []string{},
+					EntryPoints:    []string{},
+					Timeout:        "1500ms",
+					Routes: map[string]types.Route{
+						"route-host-app": {
+							Rule: "Host:app.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-app": {
+					Servers: map[string]types.Server{
+						"server-task": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "frontend profile",
+			application: marathon.Application{
+				Ports: []int{80},
+				Labels: &map[string]string{
+					types.LabelFrontendProfile: "public-api",
+				},
+			},
+			task: marathon.Task{
+				Host:  "127.0.0.1",
+				Ports: []int{80},
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-app": {
+					Backend:        "backend-app",
+					PassHostHeader: true,
+					BasicAuth:      []string{},
+					EntryPoints:    []string{},
+					Profile:        "public-api",
+					Routes: map[string]types.Route{
+						"route-host-app": {
+							Rule: "Host:app.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-app": {
+					Servers: map[string]types.Server{
+						"server-task": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "backend drain",
+			application: marathon.Application{
+				Ports: []int{80},
+				Labels: &map[string]string{
+					types.LabelBackendDrainDuration: "1m",
+				},
+			},
+			task: marathon.Task{
+				Host:  "127.0.0.1",
+				Ports: []int{80},
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-app": {
+					Backend:        "backend-app",
+					PassHostHeader: true,
+					BasicAuth:      []string{},
+					EntryPoints:    []string{},
+					Routes: map[string]types.Route{
+						"route-host-app": {
+							Rule: "Host:app.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-app": {
+					Servers: map[string]types.Server{
+						"server-task": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					Drain: &types.Drain{
+						Duration: "1m",
+					},
+				},
+			},
+		},
+		{
+			desc: "task weight label",
+			application: marathon.Application{
+				Ports: []int{80},
+				Labels: &map[string]string{
+					types.LabelWeight: "10",
+				},
+			},
+			task: marathon.Task{
+				Host:  "127.0.0.1",
+				Ports: []int{80},
+			},
+			taskMetadata: &taskMetadata{Labels: map[string]string{types.LabelWeight: "20"}},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-app": {
+					Backend:        "backend-app",
+					PassHostHeader: true,
+					BasicAuth:      []string{},
+					EntryPoints:    []string{},
+					Routes: map[string]types.Route{
+						"route-host-app": {
+							Rule: "Host:app.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-app": {
+					Servers: map[string]types.Server{
+						"server-task": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 20,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "frontend faults",
+			application: marathon.Application{
+				Ports: []int{80},
+				Labels: &map[string]string{
+					types.LabelFrontendFaultsDelay:           "200ms",
+					types.LabelFrontendFaultsDelayPercentage: "10",
+					types.LabelFrontendFaultsErrorStatus:     "500",
+					types.LabelFrontendFaultsErrorPercentage: "0.5",
+				},
+			},
+			task: marathon.Task{
+				Host:  "127.0.0.1",
+				Ports: []int{80},
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-app": {
+					Backend:        "backend-app",
+					PassHostHeader: true,
+					BasicAuth:      []string{},
+					EntryPoints:    []string{},
+					Faults: &types.Faults{
+						Delay:           "200ms",
+						DelayPercentage: 10,
+						ErrorStatus:     500,
+						ErrorPercentage: 0.5,
+					},
+					Routes: map[string]types.Route{
+						"route-host-app": {
+							Rule: "Host:app.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-app": {
+					Servers: map[string]types.Server{
+						"server-task": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -328,6 +853,9 @@ func TestMarathonLoadConfigNonAPIErrors(t *testing.T) {
 			t.Parallel()
 			c.application.ID = "/app"
 			c.task.ID = "task"
+			if c.task.State == "" {
+				c.task.State = taskStateRunning
+			}
 			c.application.Tasks = []*marathon.Task{&c.task}
 			fakeClient := newFakeClient(false,
 				marathon.Applications{Apps: []marathon.Application{c.application}})
@@ -336,6 +864,10 @@ func TestMarathonLoadConfigNonAPIErrors(t *testing.T) {
 				ExposedByDefault: true,
 				marathonClient:   fakeClient,
 			}
+			if c.taskMetadata != nil {
+				provider.MesosEndpoint = "http://mesos.example:5050"
+				provider.taskMetadataClient = fakeTaskMetadataClient{"task": *c.taskMetadata}
+			}
 			actualConfig := provider.loadMarathonConfig()
 			fakeClient.AssertExpectations(t)
 
@@ -860,14 +1392,155 @@ func TestMarathonGetWeight(t *testing.T) {
 		},
 	}
 
-	for _, c := range cases {
-		c := c
-		t.Run(c.desc, func(t *testing.T) {
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
+			t.Parallel()
+			actual := provider.getWeight(c.application)
+			if actual != c.expected {
+				t.Fatalf("actual %s, expected %s", actual, c.expected)
+			}
+		})
+	}
+}
+
+func TestMarathonGetServerWeight(t *testing.T) {
+	provider := &Provider{}
+
+	cases := []struct {
+		desc        string
+		application marathon.Application
+		metadata    taskMetadata
+		expected    string
+	}{
+		{
+			desc:        "no weight label",
+			application: application(),
+			expected:    "0",
+		},
+		{
+			desc:        "application weight label",
+			application: application(withLabel(types.LabelWeight, "10")),
+			metadata:    taskMetadata{Labels: map[string]string{"shard": "3"}},
+			expected:    "10",
+		},
+		{
+			desc:        "task weight label",
+			application: application(withLabel(types.LabelWeight, "10")),
+			metadata:    taskMetadata{Labels: map[string]string{types.LabelWeight: "20"}},
+			expected:    "20",
+		},
+		{
+			desc:        "invalid task weight label",
+			application: application(withLabel(types.LabelWeight, "10")),
+			metadata:    taskMetadata{Labels: map[string]string{types.LabelWeight: "heavy"}},
+			expected:    "10",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, c.expected, provider.getServerWeight(task(), c.application, c.metadata))
+		})
+	}
+}
+
+func TestMarathonGetZone(t *testing.T) {
+	provider := &Provider{}
+
+	cases := []struct {
+		desc        string
+		application marathon.Application
+		metadata    taskMetadata
+		expected    string
+	}{
+		{
+			desc:        "no zone",
+			application: application(),
+			expected:    "",
+		},
+		{
+			desc:        "zone label",
+			application: application(withLabel(types.LabelBackendZone, "us-east-1a")),
+			metadata:    taskMetadata{Region: "us-east-1", Zone: "us-east-1b"},
+			expected:    "us-east-1a",
+		},
+		{
+			desc:        "task zone preferred",
+			application: application(withLabel(types.LabelBackendPreferLocalZone, "true"), withLabel(types.LabelBackendZone, "us-east-1a")),
+			metadata:    taskMetadata{Region: "us-east-1", Zone: "us-east-1b"},
+			expected:    "us-east-1b",
+		},
+		{
+			desc:        "task zone preferred without fault domain",
+			application: application(withLabel(types.LabelBackendPreferLocalZone, "true"), withLabel(types.LabelBackendZone, "us-east-1a")),
+			expected:    "us-east-1a",
+		},
+		{
+			desc:        "task zone not preferred",
+			application: application(withLabel(types.LabelBackendPreferLocalZone, "false")),
+			metadata:    taskMetadata{Region: "us-east-1", Zone: "us-east-1b"},
+			expected:    "",
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.expected, provider.getZone(task(), test.application, test.metadata))
+		})
+	}
+}
+
+func TestMarathonGetServerName(t *testing.T) {
+	provider := &Provider{}
+
+	tasks := withTasks(
+		task(taskID("app.2"), taskStartedAt("2017-01-02T00:00:00.000Z")),
+		task(taskID("app.3")),
+		task(taskID("app.1"), taskStartedAt("2017-01-01T00:00:00.000Z")),
+	)
+
+	cases := []struct {
+		desc        string
+		application marathon.Application
+		task        marathon.Task
+		expected    string
+	}{
+		{
+			desc:        "task identity by default",
+			application: application(appID("/foo/app"), tasks),
+			task:        task(taskID("app.2")),
+			expected:    "server-app-2",
+		},
+		{
+			desc:        "first instance",
+			application: application(appID("/foo/app"), withLabel(types.LabelBackendServerIdentity, "instance"), tasks),
+			task:        task(taskID("app.1")),
+			expected:    "server-foo-app-0",
+		},
+		{
+			desc:        "second instance",
+			application: application(appID("/foo/app"), withLabel(types.LabelBackendServerIdentity, "instance"), tasks),
+			task:        task(taskID("app.2")),
+			expected:    "server-foo-app-1",
+		},
+		{
+			desc:        "instance not started yet",
+			application: application(appID("/foo/app"), withLabel(types.LabelBackendServerIdentity, "instance"), tasks),
+			task:        task(taskID("app.3")),
+			expected:    "server-foo-app-2",
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
 			t.Parallel()
-			actual := provider.getWeight(c.application)
-			if actual != c.expected {
-				t.Fatalf("actual %s, expected %s", actual, c.expected)
-			}
+			assert.Equal(t, test.expected, provider.getServerName(test.task, test.application))
 		})
 	}
 }
@@ -1008,6 +1681,7 @@ func TestMarathonGetFrontendRule(t *testing.T) {
 		application             marathon.Application
 		expected                string
 		marathonLBCompatibility bool
+		groupFrontends          bool
 	}{
 		{
 			application: marathon.Application{
@@ -1044,12 +1718,64 @@ func TestMarathonGetFrontendRule(t *testing.T) {
 			marathonLBCompatibility: true,
 			expected:                "Host:foo.bar",
 		},
+		{
+			application: marathon.Application{
+				ID: "/prod/payments/api/v1",
+				Labels: &map[string]string{
+					types.LabelFrontendGroup: "/prod/payments",
+				},
+			},
+			groupFrontends: true,
+			expected:       "Host:prod-payments.docker.localhost;PathPrefix:/api/v1",
+		},
+		{
+			application: marathon.Application{
+				ID: "/prod/payments/api",
+				Labels: &map[string]string{
+					types.LabelFrontendGroup: "prod/payments/",
+				},
+			},
+			groupFrontends: true,
+			expected:       "Host:prod-payments.docker.localhost;PathPrefix:/api",
+		},
+		{
+			application: marathon.Application{
+				ID: "/prod/payments/api",
+				Labels: &map[string]string{
+					types.LabelFrontendGroup: "/prod/pay",
+				},
+			},
+			groupFrontends: true,
+			expected:       "Host:prod-payments-api.docker.localhost",
+		},
+		{
+			application: marathon.Application{
+				ID: "/prod/payments/api",
+				Labels: &map[string]string{
+					types.LabelFrontendGroup: "/prod/payments",
+				},
+			},
+			groupFrontends: false,
+			expected:       "Host:prod-payments-api.docker.localhost",
+		},
+		{
+			application: marathon.Application{
+				ID: "/prod/payments/api",
+				Labels: &map[string]string{
+					types.LabelFrontendGroup: "/prod/payments",
+					types.LabelFrontendRule:  "Host:foo.bar",
+				},
+			},
+			groupFrontends: true,
+			expected:       "Host:foo.bar",
+		},
 	}
 
 	for _, a := range applications {
 		provider := &Provider{
 			Domain:                  "docker.localhost",
 			MarathonLBCompatibility: a.marathonLBCompatibility,
+			GroupFrontends:          a.groupFrontends,
 		}
 		actual := provider.getFrontendRule(a.application)
 		if actual != a.expected {
@@ -1105,347 +1831,843 @@ func TestMarathonGetSubDomain(t *testing.T) {
 		{"/c/d/a/b", "c-d-a-b", providerNoGroups},
 	}
 
-	for _, a := range apps {
-		actual := a.provider.getSubDomain(a.path)
+	for _, a := range apps {
+		actual := a.provider.getSubDomain(a.path)
+
+		if actual != a.expected {
+			t.Errorf("expected %q, got %q", a.expected, actual)
+		}
+	}
+}
+
+func TestMarathonHasHealthCheckLabels(t *testing.T) {
+	tests := []struct {
+		desc  string
+		value *string
+		want  bool
+	}{
+		{
+			desc:  "label missing",
+			value: nil,
+			want:  false,
+		},
+		{
+			desc:  "empty path",
+			value: stringp(""),
+			want:  false,
+		},
+		{
+			desc:  "non-empty path",
+			value: stringp("/path"),
+			want:  true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			app := marathon.Application{
+				Labels: &map[string]string{},
+			}
+			if test.value != nil {
+				app.AddLabel(types.LabelBackendHealthcheckPath, *test.value)
+			}
+			prov := &Provider{}
+			got := prov.hasHealthCheckLabels(app)
+			if got != test.want {
+				t.Errorf("got %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMarathonGetHealthCheckPath(t *testing.T) {
+	tests := []struct {
+		desc  string
+		value *string
+		want  string
+	}{
+		{
+			desc:  "label missing",
+			value: nil,
+			want:  "",
+		},
+		{
+			desc:  "path existing",
+			value: stringp("/path"),
+			want:  "/path",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			app := marathon.Application{}
+			app.EmptyLabels()
+			if test.value != nil {
+				app.AddLabel(types.LabelBackendHealthcheckPath, *test.value)
+			}
+			prov := &Provider{}
+			got := prov.getHealthCheckPath(app)
+			if got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMarathonGetHealthCheckInterval(t *testing.T) {
+	tests := []struct {
+		desc  string
+		value *string
+		want  string
+	}{
+		{
+			desc:  "label missing",
+			value: nil,
+			want:  "",
+		},
+		{
+			desc:  "interval existing",
+			value: stringp("5m"),
+			want:  "5m",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			app := marathon.Application{
+				Labels: &map[string]string{},
+			}
+			if test.value != nil {
+				app.AddLabel(types.LabelBackendHealthcheckInterval, *test.value)
+			}
+			prov := &Provider{}
+			got := prov.getHealthCheckInterval(app)
+			if got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func stringp(s string) *string {
+	return &s
+}
+
+func TestGetBackendServer(t *testing.T) {
+	appID := "appId"
+	host := "host"
+	tests := []struct {
+		desc              string
+		application       marathon.Application
+		addIPAddrPerTask  bool
+		task              marathon.Task
+		forceTaskHostname bool
+		wantServer        string
+	}{
+		{
+			desc:       "application without IP-per-task",
+			wantServer: host,
+		},
+		{
+			desc:              "task hostname override",
+			addIPAddrPerTask:  true,
+			forceTaskHostname: true,
+			wantServer:        host,
+		},
+		{
+			desc: "task IP address missing",
+			task: marathon.Task{
+				IPAddresses: []*marathon.IPAddress{},
+			},
+			addIPAddrPerTask: true,
+			wantServer:       "",
+		},
+		{
+			desc: "single task IP address",
+			task: marathon.Task{
+				IPAddresses: []*marathon.IPAddress{
+					{
+						IPAddress: "1.1.1.1",
+					},
+				},
+			},
+			addIPAddrPerTask: true,
+			wantServer:       "1.1.1.1",
+		},
+		{
+			desc: "multiple task IP addresses without index label",
+			task: marathon.Task{
+				IPAddresses: []*marathon.IPAddress{
+					{
+						IPAddress: "1.1.1.1",
+					},
+					{
+						IPAddress: "2.2.2.2",
+					},
+				},
+			},
+			addIPAddrPerTask: true,
+			wantServer:       "",
+		},
+		{
+			desc: "multiple task IP addresses with invalid index label",
+			application: marathon.Application{
+				Labels: &map[string]string{"traefik.ipAddressIdx": "invalid"},
+			},
+			task: marathon.Task{
+				IPAddresses: []*marathon.IPAddress{
+					{
+						IPAddress: "1.1.1.1",
+					},
+					{
+						IPAddress: "2.2.2.2",
+					},
+				},
+			},
+			addIPAddrPerTask: true,
+			wantServer:       "",
+		},
+		{
+			desc: "multiple task IP addresses with valid index label",
+			application: marathon.Application{
+				Labels: &map[string]string{"traefik.ipAddressIdx": "1"},
+			},
+			task: marathon.Task{
+				IPAddresses: []*marathon.IPAddress{
+					{
+						IPAddress: "1.1.1.1",
+					},
+					{
+						IPAddress: "2.2.2.2",
+					},
+				},
+			},
+			addIPAddrPerTask: true,
+			wantServer:       "2.2.2.2",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			provider := &Provider{ForceTaskHostname: test.forceTaskHostname}
 
-		if actual != a.expected {
-			t.Errorf("expected %q, got %q", a.expected, actual)
-		}
+			// Populate application.
+			if test.application.ID == "" {
+				test.application.ID = appID
+			}
+			if test.application.Labels == nil {
+				test.application.Labels = &map[string]string{}
+			}
+			if test.addIPAddrPerTask {
+				test.application.IPAddressPerTask = &marathon.IPAddressPerTask{
+					Discovery: &marathon.Discovery{
+						Ports: &[]marathon.Port{
+							{
+								Number: 8000,
+								Name:   "port",
+							},
+						},
+					},
+				}
+			}
+
+			// Populate task.
+			test.task.AppID = appID
+			test.task.Host = "host"
+
+			gotServer := provider.getBackendServer(test.task, test.application)
+
+			if gotServer != test.wantServer {
+				t.Errorf("got server '%s', want '%s'", gotServer, test.wantServer)
+			}
+		})
 	}
 }
 
-func TestMarathonHasHealthCheckLabels(t *testing.T) {
+func TestParseIndex(t *testing.T) {
 	tests := []struct {
-		desc  string
-		value *string
-		want  bool
+		idxStr        string
+		length        int
+		shouldSucceed bool
+		parsed        int
 	}{
 		{
-			desc:  "label missing",
-			value: nil,
-			want:  false,
+			idxStr:        "illegal",
+			length:        42,
+			shouldSucceed: false,
 		},
 		{
-			desc:  "empty path",
-			value: stringp(""),
-			want:  false,
+			idxStr:        "-1",
+			length:        42,
+			shouldSucceed: false,
 		},
 		{
-			desc:  "non-empty path",
-			value: stringp("/path"),
-			want:  true,
+			idxStr:        "10",
+			length:        1,
+			shouldSucceed: false,
+		},
+		{
+			idxStr:        "10",
+			length:        10,
+			shouldSucceed: false,
+		},
+		{
+			idxStr:        "0",
+			length:        1,
+			shouldSucceed: true,
+			parsed:        0,
+		},
+		{
+			idxStr:        "10",
+			length:        11,
+			shouldSucceed: true,
+			parsed:        10,
 		},
 	}
 
 	for _, test := range tests {
 		test := test
-		t.Run(test.desc, func(t *testing.T) {
+		t.Run(fmt.Sprintf("parseIndex(%s, %d)", test.idxStr, test.length), func(t *testing.T) {
 			t.Parallel()
-			app := marathon.Application{
-				Labels: &map[string]string{},
-			}
-			if test.value != nil {
-				app.AddLabel(types.LabelBackendHealthcheckPath, *test.value)
+			parsed, err := parseIndex(test.idxStr, test.length)
+
+			if test.shouldSucceed != (err == nil) {
+				t.Fatalf("got error '%s', want error: %t", err, !test.shouldSucceed)
 			}
-			prov := &Provider{}
-			got := prov.hasHealthCheckLabels(app)
-			if got != test.want {
-				t.Errorf("got %t, want %t", got, test.want)
+
+			if test.shouldSucceed && parsed != test.parsed {
+				t.Errorf("got parsed index %d, want %d", parsed, test.parsed)
 			}
 		})
 	}
 }
 
-func TestMarathonGetHealthCheckPath(t *testing.T) {
-	tests := []struct {
-		desc  string
-		value *string
-		want  string
+func TestMarathonGetBasicAuth(t *testing.T) {
+	provider := &Provider{}
+
+	cases := []struct {
+		desc        string
+		application marathon.Application
+		expected    []string
 	}{
 		{
-			desc:  "label missing",
-			value: nil,
-			want:  "",
+			desc: "basic auth label is empty",
+			application: marathon.Application{
+				Labels: &map[string]string{}},
+			expected: []string{},
 		},
 		{
-			desc:  "path existing",
-			value: stringp("/path"),
-			want:  "/path",
+			desc: "basic auth label is set with user:password",
+			application: marathon.Application{
+				Labels: &map[string]string{
+					types.LabelFrontendAuthBasic: "user:password",
+				},
+			},
+			expected: []string{"user:password"},
 		},
 	}
 
-	for _, test := range tests {
-		test := test
-		t.Run(test.desc, func(t *testing.T) {
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
 			t.Parallel()
-			app := marathon.Application{}
-			app.EmptyLabels()
-			if test.value != nil {
-				app.AddLabel(types.LabelBackendHealthcheckPath, *test.value)
-			}
-			prov := &Provider{}
-			got := prov.getHealthCheckPath(app)
-			if got != test.want {
-				t.Errorf("got %s, want %s", got, test.want)
+
+			actual := provider.getBasicAuth(c.application)
+			if !reflect.DeepEqual(c.expected, actual) {
+				t.Errorf("expected %q, got %q", c.expected, actual)
 			}
 		})
 	}
 }
 
-func TestMarathonGetHealthCheckInterval(t *testing.T) {
-	tests := []struct {
-		desc  string
-		value *string
-		want  string
+func TestMarathonGetAllowedUpgrades(t *testing.T) {
+	provider := &Provider{}
+
+	cases := []struct {
+		desc        string
+		application marathon.Application
+		expected    []string
 	}{
 		{
-			desc:  "label missing",
-			value: nil,
-			want:  "",
+			desc: "allowed upgrades label is missing",
+			application: marathon.Application{
+				Labels: &map[string]string{}},
+			expected: nil,
 		},
 		{
-			desc:  "interval existing",
-			value: stringp("5m"),
-			want:  "5m",
+			desc: "allowed upgrades label is set",
+			application: marathon.Application{
+				Labels: &map[string]string{
+					types.LabelFrontendAllowedUpgrades: "websocket, h2c",
+				},
+			},
+			expected: []string{"websocket", "h2c"},
 		},
 	}
 
-	for _, test := range tests {
-		test := test
-		t.Run(test.desc, func(t *testing.T) {
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
 			t.Parallel()
-			app := marathon.Application{
-				Labels: &map[string]string{},
-			}
-			if test.value != nil {
-				app.AddLabel(types.LabelBackendHealthcheckInterval, *test.value)
-			}
-			prov := &Provider{}
-			got := prov.getHealthCheckInterval(app)
-			if got != test.want {
-				t.Errorf("got %s, want %s", got, test.want)
+
+			actual := provider.getAllowedUpgrades(c.application)
+			if !reflect.DeepEqual(c.expected, actual) {
+				t.Errorf("expected %q, got %q", c.expected, actual)
 			}
 		})
 	}
 }
 
-func stringp(s string) *string {
-	return &s
-}
+func TestMarathonHasAuthBypassLabels(t *testing.T) {
+	provider := &Provider{}
 
-func TestGetBackendServer(t *testing.T) {
-	appID := "appId"
-	host := "host"
-	tests := []struct {
-		desc              string
-		application       marathon.Application
-		addIPAddrPerTask  bool
-		task              marathon.Task
-		forceTaskHostname bool
-		wantServer        string
+	cases := []struct {
+		desc        string
+		application marathon.Application
+		expected    bool
 	}{
 		{
-			desc:       "application without IP-per-task",
-			wantServer: host,
+			desc: "no auth bypass labels",
+			application: marathon.Application{
+				Labels: &map[string]string{}},
+			expected: false,
 		},
 		{
-			desc:              "task hostname override",
-			addIPAddrPerTask:  true,
-			forceTaskHostname: true,
-			wantServer:        host,
+			desc: "auth bypass paths label",
+			application: marathon.Application{
+				Labels: &map[string]string{
+					types.LabelFrontendAuthBypassPaths: "/health",
+				},
+			},
+			expected: true,
 		},
 		{
-			desc: "task IP address missing",
-			task: marathon.Task{
-				IPAddresses: []*marathon.IPAddress{},
+			desc: "auth bypass methods label",
+			application: marathon.Application{
+				Labels: &map[string]string{
+					types.LabelFrontendAuthBypassMethods: "OPTIONS",
+				},
 			},
-			addIPAddrPerTask: true,
-			wantServer:       "",
+			expected: true,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
+			t.Parallel()
+
+			actual := provider.hasAuthBypassLabels(c.application)
+			if actual != c.expected {
+				t.Errorf("expected %t, got %t", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMarathonGetForwardAuth(t *testing.T) {
+	provider := &Provider{}
+
+	cases := []struct {
+		desc        string
+		application marathon.Application
+		expected    *types.Forward
+	}{
+		{
+			desc: "no forward auth labels",
+			application: marathon.Application{
+				Labels: &map[string]string{}},
+			expected: nil,
 		},
 		{
-			desc: "single task IP address",
-			task: marathon.Task{
-				IPAddresses: []*marathon.IPAddress{
-					{
-						IPAddress: "1.1.1.1",
-					},
+			desc: "TLS labels without address",
+			application: marathon.Application{
+				Labels: &map[string]string{
+					types.LabelFrontendAuthForwardTLSCA: "/etc/ssl/ca.crt",
 				},
 			},
-			addIPAddrPerTask: true,
-			wantServer:       "1.1.1.1",
+			expected: nil,
 		},
 		{
-			desc: "multiple task IP addresses without index label",
-			task: marathon.Task{
-				IPAddresses: []*marathon.IPAddress{
-					{
-						IPAddress: "1.1.1.1",
-					},
-					{
-						IPAddress: "2.2.2.2",
-					},
+			desc: "address only",
+			application: marathon.Application{
+				Labels: &map[string]string{
+					types.LabelFrontendAuthForwardAddress: "http://oauth2-proxy:4180/oauth2/auth",
 				},
 			},
-			addIPAddrPerTask: true,
-			wantServer:       "",
+			expected: &types.Forward{
+				Address: "http://oauth2-proxy:4180/oauth2/auth",
+			},
 		},
 		{
-			desc: "multiple task IP addresses with invalid index label",
+			desc: "client certificate",
 			application: marathon.Application{
-				Labels: &map[string]string{"traefik.ipAddressIdx": "invalid"},
+				Labels: &map[string]string{
+					types.LabelFrontendAuthForwardAddress:            "https://authelia/api/verify",
+					types.LabelFrontendAuthForwardTrustForwardHeader: "false",
+					types.LabelFrontendAuthForwardTimeout:            "3s",
+					types.LabelFrontendAuthForwardTLSCert:            "/etc/ssl/client.crt",
+					types.LabelFrontendAuthForwardTLSKey:             "/etc/ssl/client.key",
+				},
 			},
-			task: marathon.Task{
-				IPAddresses: []*marathon.IPAddress{
-					{
-						IPAddress: "1.1.1.1",
-					},
-					{
-						IPAddress: "2.2.2.2",
-					},
+			expected: &types.Forward{
+				Address: "https://authelia/api/verify",
+				Timeout: "3s",
+				TLS: &types.ClientTLS{
+					Cert: "/etc/ssl/client.crt",
+					Key:  "/etc/ssl/client.key",
 				},
 			},
-			addIPAddrPerTask: true,
-			wantServer:       "",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
+			t.Parallel()
+			actual := provider.getForwardAuth(c.application)
+			assert.Equal(t, c.expected, actual)
+		})
+	}
+}
+
+func TestMarathonGetAccessLog(t *testing.T) {
+	provider := &Provider{}
+
+	cases := []struct {
+		desc     string
+		labels   map[string]string
+		expected *types.FrontendAccessLog
+	}{
+		{
+			desc:     "no access log labels",
+			labels:   map[string]string{},
+			expected: nil,
 		},
 		{
-			desc: "multiple task IP addresses with valid index label",
-			application: marathon.Application{
-				Labels: &map[string]string{"traefik.ipAddressIdx": "1"},
+			desc: "disabled",
+			labels: map[string]string{
+				types.LabelFrontendAccessLogDisabled: "true",
 			},
-			task: marathon.Task{
-				IPAddresses: []*marathon.IPAddress{
-					{
-						IPAddress: "1.1.1.1",
-					},
-					{
-						IPAddress: "2.2.2.2",
-					},
-				},
+			expected: &types.FrontendAccessLog{Disabled: true},
+		},
+		{
+			desc: "redacted query parameters and headers",
+			labels: map[string]string{
+				types.LabelFrontendAccessLogRedactQueryParams: "token,email",
+				types.LabelFrontendAccessLogRedactHeaders:     "Authorization, Cookie",
+			},
+			expected: &types.FrontendAccessLog{
+				RedactQueryParams: []string{"token", "email"},
+				RedactHeaders:     []string{"Authorization", "Cookie"},
 			},
-			addIPAddrPerTask: true,
-			wantServer:       "2.2.2.2",
 		},
 	}
 
-	for _, test := range tests {
-		test := test
-		t.Run(test.desc, func(t *testing.T) {
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
 			t.Parallel()
-			provider := &Provider{ForceTaskHostname: test.forceTaskHostname}
-
-			// Populate application.
-			if test.application.ID == "" {
-				test.application.ID = appID
-			}
-			if test.application.Labels == nil {
-				test.application.Labels = &map[string]string{}
-			}
-			if test.addIPAddrPerTask {
-				test.application.IPAddressPerTask = &marathon.IPAddressPerTask{
-					Discovery: &marathon.Discovery{
-						Ports: &[]marathon.Port{
-							{
-								Number: 8000,
-								Name:   "port",
-							},
-						},
-					},
-				}
-			}
+			actual := provider.getAccessLog(marathon.Application{Labels: &c.labels})
+			assert.Equal(t, c.expected, actual)
+		})
+	}
+}
 
-			// Populate task.
-			test.task.AppID = appID
-			test.task.Host = "host"
+func TestMarathonGetPathParams(t *testing.T) {
+	provider := &Provider{}
 
-			gotServer := provider.getBackendServer(test.task, test.application)
+	cases := []struct {
+		desc     string
+		labels   map[string]string
+		expected *types.PathParams
+	}{
+		{
+			desc:     "no path params labels",
+			labels:   map[string]string{},
+			expected: nil,
+		},
+		{
+			desc: "names",
+			labels: map[string]string{
+				types.LabelFrontendPathParamsNames: "tenant, id",
+			},
+			expected: &types.PathParams{Names: []string{"tenant", "id"}},
+		},
+		{
+			desc: "header prefix only",
+			labels: map[string]string{
+				types.LabelFrontendPathParamsHeaderPrefix: "X-Route-",
+			},
+			expected: &types.PathParams{HeaderPrefix: "X-Route-"},
+		},
+	}
 
-			if gotServer != test.wantServer {
-				t.Errorf("got server '%s', want '%s'", gotServer, test.wantServer)
-			}
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
+			t.Parallel()
+			actual := provider.getPathParams(application(withLabels(c.labels)))
+			assert.Equal(t, c.expected, actual)
 		})
 	}
 }
 
-func TestParseIndex(t *testing.T) {
-	tests := []struct {
-		idxStr        string
-		length        int
-		shouldSucceed bool
-		parsed        int
+func TestMarathonGetMaxConnAmount(t *testing.T) {
+	provider := &Provider{}
+
+	runningTask := &marathon.Task{State: taskStateRunning, Ports: []int{80}}
+	stagingTask := &marathon.Task{State: "TASK_STAGING", Ports: []int{80}}
+
+	cases := []struct {
+		desc             string
+		labels           map[string]string
+		tasks            []*marathon.Task
+		expectedHasLabel bool
+		expected         int64
 	}{
 		{
-			idxStr:        "illegal",
-			length:        42,
-			shouldSucceed: false,
+			desc:     "no labels",
+			labels:   map[string]string{},
+			expected: math.MaxInt64,
 		},
 		{
-			idxStr:        "-1",
-			length:        42,
-			shouldSucceed: false,
+			desc: "amount",
+			labels: map[string]string{
+				types.LabelBackendMaxconnAmount:        "10",
+				types.LabelBackendMaxconnExtractorfunc: "client.ip",
+			},
+			tasks:            []*marathon.Task{runningTask, runningTask},
+			expectedHasLabel: true,
+			expected:         10,
 		},
 		{
-			idxStr:        "10",
-			length:        1,
-			shouldSucceed: false,
+			desc: "per task with healthy tasks",
+			labels: map[string]string{
+				types.LabelBackendMaxconnAmount:        "10",
+				types.LabelBackendMaxconnPerTask:       "5",
+				types.LabelBackendMaxconnExtractorfunc: "client.ip",
+			},
+			tasks:            []*marathon.Task{runningTask, stagingTask, runningTask, runningTask},
+			expectedHasLabel: true,
+			expected:         15,
 		},
 		{
-			idxStr:        "10",
-			length:        10,
-			shouldSucceed: false,
+			desc: "per task without healthy tasks",
+			labels: map[string]string{
+				types.LabelBackendMaxconnPerTask:       "5",
+				types.LabelBackendMaxconnExtractorfunc: "client.ip",
+			},
+			tasks:            []*marathon.Task{stagingTask},
+			expectedHasLabel: true,
+			expected:         5,
 		},
 		{
-			idxStr:        "0",
-			length:        1,
-			shouldSucceed: true,
-			parsed:        0,
+			desc: "per task without extractor",
+			labels: map[string]string{
+				types.LabelBackendMaxconnPerTask: "5",
+			},
+			tasks:    []*marathon.Task{runningTask},
+			expected: 5,
 		},
 		{
-			idxStr:        "10",
-			length:        11,
-			shouldSucceed: true,
-			parsed:        10,
+			desc: "invalid per task",
+			labels: map[string]string{
+				types.LabelBackendMaxconnPerTask:       "many",
+				types.LabelBackendMaxconnExtractorfunc: "client.ip",
+			},
+			tasks:            []*marathon.Task{runningTask},
+			expectedHasLabel: true,
+			expected:         math.MaxInt64,
 		},
 	}
 
-	for _, test := range tests {
-		test := test
-		t.Run(fmt.Sprintf("parseIndex(%s, %d)", test.idxStr, test.length), func(t *testing.T) {
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
 			t.Parallel()
-			parsed, err := parseIndex(test.idxStr, test.length)
-
-			if test.shouldSucceed != (err == nil) {
-				t.Fatalf("got error '%s', want error: %t", err, !test.shouldSucceed)
-			}
-
-			if test.shouldSucceed && parsed != test.parsed {
-				t.Errorf("got parsed index %d, want %d", parsed, test.parsed)
+			application := marathon.Application{
+				ID:     "/app",
+				Ports:  []int{80},
+				Labels: &c.labels,
+				Tasks:  c.tasks,
 			}
+			assert.Equal(t, c.expectedHasLabel, provider.hasMaxConnLabels(application))
+			assert.Equal(t, c.expected, provider.getMaxConnAmount(application))
 		})
 	}
 }
 
-func TestMarathonGetBasicAuth(t *testing.T) {
+func newBlockingMarathonServer() (*httptest.Server, chan struct{}) {
+	requested := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested <- struct{}{}
+		<-r.Context().Done()
+	}))
+	return server, requested
+}
+
+func TestMarathonProvideStopsWithPool(t *testing.T) {
+	server, requested := newBlockingMarathonServer()
+	defer server.Close()
+
+	pool := safe.NewPool(context.Background())
+	provider := &Provider{Endpoint: server.URL}
+	configurationChan := make(chan types.ConfigMessage, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- provider.Provide(configurationChan, pool, types.Constraints{})
+	}()
+
+	<-requested
+	pool.Stop()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("provider still running after the pool was stopped")
+	}
+}
+
+func TestMarathonProvideRestart(t *testing.T) {
+	firstServer, firstRequested := newBlockingMarathonServer()
+	defer firstServer.Close()
+	secondServer, secondRequested := newBlockingMarathonServer()
+	defer secondServer.Close()
+
+	pool := safe.NewPool(context.Background())
+	defer pool.Cleanup()
+	constraint, err := types.NewConstraint("tag==api")
+	require.NoError(t, err)
+	provider := &Provider{Endpoint: firstServer.URL}
+	configurationChan := make(chan types.ConfigMessage, 10)
+	go provider.Provide(configurationChan, pool, types.Constraints{constraint})
+	<-firstRequested
+
+	provider.Endpoint = secondServer.URL
+	go provider.Provide(configurationChan, pool, types.Constraints{constraint})
+	select {
+	case <-secondRequested:
+	case <-time.After(5 * time.Second):
+		t.Fatal("restarted provider did not use the updated endpoint")
+	}
+	assert.Len(t, provider.Constraints, 1)
+
+	provider.Stop()
+}
+
+func TestMarathonStopTerminatesGoroutines(t *testing.T) {
+	app := application(appID("/app"), appPorts(80),
+		withTasks(task(taskID("task"), taskHost("10.0.0.1"), taskPorts(80), taskState(taskStateRunning))))
+	server := newMockMarathon(applications(app))
+	goroutines := runtime.NumGoroutine()
+
+	pool := safe.NewPool(context.Background())
+	defer pool.Cleanup()
+	provider := &Provider{
+		Endpoint:         server.URL,
+		Domain:           "docker.localhost",
+		ExposedByDefault: true,
+		Watch:            true,
+	}
+	configurationChan := make(chan types.ConfigMessage, 10)
+	for restart := 0; restart < 3; restart++ {
+		require.NoError(t, provider.Provide(configurationChan, pool, types.Constraints{}))
+		require.NotNil(t, receiveConfiguration(t, configurationChan))
+		require.NoError(t, server.sendEvent("status_update_event", "/app"))
+		require.NotNil(t, receiveConfiguration(t, configurationChan))
+	}
+	provider.Stop()
+	server.Close()
+
+	// the events stream and the health checks of go-marathon must not outlive the provider
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, runtime.NumGoroutine() <= goroutines, "%d goroutines left running", runtime.NumGoroutine()-goroutines)
+}
+
+func receiveConfiguration(t *testing.T, configurationChan <-chan types.ConfigMessage) *types.Configuration {
+	select {
+	case message := <-configurationChan:
+		return message.Configuration
+	case <-time.After(5 * time.Second):
+		t.Fatal("no configuration received")
+		return nil
+	}
+}
+
+func TestMarathonLoadConfigScriptedClient(t *testing.T) {
+	client := newScriptedClient(
+		respondError("fake Marathon server error"),
+		respond(
+			application(appID("/app"), appPorts(80),
+				withTasks(task(taskID("task"), taskHost("10.0.0.1"), taskPorts(80), taskState(taskStateRunning)))),
+			application(appID("/ipt"), ipAddrPerTask(8080),
+				withTasks(task(taskID("task"), taskHost("10.0.0.1"), taskIPAddresses("192.168.0.1"), taskState(taskStateRunning)))),
+		),
+	)
+	provider := &Provider{
+		Domain:           "docker.localhost",
+		ExposedByDefault: true,
+		marathonClient:   client,
+	}
+
+	assert.Nil(t, provider.loadMarathonConfig())
+
+	configuration := provider.loadMarathonConfig()
+	require.NotNil(t, configuration)
+	assert.Equal(t, 2, client.callCount())
+	assert.Equal(t, map[string]*types.Backend{
+		"backend-app": {
+			Servers: map[string]types.Server{
+				"server-task": {URL: "http://10.0.0.1:80"},
+			},
+		},
+		"backend-ipt": {
+			Servers: map[string]types.Server{
+				"server-task": {URL: "http://192.168.0.1:8080"},
+			},
+		},
+	}, configuration.Backends)
+}
+
+func TestMarathonTaskFilterHealthResults(t *testing.T) {
 	provider := &Provider{}
 
 	cases := []struct {
-		desc        string
-		application marathon.Application
-		expected    []string
+		desc     string
+		task     marathon.Task
+		expected bool
 	}{
 		{
-			desc: "basic auth label is empty",
-			application: marathon.Application{
-				Labels: &map[string]string{}},
-			expected: []string{},
+			desc:     "no health results yet",
+			task:     task(taskState(taskStateRunning), taskPorts(80)),
+			expected: true,
 		},
 		{
-			desc: "basic auth label is set with user:password",
-			application: marathon.Application{
-				Labels: &map[string]string{
-					types.LabelFrontendAuthBasic: "user:password",
-				},
-			},
-			expected: []string{"user:password"},
+			desc:     "healthy",
+			task:     task(taskState(taskStateRunning), taskPorts(80), healthResults(true, true)),
+			expected: true,
+		},
+		{
+			desc:     "one failing health check",
+			task:     task(taskState(taskStateRunning), taskPorts(80), healthResults(true, false)),
+			expected: false,
+		},
+		{
+			desc:     "not running",
+			task:     task(taskState("TASK_STAGING"), taskPorts(80), healthResults(true)),
+			expected: false,
 		},
 	}
 
@@ -1453,11 +2675,148 @@ func TestMarathonGetBasicAuth(t *testing.T) {
 		c := c
 		t.Run(c.desc, func(t *testing.T) {
 			t.Parallel()
-
-			actual := provider.getBasicAuth(c.application)
-			if !reflect.DeepEqual(c.expected, actual) {
-				t.Errorf("expected %q, got %q", c.expected, actual)
-			}
+			app := application(appID("/app"), appPorts(80), withHealthCheck())
+			assert.Equal(t, c.expected, provider.taskFilter(c.task, app))
 		})
 	}
 }
+
+func TestMarathonProvideWatchesEvents(t *testing.T) {
+	app := application(appID("/app"), appPorts(80),
+		withTasks(task(taskID("task"), taskHost("10.0.0.1"), taskPorts(80), taskState(taskStateRunning))))
+	server := newMockMarathon(applications(app))
+	defer server.Close()
+
+	pool := safe.NewPool(context.Background())
+	defer pool.Cleanup()
+	provider := &Provider{
+		Endpoint:         server.URL,
+		Domain:           "docker.localhost",
+		ExposedByDefault: true,
+		Watch:            true,
+	}
+	configurationChan := make(chan types.ConfigMessage, 10)
+	require.NoError(t, provider.Provide(configurationChan, pool, types.Constraints{}))
+	defer provider.Stop()
+
+	configuration := receiveConfiguration(t, configurationChan)
+	require.NotNil(t, configuration)
+	assert.Contains(t, configuration.Backends, "backend-app")
+
+	other := application(appID("/other"), appPorts(80),
+		withTasks(task(taskID("task"), taskHost("10.0.0.2"), taskPorts(80), taskState(taskStateRunning))))
+	server.setApplications(applications(app, other))
+	require.NoError(t, server.sendEvent("status_update_event", "/other"))
+
+	configuration = receiveConfiguration(t, configurationChan)
+	require.NotNil(t, configuration)
+	assert.Contains(t, configuration.Backends, "backend-app")
+	assert.Contains(t, configuration.Backends, "backend-other")
+}
+
+func TestMarathonProvideReconnectsSilentEventsStream(t *testing.T) {
+	app := application(appID("/app"), appPorts(80),
+		withTasks(task(taskID("task"), taskHost("10.0.0.1"), taskPorts(80), taskState(taskStateRunning))))
+	server := newMockMarathon(applications(app))
+	defer server.Close()
+
+	pool := safe.NewPool(context.Background())
+	defer pool.Cleanup()
+	provider := &Provider{
+		Endpoint:                server.URL,
+		Domain:                  "docker.localhost",
+		ExposedByDefault:        true,
+		Watch:                   true,
+		EventsHeartbeatTimeout:  flaeg.Duration(200 * time.Millisecond),
+		EventsReconnectInterval: flaeg.Duration(10 * time.Millisecond),
+	}
+	configurationChan := make(chan types.ConfigMessage, 10)
+	require.NoError(t, provider.Provide(configurationChan, pool, types.Constraints{}))
+	defer provider.Stop()
+
+	configuration := receiveConfiguration(t, configurationChan)
+	require.NotNil(t, configuration)
+	assert.NotContains(t, configuration.Backends, "backend-other")
+
+	// the mock Marathon sends no keepalive, the changes made meanwhile are only seen through the forced refresh
+	other := application(appID("/other"), appPorts(80),
+		withTasks(task(taskID("task"), taskHost("10.0.0.2"), taskPorts(80), taskState(taskStateRunning))))
+	server.setApplications(applications(app, other))
+
+	configuration = receiveConfiguration(t, configurationChan)
+	require.NotNil(t, configuration)
+	assert.Contains(t, configuration.Backends, "backend-other")
+	assert.True(t, server.eventsSubscriptions() >= 2, "events stream must be re-established")
+}
+
+func TestMarathonProvideCoalescesEvents(t *testing.T) {
+	app := application(appID("/app"), appPorts(80),
+		withTasks(task(taskID("task"), taskHost("10.0.0.1"), taskPorts(80), taskState(taskStateRunning))))
+	server := newMockMarathon(applications(app))
+	defer server.Close()
+
+	pool := safe.NewPool(context.Background())
+	defer pool.Cleanup()
+	provider := &Provider{
+		Endpoint:           server.URL,
+		Domain:             "docker.localhost",
+		ExposedByDefault:   true,
+		Watch:              true,
+		RefreshMinInterval: flaeg.Duration(300 * time.Millisecond),
+	}
+	configurationChan := make(chan types.ConfigMessage, 10)
+	require.NoError(t, provider.Provide(configurationChan, pool, types.Constraints{}))
+	defer provider.Stop()
+
+	require.NotNil(t, receiveConfiguration(t, configurationChan))
+	for i := 0; i < 5; i++ {
+		require.NoError(t, server.sendEvent("status_update_event", "/app"))
+	}
+
+	// the first event is handled right away, the next ones by a single delayed refresh
+	require.NotNil(t, receiveConfiguration(t, configurationChan))
+	require.NotNil(t, receiveConfiguration(t, configurationChan))
+	select {
+	case <-configurationChan:
+		t.Fatal("the events must be coalesced")
+	case <-time.After(500 * time.Millisecond):
+	}
+	assert.Equal(t, 3, server.applicationsRequests())
+	assert.Equal(t, 2, server.applicationsNotModified(), "the unchanged applications are not sent again")
+}
+
+func TestMarathonProvideRecoversFromApplicationsOutage(t *testing.T) {
+	app := application(appID("/app"), appPorts(80),
+		withTasks(task(taskID("task"), taskHost("10.0.0.1"), taskPorts(80), taskState(taskStateRunning))))
+	server := newMockMarathon(applications(app))
+	defer server.Close()
+	server.failApplications(1)
+
+	pool := safe.NewPool(context.Background())
+	defer pool.Cleanup()
+	provider := &Provider{
+		Endpoint:         server.URL,
+		Domain:           "docker.localhost",
+		ExposedByDefault: true,
+		Watch:            true,
+	}
+	configurationChan := make(chan types.ConfigMessage, 10)
+	require.NoError(t, provider.Provide(configurationChan, pool, types.Constraints{}))
+	defer provider.Stop()
+
+	assert.Nil(t, receiveConfiguration(t, configurationChan), "no configuration can be built while Marathon is unavailable")
+
+	// the events received until go-marathon marks Marathon up again do not produce any configuration
+	var configuration *types.Configuration
+	for attempt := 0; configuration == nil && attempt < 20; attempt++ {
+		require.NoError(t, server.sendEvent("status_update_event", "/app"))
+		select {
+		case message := <-configurationChan:
+			configuration = message.Configuration
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+	require.NotNil(t, configuration)
+	assert.Contains(t, configuration.Backends, "backend-app")
+	assert.True(t, server.applicationsRequests() >= 2)
+}
@@ -0,0 +1,209 @@
+package marathon
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gambol99/go-marathon"
+)
+
+// scriptedClient is an in-package lightMarathonClient answering the successive Applications calls
+// with the scripted responses, the last response being repeated.
+type scriptedClient struct {
+	lock      sync.Mutex
+	responses []scriptedResponse
+	calls     int
+}
+
+type scriptedResponse struct {
+	applications marathon.Applications
+	err          error
+}
+
+func newScriptedClient(responses ...scriptedResponse) *scriptedClient {
+	return &scriptedClient{responses: responses}
+}
+
+func respond(apps ...marathon.Application) scriptedResponse {
+	return scriptedResponse{applications: applications(apps...)}
+}
+
+func respondError(message string) scriptedResponse {
+	return scriptedResponse{err: errors.New(message)}
+}
+
+func (c *scriptedClient) Applications(url.Values) (*marathon.Applications, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	response := c.responses[len(c.responses)-1]
+	if c.calls < len(c.responses) {
+		response = c.responses[c.calls]
+	}
+	c.calls++
+	if response.err != nil {
+		return nil, response.err
+	}
+	apps := response.applications
+	return &apps, nil
+}
+
+func (c *scriptedClient) callCount() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.calls
+}
+
+// mockMarathon is an httptest based Marathon serving the applications endpoint and the SSE events stream,
+// so that the provider can be exercised end to end without a Marathon cluster.
+type mockMarathon struct {
+	*httptest.Server
+	lock             sync.Mutex
+	apps             marathon.Applications
+	appsFailures     int
+	appsRequestCount int
+	appsNotModified  int
+	eventsStreams    int
+	events           chan string
+	done             chan struct{}
+}
+
+func newMockMarathon(apps marathon.Applications) *mockMarathon {
+	m := &mockMarathon{
+		apps:   apps,
+		events: make(chan string),
+		done:   make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong"))
+	})
+	mux.HandleFunc("/v2/apps", m.serveApplications)
+	mux.HandleFunc("/v2/events", m.serveEvents)
+	m.Server = httptest.NewServer(mux)
+	return m
+}
+
+// Close terminates the open events streams before shutting the server down
+func (m *mockMarathon) Close() {
+	close(m.done)
+	m.Server.Close()
+}
+
+// setApplications replaces the applications returned by the next requests
+func (m *mockMarathon) setApplications(apps marathon.Applications) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.apps = apps
+}
+
+// failApplications makes the next count applications requests fail with a 503.
+// go-marathon then marks the member down until its /ping health check succeeds, within 5 seconds.
+func (m *mockMarathon) failApplications(count int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.appsFailures = count
+}
+
+func (m *mockMarathon) applicationsRequests() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.appsRequestCount
+}
+
+// eventsSubscriptions returns how many times the events stream was opened
+// applicationsNotModified returns the number of conditional applications requests answered with a 304
+func (m *mockMarathon) applicationsNotModified() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.appsNotModified
+}
+
+func (m *mockMarathon) eventsSubscriptions() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.eventsStreams
+}
+
+// sendEvent pushes an event to a subscribed events stream, waiting for the provider to subscribe
+func (m *mockMarathon) sendEvent(eventType, appID string) error {
+	data, err := json.Marshal(map[string]string{
+		"eventType":  eventType,
+		"appId":      appID,
+		"taskStatus": taskStateRunning,
+	})
+	if err != nil {
+		return err
+	}
+	select {
+	case m.events <- fmt.Sprintf("event: %s\ndata: %s\n\n", eventType, data):
+		return nil
+	case <-time.After(5 * time.Second):
+		return errors.New("no subscriber to the events stream")
+	}
+}
+
+func (m *mockMarathon) serveApplications(w http.ResponseWriter, r *http.Request) {
+	m.lock.Lock()
+	m.appsRequestCount++
+	failing := m.appsFailures > 0
+	if failing {
+		m.appsFailures--
+	}
+	apps := m.apps
+	m.lock.Unlock()
+
+	if failing {
+		http.Error(w, `{"message":"mock Marathon unavailable"}`, http.StatusServiceUnavailable)
+		return
+	}
+	body, err := json.Marshal(apps)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	etag := fmt.Sprintf(`"%x"`, sha1.Sum(body))
+	if r.Header.Get("If-None-Match") == etag {
+		m.lock.Lock()
+		m.appsNotModified++
+		m.lock.Unlock()
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag)
+	w.Write(body)
+}
+
+func (m *mockMarathon) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	m.lock.Lock()
+	m.eventsStreams++
+	m.lock.Unlock()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case event := <-m.events:
+			fmt.Fprint(w, event)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-m.done:
+			return
+		}
+	}
+}
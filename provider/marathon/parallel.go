@@ -0,0 +1,153 @@
+package marathon
+
+import (
+	"runtime"
+	"sync"
+	"text/template"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/types"
+	"github.com/gambol99/go-marathon"
+)
+
+// renderChunkSize is the number of applications rendered together by the built-in template.
+// Below it, the configuration is rendered in a single pass.
+const renderChunkSize = 100
+
+// forEachParallel calls f with every index in [0, n) from at most workers goroutines,
+// and returns once all the calls have returned
+func forEachParallel(n, workers int, f func(i int)) {
+	indexes := make(chan int, n)
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+
+	if workers > n {
+		workers = n
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		safe.Go(func() {
+			defer wg.Done()
+			for i := range indexes {
+				f(i)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func renderWorkers() int {
+	return runtime.GOMAXPROCS(0)
+}
+
+// filterApplications returns the enabled applications matching the constraints, with their valid tasks.
+// The applications are filtered concurrently, their order being kept.
+func (p *Provider) filterApplications(apps []marathon.Application) []marathon.Application {
+	enabled := make([]bool, len(apps))
+	forEachParallel(len(apps), renderWorkers(), func(i int) {
+		enabled[i] = p.applicationFilter(apps[i])
+	})
+
+	filteredApps := make([]marathon.Application, 0, len(apps))
+	for i, app := range apps {
+		if enabled[i] {
+			filteredApps = append(filteredApps, app)
+		}
+	}
+	forEachParallel(len(filteredApps), renderWorkers(), func(i int) {
+		filteredApps[i].Tasks = p.filterTasks(filteredApps[i])
+	})
+	return filteredApps
+}
+
+// filterTasks returns the tasks of the application passing the task filter
+func (p *Provider) filterTasks(application marathon.Application) []*marathon.Task {
+	tasks := make([]*marathon.Task, 0, len(application.Tasks))
+	for _, task := range application.Tasks {
+		if p.taskFilter(*task, application) {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+// renderConfiguration renders the configuration of the applications with the template.
+// The built-in template rendering every application independently, large numbers of applications are
+// rendered concurrently by chunks, and the configurations of the chunks merged. Custom templates may
+// depend on the whole list of applications, they are rendered in a single pass.
+func (p *Provider) renderConfiguration(funcMap template.FuncMap, apps []marathon.Application) (*types.Configuration, error) {
+	render := func(apps []marathon.Application) (*types.Configuration, error) {
+		templateObjects := struct {
+			Applications []marathon.Application
+			Domain       string
+		}{
+			apps,
+			p.Domain,
+		}
+		return p.GetConfiguration("templates/marathon.tmpl", funcMap, templateObjects)
+	}
+	if len(p.Filename) > 0 || len(apps) <= renderChunkSize {
+		return render(apps)
+	}
+
+	chunks := (len(apps) + renderChunkSize - 1) / renderChunkSize
+	configurations := make([]*types.Configuration, chunks)
+	errs := make([]error, chunks)
+	forEachParallel(chunks, renderWorkers(), func(i int) {
+		end := (i + 1) * renderChunkSize
+		if end > len(apps) {
+			end = len(apps)
+		}
+		configurations[i], errs[i] = render(apps[i*renderChunkSize : end])
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mergeConfigurations(configurations), nil
+}
+
+// mergeConfigurations merges the configurations rendered for distinct applications.
+// The servers of a backend shared by applications of several configurations are merged,
+// its other settings are those of the first configuration defining it.
+func mergeConfigurations(configurations []*types.Configuration) *types.Configuration {
+	merged := configurations[0]
+	if merged == nil {
+		log.Error("Missing configuration of Marathon applications")
+		merged = new(types.Configuration)
+	}
+	for _, configuration := range configurations[1:] {
+		if configuration == nil {
+			log.Error("Missing configuration of Marathon applications")
+			continue
+		}
+		for name, backend := range configuration.Backends {
+			if merged.Backends == nil {
+				merged.Backends = make(map[string]*types.Backend)
+			}
+			existing, ok := merged.Backends[name]
+			if !ok {
+				merged.Backends[name] = backend
+				continue
+			}
+			if existing.Servers == nil {
+				existing.Servers = make(map[string]types.Server)
+			}
+			for serverName, server := range backend.Servers {
+				existing.Servers[serverName] = server
+			}
+		}
+		for name, frontend := range configuration.Frontends {
+			if merged.Frontends == nil {
+				merged.Frontends = make(map[string]*types.Frontend)
+			}
+			merged.Frontends[name] = frontend
+		}
+	}
+	return merged
+}
@@ -0,0 +1,132 @@
+package marathon
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/gambol99/go-marathon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func manyApplications(count int, ops ...func(*marathon.Application)) []marathon.Application {
+	apps := make([]marathon.Application, 0, count)
+	for i := 0; i < count; i++ {
+		appOps := append([]func(*marathon.Application){
+			appID(fmt.Sprintf("/app%04d", i)),
+			appPorts(80),
+			withTasks(task(taskID("task"), taskHost(fmt.Sprintf("10.0.%d.%d", i/256, i%256)), taskPorts(80), taskState(taskStateRunning))),
+		}, ops...)
+		apps = append(apps, application(appOps...))
+	}
+	return apps
+}
+
+func TestForEachParallel(t *testing.T) {
+	for _, workers := range []int{1, 4, 100} {
+		var calls [50]int32
+		forEachParallel(len(calls), workers, func(i int) {
+			atomic.AddInt32(&calls[i], 1)
+		})
+		for i := range calls {
+			assert.EqualValues(t, 1, calls[i], "index %d with %d workers", i, workers)
+		}
+	}
+}
+
+func TestMarathonFilterApplicationsTasks(t *testing.T) {
+	apps := []marathon.Application{
+		application(appID("/app"), appPorts(80), withTasks(
+			task(taskID("running"), taskPorts(80), taskState(taskStateRunning)),
+			task(taskID("staging"), taskPorts(80), taskState("TASK_STAGING")),
+		)),
+	}
+	provider := &Provider{ExposedByDefault: true}
+
+	filteredApps := provider.filterApplications(apps)
+	require.Len(t, filteredApps, 1)
+	require.Len(t, filteredApps[0].Tasks, 1)
+	assert.Equal(t, "running", filteredApps[0].Tasks[0].ID)
+	assert.Len(t, apps[0].Tasks, 2)
+}
+
+func TestMarathonLoadConfigChunked(t *testing.T) {
+	apps := manyApplications(2*renderChunkSize + 50)
+	apps[10].AddLabel(types.LabelEnable, "false")
+	provider := &Provider{
+		Domain:           "docker.localhost",
+		ExposedByDefault: true,
+		marathonClient:   newScriptedClient(respond(apps...)),
+	}
+
+	configuration := provider.loadMarathonConfig()
+	require.NotNil(t, configuration)
+	assert.Len(t, configuration.Frontends, len(apps)-1)
+	assert.Len(t, configuration.Backends, len(apps)-1)
+	assert.NotContains(t, configuration.Backends, "backend-app0010")
+	for i, app := range apps {
+		if i == 10 {
+			continue
+		}
+		name := fmt.Sprintf("app%04d", i)
+		require.Contains(t, configuration.Backends, "backend-"+name)
+		assert.Equal(t, map[string]types.Server{
+			"server-task": {URL: "http://" + app.Tasks[0].Host + ":80"},
+		}, configuration.Backends["backend-"+name].Servers)
+		require.Contains(t, configuration.Frontends, "frontend-"+name)
+		assert.Equal(t, "backend-"+name, configuration.Frontends["frontend-"+name].Backend)
+	}
+}
+
+func TestMarathonLoadConfigChunkedSharedBackend(t *testing.T) {
+	apps := manyApplications(2 * renderChunkSize)
+	apps[0].AddLabel(types.LabelBackend, "shared")
+	apps[0].Tasks[0].ID = "first"
+	apps[len(apps)-1].AddLabel(types.LabelBackend, "shared")
+	apps[len(apps)-1].Tasks[0].ID = "last"
+	provider := &Provider{
+		Domain:           "docker.localhost",
+		ExposedByDefault: true,
+		BackendCollision: backendCollisionMerge,
+		marathonClient:   newScriptedClient(respond(apps...)),
+	}
+
+	configuration := provider.loadMarathonConfig()
+	require.NotNil(t, configuration)
+	require.Contains(t, configuration.Backends, "backendshared")
+	assert.Equal(t, map[string]types.Server{
+		"server-first": {URL: "http://" + apps[0].Tasks[0].Host + ":80"},
+		"server-last":  {URL: "http://" + apps[len(apps)-1].Tasks[0].Host + ":80"},
+	}, configuration.Backends["backendshared"].Servers)
+}
+
+func benchmarkMarathonLoadConfig(b *testing.B, count int) {
+	apps := manyApplications(count, withLabel(types.LabelFrontendPriority, "10"), withHealthCheck())
+	provider := &Provider{
+		Domain:           "docker.localhost",
+		ExposedByDefault: true,
+		marathonClient:   newScriptedClient(respond(apps...)),
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if provider.loadMarathonConfig() == nil {
+			b.Fatal("no configuration")
+		}
+	}
+}
+
+func BenchmarkMarathonLoadConfig100(b *testing.B) {
+	benchmarkMarathonLoadConfig(b, 100)
+}
+
+func BenchmarkMarathonLoadConfig1000(b *testing.B) {
+	benchmarkMarathonLoadConfig(b, 1000)
+}
+
+func BenchmarkMarathonLoadConfig5000(b *testing.B) {
+	benchmarkMarathonLoadConfig(b, 5000)
+}
@@ -0,0 +1,187 @@
+package marathon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/gambol99/go-marathon"
+)
+
+const (
+	defaultSecretStore = "default"
+	envSecretPrefix    = "env:"
+	// envSecretNamePrefix is the prefix of the only environment variables of Traefik readable as secrets,
+	// so that the applications cannot read the other ones, e.g. the DC/OS token
+	envSecretNamePrefix = "TRAEFIK_MARATHON_SECRET_"
+	// secretsCacheTTL is the duration during which a resolved secret is not read again from the DC/OS secrets API
+	secretsCacheTTL = 5 * time.Minute
+	// secretsStaleMaxAge is the maximum age of a secret whose previous value is kept while the DC/OS secrets API is unavailable
+	secretsStaleMaxAge = time.Hour
+)
+
+type secretsClient interface {
+	GetSecret(path string) (string, error)
+}
+
+// dcosSecretsClient reads secrets from the DC/OS secrets API
+type dcosSecretsClient struct {
+	endpoint   string
+	token      string
+	httpClient *http.Client
+}
+
+// secretStatusError is returned when the DC/OS secrets API answers with an unexpected status
+type secretStatusError struct {
+	statusCode int
+}
+
+func (e *secretStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d from the DC/OS secrets API", e.statusCode)
+}
+
+type dcosSecret struct {
+	Value string `json:"value"`
+}
+
+func (c *dcosSecretsClient) GetSecret(path string) (string, error) {
+	secretURL := strings.TrimRight(c.endpoint, "/") + "/secret/" + defaultSecretStore + "/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequest(http.MethodGet, secretURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	if len(c.token) > 0 {
+		req.Header.Set("Authorization", "token="+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", &secretStatusError{statusCode: resp.StatusCode}
+	}
+
+	secret := dcosSecret{}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return "", err
+	}
+	return secret.Value, nil
+}
+
+type cachedSecret struct {
+	value string
+	read  time.Time
+}
+
+// secretsCache caches the secrets read by a secrets client, so that the secrets API is not called
+// on every load of the applications
+type secretsCache struct {
+	client  secretsClient
+	lock    sync.Mutex
+	secrets map[string]cachedSecret
+}
+
+func newSecretsCache(client secretsClient) *secretsCache {
+	return &secretsCache{client: client, secrets: make(map[string]cachedSecret)}
+}
+
+// GetSecret returns the cached value of the secret, reading it again once expired. While the secrets
+// API is unavailable, the expired value is kept for secretsStaleMaxAge rather than dropping the
+// applications using it, but not when the secret was removed or its access denied.
+func (c *secretsCache) GetSecret(path string) (string, error) {
+	now := time.Now()
+	c.lock.Lock()
+	cached, ok := c.secrets[path]
+	c.lock.Unlock()
+	if ok && now.Sub(cached.read) < secretsCacheTTL {
+		return cached.value, nil
+	}
+
+	value, err := c.client.GetSecret(path)
+	if err != nil {
+		if ok && isUnavailable(err) && now.Sub(cached.read) < secretsStaleMaxAge {
+			log.Warnf("Unable to read the secret %s again, keeping its previous value: %s", path, err)
+			return cached.value, nil
+		}
+		c.lock.Lock()
+		delete(c.secrets, path)
+		c.lock.Unlock()
+		return "", err
+	}
+	c.lock.Lock()
+	c.secrets[path] = cachedSecret{value: value, read: now}
+	c.lock.Unlock()
+	return value, nil
+}
+
+// isUnavailable reports whether the error is a transport error or a server error of the secrets API,
+// as opposed to an answer about the secret itself
+func isUnavailable(err error) bool {
+	statusErr, ok := err.(*secretStatusError)
+	return !ok || statusErr.statusCode >= http.StatusInternalServerError
+}
+
+// resolveSecret returns the value of the secret reference: either the name of an environment
+// variable of Traefik prefixed by "env:", which must start with TRAEFIK_MARATHON_SECRET_,
+// or the path of a DC/OS secret
+func (p *Provider) resolveSecret(reference string) (string, error) {
+	if strings.HasPrefix(reference, envSecretPrefix) {
+		name := strings.TrimPrefix(reference, envSecretPrefix)
+		if !strings.HasPrefix(name, envSecretNamePrefix) {
+			return "", fmt.Errorf("environment variable %s cannot be read as a secret, its name must start with %s", name, envSecretNamePrefix)
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	}
+	if p.secretsClient == nil {
+		return "", fmt.Errorf("no DC/OS secrets endpoint configured to read secret %s", reference)
+	}
+	return p.secretsClient.GetSecret(reference)
+}
+
+// resolveBasicAuthSecrets resolves the basic authentication users referenced by the
+// traefik.frontend.auth.basic.usersSecret label of the applications, by application ID.
+// Applications whose users cannot be resolved are dropped, to never expose them without authentication.
+func (p *Provider) resolveBasicAuthSecrets(apps []marathon.Application) ([]marathon.Application, map[string][]string) {
+	resolvedApps := make([]marathon.Application, 0, len(apps))
+	users := make(map[string][]string)
+	for _, app := range apps {
+		reference, ok := p.getLabel(app, types.LabelFrontendAuthBasicUsersSecret)
+		if !ok {
+			resolvedApps = append(resolvedApps, app)
+			continue
+		}
+		value, err := p.resolveSecret(reference)
+		if err != nil {
+			log.Errorf("Filtering Marathon application %s, unable to resolve its basic authentication users: %s", app.ID, err)
+			continue
+		}
+		users[app.ID] = splitUsers(value)
+		resolvedApps = append(resolvedApps, app)
+	}
+	return resolvedApps, users
+}
+
+// splitUsers splits a list of users separated by new lines (htpasswd files) or commas
+func splitUsers(value string) []string {
+	users := []string{}
+	for _, user := range strings.FieldsFunc(value, func(r rune) bool { return r == '\n' || r == ',' }) {
+		user = strings.TrimSpace(user)
+		if len(user) > 0 {
+			users = append(users, user)
+		}
+	}
+	return users
+}
@@ -0,0 +1,215 @@
+package marathon
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/types"
+	"github.com/gambol99/go-marathon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSecretsClient map[string]string
+
+func (c fakeSecretsClient) GetSecret(path string) (string, error) {
+	value, ok := c[path]
+	if !ok {
+		return "", errors.New("secret not found")
+	}
+	return value, nil
+}
+
+func TestDCOSSecretsClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/secrets/v1/secret/default/team/users" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "token=dcos-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"value": "test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/"}`))
+	}))
+	defer server.Close()
+
+	client := &dcosSecretsClient{
+		endpoint:   server.URL + "/secrets/v1/",
+		token:      "dcos-token",
+		httpClient: http.DefaultClient,
+	}
+	value, err := client.GetSecret("/team/users")
+	require.NoError(t, err)
+	assert.Equal(t, "test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/", value)
+
+	_, err = client.GetSecret("/team/missing")
+	assert.Error(t, err)
+
+	client.token = "invalid"
+	_, err = client.GetSecret("/team/users")
+	assert.Error(t, err)
+}
+
+func TestMarathonResolveBasicAuthSecrets(t *testing.T) {
+	os.Setenv("TRAEFIK_MARATHON_SECRET_USERS", "test:hash")
+	defer os.Unsetenv("TRAEFIK_MARATHON_SECRET_USERS")
+
+	cases := []struct {
+		desc          string
+		secretsClient secretsClient
+		labels        map[string]string
+		expectedUsers []string
+		expectedKept  bool
+	}{
+		{
+			desc:         "no secret",
+			labels:       map[string]string{},
+			expectedKept: true,
+		},
+		{
+			desc:          "environment variable",
+			labels:        map[string]string{types.LabelFrontendAuthBasicUsersSecret: "env:TRAEFIK_MARATHON_SECRET_USERS"},
+			expectedUsers: []string{"test:hash"},
+			expectedKept:  true,
+		},
+		{
+			desc:   "missing environment variable",
+			labels: map[string]string{types.LabelFrontendAuthBasicUsersSecret: "env:TRAEFIK_MARATHON_SECRET_MISSING"},
+		},
+		{
+			desc:   "other environment variables of traefik are not readable",
+			labels: map[string]string{types.LabelFrontendAuthBasicUsersSecret: "env:HOME"},
+		},
+		{
+			desc:          "DC/OS secret",
+			secretsClient: fakeSecretsClient{"/team/users": "test:hash, test2:hash2"},
+			labels:        map[string]string{types.LabelFrontendAuthBasicUsersSecret: "/team/users"},
+			expectedUsers: []string{"test:hash", "test2:hash2"},
+			expectedKept:  true,
+		},
+		{
+			desc:          "missing DC/OS secret",
+			secretsClient: fakeSecretsClient{},
+			labels:        map[string]string{types.LabelFrontendAuthBasicUsersSecret: "/team/users"},
+		},
+		{
+			desc:   "no DC/OS secrets endpoint",
+			labels: map[string]string{types.LabelFrontendAuthBasicUsersSecret: "/team/users"},
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			provider := &Provider{secretsClient: test.secretsClient}
+			apps := []marathon.Application{{ID: "/app", Labels: &test.labels}}
+
+			resolvedApps, users := provider.resolveBasicAuthSecrets(apps)
+			if !test.expectedKept {
+				assert.Empty(t, resolvedApps)
+				return
+			}
+			assert.Len(t, resolvedApps, 1)
+			assert.Equal(t, test.expectedUsers, users["/app"])
+		})
+	}
+}
+
+type countingSecretsClient struct {
+	secrets map[string]string
+	calls   int
+}
+
+func (c *countingSecretsClient) GetSecret(path string) (string, error) {
+	c.calls++
+	return fakeSecretsClient(c.secrets).GetSecret(path)
+}
+
+func TestSecretsCache(t *testing.T) {
+	client := &countingSecretsClient{secrets: map[string]string{"/team/users": "test:hash"}}
+	cache := newSecretsCache(client)
+
+	for i := 0; i < 3; i++ {
+		value, err := cache.GetSecret("/team/users")
+		require.NoError(t, err)
+		assert.Equal(t, "test:hash", value)
+	}
+	assert.Equal(t, 1, client.calls, "the secret must be read once while cached")
+
+	_, err := cache.GetSecret("/team/missing")
+	assert.Error(t, err)
+
+	cache.secrets["/team/users"] = cachedSecret{value: "test:hash", read: time.Now().Add(-secretsCacheTTL)}
+	client.secrets["/team/users"] = "test:hash2"
+	value, err := cache.GetSecret("/team/users")
+	require.NoError(t, err)
+	assert.Equal(t, "test:hash2", value, "the expired secret must be read again")
+}
+
+type failingSecretsClient struct {
+	err error
+}
+
+func (c failingSecretsClient) GetSecret(path string) (string, error) {
+	return "", c.err
+}
+
+func TestSecretsCacheExpiredValue(t *testing.T) {
+	cases := []struct {
+		desc          string
+		err           error
+		age           time.Duration
+		expectedValue bool
+	}{
+		{
+			desc:          "transport error",
+			err:           errors.New("connection refused"),
+			age:           secretsCacheTTL,
+			expectedValue: true,
+		},
+		{
+			desc:          "server error",
+			err:           &secretStatusError{statusCode: http.StatusServiceUnavailable},
+			age:           secretsCacheTTL,
+			expectedValue: true,
+		},
+		{
+			desc: "removed secret",
+			err:  &secretStatusError{statusCode: http.StatusNotFound},
+			age:  secretsCacheTTL,
+		},
+		{
+			desc: "access denied",
+			err:  &secretStatusError{statusCode: http.StatusForbidden},
+			age:  secretsCacheTTL,
+		},
+		{
+			desc: "too old",
+			err:  errors.New("connection refused"),
+			age:  secretsStaleMaxAge,
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			cache := newSecretsCache(failingSecretsClient{err: test.err})
+			cache.secrets["/team/users"] = cachedSecret{value: "test:hash", read: time.Now().Add(-test.age)}
+
+			value, err := cache.GetSecret("/team/users")
+			if !test.expectedValue {
+				assert.Error(t, err)
+				assert.NotContains(t, cache.secrets, "/team/users")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "test:hash", value)
+		})
+	}
+}
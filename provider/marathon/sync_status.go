@@ -0,0 +1,69 @@
+package marathon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/provider"
+)
+
+// syncTracker records the outcome of the loads of the Marathon applications
+type syncTracker struct {
+	lock                sync.Mutex
+	lastSuccessfulSync  time.Time
+	lastError           string
+	lastErrorTime       time.Time
+	consecutiveFailures int
+	// failingSince is the time of the first failure since the last successful load
+	failingSince time.Time
+}
+
+func (t *syncTracker) success() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.lastSuccessfulSync = time.Now()
+	t.consecutiveFailures = 0
+	t.failingSince = time.Time{}
+}
+
+func (t *syncTracker) failure(err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.lastError = err.Error()
+	t.lastErrorTime = time.Now()
+	if t.consecutiveFailures == 0 {
+		t.failingSince = t.lastErrorTime
+	}
+	t.consecutiveFailures++
+}
+
+// SyncStatus returns the synchronization status of the provider with Marathon.
+// The last error is kept after a successful synchronization, consecutiveFailures being reset.
+func (p *Provider) SyncStatus() provider.SyncStatus {
+	p.lock.Lock()
+	leaderTransport := p.leaderTransport
+	p.lock.Unlock()
+
+	status := provider.SyncStatus{Name: "marathon", Endpoint: p.Endpoint}
+	if leaderTransport != nil {
+		status.Leader = leaderTransport.getLeader()
+	}
+
+	p.syncTracker.lock.Lock()
+	defer p.syncTracker.lock.Unlock()
+	if !p.syncTracker.lastSuccessfulSync.IsZero() {
+		lastSuccessfulSync := p.syncTracker.lastSuccessfulSync
+		status.LastSuccessfulSync = &lastSuccessfulSync
+	}
+	if !p.syncTracker.lastErrorTime.IsZero() {
+		lastErrorTime := p.syncTracker.lastErrorTime
+		status.LastError = p.syncTracker.lastError
+		status.LastErrorTime = &lastErrorTime
+	}
+	status.ConsecutiveFailures = p.syncTracker.consecutiveFailures
+	if !p.syncTracker.failingSince.IsZero() {
+		staleSince := p.syncTracker.failingSince
+		status.StaleSince = &staleSince
+	}
+	return status
+}
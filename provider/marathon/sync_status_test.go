@@ -0,0 +1,62 @@
+package marathon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarathonSyncStatus(t *testing.T) {
+	provider := &Provider{
+		Endpoint:         "http://marathon.example:8080",
+		ExposedByDefault: true,
+		marathonClient: newScriptedClient(
+			respondError("connection refused"),
+			respondError("connection refused"),
+			respond(application(appID("/app"), appPorts(80))),
+			respondError("marathon unavailable"),
+		),
+	}
+
+	status := provider.SyncStatus()
+	assert.Equal(t, "marathon", status.Name)
+	assert.Equal(t, "http://marathon.example:8080", status.Endpoint)
+	assert.Nil(t, status.LastSuccessfulSync)
+	assert.Nil(t, status.LastErrorTime)
+	assert.Equal(t, 0, status.ConsecutiveFailures)
+	assert.Nil(t, status.StaleSince)
+
+	provider.loadMarathonConfig()
+	firstFailure := provider.SyncStatus().LastErrorTime
+	provider.loadMarathonConfig()
+	status = provider.SyncStatus()
+	assert.Nil(t, status.LastSuccessfulSync)
+	assert.Contains(t, status.LastError, "connection refused")
+	require.NotNil(t, status.LastErrorTime)
+	assert.Equal(t, 2, status.ConsecutiveFailures)
+	require.NotNil(t, status.StaleSince)
+	assert.Equal(t, *firstFailure, *status.StaleSince, "the provider must be stale since its first failure")
+
+	require.NotNil(t, provider.loadMarathonConfig())
+	status = provider.SyncStatus()
+	require.NotNil(t, status.LastSuccessfulSync)
+	assert.Equal(t, 0, status.ConsecutiveFailures)
+	assert.Nil(t, status.StaleSince)
+	assert.Contains(t, status.LastError, "connection refused", "the last error must be kept after a successful sync")
+
+	provider.loadMarathonConfig()
+	status = provider.SyncStatus()
+	assert.Contains(t, status.LastError, "marathon unavailable")
+	assert.Equal(t, 1, status.ConsecutiveFailures)
+	assert.True(t, !status.LastErrorTime.Before(*status.LastSuccessfulSync))
+	assert.Equal(t, status.LastErrorTime, status.StaleSince)
+}
+
+func TestMarathonSyncStatusLeader(t *testing.T) {
+	leaderTransport := newLeaderRoundTripper(nil)
+	leaderTransport.setLeader("10.0.0.1:8080")
+	provider := &Provider{leaderTransport: leaderTransport}
+
+	assert.Equal(t, "10.0.0.1:8080", provider.SyncStatus().Leader)
+}
@@ -0,0 +1,157 @@
+package marathon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/gambol99/go-marathon"
+)
+
+// mesosTasksPageSize is the number of tasks read per request from the Mesos master
+const mesosTasksPageSize = 1000
+
+// taskMetadata holds the data of a task not decoded by go-marathon: the fault domain reported by Marathon,
+// and the labels of the Mesos task
+type taskMetadata struct {
+	Region string
+	Zone   string
+	Labels map[string]string
+}
+
+type taskMetadataClient interface {
+	TaskMetadata(zones, labels bool) (map[string]taskMetadata, error)
+}
+
+// httpTaskMetadataClient reads the fault domains of the tasks from the Marathon tasks API, and their labels
+// from the tasks API of the Mesos master
+type httpTaskMetadataClient struct {
+	marathonEndpoints []string
+	mesosEndpoint     string
+	basic             *Basic
+	token             string
+	httpClient        *http.Client
+}
+
+type marathonTasks struct {
+	Tasks []struct {
+		ID     string `json:"id"`
+		Region string `json:"region"`
+		Zone   string `json:"zone"`
+	} `json:"tasks"`
+}
+
+type mesosTasks struct {
+	Tasks []struct {
+		ID     string `json:"id"`
+		Labels []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"labels"`
+	} `json:"tasks"`
+}
+
+// TaskMetadata returns the metadata of the tasks by task ID, with their fault domains and their labels
+// when requested
+func (c *httpTaskMetadataClient) TaskMetadata(zones, labels bool) (map[string]taskMetadata, error) {
+	metadata := make(map[string]taskMetadata)
+	if zones {
+		tasks := &marathonTasks{}
+		var err error
+		// from the first Marathon endpoint answering
+		for _, endpoint := range c.marathonEndpoints {
+			if err = c.get(strings.TrimRight(endpoint, "/")+"/v2/tasks", true, tasks); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks.Tasks {
+			metadata[task.ID] = taskMetadata{Region: task.Region, Zone: task.Zone}
+		}
+	}
+	if labels {
+		for offset := 0; ; offset += mesosTasksPageSize {
+			query := url.Values{"limit": {strconv.Itoa(mesosTasksPageSize)}, "offset": {strconv.Itoa(offset)}}
+			tasks := &mesosTasks{}
+			if err := c.get(strings.TrimRight(c.mesosEndpoint, "/")+"/master/tasks?"+query.Encode(), false, tasks); err != nil {
+				return nil, err
+			}
+			for _, task := range tasks.Tasks {
+				if len(task.Labels) == 0 {
+					continue
+				}
+				taskMetadata := metadata[task.ID]
+				taskMetadata.Labels = make(map[string]string, len(task.Labels))
+				for _, label := range task.Labels {
+					taskMetadata.Labels[label.Key] = label.Value
+				}
+				metadata[task.ID] = taskMetadata
+			}
+			if len(tasks.Tasks) < mesosTasksPageSize {
+				break
+			}
+		}
+	}
+	return metadata, nil
+}
+
+// get decodes the JSON answer of a GET request, authenticated with the DC/OS token or, for Marathon,
+// with the basic authentication
+func (c *httpTaskMetadataClient) get(url string, marathonAPI bool, result interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if marathonAPI {
+		setMarathonCredentials(req, c.basic, c.token)
+	} else {
+		setMarathonCredentials(req, nil, c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// needsTaskZones returns whether some application is located in the fault domain zones of its tasks
+func (p *Provider) needsTaskZones(applications []marathon.Application) bool {
+	for _, application := range applications {
+		if label, ok := p.getLabel(application, types.LabelBackendPreferLocalZone); ok && label == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// loadTaskMetadata returns the metadata of the tasks, read on every load of the applications: their fault
+// domains when some application uses them, and their labels when the Mesos master endpoint is set.
+// The metadata previously read are kept when the tasks cannot be read.
+func (p *Provider) loadTaskMetadata(applications []marathon.Application) map[string]taskMetadata {
+	zones, labels := p.needsTaskZones(applications), len(p.MesosEndpoint) > 0
+	if p.taskMetadataClient == nil || (!zones && !labels) {
+		return nil
+	}
+	metadata, err := p.taskMetadataClient.TaskMetadata(zones, labels)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if err != nil {
+		log.Errorf("Failed to retrieve the metadata of the Marathon tasks, using the last retrieved ones: %s", err)
+		return p.lastTaskMetadata
+	}
+	p.lastTaskMetadata = metadata
+	return metadata
+}
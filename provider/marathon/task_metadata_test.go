@@ -0,0 +1,105 @@
+package marathon
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/gambol99/go-marathon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTaskMetadataClient map[string]taskMetadata
+
+func (c fakeTaskMetadataClient) TaskMetadata(zones, labels bool) (map[string]taskMetadata, error) {
+	if c == nil {
+		return nil, errors.New("fake Marathon server error")
+	}
+	return c, nil
+}
+
+func TestHTTPTaskMetadataClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/tasks":
+			if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"tasks": [{"id": "app.1", "region": "us-east-1", "zone": "us-east-1b"}, {"id": "app.2"}]}`))
+		case "/mesos/master/tasks":
+			if _, _, ok := r.BasicAuth(); ok {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			// a full page of tasks without labels, then the labelled ones
+			offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			if offset == 0 {
+				fmt.Fprint(w, `{"tasks": [`)
+				for i := 0; i < mesosTasksPageSize; i++ {
+					if i > 0 {
+						fmt.Fprint(w, ",")
+					}
+					fmt.Fprintf(w, `{"id": "other.%d"}`, i)
+				}
+				fmt.Fprint(w, `]}`)
+				return
+			}
+			w.Write([]byte(`{"tasks": [{"id": "app.1", "labels": [{"key": "shard", "value": "3"}]}, {"id": "app.3", "labels": [{"key": "traefik.weight", "value": "20"}]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := &httpTaskMetadataClient{
+		marathonEndpoints: []string{"http://127.0.0.1:1", server.URL + "/"},
+		mesosEndpoint:     server.URL + "/mesos",
+		basic:             &Basic{HTTPBasicAuthUser: "user", HTTPBasicPassword: "secret"},
+		httpClient:        http.DefaultClient,
+	}
+
+	metadata, err := client.TaskMetadata(true, false)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]taskMetadata{
+		"app.1": {Region: "us-east-1", Zone: "us-east-1b"},
+		"app.2": {},
+	}, metadata)
+
+	metadata, err = client.TaskMetadata(false, true)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]taskMetadata{
+		"app.1": {Labels: map[string]string{"shard": "3"}},
+		"app.3": {Labels: map[string]string{"traefik.weight": "20"}},
+	}, metadata)
+
+	metadata, err = client.TaskMetadata(true, true)
+	require.NoError(t, err)
+	assert.Equal(t, taskMetadata{Region: "us-east-1", Zone: "us-east-1b", Labels: map[string]string{"shard": "3"}}, metadata["app.1"])
+
+	client.basic = nil
+	_, err = client.TaskMetadata(true, true)
+	assert.Error(t, err)
+}
+
+func TestMarathonLoadTaskMetadata(t *testing.T) {
+	preferLocalZone := []marathon.Application{application(withLabel(types.LabelBackendPreferLocalZone, "true"))}
+
+	provider := &Provider{taskMetadataClient: fakeTaskMetadataClient{"app.1": {Zone: "us-east-1b"}}}
+	assert.Nil(t, provider.loadTaskMetadata([]marathon.Application{application()}))
+	assert.Equal(t, map[string]taskMetadata{"app.1": {Zone: "us-east-1b"}}, provider.loadTaskMetadata(preferLocalZone))
+
+	// the last metadata are kept when Marathon fails
+	provider.taskMetadataClient = fakeTaskMetadataClient(nil)
+	assert.Equal(t, map[string]taskMetadata{"app.1": {Zone: "us-east-1b"}}, provider.loadTaskMetadata(preferLocalZone))
+
+	// the labels are read whenever the Mesos endpoint is set
+	provider.MesosEndpoint = "http://mesos.example:5050"
+	provider.taskMetadataClient = fakeTaskMetadataClient{"app.1": {Labels: map[string]string{"shard": "3"}}}
+	assert.Equal(t, map[string]taskMetadata{"app.1": {Labels: map[string]string{"shard": "3"}}}, provider.loadTaskMetadata([]marathon.Application{application()}))
+}
@@ -0,0 +1,237 @@
+package marathon
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/provider"
+)
+
+// dialTLS opens a TLS connection to addr using the latest TLS configuration
+// provided by the reloader.
+func dialTLS(dialer *net.Dialer, tlsReloader *provider.TLSConfigReloader, handshakeTimeout time.Duration, network, addr string) (net.Conn, error) {
+	tlsConfig, err := tlsReloader.GetTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig = tlsConfig.Clone()
+	if tlsConfig.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ServerName = host
+	}
+
+	conn, err := dialer.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if handshakeTimeout > 0 {
+		conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
+// timeoutRoundTripper bounds the total duration of every Marathon API call.
+// The SSE events stream is long-lived by design and is therefore left untouched.
+type timeoutRoundTripper struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func (rt *timeoutRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt.timeout <= 0 || req.Header.Get("Accept") == "text/event-stream" {
+		return rt.next.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), rt.timeout)
+	resp, err := rt.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the deadline must keep applying while the body is read
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// setMarathonCredentials authenticates a request with the DC/OS token or, without token, with the basic authentication
+func setMarathonCredentials(req *http.Request, basic *Basic, token string) {
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "token="+token)
+	} else if basic != nil {
+		req.SetBasicAuth(basic.HTTPBasicAuthUser, basic.HTTPBasicPassword)
+	}
+}
+
+// contextRoundTripper cancels the outstanding Marathon calls, including the events stream,
+// when the provider context is done.
+// go-marathon checks the Marathon members it marked down, e.g. on the cancellation of a call, with a
+// goroutine pinging them until they answer: once the context is done, the pings are answered right
+// away so that these goroutines terminate.
+type contextRoundTripper struct {
+	next http.RoundTripper
+	ctx  context.Context
+}
+
+func (rt *contextRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := rt.ctx.Err(); err != nil {
+		if strings.HasSuffix(req.URL.Path, "/ping") {
+			return &http.Response{
+				Status:     "200 OK",
+				StatusCode: http.StatusOK,
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				Header:     make(http.Header),
+				Body:       ioutil.NopCloser(strings.NewReader("pong")),
+				Request:    req,
+			}, nil
+		}
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-rt.ctx.Done():
+			cancel()
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			close(done)
+			cancel()
+		})
+	}
+
+	resp, err := rt.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: release}
+	return resp, nil
+}
+
+// etagRoundTripper makes the GET calls to the Marathon API conditional: the last response of every URL
+// carrying an ETag is kept, its ETag is sent in the If-None-Match header of the next call of the URL,
+// and a 304 Not Modified response of Marathon is replaced by the kept response, so that the leader
+// does not serialize and send the unchanged applications again.
+// The SSE events stream is left untouched.
+type etagRoundTripper struct {
+	next      http.RoundTripper
+	lock      sync.Mutex
+	responses map[string]*etagResponse
+}
+
+type etagResponse struct {
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+func newETagRoundTripper(next http.RoundTripper) *etagRoundTripper {
+	return &etagRoundTripper{next: next, responses: make(map[string]*etagResponse)}
+}
+
+func (rt *etagRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Accept") == "text/event-stream" {
+		return rt.next.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	rt.lock.Lock()
+	cached := rt.responses[key]
+	rt.lock.Unlock()
+	if cached != nil {
+		req = cloneRequest(req)
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	resp, err := rt.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		resp.Body.Close()
+		log.Debugf("Marathon response of %s not modified", req.URL.Path)
+		return cached.response(req), nil
+	case resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") != "":
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		rt.lock.Lock()
+		rt.responses[key] = &etagResponse{etag: resp.Header.Get("ETag"), header: resp.Header, body: body}
+		rt.lock.Unlock()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		resp.ContentLength = int64(len(body))
+		return resp, nil
+	default:
+		return resp, nil
+	}
+}
+
+// response rebuilds the kept response for the request
+func (r *etagResponse) response(req *http.Request) *http.Response {
+	header := make(http.Header, len(r.header))
+	for name, values := range r.header {
+		header[name] = append([]string(nil), values...)
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(r.body)),
+		ContentLength: int64(len(r.body)),
+		Request:       req,
+	}
+}
+
+// cloneRequest returns a shallow copy of the request with its own headers,
+// a round tripper not being allowed to modify the request it is given
+func cloneRequest(req *http.Request) *http.Request {
+	clone := new(http.Request)
+	*clone = *req
+	clone.Header = make(http.Header, len(req.Header))
+	for name, values := range req.Header {
+		clone.Header[name] = append([]string(nil), values...)
+	}
+	return clone
+}
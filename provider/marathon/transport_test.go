@@ -0,0 +1,162 @@
+package marathon
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeoutRoundTripper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("slow"))
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		desc          string
+		timeout       time.Duration
+		accept        string
+		expectTimeout bool
+	}{
+		{
+			desc:          "timeout exceeded",
+			timeout:       10 * time.Millisecond,
+			expectTimeout: true,
+		},
+		{
+			desc:    "timeout disabled",
+			timeout: 0,
+		},
+		{
+			desc:    "timeout not exceeded",
+			timeout: time.Second,
+		},
+		{
+			desc:    "events stream is not bounded",
+			timeout: 10 * time.Millisecond,
+			accept:  "text/event-stream",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.desc, func(t *testing.T) {
+			client := &http.Client{
+				Transport: &timeoutRoundTripper{
+					next:    http.DefaultTransport,
+					timeout: test.timeout,
+				},
+			}
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			require.NoError(t, err)
+			if test.accept != "" {
+				req.Header.Set("Accept", test.accept)
+			}
+
+			resp, err := client.Do(req)
+			if test.expectTimeout {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "slow", string(body))
+		})
+	}
+}
+
+func TestContextRoundTripper(t *testing.T) {
+	requested := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &http.Client{
+		Transport: &contextRoundTripper{
+			next: http.DefaultTransport,
+			ctx:  ctx,
+		},
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := client.Get(server.URL)
+		errs <- err
+	}()
+
+	<-requested
+	cancel()
+	select {
+	case err := <-errs:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("outstanding request not cancelled")
+	}
+
+	_, err := client.Get(server.URL)
+	assert.Error(t, err, "requests must fail once the context is done")
+}
+
+func TestETagRoundTripper(t *testing.T) {
+	var lock sync.Mutex
+	content := "v1"
+	var conditionalRequests, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		etag := `"` + content + `"`
+		if r.Header.Get("If-None-Match") != "" {
+			conditionalRequests++
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if r.URL.Path != "/no-etag" {
+			w.Header().Set("ETag", etag)
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newETagRoundTripper(http.DefaultTransport)}
+	get := func(path string) string {
+		resp, err := client.Get(server.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	assert.Equal(t, "v1", get("/v2/apps"))
+	assert.Equal(t, "v1", get("/v2/apps"))
+	assert.Equal(t, "v1", get("/v2/apps"))
+	assert.Equal(t, 2, notModified, "unchanged responses are not sent again")
+
+	lock.Lock()
+	content = "v2"
+	lock.Unlock()
+	assert.Equal(t, "v2", get("/v2/apps"))
+	assert.Equal(t, "v2", get("/v2/apps"))
+	assert.Equal(t, 3, notModified)
+
+	conditional := conditionalRequests
+	get("/no-etag")
+	get("/no-etag")
+	assert.Equal(t, conditional, conditionalRequests, "responses without ETag are not kept")
+}
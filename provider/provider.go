@@ -3,17 +3,14 @@ package provider
 import (
 	"bytes"
 	"crypto/tls"
-	"crypto/x509"
-	"fmt"
 	"io/ioutil"
-	"os"
 	"strings"
 	"text/template"
+	"time"
 	"unicode"
 
 	"github.com/BurntSushi/toml"
 	"github.com/containous/traefik/autogen"
-	"github.com/containous/traefik/log"
 	"github.com/containous/traefik/safe"
 	"github.com/containous/traefik/types"
 )
@@ -25,6 +22,28 @@ type Provider interface {
 	Provide(configurationChan chan<- types.ConfigMessage, pool *safe.Pool, constraints types.Constraints) error
 }
 
+// SyncStatus describes the synchronization of a provider with its configuration source,
+// so that stale routing data can be detected while Traefik itself is healthy.
+type SyncStatus struct {
+	Name                string     `json:"-"`
+	LastSuccessfulSync  *time.Time `json:"lastSuccessfulSync,omitempty"`
+	LastError           string     `json:"lastError,omitempty"`
+	LastErrorTime       *time.Time `json:"lastErrorTime,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	Endpoint            string     `json:"endpoint,omitempty"`
+	Leader              string     `json:"leader,omitempty"`
+	// StaleSince is the time of the first failure since the last successful synchronization,
+	// the last configuration of the provider being kept active meanwhile
+	StaleSince *time.Time `json:"staleSince,omitempty"`
+	// RoutesDropped reports that the routes of the stale provider were dropped after the stale TTL
+	RoutesDropped bool `json:"routesDropped,omitempty"`
+}
+
+// SyncStatusReporter is implemented by the providers reporting their synchronization status
+type SyncStatusReporter interface {
+	SyncStatus() SyncStatus
+}
+
 // BaseProvider should be inherited by providers
 type BaseProvider struct {
 	Watch       bool              `description:"Watch provider"`
@@ -142,53 +161,5 @@ type ClientTLS struct {
 
 // CreateTLSConfig creates a TLS config from ClientTLS structures
 func (clientTLS *ClientTLS) CreateTLSConfig() (*tls.Config, error) {
-	var err error
-	if clientTLS == nil {
-		log.Warnf("clientTLS is nil")
-		return nil, nil
-	}
-	caPool := x509.NewCertPool()
-	if clientTLS.CA != "" {
-		var ca []byte
-		if _, errCA := os.Stat(clientTLS.CA); errCA == nil {
-			ca, err = ioutil.ReadFile(clientTLS.CA)
-			if err != nil {
-				return nil, fmt.Errorf("Failed to read CA. %s", err)
-			}
-		} else {
-			ca = []byte(clientTLS.CA)
-		}
-		caPool.AppendCertsFromPEM(ca)
-	}
-
-	cert := tls.Certificate{}
-	_, errKeyIsFile := os.Stat(clientTLS.Key)
-
-	if _, errCertIsFile := os.Stat(clientTLS.Cert); errCertIsFile == nil {
-		if errKeyIsFile == nil {
-			cert, err = tls.LoadX509KeyPair(clientTLS.Cert, clientTLS.Key)
-			if err != nil {
-				return nil, fmt.Errorf("Failed to load TLS keypair: %v", err)
-			}
-		} else {
-			return nil, fmt.Errorf("tls cert is a file, but tls key is not")
-		}
-	} else {
-		if errKeyIsFile != nil {
-			cert, err = tls.X509KeyPair([]byte(clientTLS.Cert), []byte(clientTLS.Key))
-			if err != nil {
-				return nil, fmt.Errorf("Failed to load TLS keypair: %v", err)
-
-			}
-		} else {
-			return nil, fmt.Errorf("tls key is a file, but tls cert is not")
-		}
-	}
-
-	TLSConfig := &tls.Config{
-		Certificates:       []tls.Certificate{cert},
-		RootCAs:            caPool,
-		InsecureSkipVerify: clientTLS.InsecureSkipVerify,
-	}
-	return TLSConfig, nil
+	return (*types.ClientTLS)(clientTLS).CreateTLSConfig()
 }
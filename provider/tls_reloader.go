@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+)
+
+// TLSConfigReloader keeps a TLS client configuration built from a ClientTLS up to date:
+// whenever one of the CA, Cert or Key files is modified on disk, the configuration is
+// rebuilt so that new connections use the rotated certificates.
+type TLSConfigReloader struct {
+	clientTLS *ClientTLS
+	lock      sync.Mutex
+	config    *tls.Config
+	modTimes  map[string]time.Time
+}
+
+// NewTLSConfigReloader creates a TLSConfigReloader and loads the initial TLS configuration
+func NewTLSConfigReloader(clientTLS *ClientTLS) (*TLSConfigReloader, error) {
+	reloader := &TLSConfigReloader{clientTLS: clientTLS}
+	if _, err := reloader.GetTLSConfig(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// GetTLSConfig returns the current TLS configuration, reloading it first if any of
+// the underlying files changed since the last load.
+// If the reload fails, the previous configuration is kept until the files are modified again,
+// and the error is logged.
+func (r *TLSConfigReloader) GetTLSConfig() (*tls.Config, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	modTimes := r.clientTLS.fileModTimes()
+	if r.config != nil && sameModTimes(r.modTimes, modTimes) {
+		return r.config, nil
+	}
+
+	config, err := r.clientTLS.CreateTLSConfig()
+	if err != nil {
+		if r.config == nil {
+			return nil, err
+		}
+		log.Errorf("Failed to reload TLS configuration, keeping the previous one: %s", err)
+		// the files are not loaded again until they are modified, instead of on every connection
+		r.modTimes = modTimes
+		return r.config, nil
+	}
+	if r.config != nil {
+		log.Infof("TLS client configuration reloaded")
+	}
+	r.config = config
+	r.modTimes = modTimes
+	return r.config, nil
+}
+
+// fileModTimes returns the modification times of the CA, Cert and Key when they are files
+func (clientTLS *ClientTLS) fileModTimes() map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	for _, path := range []string{clientTLS.CA, clientTLS.Cert, clientTLS.Key} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			modTimes[path] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+func sameModTimes(previous, current map[string]time.Time) bool {
+	if len(previous) != len(current) {
+		return false
+	}
+	for path, modTime := range current {
+		if !previous[path].Equal(modTime) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generateKeyPair(t *testing.T, commonName string) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func writeKeyPair(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	cert, key := generateKeyPair(t, commonName)
+	require.NoError(t, ioutil.WriteFile(certFile, cert, 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, key, 0600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func certificateCommonName(t *testing.T, der []byte) string {
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert.Subject.CommonName
+}
+
+func TestTLSConfigReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls-reloader")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	now := time.Now()
+	writeKeyPair(t, certFile, keyFile, "first", now.Add(-time.Minute))
+
+	reloader, err := NewTLSConfigReloader(&ClientTLS{Cert: certFile, Key: keyFile})
+	require.NoError(t, err)
+
+	config, err := reloader.GetTLSConfig()
+	require.NoError(t, err)
+	require.Len(t, config.Certificates, 1)
+	assert.Equal(t, "first", certificateCommonName(t, config.Certificates[0].Certificate[0]))
+
+	sameConfig, err := reloader.GetTLSConfig()
+	require.NoError(t, err)
+	assert.True(t, config == sameConfig, "configuration should not be rebuilt when files are unchanged")
+
+	writeKeyPair(t, certFile, keyFile, "second", now)
+	config, err = reloader.GetTLSConfig()
+	require.NoError(t, err)
+	require.Len(t, config.Certificates, 1)
+	assert.Equal(t, "second", certificateCommonName(t, config.Certificates[0].Certificate[0]))
+
+	// A broken key pair must not replace the last valid configuration.
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("invalid"), 0600))
+	require.NoError(t, os.Chtimes(keyFile, now.Add(time.Minute), now.Add(time.Minute)))
+	config, err = reloader.GetTLSConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "second", certificateCommonName(t, config.Certificates[0].Certificate[0]))
+	// nor be loaded again until it is modified
+	assert.True(t, reloader.modTimes[keyFile].Equal(now.Add(time.Minute)))
+
+	writeKeyPair(t, certFile, keyFile, "third", now.Add(2*time.Minute))
+	config, err = reloader.GetTLSConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "third", certificateCommonName(t, config.Certificates[0].Certificate[0]))
+}
+
+func TestNewTLSConfigReloaderError(t *testing.T) {
+	_, err := NewTLSConfigReloader(&ClientTLS{Cert: "invalid", Key: "invalid"})
+	assert.Error(t, err)
+}
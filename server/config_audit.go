@@ -0,0 +1,55 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// configAuditLog writes every applied configuration change to a file, one JSON object per line,
+// so that operators can tell which provider event removed which server.
+type configAuditLog struct {
+	includeDiff bool
+
+	lock sync.Mutex
+	file *os.File
+}
+
+func newConfigAuditLog(config *ConfigAuditLog) (*configAuditLog, error) {
+	dir := filepath.Dir(config.FilePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create configuration audit log path %s: %s", dir, err)
+	}
+	file, err := os.OpenFile(config.FilePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0664)
+	if err != nil {
+		return nil, fmt.Errorf("error opening configuration audit log file %s: %s", config.FilePath, err)
+	}
+	return &configAuditLog{includeDiff: config.IncludeDiff, file: file}, nil
+}
+
+// record writes the change, without the diff unless configured otherwise
+func (a *configAuditLog) record(change configChange) error {
+	if change.isEmpty() {
+		return nil
+	}
+	if !a.includeDiff {
+		change.Diff = nil
+	}
+	line, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	_, err = a.file.Write(append(line, '\n'))
+	return err
+}
+
+func (a *configAuditLog) Close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.file.Close()
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "traefik-config-audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "log", "config-audit.log")
+
+	auditLog, err := newConfigAuditLog(&ConfigAuditLog{FilePath: filePath})
+	require.NoError(t, err)
+
+	oldConfig := &types.Configuration{
+		Backends: map[string]*types.Backend{
+			"backend-app": {Servers: map[string]types.Server{"server-task1": {URL: "http://10.0.0.1:80"}}},
+		},
+	}
+	newConfig := &types.Configuration{
+		Backends: map[string]*types.Backend{
+			"backend-app": {Servers: map[string]types.Server{}},
+		},
+	}
+	change := newConfigChange("marathon", oldConfig, newConfig, true)
+	change.Causes = []string{"status_update_event: task task1 of application /app is TASK_KILLED"}
+	require.NoError(t, auditLog.record(change))
+	// changes without any difference are not recorded
+	require.NoError(t, auditLog.record(newConfigChange("marathon", newConfig, newConfig, true)))
+	require.NoError(t, auditLog.Close())
+
+	file, err := os.Open(filePath)
+	require.NoError(t, err)
+	defer file.Close()
+	var lines []configChange
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line configChange
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	require.Len(t, lines, 1)
+	assert.Equal(t, "marathon", lines[0].Provider)
+	assert.Equal(t, change.Causes, lines[0].Causes)
+	assert.Equal(t, []string{"backend-app"}, lines[0].Backends.Modified)
+	assert.Equal(t, []string{"server-task1"}, lines[0].Servers["backend-app"].Removed)
+	assert.Nil(t, lines[0].Diff, "diff not included by default")
+}
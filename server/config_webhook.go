@@ -0,0 +1,268 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/cenk/backoff"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+)
+
+const (
+	configWebhookSignatureHeader = "X-Traefik-Signature"
+	configWebhookQueueSize       = 100
+)
+
+// configChange is the payload POSTed to the configuration webhook.
+type configChange struct {
+	Provider  string        `json:"provider"`
+	Time      time.Time     `json:"time"`
+	Causes    []string      `json:"causes,omitempty"`
+	Frontends changeSummary `json:"frontends"`
+	Backends  changeSummary `json:"backends"`
+	// Servers are the server changes of the added, removed and modified backends, by backend name
+	Servers map[string]changeSummary `json:"servers,omitempty"`
+	Diff    *configDiff              `json:"diff,omitempty"`
+}
+
+type changeSummary struct {
+	Added    []string `json:"added,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+	Modified []string `json:"modified,omitempty"`
+}
+
+func (s changeSummary) isEmpty() bool {
+	return len(s.Added) == 0 && len(s.Removed) == 0 && len(s.Modified) == 0
+}
+
+type configDiff struct {
+	Frontends map[string]frontendDiff `json:"frontends,omitempty"`
+	Backends  map[string]backendDiff  `json:"backends,omitempty"`
+}
+
+type frontendDiff struct {
+	Old *types.Frontend `json:"old,omitempty"`
+	New *types.Frontend `json:"new,omitempty"`
+}
+
+type backendDiff struct {
+	Old *types.Backend `json:"old,omitempty"`
+	New *types.Backend `json:"new,omitempty"`
+}
+
+func (c configChange) isEmpty() bool {
+	return c.Frontends.isEmpty() && c.Backends.isEmpty()
+}
+
+// newConfigChange computes the changes between two configurations of a provider.
+func newConfigChange(providerName string, oldConfig, newConfig *types.Configuration, includeDiff bool) configChange {
+	if oldConfig == nil {
+		oldConfig = &types.Configuration{}
+	}
+	if newConfig == nil {
+		newConfig = &types.Configuration{}
+	}
+
+	change := configChange{
+		Provider: providerName,
+		Time:     time.Now().UTC(),
+	}
+	diff := &configDiff{
+		Frontends: make(map[string]frontendDiff),
+		Backends:  make(map[string]backendDiff),
+	}
+
+	for _, name := range mapKeys(oldConfig.Frontends, newConfig.Frontends) {
+		oldFrontend, newFrontend := oldConfig.Frontends[name], newConfig.Frontends[name]
+		if summarizeChange(&change.Frontends, name, oldFrontend, newFrontend) {
+			diff.Frontends[name] = frontendDiff{Old: oldFrontend, New: newFrontend}
+		}
+	}
+	for _, name := range mapKeys(oldConfig.Backends, newConfig.Backends) {
+		oldBackend, newBackend := oldConfig.Backends[name], newConfig.Backends[name]
+		if summarizeChange(&change.Backends, name, oldBackend, newBackend) {
+			diff.Backends[name] = backendDiff{Old: oldBackend, New: newBackend}
+			if servers := summarizeServerChanges(oldBackend, newBackend); !servers.isEmpty() {
+				if change.Servers == nil {
+					change.Servers = make(map[string]changeSummary)
+				}
+				change.Servers[name] = servers
+			}
+		}
+	}
+
+	if includeDiff {
+		change.Diff = diff
+	}
+	return change
+}
+
+// summarizeChange records the change of the named element in the summary and
+// reports whether it changed. A nil pointer means the element does not exist.
+func summarizeChange(summary *changeSummary, name string, oldValue, newValue interface{}) bool {
+	oldNil, newNil := reflect.ValueOf(oldValue).IsNil(), reflect.ValueOf(newValue).IsNil()
+	switch {
+	case oldNil && !newNil:
+		summary.Added = append(summary.Added, name)
+	case !oldNil && newNil:
+		summary.Removed = append(summary.Removed, name)
+	case !reflect.DeepEqual(oldValue, newValue):
+		summary.Modified = append(summary.Modified, name)
+	default:
+		return false
+	}
+	return true
+}
+
+// summarizeServerChanges returns the changes of the servers of a backend, a nil backend having no server
+func summarizeServerChanges(oldBackend, newBackend *types.Backend) changeSummary {
+	var oldServers, newServers map[string]types.Server
+	if oldBackend != nil {
+		oldServers = oldBackend.Servers
+	}
+	if newBackend != nil {
+		newServers = newBackend.Servers
+	}
+
+	var summary changeSummary
+	for _, name := range mapKeys(oldServers, newServers) {
+		oldServer, oldOk := oldServers[name]
+		newServer, newOk := newServers[name]
+		switch {
+		case !oldOk:
+			summary.Added = append(summary.Added, name)
+		case !newOk:
+			summary.Removed = append(summary.Removed, name)
+		case oldServer != newServer:
+			summary.Modified = append(summary.Modified, name)
+		}
+	}
+	return summary
+}
+
+// mapKeys returns the sorted union of the keys of the given maps
+func mapKeys(maps ...interface{}) []string {
+	keys := make(map[string]bool)
+	for _, m := range maps {
+		for _, key := range reflect.ValueOf(m).MapKeys() {
+			keys[key.String()] = true
+		}
+	}
+	var sorted []string
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// configWebhookNotifier POSTs every applied configuration change to a webhook.
+// Notifications are delivered in order by a single routine, so that a slow or
+// unavailable webhook never delays configuration reloads.
+type configWebhookNotifier struct {
+	config        *ConfigWebhook
+	client        *http.Client
+	changes       chan configChange
+	retryInterval time.Duration
+}
+
+func newConfigWebhookNotifier(config *ConfigWebhook) *configWebhookNotifier {
+	return &configWebhookNotifier{
+		config:        config,
+		client:        &http.Client{Timeout: time.Duration(config.Timeout)},
+		changes:       make(chan configChange, configWebhookQueueSize),
+		retryInterval: backoff.DefaultInitialInterval,
+	}
+}
+
+// notify queues the change for delivery, dropping it if the queue is full
+func (n *configWebhookNotifier) notify(change configChange) {
+	if change.isEmpty() {
+		return
+	}
+	select {
+	case n.changes <- change:
+	default:
+		log.Warnf("Configuration webhook queue is full, dropping notification of %s configuration change", change.Provider)
+	}
+}
+
+func (n *configWebhookNotifier) run(stop chan bool) {
+	for {
+		select {
+		case <-stop:
+			return
+		case change := <-n.changes:
+			if err := n.deliver(change, stop); err != nil {
+				log.Errorf("Unable to notify configuration webhook of %s configuration change: %s", change.Provider, err)
+			}
+		}
+	}
+}
+
+// deliver sends the change to the webhook, retrying with an exponential backoff
+func (n *configWebhookNotifier) deliver(change configChange, stop chan bool) error {
+	body, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+
+	attempts := n.config.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = n.retryInterval
+	b.MaxElapsedTime = 0
+	b.Reset()
+	for attempt := 1; ; attempt++ {
+		err = n.send(body)
+		if err == nil || attempt >= attempts {
+			return err
+		}
+		wait := b.NextBackOff()
+		log.Debugf("Configuration webhook attempt %d failed, retrying in %s: %s", attempt, wait, err)
+		select {
+		case <-stop:
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (n *configWebhookNotifier) send(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, n.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.config.Secret != "" {
+		req.Header.Set(configWebhookSignatureHeader, signPayload(n.config.Secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// signPayload returns the HMAC-SHA256 signature of the payload, as sent in the X-Traefik-Signature header
+func signPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/containous/flaeg"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewConfigChange(t *testing.T) {
+	oldConfig := &types.Configuration{
+		Frontends: map[string]*types.Frontend{
+			"frontend-kept":    {Backend: "backend-kept"},
+			"frontend-changed": {Backend: "backend-kept", Priority: 1},
+			"frontend-removed": {Backend: "backend-removed"},
+		},
+		Backends: map[string]*types.Backend{
+			"backend-kept":    {},
+			"backend-removed": {},
+		},
+	}
+	newConfig := &types.Configuration{
+		Frontends: map[string]*types.Frontend{
+			"frontend-kept":    {Backend: "backend-kept"},
+			"frontend-changed": {Backend: "backend-kept", Priority: 2},
+			"frontend-added":   {Backend: "backend-added"},
+		},
+		Backends: map[string]*types.Backend{
+			"backend-kept":  {},
+			"backend-added": {},
+		},
+	}
+
+	change := newConfigChange("file", oldConfig, newConfig, false)
+	assert.Equal(t, "file", change.Provider)
+	assert.Equal(t, changeSummary{
+		Added:    []string{"frontend-added"},
+		Removed:  []string{"frontend-removed"},
+		Modified: []string{"frontend-changed"},
+	}, change.Frontends)
+	assert.Equal(t, changeSummary{
+		Added:   []string{"backend-added"},
+		Removed: []string{"backend-removed"},
+	}, change.Backends)
+	assert.Nil(t, change.Diff)
+
+	change = newConfigChange("file", oldConfig, newConfig, true)
+	require.NotNil(t, change.Diff)
+	assert.Equal(t, frontendDiff{
+		Old: oldConfig.Frontends["frontend-changed"],
+		New: newConfig.Frontends["frontend-changed"],
+	}, change.Diff.Frontends["frontend-changed"])
+	assert.Len(t, change.Diff.Frontends, 3)
+	assert.Len(t, change.Diff.Backends, 2)
+
+	change = newConfigChange("file", nil, &types.Configuration{Backends: map[string]*types.Backend{"backend": {}}}, false)
+	assert.Equal(t, []string{"backend"}, change.Backends.Added)
+	assert.True(t, change.Frontends.isEmpty())
+}
+
+func TestNewConfigChangeServers(t *testing.T) {
+	oldConfig := &types.Configuration{
+		Backends: map[string]*types.Backend{
+			"backend-app": {Servers: map[string]types.Server{
+				"server-kept":    {URL: "http://10.0.0.1:80"},
+				"server-changed": {URL: "http://10.0.0.2:80", Weight: 1},
+				"server-removed": {URL: "http://10.0.0.3:80"},
+			}},
+			"backend-removed": {Servers: map[string]types.Server{
+				"server": {URL: "http://10.0.1.1:80"},
+			}},
+			"backend-kept": {Servers: map[string]types.Server{
+				"server": {URL: "http://10.0.2.1:80"},
+			}},
+		},
+	}
+	newConfig := &types.Configuration{
+		Backends: map[string]*types.Backend{
+			"backend-app": {Servers: map[string]types.Server{
+				"server-kept":    {URL: "http://10.0.0.1:80"},
+				"server-changed": {URL: "http://10.0.0.2:80", Weight: 2},
+				"server-added":   {URL: "http://10.0.0.4:80"},
+			}},
+			"backend-kept": {Servers: map[string]types.Server{
+				"server": {URL: "http://10.0.2.1:80"},
+			}},
+		},
+	}
+
+	change := newConfigChange("marathon", oldConfig, newConfig, false)
+	assert.Equal(t, map[string]changeSummary{
+		"backend-app": {
+			Added:    []string{"server-added"},
+			Removed:  []string{"server-removed"},
+			Modified: []string{"server-changed"},
+		},
+		"backend-removed": {
+			Removed: []string{"server"},
+		},
+	}, change.Servers)
+}
+
+func TestConfigWebhookNotifier(t *testing.T) {
+	requests := make(chan *http.Request, 10)
+	bodies := make(chan []byte, 10)
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	notifier := newConfigWebhookNotifier(&ConfigWebhook{
+		URL:      server.URL,
+		Secret:   "secret",
+		Attempts: 2,
+		Timeout:  flaeg.Duration(time.Second),
+	})
+	notifier.retryInterval = 10 * time.Millisecond
+	stop := make(chan bool)
+	defer close(stop)
+	go notifier.run(stop)
+
+	// changes without any difference are not notified
+	notifier.notify(newConfigChange("file", &types.Configuration{}, &types.Configuration{}, false))
+	notifier.notify(newConfigChange("file", nil, &types.Configuration{
+		Backends: map[string]*types.Backend{"backend": {}},
+	}, false))
+
+	select {
+	case req := <-requests:
+		body := <-bodies
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		assert.Equal(t, signPayload("secret", body), req.Header.Get(configWebhookSignatureHeader))
+
+		var change configChange
+		require.NoError(t, json.Unmarshal(body, &change))
+		assert.Equal(t, "file", change.Provider)
+		assert.Equal(t, []string{"backend"}, change.Backends.Added)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not notified")
+	}
+}
+
+func TestSignPayload(t *testing.T) {
+	assert.Equal(t, "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8", signPayload("key", []byte("The quick brown fox jumps over the lazy dog")))
+}
@@ -31,6 +31,9 @@ import (
 // DefaultHealthCheckInterval is the default health check interval.
 const DefaultHealthCheckInterval = 30 * time.Second
 
+// DefaultDrainDuration is the default time a server asking to be drained is kept out of rotation.
+const DefaultDrainDuration = 30 * time.Second
+
 // TraefikConfiguration holds GlobalConfiguration and other stuff
 type TraefikConfiguration struct {
 	GlobalConfiguration `mapstructure:",squash"`
@@ -45,6 +48,7 @@ type GlobalConfiguration struct {
 	CheckNewVersion           bool                    `description:"Periodically check if a new version has been released"`
 	AccessLogsFile            string                  `description:"(Deprecated) Access logs file"` // Deprecated
 	AccessLog                 *types.AccessLog        `description:"Access log settings"`
+	ClientIP                  *types.ClientIP         `description:"Determine the client IP address from the X-Forwarded-For header"`
 	TraefikLogsFile           string                  `description:"Traefik logs file. Stdout is used when omitted or empty"`
 	LogLevel                  string                  `short:"l" description:"Log level"`
 	EntryPoints               EntryPoints             `description:"Entrypoints definition using format: --entryPoints='Name:http Address::8000 Redirect.EntryPoint:https' --entryPoints='Name:https Address::4442 TLS:tests/traefik.crt,tests/traefik.key;prod/traefik.crt,prod/traefik.key'"`
@@ -53,12 +57,20 @@ type GlobalConfiguration struct {
 	ACME                      *acme.ACME              `description:"Enable ACME (Let's Encrypt): automatic SSL"`
 	DefaultEntryPoints        DefaultEntryPoints      `description:"Entrypoints to be used by frontends that do not specify any entrypoint"`
 	ProvidersThrottleDuration flaeg.Duration          `description:"Backends throttle duration: minimum duration between 2 events from providers before applying a new configuration. It avoids unnecessary reloads if multiples events are sent in a short amount of time."`
+	FreezeTimeout             flaeg.Duration          `description:"Maximum duration of a configuration freeze, after which the provider updates are applied again"`
+	ProviderStaleTTL          flaeg.Duration          `description:"Duration after which the routes of a provider failing to synchronize are dropped, 0 keeping them until it recovers"`
 	MaxIdleConnsPerHost       int                     `description:"If non-zero, controls the maximum idle (keep-alive) to keep per-host.  If zero, DefaultMaxIdleConnsPerHost is used"`
 	IdleTimeout               flaeg.Duration          `description:"maximum amount of time an idle (keep-alive) connection will remain idle before closing itself."`
 	InsecureSkipVerify        bool                    `description:"Disable SSL certificate verification"`
 	RootCAs                   RootCAs                 `description:"Add cert file for self-signed certicate"`
 	Retry                     *Retry                  `description:"Enable retry sending request if network error"`
 	HealthCheck               *HealthCheckConfig      `description:"Health check parameters"`
+	Locality                  *Locality               `description:"Enable locality-aware load balancing"`
+	ConfigWebhook             *ConfigWebhook          `description:"Notify a webhook of every applied configuration change"`
+	ConfigAuditLog            *ConfigAuditLog         `description:"Write every applied configuration change to an audit log file"`
+	SharedRateLimits          bool                    `description:"Apply frontend rate limits across the traefik cluster by keeping them in the cluster KV store"`
+	ServersTransports         ServersTransports       // configured in the configuration file only, referenced by name from the backends
+	FrontendProfiles          FrontendProfiles        // configured in the configuration file only, referenced by name from the frontends
 	Docker                    *docker.Provider        `description:"Enable Docker backend with default settings"`
 	File                      *file.Provider          `description:"Enable File backend with default settings"`
 	Web                       *WebProvider            `description:"Enable Web backend with default settings"`
@@ -273,6 +285,27 @@ type EntryPoint struct {
 	Auth                 *types.Auth
 	WhitelistSourceRange []string
 	Compress             bool
+	NotFound             *NotFound
+	EarlyHints           string // "strip" (the default) or "passthrough" the 103 Early Hints sent by backends
+	Push                 bool   // push to HTTP/2 clients the resources preloaded by the backends responses
+	DetectProtocol       bool   // serve both plain HTTP and TLS on the address, detecting the protocol of every connection
+	ConnectionRecycling  *ConnectionRecycling
+}
+
+// ConnectionRecycling closes the client connections opened before the last configuration change of their
+// frontend, with a GOAWAY for HTTP/2 clients and a "Connection: close" response header for HTTP/1 clients.
+// At most Rate connections are closed per second, to avoid reconnection storms.
+type ConnectionRecycling struct {
+	Rate int
+}
+
+// NotFound configures the behavior of an entry point for the requests matching no frontend:
+// a plain 404 ("404", the default), a redirection to URL ("redirect"), serving them with
+// the given frontend ("frontend") or closing the connection without response ("close")
+type NotFound struct {
+	Action   string
+	URL      string
+	Frontend string
 }
 
 // Redirect configures a redirection of an entry point to another, or to an URL
@@ -282,12 +315,18 @@ type Redirect struct {
 	Replacement string
 }
 
-// TLS configures TLS for an entry point
+// TLS configures TLS for an entry point.
+// DefaultBackend serves the requests matching no frontend instead of a 404, and SNIStrict
+// refuses the handshakes with a missing or unknown SNI instead of serving the default certificate.
 type TLS struct {
-	MinVersion    string
-	CipherSuites  []string
-	Certificates  Certificates
-	ClientCAFiles []string
+	MinVersion             string
+	CipherSuites           []string
+	Certificates           Certificates
+	ClientCAFiles          []string
+	DefaultBackend         string
+	SNIStrict              bool
+	SessionTicketsRotation flaeg.Duration // rotation interval of the session ticket keys
+	SharedSessionTickets   bool           // share the session ticket keys through the cluster KV store
 }
 
 // Map of allowed TLS minimum versions
@@ -403,12 +442,41 @@ type Certificate struct {
 
 // Retry contains request retry config
 type Retry struct {
-	Attempts int `description:"Number of attempts"`
+	Attempts                int    `description:"Number of attempts"`
+	RangeRequests           string `description:"Handling of the requests with a Range header: buffer (default), passthrough or synthesize"`
+	MaxSynthesizedRangeSize int64  `description:"Maximum size in bytes of the complete responses buffered to synthesize range responses"`
 }
 
 // HealthCheckConfig contains health check configuration parameters.
 type HealthCheckConfig struct {
-	Interval flaeg.Duration `description:"Default periodicity of enabled health checks"`
+	Interval           flaeg.Duration `description:"Default periodicity of enabled health checks"`
+	HealthyThreshold   int            `description:"Number of consecutive successful checks before a server is added back"`
+	UnhealthyThreshold int            `description:"Number of consecutive failed checks before a server is removed"`
+	MaxFlaps           int            `description:"Number of state changes within flapWindow after which a server is kept out until it stabilizes (0 to disable)"`
+	FlapWindow         flaeg.Duration `description:"Window during which the state changes of a server are counted to detect flapping"`
+}
+
+// Locality contains locality-aware load balancing configuration.
+// Servers located in the zone of traefik are preferred to the ones of other zones.
+type Locality struct {
+	Zone            string `description:"Zone (availability zone, datacenter...) traefik is running in"`
+	ZoneMetadataURL string `description:"Cloud metadata URL returning the zone traefik is running in, used when zone is not set"`
+	MinLocalServers int    `description:"Minimum number of available local servers below which requests spill over to other zones"`
+}
+
+// ConfigWebhook contains the configuration of the webhook notified of every applied configuration change.
+type ConfigWebhook struct {
+	URL         string         `description:"URL receiving a POST request for every applied configuration change"`
+	Secret      string         `description:"Secret used to sign notifications with HMAC-SHA256 in the X-Traefik-Signature header"`
+	IncludeDiff bool           `description:"Include the previous and new definitions of the changed frontends and backends"`
+	Attempts    int            `description:"Number of attempts to deliver a notification"`
+	Timeout     flaeg.Duration `description:"Timeout of a delivery attempt"`
+}
+
+// ConfigAuditLog contains the configuration of the audit log of the applied configuration changes.
+type ConfigAuditLog struct {
+	FilePath    string `description:"Audit log file path"`
+	IncludeDiff bool   `description:"Include the previous and new definitions of the changed frontends and backends"`
 }
 
 // NewTraefikDefaultPointersConfiguration creates a TraefikConfiguration with pointers default values
@@ -447,6 +515,11 @@ func NewTraefikDefaultPointersConfiguration() *TraefikConfiguration {
 	defaultMarathon.Constraints = types.Constraints{}
 	defaultMarathon.DialerTimeout = flaeg.Duration(60 * time.Second)
 	defaultMarathon.KeepAlive = flaeg.Duration(10 * time.Second)
+	defaultMarathon.ResponseHeaderTimeout = flaeg.Duration(60 * time.Second)
+	defaultMarathon.TLSHandshakeTimeout = flaeg.Duration(5 * time.Second)
+	defaultMarathon.LeaderCheckInterval = flaeg.Duration(30 * time.Second)
+	defaultMarathon.EventsReconnectInterval = flaeg.Duration(time.Second)
+	defaultMarathon.BackendCollision = "merge"
 
 	// default Consul
 	var defaultConsul consul.Provider
@@ -528,23 +601,27 @@ func NewTraefikDefaultPointersConfiguration() *TraefikConfiguration {
 	}
 
 	defaultConfiguration := GlobalConfiguration{
-		Docker:        &defaultDocker,
-		File:          &defaultFile,
-		Web:           &defaultWeb,
-		Marathon:      &defaultMarathon,
-		Consul:        &defaultConsul,
-		ConsulCatalog: &defaultConsulCatalog,
-		Etcd:          &defaultEtcd,
-		Zookeeper:     &defaultZookeeper,
-		Boltdb:        &defaultBoltDb,
-		Kubernetes:    &defaultKubernetes,
-		Mesos:         &defaultMesos,
-		ECS:           &defaultECS,
-		Rancher:       &defaultRancher,
-		DynamoDB:      &defaultDynamoDB,
-		Retry:         &Retry{},
-		HealthCheck:   &HealthCheckConfig{},
-		AccessLog:     &defaultAccessLog,
+		Docker:         &defaultDocker,
+		File:           &defaultFile,
+		Web:            &defaultWeb,
+		Marathon:       &defaultMarathon,
+		Consul:         &defaultConsul,
+		ConsulCatalog:  &defaultConsulCatalog,
+		Etcd:           &defaultEtcd,
+		Zookeeper:      &defaultZookeeper,
+		Boltdb:         &defaultBoltDb,
+		Kubernetes:     &defaultKubernetes,
+		Mesos:          &defaultMesos,
+		ECS:            &defaultECS,
+		Rancher:        &defaultRancher,
+		DynamoDB:       &defaultDynamoDB,
+		Retry:          &Retry{},
+		HealthCheck:    &HealthCheckConfig{},
+		AccessLog:      &defaultAccessLog,
+		Locality:       &Locality{MinLocalServers: 1},
+		ConfigWebhook:  &ConfigWebhook{Attempts: 3, Timeout: flaeg.Duration(10 * time.Second)},
+		ConfigAuditLog: &ConfigAuditLog{FilePath: "log/config-audit.log"},
+		ClientIP:       &types.ClientIP{},
 	}
 
 	return &TraefikConfiguration{
@@ -564,10 +641,14 @@ func NewTraefikConfiguration() *TraefikConfiguration {
 			Constraints:               types.Constraints{},
 			DefaultEntryPoints:        []string{},
 			ProvidersThrottleDuration: flaeg.Duration(2 * time.Second),
+			FreezeTimeout:             flaeg.Duration(DefaultFreezeTimeout),
 			MaxIdleConnsPerHost:       200,
 			IdleTimeout:               flaeg.Duration(180 * time.Second),
 			HealthCheck: &HealthCheckConfig{
-				Interval: flaeg.Duration(DefaultHealthCheckInterval),
+				Interval:           flaeg.Duration(DefaultHealthCheckInterval),
+				HealthyThreshold:   1,
+				UnhealthyThreshold: 1,
+				FlapWindow:         flaeg.Duration(5 * time.Minute),
 			},
 			CheckNewVersion: true,
 		},
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+)
+
+// DefaultConnectionRecyclingRate is the maximum number of client connections recycled per second,
+// when none is configured
+const DefaultConnectionRecyclingRate = 10
+
+// connectionRecycler closes the client connections of an entrypoint opened before the last configuration
+// change of the frontend of their requests, so that long-lived connections, HTTP/2 ones in particular,
+// reconnect after the change, e.g. through a load balancer to other instances.
+// The connection of a request is closed by setting the "Connection: close" response header, the HTTP/2
+// server sending a GOAWAY for it. The connections are closed gradually, with a token bucket of rate tokens
+// per second.
+type connectionRecycler struct {
+	lock        sync.Mutex
+	established map[string]time.Time
+	recycled    map[string]bool
+	changedAt   map[string]time.Time
+	rate        float64
+	tokens      float64
+	lastRefill  time.Time
+}
+
+func newConnectionRecycler(config *ConnectionRecycling) *connectionRecycler {
+	rate := DefaultConnectionRecyclingRate
+	if config.Rate > 0 {
+		rate = config.Rate
+	}
+	return &connectionRecycler{
+		established: make(map[string]time.Time),
+		recycled:    make(map[string]bool),
+		changedAt:   make(map[string]time.Time),
+		rate:        float64(rate),
+		tokens:      float64(rate),
+		lastRefill:  time.Now(),
+	}
+}
+
+func connectionKey(localAddr, remoteAddr string) string {
+	return localAddr + "|" + remoteAddr
+}
+
+// ConnState is used as the http.Server ConnState hook
+func (c *connectionRecycler) ConnState(conn net.Conn, state http.ConnState) {
+	key := connectionKey(conn.LocalAddr().String(), conn.RemoteAddr().String())
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	switch state {
+	case http.StateNew:
+		c.established[key] = time.Now()
+	case http.StateClosed, http.StateHijacked:
+		delete(c.established, key)
+		delete(c.recycled, key)
+	}
+}
+
+// frontendsChanged records the configuration change of the frontends
+func (c *connectionRecycler) frontendsChanged(frontendNames []string, at time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, frontendName := range frontendNames {
+		c.changedAt[frontendName] = at
+	}
+}
+
+// recycle returns whether the connection of the request to the frontend must be closed
+func (c *connectionRecycler) recycle(frontendName string, r *http.Request, now time.Time) bool {
+	localAddr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		return false
+	}
+	key := connectionKey(localAddr.String(), r.RemoteAddr)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	changedAt, ok := c.changedAt[frontendName]
+	if !ok || c.recycled[key] {
+		return false
+	}
+	established, ok := c.established[key]
+	if !ok || !established.Before(changedAt) {
+		return false
+	}
+
+	c.tokens = math.Min(c.rate, c.tokens+now.Sub(c.lastRefill).Seconds()*c.rate)
+	c.lastRefill = now
+	if c.tokens < 1 {
+		return false
+	}
+	c.tokens--
+	c.recycled[key] = true
+	return true
+}
+
+func (c *connectionRecycler) wrap(frontendName string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if c.recycle(frontendName, r, time.Now()) {
+			log.Debugf("Closing the connection of %s to frontend %s after its configuration change", r.RemoteAddr, frontendName)
+			rw.Header().Set("Connection", "close")
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// changedFrontends returns the frontends whose configuration changed, including the frontends of the
+// modified backends
+func changedFrontends(oldConfig, newConfig *types.Configuration) []string {
+	if newConfig == nil {
+		return nil
+	}
+	change := newConfigChange("", oldConfig, newConfig, false)
+	modifiedBackends := make(map[string]bool)
+	for _, backendName := range change.Backends.Modified {
+		modifiedBackends[backendName] = true
+	}
+	frontendNames := change.Frontends.Modified
+	for frontendName, frontend := range newConfig.Frontends {
+		if frontend != nil && modifiedBackends[frontend.Backend] {
+			frontendNames = append(frontendNames, frontendName)
+		}
+	}
+	return frontendNames
+}
+
+// recycleConnections recycles the connections of the frontends changed by the provider configuration
+func (server *Server) recycleConnections(oldConfig, newConfig *types.Configuration) {
+	var frontendNames []string
+	now := time.Now()
+	for _, serverEntryPoint := range server.serverEntryPoints {
+		if serverEntryPoint.connectionRecycler == nil {
+			continue
+		}
+		if frontendNames == nil {
+			frontendNames = changedFrontends(oldConfig, newConfig)
+			if len(frontendNames) == 0 {
+				return
+			}
+		}
+		serverEntryPoint.connectionRecycler.frontendsChanged(frontendNames, now)
+	}
+}
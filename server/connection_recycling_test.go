@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type addrConn struct {
+	net.Conn
+	local, remote net.Addr
+}
+
+func (c addrConn) LocalAddr() net.Addr  { return c.local }
+func (c addrConn) RemoteAddr() net.Addr { return c.remote }
+
+func newAddrConn(remote string) addrConn {
+	return addrConn{
+		local:  &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443},
+		remote: &net.TCPAddr{IP: net.ParseIP(remote), Port: 40000},
+	}
+}
+
+func newConnRequest(conn addrConn) *http.Request {
+	req := testhelpers.MustNewRequest(http.MethodGet, "https://example.com/", nil)
+	req.RemoteAddr = conn.remote.String()
+	return req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, conn.local))
+}
+
+func TestConnectionRecyclerRecycle(t *testing.T) {
+	recycler := newConnectionRecycler(&ConnectionRecycling{Rate: 2})
+	oldConns := []addrConn{newAddrConn("192.168.0.1"), newAddrConn("192.168.0.2"), newAddrConn("192.168.0.3")}
+	for _, conn := range oldConns {
+		recycler.ConnState(conn, http.StateNew)
+	}
+	now := time.Now()
+	assert.False(t, recycler.recycle("frontend", newConnRequest(oldConns[0]), now), "unchanged frontend")
+
+	recycler.frontendsChanged([]string{"frontend"}, now)
+	newConn := newAddrConn("192.168.0.4")
+	recycler.ConnState(newConn, http.StateNew)
+
+	assert.False(t, recycler.recycle("other", newConnRequest(oldConns[0]), now), "other frontend")
+	assert.False(t, recycler.recycle("frontend", newConnRequest(newConn), now), "connection opened after the change")
+	assert.True(t, recycler.recycle("frontend", newConnRequest(oldConns[0]), now))
+	assert.False(t, recycler.recycle("frontend", newConnRequest(oldConns[0]), now), "connection already recycled")
+	assert.True(t, recycler.recycle("frontend", newConnRequest(oldConns[1]), now))
+	assert.False(t, recycler.recycle("frontend", newConnRequest(oldConns[2]), now), "rate exceeded")
+	assert.True(t, recycler.recycle("frontend", newConnRequest(oldConns[2]), now.Add(time.Second/2)), "rate refilled")
+
+	for _, conn := range append(oldConns, newConn) {
+		recycler.ConnState(conn, http.StateClosed)
+	}
+	assert.Empty(t, recycler.established)
+	assert.Empty(t, recycler.recycled)
+}
+
+func TestConnectionRecyclerKeepAlive(t *testing.T) {
+	recycler := newConnectionRecycler(&ConnectionRecycling{})
+	ts := httptest.NewUnstartedServer(recycler.wrap("frontend", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("traefik"))
+	})))
+	ts.Config.ConnState = recycler.ConnState
+	ts.Start()
+	defer ts.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	get := func() *http.Response {
+		resp, err := client.Get(ts.URL)
+		require.NoError(t, err)
+		_, err = ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	assert.False(t, get().Close)
+	recycler.frontendsChanged([]string{"frontend"}, time.Now())
+	assert.True(t, get().Close, "the connection opened before the change must be closed")
+	assert.False(t, get().Close, "the new connection must be kept alive")
+}
+
+func TestChangedFrontends(t *testing.T) {
+	oldConfig := &types.Configuration{
+		Frontends: map[string]*types.Frontend{
+			"frontend-unchanged": {Backend: "backend-unchanged"},
+			"frontend-modified":  {Backend: "backend-unchanged", Priority: 1},
+			"frontend-servers":   {Backend: "backend-modified"},
+			"frontend-removed":   {Backend: "backend-unchanged"},
+		},
+		Backends: map[string]*types.Backend{
+			"backend-unchanged": {Servers: map[string]types.Server{"server": {URL: "http://10.0.0.1:80"}}},
+			"backend-modified":  {Servers: map[string]types.Server{"server": {URL: "http://10.0.0.2:80"}}},
+		},
+	}
+	newConfig := &types.Configuration{
+		Frontends: map[string]*types.Frontend{
+			"frontend-unchanged": {Backend: "backend-unchanged"},
+			"frontend-modified":  {Backend: "backend-unchanged", Priority: 2},
+			"frontend-servers":   {Backend: "backend-modified"},
+			"frontend-added":     {Backend: "backend-unchanged"},
+		},
+		Backends: map[string]*types.Backend{
+			"backend-unchanged": {Servers: map[string]types.Server{"server": {URL: "http://10.0.0.1:80"}}},
+			"backend-modified":  {Servers: map[string]types.Server{"server": {URL: "http://10.0.0.3:80"}}},
+		},
+	}
+
+	actual := changedFrontends(oldConfig, newConfig)
+	sort.Strings(actual)
+	assert.Equal(t, []string{"frontend-modified", "frontend-servers"}, actual)
+	assert.Empty(t, changedFrontends(oldConfig, oldConfig))
+}
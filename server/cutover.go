@@ -0,0 +1,123 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/types"
+)
+
+// cutoverCause is the cause of the configuration reloads triggered by a scheduled cutover
+const cutoverCause = "scheduled cutover"
+
+// applyFrontendCutover returns the frontend routed to its cutover backend once the cutover time is reached.
+// The given frontend is left untouched.
+func applyFrontendCutover(frontend *types.Frontend, now time.Time) (*types.Frontend, error) {
+	if frontend.Cutover == nil {
+		return frontend, nil
+	}
+	at, err := parseCutover(frontend.Cutover)
+	if err != nil {
+		return nil, err
+	}
+	if now.Before(at) {
+		return frontend, nil
+	}
+	cutover := *frontend
+	cutover.Backend = frontend.Cutover.Backend
+	return &cutover, nil
+}
+
+func parseCutover(cutover *types.Cutover) (time.Time, error) {
+	if cutover.Backend == "" {
+		return time.Time{}, fmt.Errorf("no cutover backend")
+	}
+	at, err := time.Parse(time.RFC3339, cutover.At)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid cutover time %q: %v", cutover.At, err)
+	}
+	return at, nil
+}
+
+// nextCutover returns the earliest cutover time after now, and the provider of the frontend to cut over
+func nextCutover(configurations configs, now time.Time) (time.Time, string, bool) {
+	var next time.Time
+	var nextProvider string
+	for providerName, configuration := range configurations {
+		if configuration == nil {
+			continue
+		}
+		for _, frontend := range configuration.Frontends {
+			if frontend == nil || frontend.Cutover == nil {
+				continue
+			}
+			at, err := parseCutover(frontend.Cutover)
+			if err != nil || !at.After(now) {
+				continue
+			}
+			if next.IsZero() || at.Before(next) {
+				next = at
+				nextProvider = providerName
+			}
+		}
+	}
+	return next, nextProvider, !next.IsZero()
+}
+
+// cutoverScheduler reloads the configuration at the next cutover time of the frontends,
+// so that they switch to their cutover backend without any provider event
+type cutoverScheduler struct {
+	lock   sync.Mutex
+	timer  *time.Timer
+	reload func(providerName string)
+}
+
+func newCutoverScheduler(reload func(providerName string)) *cutoverScheduler {
+	return &cutoverScheduler{reload: reload}
+}
+
+// schedule replaces the scheduled reload with one at the next cutover time of the configurations
+func (s *cutoverScheduler) schedule(configurations configs, now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	at, providerName, ok := nextCutover(configurations, now)
+	if !ok {
+		return
+	}
+	log.Debugf("Next scheduled cutover of provider %s at %s", providerName, at.Format(time.RFC3339))
+	s.timer = time.AfterFunc(at.Sub(now), func() {
+		s.reload(providerName)
+	})
+}
+
+func (s *cutoverScheduler) stop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+// reloadForCutover reloads the current configuration of the provider, applying the cutovers due
+func (server *Server) reloadForCutover(providerName string) {
+	configuration, ok := server.currentConfigurations.Get().(configs)[providerName]
+	if !ok {
+		return
+	}
+	log.Infof("Applying the scheduled cutover of provider %s", providerName)
+	safe.Go(func() {
+		server.configurationValidatedChan <- types.ConfigMessage{
+			ProviderName:  providerName,
+			Configuration: configuration,
+			Causes:        []string{cutoverCause},
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyFrontendCutover(t *testing.T) {
+	now := time.Date(2017, time.June, 1, 3, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		desc            string
+		cutover         *types.Cutover
+		expectedBackend string
+		expectedError   bool
+	}{
+		{
+			desc:            "no cutover",
+			expectedBackend: "backend-blue",
+		},
+		{
+			desc:            "before the cutover",
+			cutover:         &types.Cutover{Backend: "backend-green", At: "2017-06-01T03:00:01Z"},
+			expectedBackend: "backend-blue",
+		},
+		{
+			desc:            "at the cutover",
+			cutover:         &types.Cutover{Backend: "backend-green", At: "2017-06-01T05:00:00+02:00"},
+			expectedBackend: "backend-green",
+		},
+		{
+			desc:          "invalid time",
+			cutover:       &types.Cutover{Backend: "backend-green", At: "tonight"},
+			expectedError: true,
+		},
+		{
+			desc:          "no backend",
+			cutover:       &types.Cutover{At: "2017-06-01T03:00:00Z"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			frontend := &types.Frontend{Backend: "backend-blue", Cutover: test.cutover}
+			actual, err := applyFrontendCutover(frontend, now)
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedBackend, actual.Backend)
+			assert.Equal(t, "backend-blue", frontend.Backend, "the frontend must be left untouched")
+		})
+	}
+}
+
+func TestNextCutover(t *testing.T) {
+	now := time.Date(2017, time.June, 1, 3, 0, 0, 0, time.UTC)
+	configurations := configs{
+		"file": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"past":    {Cutover: &types.Cutover{Backend: "b", At: "2017-06-01T02:00:00Z"}},
+				"later":   {Cutover: &types.Cutover{Backend: "b", At: "2017-06-02T03:00:00Z"}},
+				"invalid": {Cutover: &types.Cutover{Backend: "b", At: "soon"}},
+				"none":    {},
+			},
+		},
+		"marathon": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"next": {Cutover: &types.Cutover{Backend: "b", At: "2017-06-01T04:00:00Z"}},
+			},
+		},
+	}
+
+	at, providerName, ok := nextCutover(configurations, now)
+	require.True(t, ok)
+	assert.Equal(t, "marathon", providerName)
+	assert.Equal(t, now.Add(time.Hour), at)
+
+	_, _, ok = nextCutover(configurations, now.Add(48*time.Hour))
+	assert.False(t, ok)
+}
+
+func TestCutoverSchedulerReload(t *testing.T) {
+	reloads := make(chan string, 1)
+	scheduler := newCutoverScheduler(func(providerName string) {
+		reloads <- providerName
+	})
+	defer scheduler.stop()
+
+	now := time.Now()
+	scheduler.schedule(configs{
+		"file": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"frontend": {Cutover: &types.Cutover{Backend: "b", At: now.Add(time.Hour).Format(time.RFC3339)}},
+			},
+		},
+	}, now)
+	// a new configuration replaces the scheduled reload
+	scheduler.schedule(configs{
+		"file": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"frontend": {Cutover: &types.Cutover{Backend: "b", At: now.Add(time.Second).Format(time.RFC3339)}},
+			},
+		},
+	}, now)
+
+	select {
+	case providerName := <-reloads:
+		assert.Equal(t, "file", providerName)
+	case <-time.After(5 * time.Second):
+		t.Fatal("configuration not reloaded at the cutover time")
+	}
+}
+
+func TestServerLoadConfigCutover(t *testing.T) {
+	newBackend := func(name string) (*types.Backend, func()) {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		}))
+		return &types.Backend{
+			Servers: map[string]types.Server{
+				"server": {URL: backend.URL},
+			},
+			LoadBalancer: &types.LoadBalancer{Method: "wrr"},
+		}, backend.Close
+	}
+	blue, closeBlue := newBackend("blue")
+	defer closeBlue()
+	green, closeGreen := newBackend("green")
+	defer closeGreen()
+
+	globalConfig := GlobalConfiguration{
+		EntryPoints: EntryPoints{
+			"http": &EntryPoint{},
+		},
+	}
+	newFrontend := func(host, at string) *types.Frontend {
+		return &types.Frontend{
+			EntryPoints: []string{"http"},
+			Backend:     "backend-blue",
+			Routes: map[string]types.Route{
+				"route": {Rule: "Host:" + host},
+			},
+			Cutover: &types.Cutover{Backend: "backend-green", At: at},
+		}
+	}
+	dynamicConfigs := configs{
+		"config": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"frontend-done":    newFrontend("done", time.Now().Add(-time.Minute).Format(time.RFC3339)),
+				"frontend-pending": newFrontend("pending", time.Now().Add(time.Hour).Format(time.RFC3339)),
+			},
+			Backends: map[string]*types.Backend{
+				"backend-blue":  blue,
+				"backend-green": green,
+			},
+		},
+	}
+
+	srv := NewServer(globalConfig)
+	entryPoints, err := srv.loadConfig(dynamicConfigs, globalConfig)
+	require.NoError(t, err)
+
+	for host, expected := range map[string]string{"done": "green", "pending": "blue"} {
+		recorder := httptest.NewRecorder()
+		entryPoints["http"].httpRouter.ServeHTTP(recorder, testhelpers.MustNewRequest(http.MethodGet, "http://"+host+"/", nil))
+		body, err := ioutil.ReadAll(recorder.Body)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(body), "host %s", host)
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/safe"
+)
+
+// connectionTracker keeps track of the open connections and in-flight requests of an entrypoint,
+// and of the idle connections and the frontend of their last request.
+type connectionTracker struct {
+	lock        sync.Mutex
+	connections map[net.Conn]*trackedConnection
+	byAddr      map[string]*trackedConnection
+	requests    int64
+}
+
+func newConnectionTracker() *connectionTracker {
+	return &connectionTracker{
+		connections: make(map[net.Conn]*trackedConnection),
+		byAddr:      make(map[string]*trackedConnection),
+	}
+}
+
+// ConnState is used as the http.Server ConnState hook
+func (t *connectionTracker) ConnState(conn net.Conn, state http.ConnState) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	switch state {
+	case http.StateClosed, http.StateHijacked:
+		delete(t.connections, conn)
+		delete(t.byAddr, connectionKey(conn.LocalAddr().String(), conn.RemoteAddr().String()))
+	default:
+		tracked, ok := t.connections[conn]
+		if !ok {
+			tracked = &trackedConnection{conn: conn}
+			t.connections[conn] = tracked
+			t.byAddr[connectionKey(conn.LocalAddr().String(), conn.RemoteAddr().String())] = tracked
+		}
+		tracked.idleSince = time.Time{}
+		if state == http.StateIdle {
+			tracked.idleSince = time.Now()
+		}
+	}
+}
+
+func (t *connectionTracker) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	atomic.AddInt64(&t.requests, 1)
+	defer atomic.AddInt64(&t.requests, -1)
+	next(rw, r)
+}
+
+func (t *connectionTracker) counts() (int, int64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return len(t.connections), atomic.LoadInt64(&t.requests)
+}
+
+// drainStatus is the drain state of an entrypoint, as returned by the API
+type drainStatus struct {
+	EntryPoint       string `json:"entryPoint"`
+	Draining         bool   `json:"draining"`
+	Connections      int    `json:"connections"`
+	InFlightRequests int64  `json:"inFlightRequests"`
+	// IdleConnections counts the idle connections by frontend of their last request
+	IdleConnections map[string]int `json:"idleConnections,omitempty"`
+}
+
+func (serverEntryPoint *serverEntryPoint) drainStatus(entryPointName string) *drainStatus {
+	connections, requests := serverEntryPoint.connectionTracker.counts()
+	return &drainStatus{
+		EntryPoint:       entryPointName,
+		Draining:         atomic.LoadInt32(&serverEntryPoint.draining) == 1,
+		Connections:      connections,
+		InFlightRequests: requests,
+		IdleConnections:  serverEntryPoint.connectionTracker.idleCounts(),
+	}
+}
+
+// getServerEntryPoint returns the entrypoint, or false if it does not exist
+func (server *Server) getServerEntryPoint(entryPointName string) (*serverEntryPoint, bool) {
+	server.serverEntryPointsLock.RLock()
+	defer server.serverEntryPointsLock.RUnlock()
+	serverEntryPoint, ok := server.serverEntryPoints[entryPointName]
+	return serverEntryPoint, ok
+}
+
+// drainEntryPoint stops accepting new connections on the entrypoint. Open
+// connections are closed as soon as they are idle.
+// The drain is final: the server of the entrypoint is shut down, and only a restart of traefik
+// accepts connections on it again.
+// It returns false if the entrypoint does not exist.
+func (server *Server) drainEntryPoint(entryPointName string) (*drainStatus, bool) {
+	serverEntryPoint, ok := server.getServerEntryPoint(entryPointName)
+	if !ok {
+		return nil, false
+	}
+	if atomic.CompareAndSwapInt32(&serverEntryPoint.draining, 0, 1) {
+		log.Infof("Draining entrypoint %s", entryPointName)
+		safe.Go(func() {
+			if err := serverEntryPoint.httpServer.Shutdown(context.Background()); err != nil {
+				log.Errorf("Error draining entrypoint %s: %s", entryPointName, err)
+				return
+			}
+			log.Infof("Entrypoint %s drained", entryPointName)
+		})
+	}
+	return serverEntryPoint.drainStatus(entryPointName), true
+}
+
+// entryPointDrainStatus returns the drain state of the entrypoint, or false if it does not exist
+func (server *Server) entryPointDrainStatus(entryPointName string) (*drainStatus, bool) {
+	serverEntryPoint, ok := server.getServerEntryPoint(entryPointName)
+	if !ok {
+		return nil, false
+	}
+	return serverEntryPoint.drainStatus(entryPointName), true
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/mux"
+	"github.com/containous/traefik/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func waitFor(t *testing.T, timeout time.Duration, condition func() bool) {
+	deadline := time.Now().Add(timeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDrainEntryPoint(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	tracker := newConnectionTracker()
+	started := make(chan bool)
+	release := make(chan bool)
+	n := negroni.New(tracker)
+	n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- true
+		<-release
+	}))
+	httpServer := &http.Server{Handler: n, ConnState: tracker.ConnState}
+	go httpServer.Serve(listener)
+
+	server := &Server{
+		serverEntryPoints: serverEntryPoints{
+			"http": &serverEntryPoint{httpServer: httpServer, connectionTracker: tracker},
+		},
+	}
+
+	_, ok := server.drainEntryPoint("https")
+	assert.False(t, ok)
+
+	url := "http://" + listener.Addr().String()
+	done := make(chan error)
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+	<-started
+
+	status, ok := server.entryPointDrainStatus("http")
+	require.True(t, ok)
+	assert.Equal(t, &drainStatus{EntryPoint: "http", Connections: 1, InFlightRequests: 1}, status)
+
+	status, ok = server.drainEntryPoint("http")
+	require.True(t, ok)
+	assert.Equal(t, &drainStatus{EntryPoint: "http", Draining: true, Connections: 1, InFlightRequests: 1}, status)
+
+	// new connections are refused while the in-flight request completes
+	waitFor(t, time.Second, func() bool {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err == nil {
+			conn.Close()
+		}
+		return err != nil
+	})
+
+	close(release)
+	require.NoError(t, <-done)
+	waitFor(t, 5*time.Second, func() bool {
+		status, _ := server.entryPointDrainStatus("http")
+		return status.Connections == 0 && status.InFlightRequests == 0
+	})
+}
+
+func TestUndrainHandler(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		entryPoint     string
+		draining       int32
+		readOnly       bool
+		expectedStatus int
+	}{
+		{
+			desc:           "entrypoint not drained",
+			entryPoint:     "http",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "drained entrypoint",
+			entryPoint:     "http",
+			draining:       1,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			desc:           "unknown entrypoint",
+			entryPoint:     "https",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "read-only API",
+			entryPoint:     "http",
+			readOnly:       true,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			server := &Server{
+				serverEntryPoints: serverEntryPoints{
+					"http": &serverEntryPoint{connectionTracker: newConnectionTracker(), draining: test.draining},
+				},
+			}
+			provider := &WebProvider{ReadOnly: test.readOnly, server: server}
+			router := mux.NewRouter()
+			router.Methods(http.MethodDelete).Path("/api/entrypoints/{entrypoint}/drain").HandlerFunc(provider.undrainHandler)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, testhelpers.MustNewRequest(http.MethodDelete, "/api/entrypoints/"+test.entryPoint+"/drain", nil))
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/types"
+)
+
+// DefaultFreezeTimeout is the default maximum duration of a configuration freeze
+const DefaultFreezeTimeout = time.Hour
+
+// configFreeze holds back the provider configurations while the configuration is frozen, keeping the
+// current routing, e.g. during change-freeze windows or while investigating a misbehaving provider.
+// The freeze is lifted after a timeout at the latest, and the last configuration held back for each
+// provider is then applied.
+type configFreeze struct {
+	lock   sync.Mutex
+	frozen bool
+	reason string
+	since  time.Time
+	until  time.Time
+	timer  *time.Timer
+	// generation identifies the timer of the current freeze, the callback of a stopped timer
+	// possibly running already
+	generation uint64
+	held       map[string]types.ConfigMessage
+	release    func(messages []types.ConfigMessage)
+}
+
+// freezeStatus is the state of the configuration freeze, as returned by the API
+type freezeStatus struct {
+	Frozen        bool       `json:"frozen"`
+	Reason        string     `json:"reason,omitempty"`
+	Since         *time.Time `json:"since,omitempty"`
+	Until         *time.Time `json:"until,omitempty"`
+	HeldProviders []string   `json:"heldProviders,omitempty"`
+}
+
+func newConfigFreeze(release func(messages []types.ConfigMessage)) *configFreeze {
+	return &configFreeze{release: release}
+}
+
+// freeze freezes the configuration for the timeout, or extends the current freeze
+func (f *configFreeze) freeze(reason string, timeout time.Duration) freezeStatus {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	now := time.Now()
+	if !f.frozen {
+		f.frozen = true
+		f.since = now
+		f.held = make(map[string]types.ConfigMessage)
+	} else {
+		f.timer.Stop()
+	}
+	f.reason = reason
+	f.until = now.Add(timeout)
+	f.generation++
+	generation := f.generation
+	f.timer = time.AfterFunc(timeout, func() {
+		f.expire(generation, timeout)
+	})
+	log.Warnf("Configuration frozen until %s, provider updates are held back: %s", f.until.Format(time.RFC3339), reason)
+	return f.statusLocked()
+}
+
+// expire lifts the freeze once its timeout elapsed, unless the freeze was extended or lifted meanwhile
+func (f *configFreeze) expire(generation uint64, timeout time.Duration) {
+	f.lock.Lock()
+	if !f.frozen || f.generation != generation {
+		f.lock.Unlock()
+		return
+	}
+	log.Warnf("Configuration freeze timed out after %s", timeout)
+	f.unfreezeLocked()
+}
+
+// unfreeze lifts the freeze and releases the configurations held back meanwhile
+func (f *configFreeze) unfreeze() freezeStatus {
+	f.lock.Lock()
+	return f.unfreezeLocked()
+}
+
+// unfreezeLocked is unfreeze, called with the lock held, which it releases
+func (f *configFreeze) unfreezeLocked() freezeStatus {
+	if !f.frozen {
+		status := f.statusLocked()
+		f.lock.Unlock()
+		return status
+	}
+	f.timer.Stop()
+	held := make([]types.ConfigMessage, 0, len(f.held))
+	for _, configMsg := range f.held {
+		held = append(held, configMsg)
+	}
+	f.frozen = false
+	f.reason = ""
+	f.held = nil
+	status := f.statusLocked()
+	f.lock.Unlock()
+
+	log.Warnf("Configuration unfrozen, applying the updates of %d provider(s)", len(held))
+	if len(held) > 0 {
+		f.release(held)
+	}
+	return status
+}
+
+// toggle freezes the configuration when it is not frozen, and unfreezes it otherwise
+func (f *configFreeze) toggle(reason string, timeout time.Duration) freezeStatus {
+	f.lock.Lock()
+	frozen := f.frozen
+	f.lock.Unlock()
+	if frozen {
+		return f.unfreeze()
+	}
+	return f.freeze(reason, timeout)
+}
+
+// hold keeps the configuration back if the configuration is frozen, and reports whether it did
+func (f *configFreeze) hold(configMsg types.ConfigMessage) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if !f.frozen {
+		return false
+	}
+	if held, ok := f.held[configMsg.ProviderName]; ok {
+		configMsg.Causes = append(append([]string{}, held.Causes...), configMsg.Causes...)
+		if len(held.ServerReasons) > 0 {
+			serverReasons := make(map[string]string, len(held.ServerReasons)+len(configMsg.ServerReasons))
+			for name, reason := range held.ServerReasons {
+				serverReasons[name] = reason
+			}
+			for name, reason := range configMsg.ServerReasons {
+				serverReasons[name] = reason
+			}
+			configMsg.ServerReasons = serverReasons
+		}
+	}
+	f.held[configMsg.ProviderName] = configMsg
+	log.Infof("Configuration frozen, holding back the configuration of provider %s", configMsg.ProviderName)
+	return true
+}
+
+func (f *configFreeze) status() freezeStatus {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.statusLocked()
+}
+
+func (f *configFreeze) statusLocked() freezeStatus {
+	if !f.frozen {
+		return freezeStatus{}
+	}
+	since, until := f.since, f.until
+	status := freezeStatus{Frozen: true, Reason: f.reason, Since: &since, Until: &until}
+	for providerName := range f.held {
+		status.HeldProviders = append(status.HeldProviders, providerName)
+	}
+	sort.Strings(status.HeldProviders)
+	return status
+}
+
+// freezeTimeout returns the maximum duration of a configuration freeze
+func (server *Server) freezeTimeout() time.Duration {
+	if server.globalConfiguration.FreezeTimeout <= 0 {
+		return DefaultFreezeTimeout
+	}
+	return time.Duration(server.globalConfiguration.FreezeTimeout)
+}
+
+// releaseFrozenConfigurations applies the configurations held back during a freeze
+func (server *Server) releaseFrozenConfigurations(messages []types.ConfigMessage) {
+	safe.Go(func() {
+		for _, configMsg := range messages {
+			server.configurationValidatedChan <- configMsg
+		}
+	})
+}
@@ -0,0 +1,24 @@
+// +build !windows
+
+package server
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// listenFreezeSignals toggles the configuration freeze on SIGUSR2
+func (server *Server) listenFreezeSignals(stop chan bool) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR2)
+	defer signal.Stop(signals)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-signals:
+			server.configFreeze.toggle("SIGUSR2 received", server.freezeTimeout())
+		}
+	}
+}
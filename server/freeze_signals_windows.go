@@ -0,0 +1,4 @@
+package server
+
+// listenFreezeSignals does nothing, the configuration freeze is only available through the API on Windows
+func (server *Server) listenFreezeSignals(stop chan bool) {}
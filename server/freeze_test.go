@@ -0,0 +1,176 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containous/flaeg"
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFreezeHoldsLatestConfigurations(t *testing.T) {
+	released := make(chan []types.ConfigMessage, 1)
+	freeze := newConfigFreeze(func(messages []types.ConfigMessage) {
+		released <- messages
+	})
+
+	assert.False(t, freeze.hold(types.ConfigMessage{ProviderName: "marathon"}), "configuration must not be held before the freeze")
+
+	status := freeze.freeze("release window", time.Hour)
+	assert.True(t, status.Frozen)
+	assert.Equal(t, "release window", status.Reason)
+
+	first := &types.Configuration{}
+	latest := &types.Configuration{}
+	assert.True(t, freeze.hold(types.ConfigMessage{ProviderName: "marathon", Configuration: first, Causes: []string{"event 1"},
+		ServerReasons: map[string]string{"server-task1": "task TASK_KILLED"}}))
+	assert.True(t, freeze.hold(types.ConfigMessage{ProviderName: "marathon", Configuration: latest, Causes: []string{"event 2"},
+		ServerReasons: map[string]string{"server-task2": "task TASK_RUNNING"}}))
+	assert.Equal(t, []string{"marathon"}, freeze.status().HeldProviders)
+
+	status = freeze.unfreeze()
+	assert.False(t, status.Frozen)
+	select {
+	case messages := <-released:
+		require.Len(t, messages, 1)
+		assert.True(t, messages[0].Configuration == latest, "the latest configuration must be released")
+		assert.Equal(t, []string{"event 1", "event 2"}, messages[0].Causes)
+		assert.Equal(t, map[string]string{"server-task1": "task TASK_KILLED", "server-task2": "task TASK_RUNNING"}, messages[0].ServerReasons)
+	default:
+		t.Fatal("held configurations were not released")
+	}
+
+	assert.False(t, freeze.hold(types.ConfigMessage{ProviderName: "marathon"}), "configuration must not be held after the freeze")
+}
+
+func TestConfigFreezeTimeout(t *testing.T) {
+	released := make(chan []types.ConfigMessage, 1)
+	freeze := newConfigFreeze(func(messages []types.ConfigMessage) {
+		released <- messages
+	})
+
+	freeze.freeze("test", 50*time.Millisecond)
+	freeze.hold(types.ConfigMessage{ProviderName: "docker"})
+
+	select {
+	case messages := <-released:
+		assert.Len(t, messages, 1)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the freeze did not time out")
+	}
+	assert.False(t, freeze.status().Frozen)
+}
+
+func TestConfigFreezeExtendedWhileTimingOut(t *testing.T) {
+	freeze := newConfigFreeze(func(messages []types.ConfigMessage) {
+		t.Error("no configuration must be released")
+	})
+
+	freeze.freeze("test", time.Hour)
+	freeze.lock.Lock()
+	timedOut := freeze.generation
+	freeze.lock.Unlock()
+
+	// the callback of the first timer is already running when the freeze is extended,
+	// stopping the timer does not cancel it
+	freeze.freeze("extended", time.Hour)
+	freeze.expire(timedOut, time.Hour)
+
+	status := freeze.status()
+	assert.True(t, status.Frozen, "the extended freeze must not be lifted by the previous timeout")
+	assert.Equal(t, "extended", status.Reason)
+
+	freeze.lock.Lock()
+	freeze.timer.Stop()
+	freeze.lock.Unlock()
+}
+
+func TestConfigFreezeToggle(t *testing.T) {
+	freeze := newConfigFreeze(func(messages []types.ConfigMessage) {
+		t.Error("no configuration must be released")
+	})
+
+	assert.True(t, freeze.toggle("signal", time.Hour).Frozen)
+	assert.False(t, freeze.toggle("signal", time.Hour).Frozen)
+}
+
+func TestFreezeHandlers(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		readOnly       bool
+		body           string
+		expectedStatus int
+		expectedFrozen bool
+		expectedReason string
+		expectedUntil  time.Duration
+	}{
+		{
+			desc:           "default timeout and reason",
+			expectedStatus: http.StatusOK,
+			expectedFrozen: true,
+			expectedReason: "requested through the API",
+			expectedUntil:  2 * time.Hour,
+		},
+		{
+			desc:           "timeout and reason",
+			body:           `{"reason": "incident 42", "timeout": "10m"}`,
+			expectedStatus: http.StatusOK,
+			expectedFrozen: true,
+			expectedReason: "incident 42",
+			expectedUntil:  10 * time.Minute,
+		},
+		{
+			desc:           "timeout exceeding the maximum",
+			body:           `{"timeout": "3h"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "invalid timeout",
+			body:           `{"timeout": "soon"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "invalid body",
+			body:           `{`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "read-only API",
+			readOnly:       true,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			srv := NewServer(GlobalConfiguration{FreezeTimeout: flaeg.Duration(2 * time.Hour)})
+			provider := &WebProvider{ReadOnly: test.readOnly, server: srv}
+
+			recorder := httptest.NewRecorder()
+			provider.freezeHandler(recorder, testhelpers.MustNewRequest(http.MethodPost, "/api/freeze", strings.NewReader(test.body)))
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+
+			status := srv.configFreeze.status()
+			assert.Equal(t, test.expectedFrozen, status.Frozen)
+			if !test.expectedFrozen {
+				return
+			}
+			assert.Equal(t, test.expectedReason, status.Reason)
+			assert.WithinDuration(t, time.Now().Add(test.expectedUntil), *status.Until, time.Minute)
+
+			recorder = httptest.NewRecorder()
+			provider.unfreezeHandler(recorder, testhelpers.MustNewRequest(http.MethodDelete, "/api/freeze", nil))
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.False(t, srv.configFreeze.status().Frozen)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/containous/traefik/types"
+)
+
+// FrontendProfile is a named bundle of middlewares shared by frontends.
+// Profiles are defined once in the static configuration and referenced by name from the frontends,
+// so that many applications get the same authentication, headers and rate limit without repeating them.
+type FrontendProfile struct {
+	BasicAuth            []string         `description:"Basic authentication users"`
+	Auth                 *types.Auth      `description:"Authentication configuration"`
+	WhitelistSourceRange []string         `description:"Allowed source IP ranges"`
+	RateLimit            *types.RateLimit `description:"Rate limit configuration"`
+	Headers              types.Headers    `description:"Custom and security headers"`
+}
+
+// FrontendProfiles holds the frontend profiles, indexed by name
+type FrontendProfiles map[string]*FrontendProfile
+
+// applyFrontendProfile returns the frontend with the middlewares of its profile it does not configure itself.
+// The settings of the frontend take precedence, each middleware being taken as a whole from either the
+// frontend or the profile. The given frontend is left untouched.
+func (server *Server) applyFrontendProfile(frontend *types.Frontend) (*types.Frontend, error) {
+	if frontend.Profile == "" {
+		return frontend, nil
+	}
+	profile, ok := server.globalConfiguration.FrontendProfiles[frontend.Profile]
+	if !ok || profile == nil {
+		return nil, fmt.Errorf("unknown frontend profile %s", frontend.Profile)
+	}
+
+	profiled := *frontend
+	if len(profiled.BasicAuth) == 0 {
+		profiled.BasicAuth = profile.BasicAuth
+	}
+	if profiled.Auth == nil {
+		profiled.Auth = profile.Auth
+	}
+	if len(profiled.WhitelistSourceRange) == 0 {
+		profiled.WhitelistSourceRange = profile.WhitelistSourceRange
+	}
+	if profiled.RateLimit == nil {
+		profiled.RateLimit = profile.RateLimit
+	}
+	if !profiled.Headers.HasCustomHeadersDefined() && !profiled.Headers.HasSecureHeadersDefined() {
+		profiled.Headers = profile.Headers
+	}
+	return &profiled, nil
+}
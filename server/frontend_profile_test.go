@@ -0,0 +1,87 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyFrontendProfile(t *testing.T) {
+	publicAPI := &FrontendProfile{
+		BasicAuth:            []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/"},
+		Auth:                 &types.Auth{Forward: &types.Forward{Address: "https://auth.example.com"}},
+		WhitelistSourceRange: []string{"10.0.0.0/8"},
+		RateLimit:            &types.RateLimit{Average: 10, Burst: 20},
+		Headers:              types.Headers{CustomRequestHeaders: map[string]string{"X-Api": "public"}},
+	}
+	srv := &Server{globalConfiguration: GlobalConfiguration{
+		FrontendProfiles: FrontendProfiles{"public-api": publicAPI},
+	}}
+
+	cases := []struct {
+		desc          string
+		frontend      *types.Frontend
+		expected      *types.Frontend
+		expectedError bool
+	}{
+		{
+			desc:     "no profile",
+			frontend: &types.Frontend{Backend: "backend"},
+			expected: &types.Frontend{Backend: "backend"},
+		},
+		{
+			desc:          "unknown profile",
+			frontend:      &types.Frontend{Backend: "backend", Profile: "internal"},
+			expectedError: true,
+		},
+		{
+			desc:     "profile middlewares",
+			frontend: &types.Frontend{Backend: "backend", Profile: "public-api"},
+			expected: &types.Frontend{
+				Backend:              "backend",
+				Profile:              "public-api",
+				BasicAuth:            publicAPI.BasicAuth,
+				Auth:                 publicAPI.Auth,
+				WhitelistSourceRange: publicAPI.WhitelistSourceRange,
+				RateLimit:            publicAPI.RateLimit,
+				Headers:              publicAPI.Headers,
+			},
+		},
+		{
+			desc: "frontend middlewares take precedence",
+			frontend: &types.Frontend{
+				Backend:   "backend",
+				Profile:   "public-api",
+				RateLimit: &types.RateLimit{Average: 100},
+				Headers:   types.Headers{SSLRedirect: true},
+			},
+			expected: &types.Frontend{
+				Backend:              "backend",
+				Profile:              "public-api",
+				BasicAuth:            publicAPI.BasicAuth,
+				Auth:                 publicAPI.Auth,
+				WhitelistSourceRange: publicAPI.WhitelistSourceRange,
+				RateLimit:            &types.RateLimit{Average: 100},
+				Headers:              types.Headers{SSLRedirect: true},
+			},
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			original := *test.frontend
+			frontend, err := srv.applyFrontendProfile(test.frontend)
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, frontend)
+			assert.Equal(t, original, *test.frontend, "frontend left untouched")
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/middlewares"
+)
+
+const idleReapInterval = time.Second
+
+// trackedConnection is an open connection of an entrypoint
+type trackedConnection struct {
+	conn net.Conn
+	// idleSince is zero unless the connection is idle, waiting for a keep-alive request
+	idleSince time.Time
+	// frontend is the frontend of the last request of the connection, whose idleTimeout applies
+	frontend    string
+	idleTimeout time.Duration
+	reaped      bool
+}
+
+// wrap records the frontend of the requests on their connection, the connection being closed once idle
+// for idleTimeout, when set
+func (t *connectionTracker) wrap(frontendName string, idleTimeout time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if localAddr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+			t.lock.Lock()
+			if tracked, ok := t.byAddr[connectionKey(localAddr.String(), r.RemoteAddr)]; ok {
+				tracked.frontend = frontendName
+				tracked.idleTimeout = idleTimeout
+			}
+			t.lock.Unlock()
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// reap closes the connections idle for longer than the idle timeout of the frontend of their last request,
+// returning the idle connections left and the reaped ones, by frontend
+func (t *connectionTracker) reap(now time.Time) (map[string]int, map[string]int) {
+	idle := make(map[string]int)
+	reaped := make(map[string]int)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, tracked := range t.connections {
+		if tracked.idleSince.IsZero() || tracked.reaped {
+			continue
+		}
+		if tracked.idleTimeout <= 0 || now.Sub(tracked.idleSince) < tracked.idleTimeout {
+			idle[tracked.frontend]++
+			continue
+		}
+		// closed by the http.Server ConnState hook
+		tracked.reaped = true
+		if err := tracked.conn.Close(); err != nil {
+			log.Debugf("Error closing idle connection of %s: %s", tracked.conn.RemoteAddr(), err)
+		}
+		reaped[tracked.frontend]++
+	}
+	return idle, reaped
+}
+
+// idleCounts returns the number of idle connections by frontend of their last request, nil if none
+func (t *connectionTracker) idleCounts() map[string]int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var idle map[string]int
+	for _, tracked := range t.connections {
+		if tracked.idleSince.IsZero() || tracked.reaped {
+			continue
+		}
+		if idle == nil {
+			idle = make(map[string]int)
+		}
+		idle[tracked.frontend]++
+	}
+	return idle
+}
+
+// hasIdleTimeouts returns true when a frontend of the configurations sets an idleTimeout
+func hasIdleTimeouts(configurations configs) bool {
+	for _, configuration := range configurations {
+		if configuration == nil {
+			continue
+		}
+		for _, frontend := range configuration.Frontends {
+			if len(frontend.IdleTimeout) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setIdleTimeouts records whether a frontend of the applied configurations sets an idleTimeout
+func (server *Server) setIdleTimeouts(configurations configs) {
+	var idleTimeouts int32
+	if hasIdleTimeouts(configurations) {
+		idleTimeouts = 1
+	}
+	atomic.StoreInt32(&server.idleTimeouts, idleTimeouts)
+}
+
+// reapIdleConnections periodically closes the idle connections of the entrypoints whose frontends set an
+// idleTimeout, and reports the idle connections to Prometheus, when enabled.
+// The connections are not scanned while no frontend sets an idleTimeout.
+func (server *Server) reapIdleConnections(stop chan bool) {
+	ticker := time.NewTicker(idleReapInterval)
+	defer ticker.Stop()
+	report := prometheusEnabled(server.globalConfiguration)
+	reaping := false
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if atomic.LoadInt32(&server.idleTimeouts) == 0 {
+				if reaping && report {
+					// the idle connections are no longer counted
+					for entryPointName := range server.serverEntryPoints {
+						reportIdleConnections(entryPointName, nil, nil)
+					}
+				}
+				reaping = false
+				continue
+			}
+			reaping = true
+			for entryPointName, serverEntryPoint := range server.serverEntryPoints {
+				idle, reaped := serverEntryPoint.connectionTracker.reap(now)
+				for frontendName, count := range reaped {
+					log.Debugf("Closed %d idle connections of entrypoint %s to frontend %s", count, entryPointName, frontendName)
+				}
+				if report {
+					reportIdleConnections(entryPointName, idle, reaped)
+				}
+			}
+		}
+	}
+}
+
+func reportIdleConnections(entryPointName string, idle, reaped map[string]int) {
+	if err := middlewares.SetPrometheusIdleConnections(entryPointName, idle, reaped); err != nil {
+		log.Errorf("Error reporting the idle connections of entrypoint %s: %s", entryPointName, err)
+	}
+}
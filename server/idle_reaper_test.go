@@ -0,0 +1,64 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionTrackerReap(t *testing.T) {
+	tracker := newConnectionTracker()
+	mux := http.NewServeMux()
+	mux.Handle("/bots", tracker.wrap("bots", 50*time.Millisecond, http.NotFoundHandler()))
+	mux.Handle("/users", tracker.wrap("users", 0, http.NotFoundHandler()))
+	ts := httptest.NewUnstartedServer(mux)
+	ts.Config.ConnState = tracker.ConnState
+	ts.Start()
+	defer ts.Close()
+
+	get := func(client *http.Client, path string) {
+		resp, err := client.Get(ts.URL + path)
+		require.NoError(t, err)
+		_, err = ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	get(&http.Client{Transport: &http.Transport{}}, "/bots")
+	get(&http.Client{Transport: &http.Transport{}}, "/users")
+	waitFor(t, 5*time.Second, func() bool {
+		idle := tracker.idleCounts()
+		return idle["bots"] == 1 && idle["users"] == 1
+	})
+
+	idle, reaped := tracker.reap(time.Now())
+	assert.Equal(t, map[string]int{"bots": 1, "users": 1}, idle)
+	assert.Empty(t, reaped)
+
+	idle, reaped = tracker.reap(time.Now().Add(time.Minute))
+	assert.Equal(t, map[string]int{"users": 1}, idle)
+	assert.Equal(t, map[string]int{"bots": 1}, reaped)
+
+	waitFor(t, 5*time.Second, func() bool {
+		connections, _ := tracker.counts()
+		return connections == 1
+	})
+	assert.Equal(t, map[string]int{"users": 1}, tracker.idleCounts())
+}
+
+func TestHasIdleTimeouts(t *testing.T) {
+	assert.False(t, hasIdleTimeouts(configs{}))
+	assert.False(t, hasIdleTimeouts(configs{
+		"file":     &types.Configuration{Frontends: map[string]*types.Frontend{"users": {}}},
+		"marathon": nil,
+	}))
+	assert.True(t, hasIdleTimeouts(configs{
+		"file":     &types.Configuration{Frontends: map[string]*types.Frontend{"users": {}}},
+		"marathon": &types.Configuration{Frontends: map[string]*types.Frontend{"bots": {IdleTimeout: "5s"}}},
+	}))
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cenk/backoff"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/middlewares"
+	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+const (
+	zoneMetadataTimeout = 5 * time.Second
+	localZoneCause      = "zone of traefik resolved"
+)
+
+// fetchZone fetches the zone traefik is running in from the metadata URL
+func (l *Locality) fetchZone() (string, error) {
+	req, err := http.NewRequest(http.MethodGet, l.ZoneMetadataURL, nil)
+	if err != nil {
+		return "", err
+	}
+	// required by the Google Cloud metadata server, whose paths all start with /computeMetadata/
+	if strings.HasPrefix(req.URL.Path, "/computeMetadata/") {
+		req.Header.Set("Metadata-Flavor", "Google")
+	}
+
+	client := &http.Client{Timeout: zoneMetadataTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	// Google Cloud returns projects/<project>/zones/<zone>, AWS returns the bare zone
+	zone := strings.TrimSpace(string(body))
+	zone = zone[strings.LastIndex(zone, "/")+1:]
+	if zone == "" {
+		return "", fmt.Errorf("empty zone")
+	}
+	return zone, nil
+}
+
+// resolveLocalZone fetches the zone of traefik from the metadata URL until it succeeds, without
+// delaying the start of traefik: the servers of every zone are used until the zone is known, and
+// the current configuration is applied again once it is.
+func (server *Server) resolveLocalZone(stop chan bool) {
+	locality := server.globalConfiguration.Locality
+	retry := backoff.NewExponentialBackOff()
+	retry.MaxElapsedTime = 0
+	for {
+		zone, err := locality.fetchZone()
+		if err == nil {
+			log.Infof("Using zone %s from %s", zone, locality.ZoneMetadataURL)
+			server.setLocalZone(zone)
+			server.reloadForLocalZone(stop)
+			return
+		}
+
+		next := retry.NextBackOff()
+		log.Errorf("Unable to fetch zone from %s, retrying in %s: %s", locality.ZoneMetadataURL, next, err)
+		select {
+		case <-stop:
+			return
+		case <-time.After(next):
+		}
+	}
+}
+
+func (server *Server) getLocalZone() string {
+	server.localZoneLock.RLock()
+	defer server.localZoneLock.RUnlock()
+	return server.localZone
+}
+
+func (server *Server) setLocalZone(zone string) {
+	server.localZoneLock.Lock()
+	defer server.localZoneLock.Unlock()
+	server.localZone = zone
+}
+
+// reloadForLocalZone applies the current configuration again, so that the load balancers
+// prefer the servers of the zone of traefik. The configuration of any provider will do, the
+// load balancers of every provider being rebuilt. The configuration is not sent once traefik stops.
+func (server *Server) reloadForLocalZone(stop chan bool) {
+	for providerName, configuration := range server.currentConfigurations.Get().(configs) {
+		configMsg := types.ConfigMessage{
+			ProviderName:  providerName,
+			Configuration: configuration,
+			Causes:        []string{localZoneCause},
+		}
+		safe.Go(func() {
+			select {
+			case server.configurationValidatedChan <- configMsg:
+			case <-stop:
+			}
+		})
+		return
+	}
+}
+
+// newLocalityPool wraps the local load balancer into a locality-aware one when the zone
+// of traefik is known and some of the backend servers are located in another zone.
+// newRemote builds the load balancer used for the servers of the other zones, and sticky,
+// when not nil, is the sticky session shared by both load balancers.
+func (server *Server) newLocalityPool(local middlewares.LocalityPool, backend *types.Backend, sticky *roundrobin.StickySession, newRemote func() middlewares.LocalityPool) middlewares.LocalityPool {
+	localZone := server.getLocalZone()
+	if localZone == "" {
+		return local
+	}
+
+	var localServers []*url.URL
+	remoteServers := false
+	for _, srv := range backend.Servers {
+		if srv.Zone == "" || srv.Zone == localZone {
+			u, err := url.Parse(srv.URL)
+			if err != nil {
+				// reported when configuring the load balancer servers
+				continue
+			}
+			localServers = append(localServers, u)
+		} else {
+			remoteServers = true
+		}
+	}
+	if !remoteServers {
+		return local
+	}
+
+	log.Debugf("Creating locality-aware load-balancer for zone %s", localZone)
+	return middlewares.NewLocalityBalancer(local, newRemote(), localServers, server.globalConfiguration.Locality.MinLocalServers, sticky)
+}
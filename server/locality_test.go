@@ -0,0 +1,100 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalityFetchZone(t *testing.T) {
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/zone":
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte("projects/123/zones/europe-west1-b\n"))
+		case "/latest/meta-data/placement/availability-zone":
+			if r.Header.Get("Metadata-Flavor") != "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte("us-east-1a"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer metadata.Close()
+
+	zone, err := (&Locality{ZoneMetadataURL: metadata.URL + "/computeMetadata/v1/instance/zone"}).fetchZone()
+	require.NoError(t, err)
+	assert.Equal(t, "europe-west1-b", zone)
+
+	zone, err = (&Locality{ZoneMetadataURL: metadata.URL + "/latest/meta-data/placement/availability-zone"}).fetchZone()
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1a", zone)
+
+	_, err = (&Locality{ZoneMetadataURL: metadata.URL + "/unknown"}).fetchZone()
+	assert.Error(t, err)
+}
+
+func TestServerResolveLocalZone(t *testing.T) {
+	var calls int32
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("us-east-1a"))
+	}))
+	defer metadata.Close()
+
+	configuration := &types.Configuration{}
+	server := &Server{
+		configurationValidatedChan: make(chan types.ConfigMessage, 1),
+		globalConfiguration: GlobalConfiguration{
+			Locality: &Locality{ZoneMetadataURL: metadata.URL},
+		},
+	}
+	server.currentConfigurations.Set(configs{"file": configuration})
+
+	stop := make(chan bool)
+	defer close(stop)
+	go server.resolveLocalZone(stop)
+
+	// the current configuration is applied again once the zone is known
+	select {
+	case configMsg := <-server.configurationValidatedChan:
+		assert.Equal(t, "file", configMsg.ProviderName)
+		assert.True(t, configMsg.Configuration == configuration)
+		assert.Equal(t, []string{localZoneCause}, configMsg.Causes)
+	case <-time.After(5 * time.Second):
+		t.Fatal("configuration not applied again")
+	}
+	assert.Equal(t, "us-east-1a", server.getLocalZone())
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+}
+
+func TestServerReloadForLocalZoneStopped(t *testing.T) {
+	server := &Server{configurationValidatedChan: make(chan types.ConfigMessage)}
+	server.currentConfigurations.Set(configs{"file": &types.Configuration{}})
+
+	stop := make(chan bool)
+	close(stop)
+	server.reloadForLocalZone(stop)
+
+	// nothing reads the configurations once stopped, the sending goroutine must give up
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case <-server.configurationValidatedChan:
+		t.Fatal("configuration applied again after stop")
+	default:
+	}
+}
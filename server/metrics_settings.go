@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/middlewares"
+)
+
+type metricsDisabledKey struct{}
+
+// metricsSettings holds the metrics settings changed at runtime through the API:
+// the frontends and backends whose metrics are not collected, and the Prometheus buckets.
+// They apply immediately, without reloading the configuration, and are kept until restart.
+type metricsSettings struct {
+	lock              sync.RWMutex
+	disabledFrontends map[string]bool
+	disabledBackends  map[string]bool
+	buckets           []float64
+}
+
+// metricsSettingsRepresentation is the representation of the metrics settings in the API
+type metricsSettingsRepresentation struct {
+	DisabledFrontends []string  `json:"disabledFrontends"`
+	DisabledBackends  []string  `json:"disabledBackends"`
+	Buckets           []float64 `json:"buckets,omitempty"`
+}
+
+func newMetricsSettings(buckets []float64) *metricsSettings {
+	return &metricsSettings{
+		disabledFrontends: make(map[string]bool),
+		disabledBackends:  make(map[string]bool),
+		buckets:           buckets,
+	}
+}
+
+func (s *metricsSettings) get() *metricsSettingsRepresentation {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return &metricsSettingsRepresentation{
+		DisabledFrontends: sortedKeys(s.disabledFrontends),
+		DisabledBackends:  sortedKeys(s.disabledBackends),
+		Buckets:           s.buckets,
+	}
+}
+
+// update replaces the disabled frontends and backends, and the Prometheus buckets when given
+func (s *metricsSettings) update(settings *metricsSettingsRepresentation) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if settings.Buckets != nil {
+		if err := middlewares.SetPrometheusBuckets(settings.Buckets); err != nil {
+			return err
+		}
+		s.buckets = settings.Buckets
+		log.Infof("Prometheus buckets set to %v", settings.Buckets)
+	}
+	s.disabledFrontends = toSet(settings.DisabledFrontends)
+	s.disabledBackends = toSet(settings.DisabledBackends)
+	log.Infof("Metrics disabled for frontends %v and backends %v", settings.DisabledFrontends, settings.DisabledBackends)
+	return nil
+}
+
+func (s *metricsSettings) frontendDisabled(frontendName string) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.disabledFrontends[frontendName]
+}
+
+// backendEnabled returns whether the metrics of a request to the backend are collected
+func (s *metricsSettings) backendEnabled(backendName string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		s.lock.RLock()
+		defer s.lock.RUnlock()
+		return !s.disabledBackends[backendName] && r.Context().Value(metricsDisabledKey{}) == nil
+	}
+}
+
+// withFrontendMetricsSwitch disables the collection of the metrics of the frontend requests when
+// the frontend metrics are disabled. The backend handlers being shared by the frontends, the
+// requests are flagged: the handler must be the innermost of the frontend, the route variables
+// being bound to the original request.
+func (s *metricsSettings) withFrontendMetricsSwitch(frontendName string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if s.frontendDisabled(frontendName) {
+			r = r.WithContext(context.WithValue(r.Context(), metricsDisabledKey{}, true))
+		}
+		handler.ServeHTTP(rw, r)
+	})
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
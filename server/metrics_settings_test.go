@@ -0,0 +1,120 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsSettingsUpdate(t *testing.T) {
+	settings := newMetricsSettings([]float64{0.1, 1})
+
+	assert.Equal(t, &metricsSettingsRepresentation{
+		DisabledFrontends: []string{},
+		DisabledBackends:  []string{},
+		Buckets:           []float64{0.1, 1},
+	}, settings.get())
+
+	require.NoError(t, settings.update(&metricsSettingsRepresentation{
+		DisabledFrontends: []string{"frontend-b", "frontend-a"},
+		DisabledBackends:  []string{"backend-a"},
+	}))
+	assert.Equal(t, &metricsSettingsRepresentation{
+		DisabledFrontends: []string{"frontend-a", "frontend-b"},
+		DisabledBackends:  []string{"backend-a"},
+		Buckets:           []float64{0.1, 1},
+	}, settings.get())
+
+	assert.Error(t, settings.update(&metricsSettingsRepresentation{Buckets: []float64{1, 0.1}}))
+	assert.Equal(t, []float64{0.1, 1}, settings.get().Buckets)
+	assert.Equal(t, []string{"backend-a"}, settings.get().DisabledBackends, "settings must be left unchanged on error")
+}
+
+func TestServerLoadConfigMetricsSettings(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	globalConfig := GlobalConfiguration{
+		EntryPoints: EntryPoints{
+			"http": &EntryPoint{},
+		},
+		Web: &WebProvider{
+			Metrics: &types.Metrics{Prometheus: &types.Prometheus{}},
+		},
+	}
+	newFrontend := func(host, backend string) *types.Frontend {
+		return &types.Frontend{
+			EntryPoints: []string{"http"},
+			Backend:     backend,
+			Routes: map[string]types.Route{
+				"route": {Rule: "Host:" + host},
+			},
+		}
+	}
+	newBackend := func() *types.Backend {
+		return &types.Backend{
+			Servers: map[string]types.Server{
+				"server": {URL: backend.URL},
+			},
+			LoadBalancer: &types.LoadBalancer{Method: "wrr"},
+		}
+	}
+	dynamicConfigs := configs{
+		"config": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"frontend-a": newFrontend("a", "backend-metrics-shared"),
+				"frontend-b": newFrontend("b", "backend-metrics-shared"),
+				"frontend-c": newFrontend("c", "backend-metrics-other"),
+			},
+			Backends: map[string]*types.Backend{
+				"backend-metrics-shared": newBackend(),
+				"backend-metrics-other":  newBackend(),
+			},
+		},
+	}
+
+	srv := NewServer(globalConfig)
+	entryPoints, err := srv.loadConfig(dynamicConfigs, globalConfig)
+	require.NoError(t, err)
+	require.NoError(t, srv.metricsSettings.update(&metricsSettingsRepresentation{
+		DisabledFrontends: []string{"frontend-a"},
+		DisabledBackends:  []string{"backend-metrics-other"},
+	}))
+
+	for _, host := range []string{"a", "b", "b", "c"} {
+		recorder := httptest.NewRecorder()
+		entryPoints["http"].httpRouter.ServeHTTP(recorder, testhelpers.MustNewRequest(http.MethodGet, "http://"+host+"/", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code, "host %s", host)
+	}
+
+	assert.Equal(t, float64(2), requestsTotal(t, "backend-metrics-shared"))
+	assert.Equal(t, float64(0), requestsTotal(t, "backend-metrics-other"))
+}
+
+// requestsTotal returns the number of requests counted by Prometheus for the service
+func requestsTotal(t *testing.T, service string) float64 {
+	metricsFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	total := float64(0)
+	for _, family := range metricsFamilies {
+		if family.GetName() != "traefik_requests_total" {
+			continue
+		}
+		for _, metric := range family.Metric {
+			for _, label := range metric.Label {
+				if label.GetName() == "service" && label.GetValue() == service {
+					total += metric.Counter.GetValue()
+				}
+			}
+		}
+	}
+	return total
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/containous/traefik/log"
+)
+
+const (
+	notFoundActionStatus   = "404"
+	notFoundActionRedirect = "redirect"
+	notFoundActionFrontend = "frontend"
+	notFoundActionClose    = "close"
+)
+
+// buildNotFoundHandler returns the handler of the requests matching no frontend
+// of an entry point. frontendHandlers holds the handlers of the frontends of the
+// entry point by name.
+func buildNotFoundHandler(notFound *NotFound, frontendHandlers map[string]http.Handler) (http.Handler, error) {
+	if notFound == nil {
+		return http.HandlerFunc(notFoundHandler), nil
+	}
+	switch notFound.Action {
+	case "", notFoundActionStatus:
+		return http.HandlerFunc(notFoundHandler), nil
+	case notFoundActionRedirect:
+		if notFound.URL == "" {
+			return nil, fmt.Errorf("no URL to redirect to")
+		}
+		return http.RedirectHandler(notFound.URL, http.StatusFound), nil
+	case notFoundActionFrontend:
+		handler, ok := frontendHandlers[notFound.Frontend]
+		if !ok {
+			return nil, fmt.Errorf("undefined frontend %q", notFound.Frontend)
+		}
+		return handler, nil
+	case notFoundActionClose:
+		return http.HandlerFunc(closeConnectionHandler), nil
+	default:
+		return nil, fmt.Errorf("unknown action %q", notFound.Action)
+	}
+}
+
+// closeConnectionHandler closes the connection without any response.
+// Connections which cannot be hijacked, like HTTP/2 ones, get a 404 instead.
+func closeConnectionHandler(w http.ResponseWriter, r *http.Request) {
+	if hijacker, ok := w.(http.Hijacker); ok {
+		conn, _, err := hijacker.Hijack()
+		if err == nil {
+			conn.Close()
+			return
+		}
+		log.Debugf("Unable to hijack connection to close it: %s", err)
+	}
+	w.Header().Set("Connection", "close")
+	notFoundHandler(w, r)
+}
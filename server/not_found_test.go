@@ -0,0 +1,143 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildNotFoundHandler(t *testing.T) {
+	frontendHandlers := map[string]http.Handler{
+		"catchall": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		}),
+	}
+
+	cases := []struct {
+		desc             string
+		notFound         *NotFound
+		expectedStatus   int
+		expectedLocation string
+		expectedError    bool
+	}{
+		{
+			desc:           "default",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "404",
+			notFound:       &NotFound{Action: "404"},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:             "redirect",
+			notFound:         &NotFound{Action: "redirect", URL: "https://www.example.com/"},
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://www.example.com/",
+		},
+		{
+			desc:          "redirect without URL",
+			notFound:      &NotFound{Action: "redirect"},
+			expectedError: true,
+		},
+		{
+			desc:           "frontend",
+			notFound:       &NotFound{Action: "frontend", Frontend: "catchall"},
+			expectedStatus: http.StatusTeapot,
+		},
+		{
+			desc:          "undefined frontend",
+			notFound:      &NotFound{Action: "frontend", Frontend: "missing"},
+			expectedError: true,
+		},
+		{
+			desc:           "close without hijacking support",
+			notFound:       &NotFound{Action: "close"},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:          "unknown action",
+			notFound:      &NotFound{Action: "drop"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			handler, err := buildNotFoundHandler(test.notFound, frontendHandlers)
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, testhelpers.MustNewRequest(http.MethodGet, "http://unknown.localhost/", nil))
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedLocation, recorder.Header().Get("Location"))
+		})
+	}
+}
+
+func TestCloseConnectionHandler(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(closeConnectionHandler))
+	defer ts.Close()
+
+	_, err := http.Get(ts.URL)
+	assert.Error(t, err)
+}
+
+func TestServerLoadConfigNotFoundFrontend(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("catch-all"))
+	}))
+	defer backend.Close()
+
+	globalConfig := GlobalConfiguration{
+		EntryPoints: EntryPoints{
+			"http": &EntryPoint{
+				NotFound: &NotFound{Action: "frontend", Frontend: "frontend-marketing"},
+			},
+		},
+	}
+	dynamicConfigs := configs{
+		"config": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"frontend-marketing": {
+					EntryPoints: []string{"http"},
+					Backend:     "backend",
+					Routes: map[string]types.Route{
+						"route": {Rule: "Host:www.example.com"},
+					},
+				},
+			},
+			Backends: map[string]*types.Backend{
+				"backend": {
+					Servers: map[string]types.Server{
+						"server": {URL: backend.URL},
+					},
+					LoadBalancer: &types.LoadBalancer{Method: "wrr"},
+				},
+			},
+		},
+	}
+
+	srv := NewServer(globalConfig)
+	entryPoints, err := srv.loadConfig(dynamicConfigs, globalConfig)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	entryPoints["http"].httpRouter.ServeHTTP(recorder, testhelpers.MustNewRequest(http.MethodGet, "http://unknown.example.com/", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	body, err := ioutil.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "catch-all", string(body))
+}
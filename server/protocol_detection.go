@@ -0,0 +1,139 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+)
+
+const (
+	// protocolDetectionTimeout is the maximum time to wait for the first byte of a connection
+	protocolDetectionTimeout = 10 * time.Second
+	// tlsRecordTypeHandshake is the first byte of the TLS connections, the type of the record holding the ClientHello
+	tlsRecordTypeHandshake = 0x16
+)
+
+var errListenerClosed = errors.New("listener closed")
+
+// protocolDetectionListener accepts both plain HTTP and TLS connections on the same listener,
+// the TLS connections being detected by their first byte, the start of the TLS handshake.
+// The first byte is awaited in a goroutine per connection, so that slow clients do not delay
+// the connections accepted after them.
+type protocolDetectionListener struct {
+	net.Listener
+	tlsConfig *tls.Config
+	conns     chan net.Conn
+	errs      chan error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newProtocolDetectionListener(listener net.Listener, tlsConfig *tls.Config) *protocolDetectionListener {
+	l := &protocolDetectionListener{
+		Listener:  listener,
+		tlsConfig: tlsConfig,
+		conns:     make(chan net.Conn),
+		errs:      make(chan error),
+		done:      make(chan struct{}),
+	}
+	go l.acceptConnections()
+	return l
+}
+
+func (l *protocolDetectionListener) acceptConnections() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			select {
+			case l.errs <- err:
+			case <-l.done:
+				return
+			}
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				continue
+			}
+			return
+		}
+		go l.detectProtocol(conn)
+	}
+}
+
+func (l *protocolDetectionListener) detectProtocol(conn net.Conn) {
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetKeepAlive(true)
+		tcpConn.SetKeepAlivePeriod(3 * time.Minute)
+	}
+
+	first := make([]byte, 1)
+	conn.SetReadDeadline(time.Now().Add(protocolDetectionTimeout))
+	if _, err := io.ReadFull(conn, first); err != nil {
+		log.Debugf("Unable to detect the protocol of the connection from %s: %s", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	var detected net.Conn = &peekedConn{Conn: conn, peeked: first}
+	if first[0] == tlsRecordTypeHandshake {
+		detected = tls.Server(detected, l.tlsConfig)
+	}
+	select {
+	case l.conns <- detected:
+	case <-l.done:
+		conn.Close()
+	}
+}
+
+// Accept returns the next connection, TLS connections being returned as *tls.Conn
+func (l *protocolDetectionListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case err := <-l.errs:
+		return nil, err
+	case <-l.done:
+		return nil, errListenerClosed
+	}
+}
+
+// Close closes the listener, the connections whose protocol is detected afterwards are closed
+func (l *protocolDetectionListener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.done)
+	})
+	return l.Listener.Close()
+}
+
+// serveDetectingProtocol serves both plain HTTP and TLS on the address of the server
+func serveDetectingProtocol(srv *http.Server) error {
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return srv.Serve(newProtocolDetectionListener(listener, srv.TLSConfig))
+}
+
+// peekedConn is a connection whose first bytes were already read
+type peekedConn struct {
+	net.Conn
+	peeked []byte
+}
+
+func (c *peekedConn) Read(p []byte) (int, error) {
+	if len(c.peeked) > 0 {
+		n := copy(p, c.peeked)
+		c.peeked = c.peeked[n:]
+		return n, nil
+	}
+	return c.Conn.Read(p)
+}
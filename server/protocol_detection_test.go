@@ -0,0 +1,80 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocolDetectionListener(t *testing.T) {
+	// borrow the certificate of httptest
+	certServer := httptest.NewUnstartedServer(http.NotFoundHandler())
+	certServer.StartTLS()
+	certificates := certServer.TLS.Certificates
+	certServer.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "tls=%t", r.TLS != nil)
+		}),
+		TLSConfig: &tls.Config{Certificates: certificates, NextProtos: []string{"http/1.1"}},
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(newProtocolDetectionListener(listener, srv.TLSConfig))
+	}()
+	address := listener.Addr().String()
+
+	// a client sending nothing must not delay the other connections
+	idle, err := net.Dial("tcp", address)
+	require.NoError(t, err)
+	defer idle.Close()
+
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+	}
+	for scheme, expected := range map[string]string{"http": "tls=false", "https": "tls=true"} {
+		resp, err := client.Get(scheme + "://" + address + "/")
+		require.NoError(t, err, scheme)
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err, scheme)
+		assert.Equal(t, expected, string(body), scheme)
+	}
+
+	require.NoError(t, srv.Close())
+	select {
+	case err := <-served:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server still serving after being closed")
+	}
+}
+
+func TestPeekedConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		client.Write([]byte("ello"))
+	}()
+
+	conn := &peekedConn{Conn: server, peeked: []byte("h")}
+	buf := make([]byte, 5)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "h", string(buf[:n]))
+	n, err = conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ello", string(buf[:n]))
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/provider"
+	"github.com/containous/traefik/provider/marathon"
+	"github.com/containous/traefik/safe"
+)
+
+// SetGlobalConfigurationLoader sets the function reading the global configuration again when the providers
+// are reloaded
+func (server *Server) SetGlobalConfigurationLoader(loader func() (GlobalConfiguration, error)) {
+	server.providersLock.Lock()
+	defer server.providersLock.Unlock()
+	server.globalConfigurationLoader = loader
+}
+
+// reloadProviders reads the global configuration again and restarts the providers whose configuration
+// changed. Only the Marathon provider can be restarted, adding or removing a provider and changing the
+// other ones require restarting traefik.
+func (server *Server) reloadProviders() {
+	server.providersLock.Lock()
+	loader := server.globalConfigurationLoader
+	server.providersLock.Unlock()
+	if loader == nil {
+		log.Warn("The global configuration cannot be reloaded")
+		return
+	}
+	globalConfiguration, err := loader()
+	if err != nil {
+		log.Errorf("Error reloading the global configuration: %s", err)
+		return
+	}
+	server.restartMarathon(globalConfiguration.Marathon)
+}
+
+// restartMarathon stops the Marathon provider and starts the given one in its place, if its configuration changed.
+// The running provider is tracked in marathonProvider, the global configuration being left untouched.
+func (server *Server) restartMarathon(configuration *marathon.Provider) {
+	server.providersLock.Lock()
+	current := server.marathonProvider
+	if current == nil || configuration == nil {
+		server.providersLock.Unlock()
+		if current != configuration {
+			log.Warn("Adding or removing the Marathon provider requires restarting traefik")
+		}
+		return
+	}
+	configurationJSON, _ := json.Marshal(configuration)
+	if bytes.Equal(server.providerConfigurations[current], configurationJSON) {
+		server.providersLock.Unlock()
+		log.Debug("Marathon provider configuration unchanged")
+		return
+	}
+	for i, p := range server.providers {
+		if p == current {
+			server.providers[i] = configuration
+		}
+	}
+	server.marathonProvider = configuration
+	delete(server.providerConfigurations, current)
+	server.providersLock.Unlock()
+
+	log.Info("Restarting the Marathon provider with its new configuration")
+	current.Stop()
+	server.startProvider(configuration)
+}
+
+// startProvider starts a provider sending its configurations to the server, keeping its configuration
+// before it is started, the providers being allowed to update it
+func (server *Server) startProvider(p provider.Provider) {
+	configuration, _ := json.Marshal(p)
+	server.providersLock.Lock()
+	server.providerConfigurations[p] = configuration
+	constraints := server.globalConfiguration.Constraints
+	server.providersLock.Unlock()
+	safe.Go(func() {
+		err := p.Provide(server.configurationChan, server.routinesPool, constraints)
+		if err != nil {
+			log.Errorf("Error starting provider %T: %s", p, err)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+// +build !windows
+
+package server
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/containous/traefik/log"
+)
+
+// listenReloadSignals reloads the providers on SIGHUP
+func (server *Server) listenReloadSignals(stop chan bool) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-signals:
+			log.Info("SIGHUP received, reloading the providers")
+			server.reloadProviders()
+		}
+	}
+}
@@ -0,0 +1,4 @@
+package server
+
+// listenReloadSignals does nothing, the providers cannot be reloaded on Windows
+func (server *Server) listenReloadSignals(stop chan bool) {}
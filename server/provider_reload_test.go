@@ -0,0 +1,78 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/provider/marathon"
+	"github.com/stretchr/testify/assert"
+)
+
+// newMarathonEndpoint returns a Marathon server notifying its requests, which are never answered
+func newMarathonEndpoint() (*httptest.Server, chan struct{}) {
+	requested := make(chan struct{}, 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested <- struct{}{}
+		<-r.Context().Done()
+	}))
+	return server, requested
+}
+
+func (server *Server) currentMarathonProvider() *marathon.Provider {
+	server.providersLock.Lock()
+	defer server.providersLock.Unlock()
+	return server.marathonProvider
+}
+
+func TestServerReloadProvidersRestartsMarathon(t *testing.T) {
+	first, firstRequested := newMarathonEndpoint()
+	defer first.Close()
+	second, secondRequested := newMarathonEndpoint()
+	defer second.Close()
+
+	server := NewServer(GlobalConfiguration{Marathon: &marathon.Provider{Endpoint: first.URL}})
+	defer server.routinesPool.Cleanup()
+	server.configureProviders()
+	server.startProviders()
+	<-firstRequested
+	started := server.currentMarathonProvider()
+
+	// no loader
+	server.reloadProviders()
+	assert.True(t, started == server.currentMarathonProvider())
+
+	var loaded GlobalConfiguration
+	var loadErr error
+	server.SetGlobalConfigurationLoader(func() (GlobalConfiguration, error) {
+		return loaded, loadErr
+	})
+
+	loadErr = errors.New("invalid TOML")
+	server.reloadProviders()
+	assert.True(t, started == server.currentMarathonProvider())
+
+	loadErr = nil
+	loaded = GlobalConfiguration{Marathon: &marathon.Provider{Endpoint: first.URL}}
+	server.reloadProviders()
+	assert.True(t, started == server.currentMarathonProvider(), "unchanged configuration must not restart the provider")
+
+	loaded = GlobalConfiguration{}
+	server.reloadProviders()
+	assert.True(t, started == server.currentMarathonProvider(), "the provider cannot be removed")
+
+	loaded = GlobalConfiguration{Marathon: &marathon.Provider{Endpoint: second.URL}}
+	server.reloadProviders()
+	select {
+	case <-secondRequested:
+	case <-time.After(5 * time.Second):
+		t.Fatal("restarted provider did not use the updated endpoint")
+	}
+	assert.True(t, loaded.Marathon == server.currentMarathonProvider())
+	assert.Contains(t, server.providers, loaded.Marathon)
+	assert.True(t, started == server.globalConfiguration.Marathon, "the global configuration must not be modified")
+	assert.NotContains(t, server.providers, started)
+	loaded.Marathon.Stop()
+}
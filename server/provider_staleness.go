@@ -0,0 +1,101 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/middlewares"
+	"github.com/containous/traefik/provider"
+	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/types"
+)
+
+// staleCheckInterval is the interval between two checks of the staleness of the providers
+const staleCheckInterval = 10 * time.Second
+
+// staleProviders keeps the last configuration of the providers failing to synchronize active,
+// and drops their routes once they have been stale for longer than the stale TTL, if any.
+// The routes of a provider are restored by its next configuration, once it synchronizes again.
+type staleProviders struct {
+	lock    sync.Mutex
+	ttl     time.Duration
+	dropped map[string]bool
+	drop    func(providerName string, staleSince time.Time)
+	report  func(providerName string, stale bool)
+}
+
+func newStaleProviders(ttl time.Duration, drop func(providerName string, staleSince time.Time), report func(providerName string, stale bool)) *staleProviders {
+	return &staleProviders{
+		ttl:     ttl,
+		dropped: make(map[string]bool),
+		drop:    drop,
+		report:  report,
+	}
+}
+
+// check drops the routes of the providers stale for longer than the TTL
+func (s *staleProviders) check(statuses map[string]provider.SyncStatus, now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for providerName, status := range statuses {
+		stale := status.StaleSince != nil
+		if s.report != nil {
+			s.report(providerName, stale)
+		}
+		if !stale {
+			if s.dropped[providerName] {
+				log.Infof("Provider %s synchronized again, its routes are restored", providerName)
+			}
+			delete(s.dropped, providerName)
+			continue
+		}
+		if s.ttl <= 0 || s.dropped[providerName] || now.Sub(*status.StaleSince) < s.ttl {
+			continue
+		}
+		log.Warnf("Provider %s stale since %s, dropping its routes", providerName, status.StaleSince.Format(time.RFC3339))
+		s.dropped[providerName] = true
+		s.drop(providerName, *status.StaleSince)
+	}
+}
+
+func (s *staleProviders) isDropped(providerName string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.dropped[providerName]
+}
+
+// monitorStaleProviders checks the staleness of the providers reporting their synchronization status
+func (server *Server) monitorStaleProviders(stop chan bool) {
+	ticker := time.NewTicker(staleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			server.staleProviders.check(server.providersSyncStatus(), time.Now())
+		}
+	}
+}
+
+// dropStaleProvider replaces the configuration of the stale provider with an empty one
+func (server *Server) dropStaleProvider(providerName string, staleSince time.Time) {
+	safe.Go(func() {
+		server.configurationValidatedChan <- types.ConfigMessage{
+			ProviderName: providerName,
+			Configuration: &types.Configuration{
+				Backends:  map[string]*types.Backend{},
+				Frontends: map[string]*types.Frontend{},
+			},
+			Causes: []string{fmt.Sprintf("provider stale since %s", staleSince.Format(time.RFC3339))},
+		}
+	})
+}
+
+func reportProviderStale(providerName string, stale bool) {
+	if err := middlewares.SetPrometheusProviderStale(providerName, stale); err != nil {
+		log.Errorf("Error reporting the staleness of provider %s: %s", providerName, err)
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/containous/flaeg"
+	"github.com/containous/traefik/provider"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaleProvidersCheck(t *testing.T) {
+	now := time.Now()
+	staleSince := now.Add(-time.Hour)
+	healthy := map[string]provider.SyncStatus{"marathon": {Name: "marathon"}}
+	stale := map[string]provider.SyncStatus{"marathon": {Name: "marathon", ConsecutiveFailures: 4, StaleSince: &staleSince}}
+
+	testCases := []struct {
+		desc            string
+		ttl             time.Duration
+		checks          []map[string]provider.SyncStatus
+		expectedDrops   int
+		expectedDropped bool
+	}{
+		{
+			desc:   "healthy provider",
+			ttl:    time.Minute,
+			checks: []map[string]provider.SyncStatus{healthy, healthy},
+		},
+		{
+			desc:   "stale within the TTL",
+			ttl:    2 * time.Hour,
+			checks: []map[string]provider.SyncStatus{stale, stale},
+		},
+		{
+			desc:   "stale without TTL",
+			checks: []map[string]provider.SyncStatus{stale, stale},
+		},
+		{
+			desc:            "stale beyond the TTL",
+			ttl:             time.Minute,
+			checks:          []map[string]provider.SyncStatus{stale, stale},
+			expectedDrops:   1,
+			expectedDropped: true,
+		},
+		{
+			desc:          "recovered after the drop",
+			ttl:           time.Minute,
+			checks:        []map[string]provider.SyncStatus{stale, healthy},
+			expectedDrops: 1,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			var drops int
+			var reported []bool
+			staleProviders := newStaleProviders(test.ttl, func(providerName string, since time.Time) {
+				assert.Equal(t, "marathon", providerName)
+				assert.Equal(t, staleSince, since)
+				drops++
+			}, func(providerName string, stale bool) {
+				reported = append(reported, stale)
+			})
+
+			var expectedReports []bool
+			for _, statuses := range test.checks {
+				staleProviders.check(statuses, now)
+				expectedReports = append(expectedReports, statuses["marathon"].StaleSince != nil)
+			}
+			assert.Equal(t, test.expectedDrops, drops)
+			assert.Equal(t, test.expectedDropped, staleProviders.isDropped("marathon"))
+			assert.Equal(t, expectedReports, reported)
+		})
+	}
+}
+
+func TestServerDropStaleProvider(t *testing.T) {
+	staleSince := time.Now().Add(-time.Hour)
+	server := NewServer(GlobalConfiguration{ProviderStaleTTL: flaeg.Duration(time.Minute)})
+	server.providers = []provider.Provider{&syncStatusProvider{status: provider.SyncStatus{Name: "marathon", StaleSince: &staleSince}}}
+
+	server.staleProviders.check(server.providersSyncStatus(), time.Now())
+
+	select {
+	case configMsg := <-server.configurationValidatedChan:
+		assert.Equal(t, "marathon", configMsg.ProviderName)
+		assert.Equal(t, &types.Configuration{
+			Backends:  map[string]*types.Backend{},
+			Frontends: map[string]*types.Frontend{},
+		}, configMsg.Configuration)
+		require.Len(t, configMsg.Causes, 1)
+		assert.Contains(t, configMsg.Causes[0], "provider stale since")
+	case <-time.After(5 * time.Second):
+		t.Fatal("routes of the stale provider not dropped")
+	}
+	assert.True(t, server.providersSyncStatus()["marathon"].RoutesDropped)
+}
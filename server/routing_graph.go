@@ -0,0 +1,186 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/containous/traefik/types"
+)
+
+// graphNode is an entrypoint, a frontend, a backend or a server of the routing graph
+type graphNode struct {
+	ID       string `json:"id"`
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Provider string `json:"provider,omitempty"`
+	// Label is the rules of a frontend or the URL of a server
+	Label string `json:"label,omitempty"`
+}
+
+// graphEdge links an entrypoint to a frontend, a frontend to its backend, or a backend to a server
+type graphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Label is the weight of a server
+	Label string `json:"label,omitempty"`
+}
+
+// routingGraph is the routing of the entrypoints to the frontends, backends and servers
+type routingGraph struct {
+	Nodes []graphNode `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+}
+
+// buildRoutingGraph builds the routing graph of the configurations, the frontends without entrypoints
+// being bound to the default entrypoints
+func buildRoutingGraph(configurations configs, defaultEntryPoints []string) *routingGraph {
+	graph := &routingGraph{Nodes: []graphNode{}, Edges: []graphEdge{}}
+	entryPoints := make(map[string]bool)
+	providers := make(map[string]bool)
+	for providerName := range configurations {
+		providers[providerName] = true
+	}
+
+	var edges []graphEdge
+	for _, providerName := range sortedKeys(providers) {
+		configuration := configurations[providerName]
+		if configuration == nil {
+			continue
+		}
+
+		frontendNames := make(map[string]bool)
+		for frontendName := range configuration.Frontends {
+			frontendNames[frontendName] = true
+		}
+		for _, frontendName := range sortedKeys(frontendNames) {
+			frontend := configuration.Frontends[frontendName]
+			if frontend == nil {
+				continue
+			}
+			frontendID := "frontend:" + providerName + "/" + frontendName
+			graph.Nodes = append(graph.Nodes, graphNode{
+				ID:       frontendID,
+				Kind:     "frontend",
+				Name:     frontendName,
+				Provider: providerName,
+				Label:    frontendRules(frontend),
+			})
+			frontendEntryPoints := frontend.EntryPoints
+			if len(frontendEntryPoints) == 0 {
+				frontendEntryPoints = defaultEntryPoints
+			}
+			for _, entryPointName := range frontendEntryPoints {
+				entryPoints[entryPointName] = true
+				edges = append(edges, graphEdge{From: "entrypoint:" + entryPointName, To: frontendID})
+			}
+			if frontend.Backend != "" {
+				edges = append(edges, graphEdge{From: frontendID, To: "backend:" + providerName + "/" + frontend.Backend})
+			}
+		}
+
+		backendNames := make(map[string]bool)
+		for backendName := range configuration.Backends {
+			backendNames[backendName] = true
+		}
+		for _, backendName := range sortedKeys(backendNames) {
+			backend := configuration.Backends[backendName]
+			if backend == nil {
+				continue
+			}
+			backendID := "backend:" + providerName + "/" + backendName
+			graph.Nodes = append(graph.Nodes, graphNode{ID: backendID, Kind: "backend", Name: backendName, Provider: providerName})
+
+			serverNames := make(map[string]bool)
+			for serverName := range backend.Servers {
+				serverNames[serverName] = true
+			}
+			for _, serverName := range sortedKeys(serverNames) {
+				server := backend.Servers[serverName]
+				serverID := backendID + "/" + serverName
+				graph.Nodes = append(graph.Nodes, graphNode{
+					ID:       serverID,
+					Kind:     "server",
+					Name:     serverName,
+					Provider: providerName,
+					Label:    server.URL,
+				})
+				edges = append(edges, graphEdge{From: backendID, To: serverID, Label: strconv.Itoa(server.Weight)})
+			}
+		}
+	}
+
+	entryPointNodes := make([]graphNode, 0, len(entryPoints))
+	for _, entryPointName := range sortedKeys(entryPoints) {
+		entryPointNodes = append(entryPointNodes, graphNode{ID: "entrypoint:" + entryPointName, Kind: "entrypoint", Name: entryPointName})
+	}
+	graph.Nodes = append(entryPointNodes, graph.Nodes...)
+	graph.Edges = append(graph.Edges, edges...)
+	return graph
+}
+
+// frontendRules returns the sorted rules of the routes of a frontend
+func frontendRules(frontend *types.Frontend) string {
+	rules := make([]string, 0, len(frontend.Routes))
+	for _, route := range frontend.Routes {
+		rules = append(rules, route.Rule)
+	}
+	sort.Strings(rules)
+	return strings.Join(rules, "\n")
+}
+
+var graphNodeShapes = map[string]string{
+	"entrypoint": "cds",
+	"frontend":   "box",
+	"backend":    "ellipse",
+	"server":     "plaintext",
+}
+
+// dot renders the routing graph in the GraphViz DOT language
+func (g *routingGraph) dot() []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString("digraph traefik {\n  rankdir=LR;\n")
+	for _, node := range g.Nodes {
+		label := node.Name
+		if node.Provider != "" && node.Kind != "server" {
+			label += " (" + node.Provider + ")"
+		}
+		if node.Label != "" {
+			label += "\n" + node.Label
+		}
+		fmt.Fprintf(buf, "  %s [shape=%s, label=%s];\n", dotQuote(node.ID), graphNodeShapes[node.Kind], dotQuote(label))
+	}
+	for _, edge := range g.Edges {
+		if edge.Label != "" {
+			fmt.Fprintf(buf, "  %s -> %s [label=%s];\n", dotQuote(edge.From), dotQuote(edge.To), dotQuote(edge.Label))
+		} else {
+			fmt.Fprintf(buf, "  %s -> %s;\n", dotQuote(edge.From), dotQuote(edge.To))
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+// dotQuote quotes a DOT identifier
+func dotQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return `"` + s + `"`
+}
+
+func (provider *WebProvider) getGraphHandler(response http.ResponseWriter, request *http.Request) {
+	graph := buildRoutingGraph(provider.currentConfigurations(request), provider.server.globalConfiguration.DefaultEntryPoints)
+	switch format := request.URL.Query().Get("format"); format {
+	case "", "json":
+		templatesRenderer.JSON(response, http.StatusOK, graph)
+	case "dot":
+		response.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		response.Write(graph.dot())
+	default:
+		http.Error(response, fmt.Sprintf("Unknown graph format %q, expected json or dot", format), http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildRoutingGraph(t *testing.T) {
+	configurations := configs{
+		"file": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"frontend1": {
+					Backend:     "backend1",
+					EntryPoints: []string{"https"},
+					Routes: map[string]types.Route{
+						"route1": {Rule: "Path:/api"},
+						"route0": {Rule: "Host:example.com"},
+					},
+				},
+				"frontend0": {Backend: "backend1"},
+			},
+			Backends: map[string]*types.Backend{
+				"backend1": {
+					Servers: map[string]types.Server{
+						"server1": {URL: "http://10.0.0.1:80", Weight: 2},
+						"server0": {URL: "http://10.0.0.0:80", Weight: 1},
+					},
+				},
+			},
+		},
+	}
+
+	graph := buildRoutingGraph(configurations, []string{"http"})
+
+	assert.Equal(t, []graphNode{
+		{ID: "entrypoint:http", Kind: "entrypoint", Name: "http"},
+		{ID: "entrypoint:https", Kind: "entrypoint", Name: "https"},
+		{ID: "frontend:file/frontend0", Kind: "frontend", Name: "frontend0", Provider: "file"},
+		{ID: "frontend:file/frontend1", Kind: "frontend", Name: "frontend1", Provider: "file", Label: "Host:example.com\nPath:/api"},
+		{ID: "backend:file/backend1", Kind: "backend", Name: "backend1", Provider: "file"},
+		{ID: "backend:file/backend1/server0", Kind: "server", Name: "server0", Provider: "file", Label: "http://10.0.0.0:80"},
+		{ID: "backend:file/backend1/server1", Kind: "server", Name: "server1", Provider: "file", Label: "http://10.0.0.1:80"},
+	}, graph.Nodes)
+	assert.Equal(t, []graphEdge{
+		{From: "entrypoint:http", To: "frontend:file/frontend0"},
+		{From: "frontend:file/frontend0", To: "backend:file/backend1"},
+		{From: "entrypoint:https", To: "frontend:file/frontend1"},
+		{From: "frontend:file/frontend1", To: "backend:file/backend1"},
+		{From: "backend:file/backend1", To: "backend:file/backend1/server0", Label: "1"},
+		{From: "backend:file/backend1", To: "backend:file/backend1/server1", Label: "2"},
+	}, graph.Edges)
+}
+
+func TestRoutingGraphDot(t *testing.T) {
+	graph := &routingGraph{
+		Nodes: []graphNode{
+			{ID: "entrypoint:http", Kind: "entrypoint", Name: "http"},
+			{ID: "frontend:file/frontend1", Kind: "frontend", Name: "frontend1", Provider: "file", Label: `Headers:X-Name,"a"`},
+		},
+		Edges: []graphEdge{
+			{From: "entrypoint:http", To: "frontend:file/frontend1"},
+		},
+	}
+
+	expected := `digraph traefik {
+  rankdir=LR;
+  "entrypoint:http" [shape=cds, label="http"];
+  "frontend:file/frontend1" [shape=box, label="frontend1 (file)\nHeaders:X-Name,\"a\""];
+  "entrypoint:http" -> "frontend:file/frontend1";
+}
+`
+	assert.Equal(t, expected, string(graph.dot()))
+}
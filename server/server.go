@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -26,6 +27,7 @@ import (
 	"github.com/containous/traefik/middlewares"
 	"github.com/containous/traefik/middlewares/accesslog"
 	"github.com/containous/traefik/provider"
+	"github.com/containous/traefik/provider/marathon"
 	"github.com/containous/traefik/safe"
 	"github.com/containous/traefik/types"
 	"github.com/streamrail/concurrent-map"
@@ -33,31 +35,53 @@ import (
 	"github.com/vulcand/oxy/connlimit"
 	"github.com/vulcand/oxy/forward"
 	"github.com/vulcand/oxy/roundrobin"
-	"github.com/vulcand/oxy/utils"
 )
 
 var oxyLogger = &OxyLogger{}
 
 // Server is the reverse-proxy/load-balancer engine
 type Server struct {
+	serverEntryPointsLock      sync.RWMutex
 	serverEntryPoints          serverEntryPoints
 	configurationChan          chan types.ConfigMessage
 	configurationValidatedChan chan types.ConfigMessage
 	signals                    chan os.Signal
 	stopChan                   chan bool
+	providersLock              sync.Mutex
 	providers                  []provider.Provider
+	providerConfigurations     map[provider.Provider][]byte
+	marathonProvider           *marathon.Provider
+	globalConfigurationLoader  func() (GlobalConfiguration, error)
 	currentConfigurations      safe.Safe
 	globalConfiguration        GlobalConfiguration
 	accessLoggerMiddleware     *accesslog.LogHandler
 	routinesPool               *safe.Pool
 	leadership                 *cluster.Leadership
+	idleTimeouts               int32
+	localZoneLock              sync.RWMutex
+	localZone                  string
+	configWebhook              *configWebhookNotifier
+	configAuditLog             *configAuditLog
+	localRateLimitStore        *middlewares.LocalTokenBucketStore
+	sharedRateLimitStore       *middlewares.KVTokenBucketStore
+	drainStates                *middlewares.DrainStates
+	serversTransports          map[string]http.RoundTripper
+	metricsSettings            *metricsSettings
+	clientIPStrategy           middlewares.ClientIPStrategy
+	configFreeze               *configFreeze
+	cutoverScheduler           *cutoverScheduler
+	staleProviders             *staleProviders
 }
 
 type serverEntryPoints map[string]*serverEntryPoint
 
 type serverEntryPoint struct {
-	httpServer *http.Server
-	httpRouter *middlewares.HandlerSwitcher
+	httpServer        *http.Server
+	httpRouter        *middlewares.HandlerSwitcher
+	connectionTracker *connectionTracker
+	// connectionRecycler is nil when the connections of the entrypoint are not recycled
+	connectionRecycler *connectionRecycler
+	draining           int32
 }
 
 type serverRoute struct {
@@ -78,7 +102,10 @@ func NewServer(globalConfiguration GlobalConfiguration) *Server {
 	server.signals = make(chan os.Signal, 1)
 	server.stopChan = make(chan bool, 1)
 	server.providers = []provider.Provider{}
+	server.providerConfigurations = make(map[provider.Provider][]byte)
 	signal.Notify(server.signals, syscall.SIGINT, syscall.SIGTERM)
+	server.configFreeze = newConfigFreeze(server.releaseFrozenConfigurations)
+	server.cutoverScheduler = newCutoverScheduler(server.reloadForCutover)
 	currentConfigurations := make(configs)
 	server.currentConfigurations.Set(currentConfigurations)
 	server.globalConfiguration = globalConfiguration
@@ -88,13 +115,60 @@ func NewServer(globalConfiguration GlobalConfiguration) *Server {
 		server.leadership = cluster.NewLeadership(server.routinesPool.Ctx(), globalConfiguration.Cluster)
 	}
 
+	if globalConfiguration.Locality != nil {
+		// the zone is otherwise fetched from the metadata URL once traefik is started
+		server.localZone = globalConfiguration.Locality.Zone
+	}
+
+	server.localRateLimitStore = middlewares.NewLocalTokenBucketStore()
+	server.drainStates = middlewares.NewDrainStates()
+	if globalConfiguration.SharedRateLimits {
+		if globalConfiguration.Cluster == nil || globalConfiguration.Cluster.Store == nil {
+			log.Warn("Shared rate limits require a cluster KV store, rate limits are applied per instance")
+		} else {
+			clusterStore := globalConfiguration.Cluster.Store
+			server.sharedRateLimitStore = middlewares.NewKVTokenBucketStore(clusterStore.Store, clusterStore.Prefix+"/ratelimits")
+		}
+	}
+
+	server.serversTransports = buildServersTransports(globalConfiguration.ServersTransports)
+
+	clientIPStrategy, err := middlewares.NewClientIPStrategy(globalConfiguration.ClientIP)
+	if err != nil {
+		log.Errorf("Error creating the client IP strategy, using the remote address of the requests: %s", err)
+		clientIPStrategy = middlewares.RemoteAddrStrategy{}
+	}
+	server.clientIPStrategy = clientIPStrategy
+
+	var buckets []float64
+	if prometheusEnabled(globalConfiguration) {
+		buckets = globalConfiguration.Web.Metrics.Prometheus.Buckets
+	}
+	server.metricsSettings = newMetricsSettings(buckets)
+
+	var reportStale func(providerName string, stale bool)
+	if prometheusEnabled(globalConfiguration) {
+		reportStale = reportProviderStale
+	}
+	server.staleProviders = newStaleProviders(time.Duration(globalConfiguration.ProviderStaleTTL), server.dropStaleProvider, reportStale)
+
+	if globalConfiguration.ConfigWebhook != nil && globalConfiguration.ConfigWebhook.URL != "" {
+		server.configWebhook = newConfigWebhookNotifier(globalConfiguration.ConfigWebhook)
+	}
+
+	if globalConfiguration.ConfigAuditLog != nil && globalConfiguration.ConfigAuditLog.FilePath != "" {
+		server.configAuditLog, err = newConfigAuditLog(globalConfiguration.ConfigAuditLog)
+		if err != nil {
+			log.Warnf("Unable to create configuration audit log: %s", err)
+		}
+	}
+
 	if globalConfiguration.AccessLogsFile != "" {
 		globalConfiguration.AccessLog = &types.AccessLog{FilePath: globalConfiguration.AccessLogsFile, Format: accesslog.CommonFormat}
 	}
 
 	if globalConfiguration.AccessLog != nil {
-		var err error
-		server.accessLoggerMiddleware, err = accesslog.NewLogHandler(globalConfiguration.AccessLog)
+		server.accessLoggerMiddleware, err = accesslog.NewLogHandler(globalConfiguration.AccessLog, server.clientIPStrategy)
 		if err != nil {
 			log.Warnf("Unable to create log handler: %s", err)
 		}
@@ -112,6 +186,33 @@ func (server *Server) Start() {
 	server.routinesPool.Go(func(stop chan bool) {
 		server.listenConfigurations(stop)
 	})
+	if server.configWebhook != nil {
+		server.routinesPool.Go(func(stop chan bool) {
+			server.configWebhook.run(stop)
+		})
+	}
+	if server.sharedRateLimitStore != nil {
+		server.routinesPool.Go(func(stop chan bool) {
+			server.sharedRateLimitStore.Run(stop)
+		})
+	}
+	if locality := server.globalConfiguration.Locality; locality != nil && locality.Zone == "" && locality.ZoneMetadataURL != "" {
+		server.routinesPool.Go(func(stop chan bool) {
+			server.resolveLocalZone(stop)
+		})
+	}
+	server.routinesPool.Go(func(stop chan bool) {
+		server.listenFreezeSignals(stop)
+	})
+	server.routinesPool.Go(func(stop chan bool) {
+		server.listenReloadSignals(stop)
+	})
+	server.routinesPool.Go(func(stop chan bool) {
+		server.monitorStaleProviders(stop)
+	})
+	server.routinesPool.Go(func(stop chan bool) {
+		server.reapIdleConnections(stop)
+	})
 	server.configureProviders()
 	server.startProviders()
 	go server.listenSignals()
@@ -158,6 +259,7 @@ func (server *Server) Close() {
 		}
 	}(ctx)
 	server.stopLeadership()
+	server.cutoverScheduler.stop()
 	server.routinesPool.Cleanup()
 	close(server.configurationChan)
 	close(server.configurationValidatedChan)
@@ -169,6 +271,11 @@ func (server *Server) Close() {
 			log.Errorf("Error closing access log file: %s", err)
 		}
 	}
+	if server.configAuditLog != nil {
+		if err := server.configAuditLog.Close(); err != nil {
+			log.Errorf("Error closing configuration audit log file: %s", err)
+		}
+	}
 	cancel()
 }
 
@@ -185,16 +292,18 @@ func (server *Server) stopLeadership() {
 }
 
 func (server *Server) startHTTPServers() {
+	server.serverEntryPointsLock.Lock()
 	server.serverEntryPoints = server.buildEntryPoints(server.globalConfiguration)
+	server.serverEntryPointsLock.Unlock()
 
 	for newServerEntryPointName, newServerEntryPoint := range server.serverEntryPoints {
 		serverEntryPoint := server.setupServerEntryPoint(newServerEntryPointName, newServerEntryPoint)
-		go server.startServer(serverEntryPoint.httpServer, server.globalConfiguration)
+		go server.startServer(serverEntryPoint.httpServer, server.globalConfiguration.EntryPoints[newServerEntryPointName])
 	}
 }
 
 func (server *Server) setupServerEntryPoint(newServerEntryPointName string, newServerEntryPoint *serverEntryPoint) *serverEntryPoint {
-	serverMiddlewares := []negroni.Handler{middlewares.NegroniRecoverHandler(), metrics}
+	serverMiddlewares := []negroni.Handler{newServerEntryPoint.connectionTracker, middlewares.NegroniRecoverHandler(), metrics}
 	if server.accessLoggerMiddleware != nil {
 		serverMiddlewares = append(serverMiddlewares, server.accessLoggerMiddleware)
 	}
@@ -217,7 +326,7 @@ func (server *Server) setupServerEntryPoint(newServerEntryPointName string, newS
 		serverMiddlewares = append(serverMiddlewares, &middlewares.Compress{})
 	}
 	if len(server.globalConfiguration.EntryPoints[newServerEntryPointName].WhitelistSourceRange) > 0 {
-		ipWhitelistMiddleware, err := middlewares.NewIPWhitelister(server.globalConfiguration.EntryPoints[newServerEntryPointName].WhitelistSourceRange)
+		ipWhitelistMiddleware, err := middlewares.NewIPWhitelister(server.globalConfiguration.EntryPoints[newServerEntryPointName].WhitelistSourceRange, server.clientIPStrategy)
 		if err != nil {
 			log.Fatal("Error starting server: ", err)
 		}
@@ -227,6 +336,19 @@ func (server *Server) setupServerEntryPoint(newServerEntryPointName string, newS
 	if err != nil {
 		log.Fatal("Error preparing server: ", err)
 	}
+	if entryPoint := server.globalConfiguration.EntryPoints[newServerEntryPointName]; entryPoint.EarlyHints != "" || entryPoint.Push {
+		// outermost, the response writers of the negroni middlewares hiding http.Pusher
+		newsrv.Handler = middlewares.NewResponseHints(newsrv.Handler, entryPoint.EarlyHints, entryPoint.Push)
+	}
+	newsrv.ConnState = newServerEntryPoint.connectionTracker.ConnState
+	if recycling := server.globalConfiguration.EntryPoints[newServerEntryPointName].ConnectionRecycling; recycling != nil {
+		recycler := newConnectionRecycler(recycling)
+		newServerEntryPoint.connectionRecycler = recycler
+		newsrv.ConnState = func(conn net.Conn, state http.ConnState) {
+			newServerEntryPoint.connectionTracker.ConnState(conn, state)
+			recycler.ConnState(conn, state)
+		}
+	}
 	serverEntryPoint := server.serverEntryPoints[newServerEntryPointName]
 	serverEntryPoint.httpServer = newsrv
 
@@ -296,6 +418,9 @@ func (server *Server) listenConfigurations(stop chan bool) {
 			if !ok {
 				return
 			}
+			if server.configFreeze.hold(configMsg) {
+				continue
+			}
 			currentConfigurations := server.currentConfigurations.Get().(configs)
 
 			// Copy configurations to new map so we don't change current if LoadConfig fails
@@ -307,12 +432,18 @@ func (server *Server) listenConfigurations(stop chan bool) {
 
 			newServerEntryPoints, err := server.loadConfig(newConfigurations, server.globalConfiguration)
 			if err == nil {
+				server.serverEntryPointsLock.RLock()
 				for newServerEntryPointName, newServerEntryPoint := range newServerEntryPoints {
 					server.serverEntryPoints[newServerEntryPointName].httpRouter.UpdateHandler(newServerEntryPoint.httpRouter.GetHandler())
 					log.Infof("Server configuration reloaded on %s", server.serverEntryPoints[newServerEntryPointName].httpServer.Addr)
 				}
+				server.serverEntryPointsLock.RUnlock()
 				server.currentConfigurations.Set(newConfigurations)
+				server.setIdleTimeouts(newConfigurations)
+				server.cutoverScheduler.schedule(newConfigurations, time.Now())
+				server.recycleConnections(currentConfigurations[configMsg.ProviderName], configMsg.Configuration)
 				server.postLoadConfig()
+				server.recordConfigChange(configMsg, currentConfigurations[configMsg.ProviderName])
 			} else {
 				log.Error("Error loading new configuration, aborted ", err)
 			}
@@ -320,6 +451,38 @@ func (server *Server) listenConfigurations(stop chan bool) {
 	}
 }
 
+// recordConfigChange logs the changes of an applied provider configuration, and reports them to the
+// configuration audit log and webhook
+func (server *Server) recordConfigChange(configMsg types.ConfigMessage, oldConfiguration *types.Configuration) {
+	includeDiff := server.configWebhook != nil && server.globalConfiguration.ConfigWebhook.IncludeDiff ||
+		server.configAuditLog != nil && server.globalConfiguration.ConfigAuditLog.IncludeDiff
+	change := newConfigChange(configMsg.ProviderName, oldConfiguration, configMsg.Configuration, includeDiff)
+	change.Causes = configMsg.Causes
+	if change.isEmpty() {
+		return
+	}
+
+	summary := change
+	summary.Diff = nil
+	if jsonSummary, err := json.Marshal(summary); err == nil {
+		log.Debugf("Configuration change applied: %s", jsonSummary)
+	}
+	for _, churn := range serverChurnSummaries(change, oldConfiguration, configMsg.Configuration, configMsg.ServerReasons) {
+		log.Infof("Servers changed on %s", churn)
+	}
+	if server.configAuditLog != nil {
+		if err := server.configAuditLog.record(change); err != nil {
+			log.Errorf("Error writing configuration change to the audit log: %s", err)
+		}
+	}
+	if server.configWebhook != nil {
+		if !server.globalConfiguration.ConfigWebhook.IncludeDiff {
+			change.Diff = nil
+		}
+		server.configWebhook.notify(change)
+	}
+}
+
 func (server *Server) postLoadConfig() {
 	if server.globalConfiguration.ACME == nil {
 		return
@@ -365,6 +528,9 @@ func (server *Server) configureProviders() {
 	}
 	if server.globalConfiguration.Marathon != nil {
 		server.providers = append(server.providers, server.globalConfiguration.Marathon)
+		server.providersLock.Lock()
+		server.marathonProvider = server.globalConfiguration.Marathon
+		server.providersLock.Unlock()
 	}
 	if server.globalConfiguration.File != nil {
 		server.providers = append(server.providers, server.globalConfiguration.File)
@@ -408,19 +574,33 @@ func (server *Server) configureProviders() {
 	}
 }
 
+// providersSyncStatus returns the synchronization status of the providers reporting it, by provider name
+func (server *Server) providersSyncStatus() map[string]provider.SyncStatus {
+	server.providersLock.Lock()
+	defer server.providersLock.Unlock()
+	var statuses map[string]provider.SyncStatus
+	for _, p := range server.providers {
+		reporter, ok := p.(provider.SyncStatusReporter)
+		if !ok {
+			continue
+		}
+		if statuses == nil {
+			statuses = make(map[string]provider.SyncStatus)
+		}
+		status := reporter.SyncStatus()
+		status.RoutesDropped = server.staleProviders.isDropped(status.Name)
+		statuses[status.Name] = status
+	}
+	return statuses
+}
+
 func (server *Server) startProviders() {
 	// start providers
 	for _, provider := range server.providers {
 		providerType := reflect.TypeOf(provider)
 		jsonConf, _ := json.Marshal(provider)
 		log.Infof("Starting provider %v %s", providerType, jsonConf)
-		currentProvider := provider
-		safe.Go(func() {
-			err := currentProvider.Provide(server.configurationChan, server.routinesPool, server.globalConfiguration.Constraints)
-			if err != nil {
-				log.Errorf("Error starting provider %v: %s", providerType, err)
-			}
-		})
+		server.startProvider(provider)
 	}
 }
 
@@ -541,18 +721,32 @@ func (server *Server) createTLSConfig(entryPointName string, tlsOption *TLS, rou
 		}
 	}
 
+	if tlsOption.SNIStrict {
+		enableStrictSNI(config)
+	}
+
+	if tlsOption.SessionTicketsRotation > 0 || tlsOption.SharedSessionTickets {
+		server.startSessionTicketKeyRotation(entryPointName, tlsOption, config)
+	}
+
 	return config, nil
 }
 
-func (server *Server) startServer(srv *http.Server, globalConfiguration GlobalConfiguration) {
+func (server *Server) startServer(srv *http.Server, entryPoint *EntryPoint) {
 	log.Infof("Starting server on %s", srv.Addr)
 	var err error
-	if srv.TLSConfig != nil {
+	switch {
+	case srv.TLSConfig != nil && entryPoint.DetectProtocol:
+		err = serveDetectingProtocol(srv)
+	case srv.TLSConfig != nil:
 		err = srv.ListenAndServeTLS("", "")
-	} else {
+	default:
+		if entryPoint.DetectProtocol {
+			log.Warnf("Protocol detection requires TLS on the entrypoint, serving plain HTTP only on %s", srv.Addr)
+		}
 		err = srv.ListenAndServe()
 	}
-	if err != nil {
+	if err != nil && err != http.ErrServerClosed {
 		log.Error("Error creating server: ", err)
 	}
 }
@@ -584,7 +778,8 @@ func (server *Server) buildEntryPoints(globalConfiguration GlobalConfiguration)
 	for entryPointName := range globalConfiguration.EntryPoints {
 		router := server.buildDefaultHTTPRouter()
 		serverEntryPoints[entryPointName] = &serverEntryPoint{
-			httpRouter: middlewares.NewHandlerSwitcher(router),
+			httpRouter:        middlewares.NewHandlerSwitcher(router),
+			connectionTracker: newConnectionTracker(),
 		}
 	}
 	return serverEntryPoints
@@ -609,12 +804,30 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 	backends := map[string]http.Handler{}
 	backendsHealthcheck := map[string]*healthcheck.BackendHealthCheck{}
 	errorHandler := NewRecordingErrorHandler(middlewares.DefaultNetErrorRecorder{})
+	frontendHandlers := make(map[string]map[string]http.Handler)
+	for entryPointName := range serverEntryPoints {
+		frontendHandlers[entryPointName] = make(map[string]http.Handler)
+	}
 
 	for _, configuration := range configurations {
 		frontendNames := sortedFrontendNamesForConfig(configuration)
 	frontend:
 		for _, frontendName := range frontendNames {
-			frontend := configuration.Frontends[frontendName]
+			frontend, err := server.applyFrontendProfile(configuration.Frontends[frontendName])
+			if err != nil {
+				log.Errorf("Error applying the profile of frontend %s: %v", frontendName, err)
+				log.Errorf("Skipping frontend %s...", frontendName)
+				continue frontend
+			}
+			if frontend.Cutover != nil {
+				frontend, err = applyFrontendCutover(frontend, time.Now())
+				if err != nil {
+					log.Errorf("Error applying the cutover of frontend %s: %v", frontendName, err)
+					log.Errorf("Skipping frontend %s...", frontendName)
+					continue frontend
+				}
+				log.Debugf("Routing frontend %s to backend %s", frontendName, frontend.Backend)
+			}
 
 			log.Debugf("Creating frontend %s", frontendName)
 
@@ -670,6 +883,7 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 						tlsConfig *tls.Config
 						err       error
 						lb        http.Handler
+						rt        http.RoundTripper
 					)
 
 					if frontend.PassTLSCert {
@@ -678,12 +892,17 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 							log.Errorf("Failed to create TLS config for frontend %s: %v", frontendName, err)
 							continue frontend
 						}
+						rt = clientTLSRoundTripper(tlsConfig)
+					} else {
+						rt, err = server.getServersTransport(configuration.Backends[frontend.Backend])
+						if err != nil {
+							log.Errorf("Error selecting servers transport for frontend %s: %v", frontendName, err)
+							log.Errorf("Skipping frontend %s...", frontendName)
+							continue frontend
+						}
 					}
 
-					// passing nil will use the roundtripper http.DefaultTransport
-					rt := clientTLSRoundTripper(tlsConfig)
-
-					fwd, err := forward.New(
+					forwarder, err := forward.New(
 						forward.Logger(oxyLogger),
 						forward.PassHostHeader(frontend.PassHostHeader),
 						forward.RoundTripper(rt),
@@ -694,6 +913,23 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 						log.Errorf("Skipping frontend %s...", frontendName)
 						continue frontend
 					}
+					var fwd http.Handler = middlewares.NewDeadlinePropagator(forwarder)
+
+					var drainer *middlewares.Drainer
+					if backend := configuration.Backends[frontend.Backend]; backend != nil && backend.Drain != nil {
+						drainDuration := DefaultDrainDuration
+						if len(backend.Drain.Duration) > 0 {
+							drainDuration, err = time.ParseDuration(backend.Drain.Duration)
+							if err != nil || drainDuration <= 0 {
+								log.Errorf("Invalid drain duration %q for backend %s", backend.Drain.Duration, frontend.Backend)
+								log.Errorf("Skipping frontend %s...", frontendName)
+								continue frontend
+							}
+						}
+						log.Debugf("Letting the servers of backend %s drain themselves for %s", frontend.Backend, drainDuration)
+						drainer = middlewares.NewDrainer(fwd, drainDuration, frontend.Backend, backend.Servers, server.drainStates)
+						fwd = drainer
+					}
 
 					var rr *roundrobin.RoundRobin
 					var saveFrontend http.Handler
@@ -734,12 +970,40 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 							log.Debugf("Sticky session with cookie %v", cookiename)
 							rebalancer, _ = roundrobin.NewRebalancer(rr, roundrobin.RebalancerLogger(oxyLogger), roundrobin.RebalancerStickySession(sticky))
 						}
-						lb = rebalancer
-						if err := configureLBServers(rebalancer, configuration, frontend); err != nil {
+						pool := server.newLocalityPool(rebalancer, configuration.Backends[frontend.Backend], sticky, func() middlewares.LocalityPool {
+							remoteRR, _ := roundrobin.New(rr.Next())
+							remoteRebalancer, _ := roundrobin.NewRebalancer(remoteRR, roundrobin.RebalancerLogger(oxyLogger))
+							if stickysession {
+								remoteRebalancer, _ = roundrobin.NewRebalancer(remoteRR, roundrobin.RebalancerLogger(oxyLogger), roundrobin.RebalancerStickySession(sticky))
+							}
+							return remoteRebalancer
+						})
+						lb = pool
+						if err := configureLBServers(pool, configuration, frontend); err != nil {
+							log.Errorf("Skipping frontend %s...", frontendName)
+							continue frontend
+						}
+						hcOpts := parseHealthCheckOptions(pool, frontend.Backend, configuration.Backends[frontend.Backend].HealthCheck, globalConfiguration.HealthCheck)
+						if hcOpts != nil {
+							log.Debugf("Setting up backend health check %s", *hcOpts)
+							backendsHealthcheck[frontend.Backend] = healthcheck.NewBackendHealthCheck(*hcOpts)
+						}
+					case types.Hash:
+						log.Debugf("Creating load-balancer hash")
+						if stickysession {
+							log.Debugf("Ignoring sticky session with consistent hashing, which keeps clients on the same server")
+						}
+						next := rr.Next()
+						backendServers := configuration.Backends[frontend.Backend].Servers
+						pool := server.newLocalityPool(middlewares.NewHashBalancer(next, server.clientIPStrategy, backendServers), configuration.Backends[frontend.Backend], nil, func() middlewares.LocalityPool {
+							return middlewares.NewHashBalancer(next, server.clientIPStrategy, backendServers)
+						})
+						lb = pool
+						if err := configureLBServers(pool, configuration, frontend); err != nil {
 							log.Errorf("Skipping frontend %s...", frontendName)
 							continue frontend
 						}
-						hcOpts := parseHealthCheckOptions(rebalancer, frontend.Backend, configuration.Backends[frontend.Backend].HealthCheck, globalConfiguration.HealthCheck)
+						hcOpts := parseHealthCheckOptions(pool, frontend.Backend, configuration.Backends[frontend.Backend].HealthCheck, globalConfiguration.HealthCheck)
 						if hcOpts != nil {
 							log.Debugf("Setting up backend health check %s", *hcOpts)
 							backendsHealthcheck[frontend.Backend] = healthcheck.NewBackendHealthCheck(*hcOpts)
@@ -754,18 +1018,29 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 								rr, _ = roundrobin.New(fwd, roundrobin.EnableStickySession(sticky))
 							}
 						}
-						lb = rr
-						if err := configureLBServers(rr, configuration, frontend); err != nil {
+						pool := server.newLocalityPool(rr, configuration.Backends[frontend.Backend], sticky, func() middlewares.LocalityPool {
+							remoteRR, _ := roundrobin.New(rr.Next())
+							if stickysession {
+								remoteRR, _ = roundrobin.New(rr.Next(), roundrobin.EnableStickySession(sticky))
+							}
+							return remoteRR
+						})
+						lb = pool
+						if err := configureLBServers(pool, configuration, frontend); err != nil {
 							log.Errorf("Skipping frontend %s...", frontendName)
 							continue frontend
 						}
-						hcOpts := parseHealthCheckOptions(rr, frontend.Backend, configuration.Backends[frontend.Backend].HealthCheck, globalConfiguration.HealthCheck)
+						hcOpts := parseHealthCheckOptions(pool, frontend.Backend, configuration.Backends[frontend.Backend].HealthCheck, globalConfiguration.HealthCheck)
 						if hcOpts != nil {
 							log.Debugf("Setting up backend health check %s", *hcOpts)
 							backendsHealthcheck[frontend.Backend] = healthcheck.NewBackendHealthCheck(*hcOpts)
 						}
 					}
 
+					if pool, ok := lb.(middlewares.LocalityPool); ok && drainer != nil {
+						drainer.SetPool(pool)
+					}
+
 					if len(frontend.Errors) > 0 {
 						for _, errorPage := range frontend.Errors {
 							if configuration.Backends[errorPage.Backend] != nil && configuration.Backends[errorPage.Backend].Servers["error"].URL != "" {
@@ -783,7 +1058,7 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 
 					maxConns := configuration.Backends[frontend.Backend].MaxConn
 					if maxConns != nil && maxConns.Amount != 0 {
-						extractFunc, err := utils.NewExtractor(maxConns.ExtractorFunc)
+						extractFunc, err := middlewares.NewSourceExtractor(maxConns.ExtractorFunc, server.clientIPStrategy)
 						if err != nil {
 							log.Errorf("Error creating connlimit: %v", err)
 							log.Errorf("Skipping frontend %s...", frontendName)
@@ -798,17 +1073,40 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 						}
 					}
 
+					if priorityQueue := configuration.Backends[frontend.Backend].PriorityQueue; priorityQueue != nil {
+						queue, err := middlewares.NewPriorityQueue(lb, priorityQueue)
+						if err != nil {
+							log.Errorf("Error creating priority queue for backend %s: %v", frontend.Backend, err)
+							log.Errorf("Skipping frontend %s...", frontendName)
+							continue frontend
+						}
+						log.Debugf("Queueing the requests of backend %s beyond %d requests in flight", frontend.Backend, priorityQueue.MaxConcurrency)
+						lb = queue
+					}
+
 					metrics := newMetrics(server.globalConfiguration, frontend.Backend)
 
 					if globalConfiguration.Retry != nil {
 						retryListener := middlewares.NewMetricsRetryListener(metrics)
 						lb = registerRetryMiddleware(lb, globalConfiguration, configuration, frontend.Backend, retryListener)
 					}
+
+					if grpcTranscoding := configuration.Backends[frontend.Backend].GRPCTranscoding; grpcTranscoding != nil {
+						transcoder, err := middlewares.NewGRPCTranscoder(lb, grpcTranscoding)
+						if err != nil {
+							log.Errorf("Error creating gRPC transcoder for backend %s: %v", frontend.Backend, err)
+							log.Errorf("Skipping frontend %s...", frontendName)
+							continue frontend
+						}
+						lb = transcoder
+					}
 					if metrics != nil {
-						negroni.Use(middlewares.NewMetricsWrapper(metrics))
+						metricsWrapper := middlewares.NewMetricsWrapper(metrics)
+						metricsWrapper.Enabled = server.metricsSettings.backendEnabled(frontend.Backend)
+						negroni.Use(metricsWrapper)
 					}
 
-					ipWhitelistMiddleware, err := configureIPWhitelistMiddleware(frontend.WhitelistSourceRange)
+					ipWhitelistMiddleware, err := configureIPWhitelistMiddleware(frontend.WhitelistSourceRange, server.clientIPStrategy)
 					if err != nil {
 						log.Fatalf("Error creating IP Whitelister: %s", err)
 					} else if ipWhitelistMiddleware != nil {
@@ -816,6 +1114,17 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 						log.Infof("Configured IP Whitelists: %s", frontend.WhitelistSourceRange)
 					}
 
+					if len(frontend.AllowedUpgrades) > 0 {
+						upgradeFilter, err := middlewares.NewUpgradeFilter(frontend.AllowedUpgrades)
+						if err != nil {
+							log.Errorf("Error creating upgrade filter for frontend %s: %v", frontendName, err)
+							log.Errorf("Skipping frontend %s...", frontendName)
+							continue frontend
+						}
+						log.Debugf("Allowing upgrades %s for frontend %s", frontend.AllowedUpgrades, frontendName)
+						negroni.Use(upgradeFilter)
+					}
+
 					if len(frontend.BasicAuth) > 0 {
 						users := types.Users{}
 						for _, user := range frontend.BasicAuth {
@@ -830,8 +1139,29 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 						if err != nil {
 							log.Errorf("Error creating Auth: %s", err)
 						} else {
-							negroni.Use(authMiddleware)
+							negroni.Use(withAuthBypass(frontendName, frontend, authMiddleware))
+						}
+					}
+
+					if frontend.Auth != nil {
+						authMiddleware, err := middlewares.NewAuthenticator(frontend.Auth)
+						if err != nil {
+							log.Errorf("Error creating authenticator for frontend %s: %v", frontendName, err)
+							log.Errorf("Skipping frontend %s...", frontendName)
+							continue frontend
+						}
+						negroni.Use(withAuthBypass(frontendName, frontend, authMiddleware))
+					}
+
+					if frontend.RateLimit != nil {
+						rateLimiter, err := server.newRateLimiter(frontendName, frontend.RateLimit)
+						if err != nil {
+							log.Errorf("Error creating rate limiter for frontend %s: %v", frontendName, err)
+							log.Errorf("Skipping frontend %s...", frontendName)
+							continue frontend
 						}
+						log.Debugf("Limiting frontend %s to %d requests per second", frontendName, frontend.RateLimit.Average)
+						negroni.Use(rateLimiter)
 					}
 
 					if frontend.Headers.HasCustomHeadersDefined() {
@@ -864,7 +1194,64 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 				if frontend.Priority > 0 {
 					newServerRoute.route.Priority(frontend.Priority)
 				}
-				server.wireFrontendBackend(newServerRoute, backends[entryPointName+frontend.Backend])
+				handler := backends[entryPointName+frontend.Backend]
+				if frontend.RequestPriority != "" {
+					requestPriority, err := middlewares.NewRequestPriority(handler, frontend.RequestPriority)
+					if err != nil {
+						log.Errorf("Error setting the request priority of frontend %s: %v", frontendName, err)
+						log.Errorf("Skipping frontend %s...", frontendName)
+						continue frontend
+					}
+					handler = requestPriority
+				}
+				if prometheusEnabled(globalConfiguration) {
+					handler = server.metricsSettings.withFrontendMetricsSwitch(frontendName, handler)
+				}
+				if frontend.Faults != nil {
+					faultInjector, err := middlewares.NewFaultInjector(handler, frontend.Faults)
+					if err != nil {
+						log.Errorf("Error creating fault injector for frontend %s: %v", frontendName, err)
+						log.Errorf("Skipping frontend %s...", frontendName)
+						continue frontend
+					}
+					log.Warnf("Injecting faults into the requests of frontend %s", frontendName)
+					handler = faultInjector
+				}
+				if len(frontend.Timeout) > 0 {
+					timeout, err := time.ParseDuration(frontend.Timeout)
+					if err != nil || timeout <= 0 {
+						log.Errorf("Invalid timeout %q for frontend %s", frontend.Timeout, frontendName)
+						log.Errorf("Skipping frontend %s...", frontendName)
+						continue frontend
+					}
+					log.Debugf("Limiting the requests of frontend %s to %s", frontendName, timeout)
+					handler = middlewares.NewDeadline(handler, timeout)
+				}
+				if server.accessLoggerMiddleware != nil && frontend.AccessLog != nil {
+					handler = accesslog.NewSaveFrontendLogOptions(handler, frontend.AccessLog)
+				}
+				if frontend.PathParams != nil {
+					handler = middlewares.NewPathParams(handler, frontend.PathParams)
+				}
+				var idleTimeout time.Duration
+				if len(frontend.IdleTimeout) > 0 {
+					timeout, err := time.ParseDuration(frontend.IdleTimeout)
+					if err != nil || timeout <= 0 {
+						log.Errorf("Invalid idle timeout %q for frontend %s", frontend.IdleTimeout, frontendName)
+						log.Errorf("Skipping frontend %s...", frontendName)
+						continue frontend
+					}
+					log.Debugf("Closing the connections of frontend %s idle for %s", frontendName, timeout)
+					idleTimeout = timeout
+				}
+				if serverEntryPoint, ok := server.serverEntryPoints[entryPointName]; ok {
+					if serverEntryPoint.connectionRecycler != nil {
+						handler = serverEntryPoint.connectionRecycler.wrap(frontendName, handler)
+					}
+					handler = serverEntryPoint.connectionTracker.wrap(frontendName, idleTimeout, handler)
+				}
+				server.wireFrontendBackend(newServerRoute, handler)
+				frontendHandlers[entryPointName][frontendName] = newServerRoute.route.GetHandler()
 
 				err := newServerRoute.route.GetError()
 				if err != nil {
@@ -873,11 +1260,27 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 			}
 		}
 	}
-	healthcheck.GetHealthCheck().SetBackendsConfiguration(server.routinesPool.Ctx(), backendsHealthcheck)
 	//sort routes
-	for _, serverEntryPoint := range serverEntryPoints {
+	for entryPointName, serverEntryPoint := range serverEntryPoints {
 		serverEntryPoint.httpRouter.GetHandler().SortRoutes()
+
+		notFound := globalConfiguration.EntryPoints[entryPointName].NotFound
+		handler, err := buildNotFoundHandler(notFound, frontendHandlers[entryPointName])
+		if err != nil {
+			log.Errorf("Error creating not found handler of entrypoint %s, using 404: %s", entryPointName, err)
+			handler = http.HandlerFunc(notFoundHandler)
+		}
+		if tlsOption := globalConfiguration.EntryPoints[entryPointName].TLS; tlsOption != nil && len(tlsOption.DefaultBackend) > 0 {
+			defaultBackendHandler, err := server.buildDefaultBackendHandler(configurations, entryPointName, tlsOption.DefaultBackend, globalConfiguration, errorHandler, backendsHealthcheck)
+			if err != nil {
+				log.Errorf("Error creating default backend of entrypoint %s: %s", entryPointName, err)
+			} else {
+				handler = withDefaultBackend(defaultBackendHandler, handler)
+			}
+		}
+		serverEntryPoint.httpRouter.GetHandler().NotFoundHandler = handler
 	}
+	healthcheck.GetHealthCheck().SetBackendsConfiguration(server.routinesPool.Ctx(), backendsHealthcheck)
 	return serverEntryPoints, nil
 }
 
@@ -897,10 +1300,10 @@ func configureLBServers(lb healthcheck.LoadBalancer, config *types.Configuration
 	return nil
 }
 
-func configureIPWhitelistMiddleware(whitelistSourceRanges []string) (negroni.Handler, error) {
+func configureIPWhitelistMiddleware(whitelistSourceRanges []string, clientIPStrategy middlewares.ClientIPStrategy) (negroni.Handler, error) {
 	if len(whitelistSourceRanges) > 0 {
 		ipSourceRanges := whitelistSourceRanges
-		ipWhitelistMiddleware, err := middlewares.NewIPWhitelister(ipSourceRanges)
+		ipWhitelistMiddleware, err := middlewares.NewIPWhitelister(ipSourceRanges, clientIPStrategy)
 
 		if err != nil {
 			return nil, err
@@ -912,6 +1315,25 @@ func configureIPWhitelistMiddleware(whitelistSourceRanges []string) (negroni.Han
 	return nil, nil
 }
 
+// withAuthBypass lets the requests matching the frontend authentication bypass
+// rules skip the given authentication middleware
+func withAuthBypass(frontendName string, frontend *types.Frontend, authMiddleware negroni.Handler) negroni.Handler {
+	if frontend.AuthBypass == nil {
+		return authMiddleware
+	}
+	log.Debugf("Bypassing authentication for frontend %s on %+v", frontendName, *frontend.AuthBypass)
+	return middlewares.NewAuthBypass(authMiddleware, frontend.AuthBypass)
+}
+
+// newRateLimiter creates the rate limiter of a frontend. Buckets live in the
+// cluster KV store when rate limits are shared, with a local fallback.
+func (server *Server) newRateLimiter(frontendName string, rateLimit *types.RateLimit) (*middlewares.RateLimiter, error) {
+	if server.sharedRateLimitStore != nil {
+		return middlewares.NewRateLimiter(frontendName, rateLimit, server.sharedRateLimitStore, server.localRateLimitStore, server.clientIPStrategy)
+	}
+	return middlewares.NewRateLimiter(frontendName, rateLimit, server.localRateLimitStore, nil, server.clientIPStrategy)
+}
+
 func (server *Server) wireFrontendBackend(serverRoute *serverRoute, handler http.Handler) {
 	// path replace - This needs to always be the very last on the handler chain (first in the order in this function)
 	// -- Replacing Path should happen at the very end of the Modifier chain, after all the Matcher+Modifiers ran
@@ -1002,9 +1424,13 @@ func parseHealthCheckOptions(lb healthcheck.LoadBalancer, backend string, hc *ty
 	}
 
 	return &healthcheck.Options{
-		Path:     hc.Path,
-		Interval: interval,
-		LB:       lb,
+		Path:               hc.Path,
+		Interval:           interval,
+		HealthyThreshold:   hcConfig.HealthyThreshold,
+		UnhealthyThreshold: hcConfig.UnhealthyThreshold,
+		MaxFlaps:           hcConfig.MaxFlaps,
+		FlapWindow:         time.Duration(hcConfig.FlapWindow),
+		LB:                 lb,
 	}
 }
 
@@ -1057,8 +1483,7 @@ func (*Server) configureBackends(backends map[string]*types.Backend) {
 // newMetrics instantiates the proper Metrics implementation, depending on the global configuration.
 // Note that given there is no metrics instrumentation configured, it will return nil.
 func newMetrics(globalConfig GlobalConfiguration, name string) middlewares.Metrics {
-	metricsEnabled := globalConfig.Web != nil && globalConfig.Web.Metrics != nil
-	if metricsEnabled && globalConfig.Web.Metrics.Prometheus != nil {
+	if prometheusEnabled(globalConfig) {
 		metrics, _, err := middlewares.NewPrometheus(name, globalConfig.Web.Metrics.Prometheus)
 		if err != nil {
 			log.Errorf("Error creating Prometheus Metrics implementation: %s", err)
@@ -1070,6 +1495,10 @@ func newMetrics(globalConfig GlobalConfiguration, name string) middlewares.Metri
 	return nil
 }
 
+func prometheusEnabled(globalConfig GlobalConfiguration) bool {
+	return globalConfig.Web != nil && globalConfig.Web.Metrics != nil && globalConfig.Web.Metrics.Prometheus != nil
+}
+
 func registerRetryMiddleware(
 	httpHandler http.Handler,
 	globalConfig GlobalConfiguration,
@@ -1082,8 +1511,13 @@ func registerRetryMiddleware(
 		retries = globalConfig.Retry.Attempts
 	}
 
-	httpHandler = middlewares.NewRetry(retries, httpHandler, listener)
+	retry := middlewares.NewRetry(retries, httpHandler, listener)
 	log.Debugf("Creating retries max attempts %d", retries)
+	if len(globalConfig.Retry.RangeRequests) > 0 || globalConfig.Retry.MaxSynthesizedRangeSize > 0 {
+		if err := retry.SetRangeRequests(globalConfig.Retry.RangeRequests, globalConfig.Retry.MaxSynthesizedRangeSize); err != nil {
+			log.Errorf("Error configuring the range requests of backend %s, buffering them: %v", backend, err)
+		}
+	}
 
-	return httpHandler
+	return retry
 }
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/containous/traefik/types"
+)
+
+// maxServerChurnDetails is the maximum number of servers detailed in the summary of the server changes of a backend
+const maxServerChurnDetails = 5
+
+// serverChurnSummaries returns a concise summary of the server changes of every changed backend, e.g.
+// "backend-payments: +2 servers, -1 server (10.0.3.4:31002 removed: task TASK_KILLED)".
+// The removed servers are detailed, the added and modified ones only when the reason of their change is known.
+func serverChurnSummaries(change configChange, oldConfig, newConfig *types.Configuration, reasons map[string]string) []string {
+	var summaries []string
+	for _, backendName := range mapKeys(change.Servers) {
+		servers := change.Servers[backendName]
+		oldServers := backendServers(oldConfig, backendName)
+		newServers := backendServers(newConfig, backendName)
+
+		var counts, details []string
+		detail := func(name, verb string, servers map[string]types.Server, always bool) {
+			reason, ok := reasons[name]
+			if !ok && !always {
+				return
+			}
+			description := serverAddress(name, servers) + " " + verb
+			if ok {
+				description += ": " + reason
+			}
+			details = append(details, description)
+		}
+		if len(servers.Added) > 0 {
+			counts = append(counts, "+"+pluralServers(len(servers.Added)))
+			for _, name := range servers.Added {
+				detail(name, "added", newServers, false)
+			}
+		}
+		if len(servers.Removed) > 0 {
+			counts = append(counts, "-"+pluralServers(len(servers.Removed)))
+			for _, name := range servers.Removed {
+				detail(name, "removed", oldServers, true)
+			}
+		}
+		if len(servers.Modified) > 0 {
+			counts = append(counts, "~"+pluralServers(len(servers.Modified)))
+			for _, name := range servers.Modified {
+				detail(name, "modified", newServers, false)
+			}
+		}
+
+		summary := backendName + ": " + strings.Join(counts, ", ")
+		if len(details) > maxServerChurnDetails {
+			details = append(details[:maxServerChurnDetails], fmt.Sprintf("%d more", len(details)-maxServerChurnDetails))
+		}
+		if len(details) > 0 {
+			summary += " (" + strings.Join(details, ", ") + ")"
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+func backendServers(configuration *types.Configuration, backendName string) map[string]types.Server {
+	if configuration == nil || configuration.Backends[backendName] == nil {
+		return nil
+	}
+	return configuration.Backends[backendName].Servers
+}
+
+// serverAddress returns the host and port of the named server, or its name if unknown
+func serverAddress(name string, servers map[string]types.Server) string {
+	server, ok := servers[name]
+	if !ok {
+		return name
+	}
+	serverURL, err := url.Parse(server.URL)
+	if err != nil || len(serverURL.Host) == 0 {
+		return name
+	}
+	return serverURL.Host
+}
+
+func pluralServers(count int) string {
+	if count == 1 {
+		return "1 server"
+	}
+	return fmt.Sprintf("%d servers", count)
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerChurnSummaries(t *testing.T) {
+	backend := func(servers map[string]types.Server) *types.Configuration {
+		return &types.Configuration{Backends: map[string]*types.Backend{"backend-payments": {Servers: servers}}}
+	}
+
+	testCases := []struct {
+		desc      string
+		oldConfig *types.Configuration
+		newConfig *types.Configuration
+		reasons   map[string]string
+		expected  []string
+	}{
+		{
+			desc: "added and removed servers with reasons",
+			oldConfig: backend(map[string]types.Server{
+				"server-task1": {URL: "http://10.0.3.4:31002"},
+				"server-task2": {URL: "http://10.0.3.5:31002"},
+			}),
+			newConfig: backend(map[string]types.Server{
+				"server-task2": {URL: "http://10.0.3.5:31002"},
+				"server-task3": {URL: "http://10.0.3.6:31002"},
+				"server-task4": {URL: "http://10.0.3.7:31002"},
+			}),
+			reasons:  map[string]string{"server-task1": "task TASK_KILLED"},
+			expected: []string{"backend-payments: +2 servers, -1 server (10.0.3.4:31002 removed: task TASK_KILLED)"},
+		},
+		{
+			desc:      "new backend",
+			newConfig: backend(map[string]types.Server{"server-task1": {URL: "http://10.0.3.4:31002"}}),
+			reasons:   map[string]string{"server-task1": "task TASK_RUNNING"},
+			expected:  []string{"backend-payments: +1 server (10.0.3.4:31002 added: task TASK_RUNNING)"},
+		},
+		{
+			desc:      "removed servers without reasons",
+			oldConfig: backend(map[string]types.Server{"server-task1": {URL: "http://10.0.3.4:31002"}}),
+			newConfig: backend(map[string]types.Server{}),
+			expected:  []string{"backend-payments: -1 server (10.0.3.4:31002 removed)"},
+		},
+		{
+			desc:      "modified server",
+			oldConfig: backend(map[string]types.Server{"server-task1": {URL: "http://10.0.3.4:31002", Weight: 1}}),
+			newConfig: backend(map[string]types.Server{"server-task1": {URL: "http://10.0.3.4:31002", Weight: 0}}),
+			reasons:   map[string]string{"server-task1": "task unhealthy"},
+			expected:  []string{"backend-payments: ~1 server (10.0.3.4:31002 modified: task unhealthy)"},
+		},
+		{
+			desc: "many removed servers",
+			oldConfig: backend(map[string]types.Server{
+				"server-1": {URL: "http://10.0.0.1:80"},
+				"server-2": {URL: "http://10.0.0.2:80"},
+				"server-3": {URL: "http://10.0.0.3:80"},
+				"server-4": {URL: "http://10.0.0.4:80"},
+				"server-5": {URL: "http://10.0.0.5:80"},
+				"server-6": {URL: "http://10.0.0.6:80"},
+				"server-7": {URL: "http://10.0.0.7:80"},
+			}),
+			expected: []string{"backend-payments: -7 servers (10.0.0.1:80 removed, 10.0.0.2:80 removed, 10.0.0.3:80 removed, 10.0.0.4:80 removed, 10.0.0.5:80 removed, 2 more)"},
+		},
+		{
+			desc:      "unchanged servers",
+			oldConfig: backend(map[string]types.Server{"server-task1": {URL: "http://10.0.3.4:31002"}}),
+			newConfig: backend(map[string]types.Server{"server-task1": {URL: "http://10.0.3.4:31002"}}),
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			change := newConfigChange("marathon", test.oldConfig, test.newConfig, false)
+			assert.Equal(t, test.expected, serverChurnSummaries(change, test.oldConfig, test.newConfig, test.reasons))
+		})
+	}
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"testing"
@@ -13,6 +14,8 @@ import (
 	"github.com/containous/mux"
 	"github.com/containous/traefik/healthcheck"
 	"github.com/containous/traefik/middlewares"
+	"github.com/containous/traefik/provider"
+	"github.com/containous/traefik/safe"
 	"github.com/containous/traefik/testhelpers"
 	"github.com/containous/traefik/types"
 	"github.com/davecgh/go-spew/spew"
@@ -122,7 +125,7 @@ func TestServerLoadConfigHealthCheckOptions(t *testing.T) {
 		},
 	}
 
-	for _, lbMethod := range []string{"Wrr", "Drr"} {
+	for _, lbMethod := range []string{"Wrr", "Drr", "Hash"} {
 		for _, healthCheck := range healthChecks {
 			t.Run(fmt.Sprintf("%s/hc=%t", lbMethod, healthCheck != nil), func(t *testing.T) {
 				globalConfig := GlobalConfiguration{
@@ -280,7 +283,7 @@ func TestNewServerWithWhitelistSourceRange(t *testing.T) {
 		tc := tc
 		t.Run(tc.desc, func(t *testing.T) {
 			t.Parallel()
-			middleware, err := configureIPWhitelistMiddleware(tc.whitelistStrings)
+			middleware, err := configureIPWhitelistMiddleware(tc.whitelistStrings, nil)
 
 			if tc.errMessage != "" {
 				require.EqualError(t, err, tc.errMessage)
@@ -576,3 +579,126 @@ func TestServerEntrypointWhitelistConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestServerNewLocalityPool(t *testing.T) {
+	cases := []struct {
+		desc             string
+		localZone        string
+		servers          map[string]types.Server
+		expectedLocality bool
+	}{
+		{
+			desc:      "zone of traefik unknown",
+			localZone: "",
+			servers: map[string]types.Server{
+				"foo": {URL: "http://10.0.0.1", Zone: "a"},
+				"bar": {URL: "http://10.0.0.2", Zone: "b"},
+			},
+			expectedLocality: false,
+		},
+		{
+			desc:      "every server in the local zone",
+			localZone: "a",
+			servers: map[string]types.Server{
+				"foo": {URL: "http://10.0.0.1", Zone: "a"},
+				"bar": {URL: "http://10.0.0.2"},
+			},
+			expectedLocality: false,
+		},
+		{
+			desc:      "servers spread across zones",
+			localZone: "a",
+			servers: map[string]types.Server{
+				"foo": {URL: "http://10.0.0.1", Zone: "a"},
+				"bar": {URL: "http://10.0.0.2", Zone: "b"},
+			},
+			expectedLocality: true,
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			srv := Server{
+				localZone: test.localZone,
+				globalConfiguration: GlobalConfiguration{
+					Locality: &Locality{MinLocalServers: 1},
+				},
+			}
+			local, _ := roundrobin.New(http.NotFoundHandler())
+			pool := srv.newLocalityPool(local, &types.Backend{Servers: test.servers}, nil, func() middlewares.LocalityPool {
+				remote, _ := roundrobin.New(http.NotFoundHandler())
+				return remote
+			})
+
+			_, isLocality := pool.(*middlewares.LocalityBalancer)
+			assert.Equal(t, test.expectedLocality, isLocality)
+		})
+	}
+}
+
+func TestServerLoadConfigPathParams(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Path-Param-Id")))
+	}))
+	defer backend.Close()
+
+	globalConfig := GlobalConfiguration{
+		EntryPoints: EntryPoints{
+			"http": &EntryPoint{},
+		},
+	}
+	dynamicConfigs := configs{
+		"config": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"frontend": {
+					EntryPoints: []string{"http"},
+					Backend:     "backend",
+					PathParams:  &types.PathParams{},
+					Routes: map[string]types.Route{
+						"route": {Rule: "PathPrefix:/tenants/{id}"},
+					},
+				},
+			},
+			Backends: map[string]*types.Backend{
+				"backend": {
+					Servers: map[string]types.Server{
+						"server": {URL: backend.URL},
+					},
+					LoadBalancer: &types.LoadBalancer{Method: "wrr"},
+				},
+			},
+		},
+	}
+
+	srv := NewServer(globalConfig)
+	entryPoints, err := srv.loadConfig(dynamicConfigs, globalConfig)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	entryPoints["http"].httpRouter.ServeHTTP(recorder, testhelpers.MustNewRequest(http.MethodGet, "http://localhost/tenants/42/orders", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "42", recorder.Body.String())
+}
+
+type syncStatusProvider struct {
+	status provider.SyncStatus
+}
+
+func (p *syncStatusProvider) Provide(configurationChan chan<- types.ConfigMessage, pool *safe.Pool, constraints types.Constraints) error {
+	return nil
+}
+
+func (p *syncStatusProvider) SyncStatus() provider.SyncStatus {
+	return p.status
+}
+
+func TestServerProvidersSyncStatus(t *testing.T) {
+	server := NewServer(GlobalConfiguration{})
+	assert.Nil(t, server.providersSyncStatus())
+
+	status := provider.SyncStatus{Name: "marathon", Endpoint: "http://marathon:8080", ConsecutiveFailures: 3}
+	server.providers = []provider.Provider{&WebProvider{}, &syncStatusProvider{status: status}}
+	assert.Equal(t, map[string]provider.SyncStatus{"marathon": status}, server.providersSyncStatus())
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/containous/flaeg"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+)
+
+// ServersTransport is a named profile describing how traefik connects to the servers of a backend.
+// Profiles are defined once in the static configuration and referenced by name from the backends,
+// so that many applications can share the same upstream TLS settings.
+type ServersTransport struct {
+	TLS                   *types.ClientTLS `description:"TLS configuration used to connect to the servers"`
+	DialTimeout           flaeg.Duration   `description:"Timeout to establish a connection to a server"`
+	ResponseHeaderTimeout flaeg.Duration   `description:"Time to wait for the response headers of a server, 0 means no timeout"`
+	IdleConnTimeout       flaeg.Duration   `description:"Maximum amount of time an idle (keep-alive) connection to a server remains open"`
+	MaxIdleConnsPerHost   int              `description:"Maximum idle (keep-alive) connections to keep per server. If zero, DefaultMaxIdleConnsPerHost is used"`
+}
+
+// ServersTransports holds the servers transports, indexed by name
+type ServersTransports map[string]*ServersTransport
+
+// createRoundTripper builds the HTTP transport of a servers transport profile
+func (t *ServersTransport) createRoundTripper() (http.RoundTripper, error) {
+	dialTimeout := time.Duration(t.DialTimeout)
+	if dialTimeout == 0 {
+		dialTimeout = 30 * time.Second
+	}
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: 30 * time.Second,
+			DualStack: true,
+		}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		ResponseHeaderTimeout: time.Duration(t.ResponseHeaderTimeout),
+		IdleConnTimeout:       time.Duration(t.IdleConnTimeout),
+		MaxIdleConnsPerHost:   t.MaxIdleConnsPerHost,
+	}
+	if t.TLS != nil {
+		tlsConfig, err := t.TLS.CreateTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		if t.TLS.CA == "" {
+			// trust the system roots when no CA is given
+			tlsConfig.RootCAs = nil
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport, nil
+}
+
+// buildServersTransports creates the round trippers of the configured servers transports.
+// The round trippers are shared by all the backends referencing them, and so are their connection pools.
+// A profile that cannot be built is logged and left out.
+func buildServersTransports(transports ServersTransports) map[string]http.RoundTripper {
+	roundTrippers := make(map[string]http.RoundTripper, len(transports))
+	for name, transport := range transports {
+		if transport == nil {
+			continue
+		}
+		rt, err := transport.createRoundTripper()
+		if err != nil {
+			log.Errorf("Error creating servers transport %s: %v", name, err)
+			continue
+		}
+		roundTrippers[name] = rt
+	}
+	return roundTrippers
+}
+
+// getServersTransport returns the round tripper to use for a backend.
+// Backends not referencing any servers transport use http.DefaultTransport.
+func (server *Server) getServersTransport(backend *types.Backend) (http.RoundTripper, error) {
+	if backend == nil || backend.ServersTransport == "" {
+		return http.DefaultTransport, nil
+	}
+	rt, ok := server.serversTransports[backend.ServersTransport]
+	if !ok {
+		return nil, fmt.Errorf("unknown servers transport %s", backend.ServersTransport)
+	}
+	return rt, nil
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/containous/flaeg"
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildServersTransports(t *testing.T) {
+	roundTrippers := buildServersTransports(ServersTransports{
+		"internal": {
+			TLS:                   &types.ClientTLS{InsecureSkipVerify: true},
+			ResponseHeaderTimeout: flaeg.Duration(5 * time.Second),
+			IdleConnTimeout:       flaeg.Duration(time.Minute),
+			MaxIdleConnsPerHost:   42,
+		},
+		"invalid": {
+			TLS: &types.ClientTLS{Cert: "missing.crt", Key: "missing.key"},
+		},
+		"empty": nil,
+	})
+
+	require.Len(t, roundTrippers, 1)
+	transport, ok := roundTrippers["internal"].(*http.Transport)
+	require.True(t, ok)
+	assert.True(t, transport.TLSClientConfig.InsecureSkipVerify)
+	assert.Equal(t, 5*time.Second, transport.ResponseHeaderTimeout)
+	assert.Equal(t, time.Minute, transport.IdleConnTimeout)
+	assert.Equal(t, 42, transport.MaxIdleConnsPerHost)
+}
+
+func TestGetServersTransport(t *testing.T) {
+	internal := &http.Transport{}
+	srv := &Server{serversTransports: map[string]http.RoundTripper{"internal": internal}}
+
+	cases := []struct {
+		desc          string
+		backend       *types.Backend
+		expected      http.RoundTripper
+		expectedError bool
+	}{
+		{
+			desc:     "no backend",
+			expected: http.DefaultTransport,
+		},
+		{
+			desc:     "no servers transport",
+			backend:  &types.Backend{},
+			expected: http.DefaultTransport,
+		},
+		{
+			desc:     "known servers transport",
+			backend:  &types.Backend{ServersTransport: "internal"},
+			expected: internal,
+		},
+		{
+			desc:          "unknown servers transport",
+			backend:       &types.Backend{ServersTransport: "unknown"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			rt, err := srv.getServersTransport(test.backend)
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, test.expected == rt, "unexpected round tripper")
+		})
+	}
+}
+
+func TestServerLoadConfigServersTransport(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	globalConfig := GlobalConfiguration{
+		EntryPoints: EntryPoints{
+			"http": &EntryPoint{},
+		},
+		ServersTransports: ServersTransports{
+			"self-signed": {TLS: &types.ClientTLS{InsecureSkipVerify: true}},
+		},
+	}
+	newFrontend := func(host string) *types.Frontend {
+		return &types.Frontend{
+			EntryPoints: []string{"http"},
+			Backend:     "backend-" + host,
+			Routes: map[string]types.Route{
+				"route": {Rule: "Host:" + host},
+			},
+		}
+	}
+	newBackend := func(serversTransport string) *types.Backend {
+		return &types.Backend{
+			Servers: map[string]types.Server{
+				"server": {URL: backend.URL},
+			},
+			LoadBalancer:     &types.LoadBalancer{Method: "wrr"},
+			ServersTransport: serversTransport,
+		}
+	}
+	dynamicConfigs := configs{
+		"config": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"frontend-known":   newFrontend("known"),
+				"frontend-unknown": newFrontend("unknown"),
+			},
+			Backends: map[string]*types.Backend{
+				"backend-known":   newBackend("self-signed"),
+				"backend-unknown": newBackend("missing"),
+			},
+		},
+	}
+
+	srv := NewServer(globalConfig)
+	entryPoints, err := srv.loadConfig(dynamicConfigs, globalConfig)
+	require.NoError(t, err)
+
+	cases := []struct {
+		host     string
+		expected int
+	}{
+		{host: "known", expected: http.StatusOK},
+		{host: "unknown", expected: http.StatusNotFound},
+	}
+
+	for _, test := range cases {
+		recorder := httptest.NewRecorder()
+		entryPoints["http"].httpRouter.ServeHTTP(recorder, testhelpers.MustNewRequest(http.MethodGet, "http://"+test.host+"/", nil))
+		assert.Equal(t, test.expected, recorder.Code, "host %s", test.host)
+	}
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/docker/libkv/store"
+)
+
+const (
+	// DefaultSessionTicketsRotation is the rotation interval of the session ticket keys shared through the cluster store,
+	// when none is configured
+	DefaultSessionTicketsRotation = 12 * time.Hour
+	// sessionTicketKeysKept is the number of session ticket keys kept, the first one encrypting the new tickets
+	// and the others decrypting the tickets issued before the last rotations
+	sessionTicketKeysKept = 3
+	// sessionTicketsSyncInterval is the maximum interval between two reads of the shared session ticket keys
+	sessionTicketsSyncInterval = 10 * time.Second
+	// sessionTicketsMaxAttempts is the maximum number of attempts to rotate the shared session ticket keys
+	sessionTicketsMaxAttempts = 5
+)
+
+// sessionTicketKeys are the session ticket keys of an entrypoint, as kept in the cluster store
+type sessionTicketKeys struct {
+	Keys      [][]byte  `json:"keys"`
+	RotatedAt time.Time `json:"rotatedAt"`
+}
+
+// rotated returns the keys with a new random first key, the oldest keys being dropped
+func (k sessionTicketKeys) rotated(now time.Time) (sessionTicketKeys, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return k, err
+	}
+	keys := append([][]byte{key}, k.Keys...)
+	if len(keys) > sessionTicketKeysKept {
+		keys = keys[:sessionTicketKeysKept]
+	}
+	return sessionTicketKeys{Keys: keys, RotatedAt: now.UTC()}, nil
+}
+
+func (k sessionTicketKeys) ticketKeys() [][32]byte {
+	var keys [][32]byte
+	for _, key := range k.Keys {
+		if len(key) != 32 {
+			continue
+		}
+		var ticketKey [32]byte
+		copy(ticketKey[:], key)
+		keys = append(keys, ticketKey)
+	}
+	return keys
+}
+
+// sessionTicketKeyRotator rotates the session ticket keys of the TLS configuration of an entrypoint.
+// When a cluster store is given, the keys are kept in it, so that the sessions can be resumed by
+// every traefik instance of the cluster: the instances rotate the shared keys with compare-and-swap
+// operations, and read them at least every sessionTicketsSyncInterval.
+type sessionTicketKeyRotator struct {
+	entryPointName string
+	config         *tls.Config
+	interval       time.Duration
+	kv             store.Store
+	kvKey          string
+	keys           sessionTicketKeys
+}
+
+func newSessionTicketKeyRotator(entryPointName string, config *tls.Config, interval time.Duration, kv store.Store, prefix string) *sessionTicketKeyRotator {
+	if interval <= 0 {
+		interval = DefaultSessionTicketsRotation
+	}
+	return &sessionTicketKeyRotator{
+		entryPointName: entryPointName,
+		config:         config,
+		interval:       interval,
+		kv:             kv,
+		kvKey:          prefix + "/tlstickets/" + entryPointName,
+	}
+}
+
+// startSessionTicketKeyRotation sets the session ticket keys of the TLS configuration of the entrypoint,
+// and rotates them in the background
+func (server *Server) startSessionTicketKeyRotation(entryPointName string, tlsOption *TLS, config *tls.Config) {
+	var kv store.Store
+	var prefix string
+	if tlsOption.SharedSessionTickets {
+		if server.globalConfiguration.Cluster == nil || server.globalConfiguration.Cluster.Store == nil {
+			log.Warnf("Shared TLS session tickets require a cluster KV store, the session ticket keys of entrypoint %s are local to this instance", entryPointName)
+		} else {
+			kv = server.globalConfiguration.Cluster.Store.Store
+			prefix = server.globalConfiguration.Cluster.Store.Prefix
+		}
+	}
+
+	rotator := newSessionTicketKeyRotator(entryPointName, config, time.Duration(tlsOption.SessionTicketsRotation), kv, prefix)
+	rotator.update(time.Now())
+	server.routinesPool.Go(func(stop chan bool) {
+		rotator.run(stop)
+	})
+}
+
+func (r *sessionTicketKeyRotator) run(stop chan bool) {
+	checkInterval := r.interval
+	if r.kv != nil && checkInterval > sessionTicketsSyncInterval {
+		checkInterval = sessionTicketsSyncInterval
+	}
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.update(time.Now())
+		}
+	}
+}
+
+// update rotates the keys if they are older than the rotation interval, and applies them to the TLS configuration.
+// When the cluster store fails, the current keys are kept.
+func (r *sessionTicketKeyRotator) update(now time.Time) {
+	keys := r.keys
+	var err error
+	if r.kv != nil {
+		keys, err = r.syncSharedKeys(now)
+		if err != nil {
+			log.Warnf("Unable to read the shared TLS session ticket keys of entrypoint %s, keeping the current keys: %s", r.entryPointName, err)
+			if len(r.keys.Keys) > 0 {
+				return
+			}
+			keys = r.keys
+		}
+	}
+	if r.expired(keys, now) {
+		keys, err = keys.rotated(now)
+		if err != nil {
+			log.Errorf("Unable to rotate the TLS session ticket keys of entrypoint %s: %s", r.entryPointName, err)
+			return
+		}
+		log.Debugf("TLS session ticket keys of entrypoint %s rotated", r.entryPointName)
+	}
+
+	r.keys = keys
+	if ticketKeys := keys.ticketKeys(); len(ticketKeys) > 0 {
+		r.config.SetSessionTicketKeys(ticketKeys)
+	}
+}
+
+func (r *sessionTicketKeyRotator) expired(keys sessionTicketKeys, now time.Time) bool {
+	return len(keys.ticketKeys()) == 0 || now.Sub(keys.RotatedAt) >= r.interval
+}
+
+// syncSharedKeys returns the keys of the cluster store, after having rotated them if they expired
+func (r *sessionTicketKeyRotator) syncSharedKeys(now time.Time) (sessionTicketKeys, error) {
+	for attempt := 0; attempt < sessionTicketsMaxAttempts; attempt++ {
+		var keys sessionTicketKeys
+		pair, err := r.kv.Get(r.kvKey)
+		if err == store.ErrKeyNotFound {
+			pair = nil
+		} else if err != nil {
+			return keys, err
+		} else if err := json.Unmarshal(pair.Value, &keys); err != nil {
+			log.Warnf("Resetting invalid TLS session ticket keys %s: %s", r.kvKey, err)
+			keys = sessionTicketKeys{}
+		}
+		if !r.expired(keys, now) {
+			return keys, nil
+		}
+
+		rotated, err := keys.rotated(now)
+		if err != nil {
+			return keys, err
+		}
+		value, err := json.Marshal(rotated)
+		if err != nil {
+			return keys, err
+		}
+		ok, _, err := r.kv.AtomicPut(r.kvKey, value, pair, nil)
+		if err == store.ErrKeyModified || err == store.ErrKeyExists || err == nil && !ok {
+			continue
+		}
+		if err != nil {
+			return keys, err
+		}
+		log.Debugf("Shared TLS session ticket keys of entrypoint %s rotated", r.entryPointName)
+		return rotated, nil
+	}
+	return sessionTicketKeys{}, fmt.Errorf("too many concurrent rotations of the TLS session ticket keys %s", r.kvKey)
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/docker/libkv/store/boltdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSessionTicketsStore(t *testing.T) (store.Store, func()) {
+	dir, err := ioutil.TempDir("", "sessiontickets")
+	require.NoError(t, err)
+	kv, err := boltdb.New([]string{filepath.Join(dir, "sessiontickets.db")}, &store.Config{Bucket: "traefik"})
+	require.NoError(t, err)
+	return kv, func() {
+		kv.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSessionTicketKeyRotatorLocal(t *testing.T) {
+	now := time.Now()
+	rotator := newSessionTicketKeyRotator("https", &tls.Config{}, time.Hour, nil, "")
+
+	rotator.update(now)
+	require.Len(t, rotator.keys.Keys, 1)
+	first := rotator.keys.Keys[0]
+
+	rotator.update(now.Add(30 * time.Minute))
+	assert.Equal(t, [][]byte{first}, rotator.keys.Keys, "keys must not be rotated before the interval")
+
+	rotator.update(now.Add(time.Hour))
+	require.Len(t, rotator.keys.Keys, 2)
+	assert.NotEqual(t, first, rotator.keys.Keys[0])
+	assert.Equal(t, first, rotator.keys.Keys[1], "the previous key must be kept to decrypt the tickets it encrypted")
+
+	rotator.update(now.Add(2 * time.Hour))
+	rotator.update(now.Add(3 * time.Hour))
+	assert.Len(t, rotator.keys.Keys, sessionTicketKeysKept)
+	assert.NotContains(t, rotator.keys.Keys, first)
+}
+
+func TestSessionTicketKeyRotatorShared(t *testing.T) {
+	kv, cleanup := newSessionTicketsStore(t)
+	defer cleanup()
+
+	now := time.Now()
+	first := newSessionTicketKeyRotator("https", &tls.Config{}, time.Hour, kv, "traefik")
+	second := newSessionTicketKeyRotator("https", &tls.Config{}, time.Hour, kv, "traefik")
+	other := newSessionTicketKeyRotator("other", &tls.Config{}, time.Hour, kv, "traefik")
+
+	first.update(now)
+	second.update(now)
+	other.update(now)
+	require.Len(t, first.keys.Keys, 1)
+	assert.Equal(t, first.keys, second.keys, "instances must share the keys of an entrypoint")
+	assert.NotEqual(t, first.keys.Keys, other.keys.Keys, "entrypoints must not share keys")
+
+	second.update(now.Add(time.Hour))
+	require.Len(t, second.keys.Keys, 2)
+	first.update(now.Add(time.Hour + time.Second))
+	assert.Equal(t, second.keys, first.keys, "keys must be rotated once for the cluster")
+}
+
+func TestSessionTicketKeyRotatorSharedStoreFailure(t *testing.T) {
+	kv, cleanup := newSessionTicketsStore(t)
+	rotator := newSessionTicketKeyRotator("https", &tls.Config{}, time.Hour, kv, "traefik")
+	now := time.Now()
+	rotator.update(now)
+	keys := rotator.keys
+	cleanup()
+
+	rotator.update(now.Add(2 * time.Hour))
+	assert.Equal(t, keys, rotator.keys, "keys must be kept when the store fails")
+}
+
+func TestSharedSessionTicketsResumption(t *testing.T) {
+	kv, cleanup := newSessionTicketsStore(t)
+	defer cleanup()
+
+	testCases := []struct {
+		desc           string
+		prefixes       [2]string
+		expectedResume bool
+	}{
+		{
+			desc:           "shared keys",
+			prefixes:       [2]string{"traefik", "traefik"},
+			expectedResume: true,
+		},
+		{
+			desc:     "distinct keys",
+			prefixes: [2]string{"traefik-a", "traefik-b"},
+		},
+	}
+
+	for _, test := range testCases {
+		var addresses [2]string
+		for i, prefix := range test.prefixes {
+			backend := httptest.NewUnstartedServer(http.NotFoundHandler())
+			backend.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+			backend.StartTLS()
+			defer backend.Close()
+			newSessionTicketKeyRotator("https", backend.TLS, time.Hour, kv, prefix).update(time.Now())
+			addresses[i] = backend.Listener.Addr().String()
+		}
+
+		clientConfig := &tls.Config{
+			ServerName:         "example.com",
+			InsecureSkipVerify: true,
+			ClientSessionCache: tls.NewLRUClientSessionCache(1),
+		}
+		var resumed []bool
+		for _, address := range addresses {
+			conn, err := tls.Dial("tcp", address, clientConfig)
+			require.NoError(t, err, test.desc)
+			resumed = append(resumed, conn.ConnectionState().DidResume)
+			conn.Close()
+		}
+		assert.Equal(t, []bool{false, test.expectedResume}, resumed, test.desc)
+	}
+}
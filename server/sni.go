@@ -0,0 +1,152 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/containous/traefik/healthcheck"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/middlewares"
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/forward"
+	"github.com/vulcand/oxy/roundrobin"
+	"github.com/vulcand/oxy/utils"
+)
+
+// enableStrictSNI makes config refuse the handshakes with a missing or unknown SNI. crypto/tls serves
+// the first certificate to the handshakes without SNI without asking GetCertificate, so the certificates
+// are only selected by GetCertificate.
+func enableStrictSNI(config *tls.Config) {
+	config.GetCertificate = strictSNIGetCertificate(config)
+	config.Certificates = nil
+}
+
+// strictSNIGetCertificate wraps the certificate selection of config so that handshakes
+// with a missing or unknown SNI fail instead of being served the default certificate.
+func strictSNIGetCertificate(config *tls.Config) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	getCertificate := config.GetCertificate
+	nameToCertificate := config.NameToCertificate
+	return func(clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if getCertificate != nil {
+			cert, err := getCertificate(clientHello)
+			if err != nil || cert != nil {
+				return cert, err
+			}
+		}
+		if cert := matchCertificate(nameToCertificate, clientHello.ServerName); cert != nil {
+			return cert, nil
+		}
+		log.Debugf("No certificate matching SNI %q, refusing the TLS handshake", clientHello.ServerName)
+		return nil, fmt.Errorf("no certificate for server name %q", clientHello.ServerName)
+	}
+}
+
+// matchCertificate looks the server name up like crypto/tls does, including wildcard certificates
+func matchCertificate(nameToCertificate map[string]*tls.Certificate, serverName string) *tls.Certificate {
+	name := strings.TrimRight(strings.ToLower(serverName), ".")
+	if name == "" {
+		return nil
+	}
+	if cert, ok := nameToCertificate[name]; ok {
+		return cert
+	}
+	labels := strings.Split(name, ".")
+	labels[0] = "*"
+	return nameToCertificate[strings.Join(labels, ".")]
+}
+
+// buildDefaultBackendHandler creates a load balancer over the servers of the given backend, used to
+// serve the TLS requests matching no frontend of an entry point. It honors the load-balancing method,
+// the sticky sessions and the servers transport of the backend, and its health check is registered in
+// backendsHealthcheck.
+func (server *Server) buildDefaultBackendHandler(configurations configs, entryPointName string, backendName string,
+	globalConfiguration GlobalConfiguration, errorHandler utils.ErrorHandler, backendsHealthcheck map[string]*healthcheck.BackendHealthCheck) (http.Handler, error) {
+	var configuration *types.Configuration
+	for _, candidate := range configurations {
+		if candidate.Backends[backendName] != nil {
+			configuration = candidate
+			break
+		}
+	}
+	if configuration == nil {
+		return nil, fmt.Errorf("undefined backend %q", backendName)
+	}
+	backend := configuration.Backends[backendName]
+
+	rt, err := server.getServersTransport(backend)
+	if err != nil {
+		return nil, err
+	}
+	fwd, err := forward.New(
+		forward.Logger(oxyLogger),
+		forward.PassHostHeader(true),
+		forward.RoundTripper(rt),
+		forward.ErrorHandler(errorHandler),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	lbMethod := types.Wrr
+	stickysession := false
+	if backend.LoadBalancer != nil {
+		lbMethod, err = types.NewLoadBalancerMethod(backend.LoadBalancer)
+		if err != nil {
+			return nil, err
+		}
+		stickysession = backend.LoadBalancer.Sticky
+	}
+	// Same cookie as the frontends of the backend, so that the clients stick to the same server
+	sticky := roundrobin.NewStickySession("_TRAEFIK_BACKEND_" + backendName)
+
+	var lb middlewares.LocalityPool
+	switch lbMethod {
+	case types.Drr:
+		rr, _ := roundrobin.New(fwd)
+		if stickysession {
+			lb, err = roundrobin.NewRebalancer(rr, roundrobin.RebalancerLogger(oxyLogger), roundrobin.RebalancerStickySession(sticky))
+		} else {
+			lb, err = roundrobin.NewRebalancer(rr, roundrobin.RebalancerLogger(oxyLogger))
+		}
+	case types.Hash:
+		lb = middlewares.NewHashBalancer(fwd, server.clientIPStrategy, backend.Servers)
+	default:
+		if stickysession {
+			lb, err = roundrobin.New(fwd, roundrobin.EnableStickySession(sticky))
+		} else {
+			lb, err = roundrobin.New(fwd)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := configureLBServers(lb, configuration, &types.Frontend{Backend: backendName}); err != nil {
+		return nil, err
+	}
+
+	hcOpts := parseHealthCheckOptions(lb, backendName, backend.HealthCheck, globalConfiguration.HealthCheck)
+	if hcOpts != nil {
+		log.Debugf("Setting up health check %s of the default backend of entrypoint %s", *hcOpts, entryPointName)
+		backendsHealthcheck[defaultBackendHealthCheckID(entryPointName, backendName)] = healthcheck.NewBackendHealthCheck(*hcOpts)
+	}
+	return lb, nil
+}
+
+// defaultBackendHealthCheckID identifies the health check of the default backend of an entrypoint,
+// apart from the health check of the load balancer of the frontends using the same backend
+func defaultBackendHealthCheckID(entryPointName string, backendName string) string {
+	return entryPointName + "/default/" + backendName
+}
+
+// withDefaultBackend sends the TLS requests to defaultBackend and the others to notFound
+func withDefaultBackend(defaultBackend http.Handler, notFound http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			defaultBackend.ServeHTTP(w, r)
+			return
+		}
+		notFound.ServeHTTP(w, r)
+	})
+}
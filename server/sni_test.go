@@ -0,0 +1,248 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictSNIGetCertificate(t *testing.T) {
+	exampleCert := &tls.Certificate{}
+	wildcardCert := &tls.Certificate{}
+	acmeCert := &tls.Certificate{}
+
+	cases := []struct {
+		desc           string
+		serverName     string
+		getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)
+		expectedCert   *tls.Certificate
+		expectedError  bool
+	}{
+		{
+			desc:         "known server name",
+			serverName:   "www.example.com",
+			expectedCert: exampleCert,
+		},
+		{
+			desc:         "server name case and trailing dot",
+			serverName:   "WWW.Example.com.",
+			expectedCert: exampleCert,
+		},
+		{
+			desc:         "wildcard certificate",
+			serverName:   "api.example.org",
+			expectedCert: wildcardCert,
+		},
+		{
+			desc:          "unknown server name",
+			serverName:    "www.example.net",
+			expectedError: true,
+		},
+		{
+			desc:          "missing server name",
+			expectedError: true,
+		},
+		{
+			desc:       "certificate provided by the previous selection",
+			serverName: "www.example.net",
+			getCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+				return acmeCert, nil
+			},
+			expectedCert: acmeCert,
+		},
+		{
+			desc:       "previous selection error",
+			serverName: "www.example.com",
+			getCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+				return nil, errors.New("no way")
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			config := &tls.Config{
+				NameToCertificate: map[string]*tls.Certificate{
+					"www.example.com": exampleCert,
+					"*.example.org":   wildcardCert,
+				},
+				GetCertificate: test.getCertificate,
+			}
+
+			cert, err := strictSNIGetCertificate(config)(&tls.ClientHelloInfo{ServerName: test.serverName})
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, test.expectedCert == cert, "unexpected certificate")
+		})
+	}
+}
+
+func TestStrictSNIHandshake(t *testing.T) {
+	// borrow the certificate of httptest, valid for example.com
+	certServer := httptest.NewUnstartedServer(http.NotFoundHandler())
+	certServer.StartTLS()
+	certificates := certServer.TLS.Certificates
+	certServer.Close()
+
+	config := &tls.Config{Certificates: certificates}
+	config.BuildNameToCertificate()
+	enableStrictSNI(config)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	cases := []struct {
+		desc          string
+		serverName    string
+		expectedError bool
+	}{
+		{
+			desc:       "known server name",
+			serverName: "example.com",
+		},
+		{
+			desc:          "unknown server name",
+			serverName:    "www.example.net",
+			expectedError: true,
+		},
+		{
+			desc:          "missing server name",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			// an IP address is never sent as SNI
+			conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{ServerName: test.serverName, InsecureSkipVerify: true})
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			conn.Close()
+		})
+	}
+}
+
+func TestServerLoadConfigTLSDefaultBackend(t *testing.T) {
+	onboarding := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("onboarding"))
+	}))
+	defer onboarding.Close()
+
+	globalConfig := GlobalConfiguration{
+		EntryPoints: EntryPoints{
+			"https": &EntryPoint{
+				TLS: &TLS{DefaultBackend: "backend-onboarding"},
+			},
+		},
+	}
+	dynamicConfigs := configs{
+		"config": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"frontend": {
+					EntryPoints: []string{"https"},
+					Backend:     "backend",
+					Routes: map[string]types.Route{
+						"route": {Rule: "Host:www.example.com"},
+					},
+				},
+			},
+			Backends: map[string]*types.Backend{
+				"backend": {
+					Servers: map[string]types.Server{
+						"server": {URL: "http://127.0.0.1:1"},
+					},
+					LoadBalancer: &types.LoadBalancer{Method: "wrr"},
+				},
+				"backend-onboarding": {
+					Servers: map[string]types.Server{
+						"server": {URL: onboarding.URL, Weight: 1},
+					},
+				},
+			},
+		},
+	}
+
+	srv := NewServer(globalConfig)
+	entryPoints, err := srv.loadConfig(dynamicConfigs, globalConfig)
+	require.NoError(t, err)
+
+	req := testhelpers.MustNewRequest(http.MethodGet, "https://tenant.example.com/", nil)
+	req.TLS = &tls.ConnectionState{ServerName: "tenant.example.com"}
+	recorder := httptest.NewRecorder()
+	entryPoints["https"].httpRouter.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	body, err := ioutil.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "onboarding", string(body))
+
+	recorder = httptest.NewRecorder()
+	entryPoints["https"].httpRouter.ServeHTTP(recorder, testhelpers.MustNewRequest(http.MethodGet, "http://tenant.example.com/", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestServerLoadConfigTLSDefaultBackendStickySession(t *testing.T) {
+	onboarding := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("onboarding"))
+	}))
+	defer onboarding.Close()
+
+	globalConfig := GlobalConfiguration{
+		EntryPoints: EntryPoints{
+			"https": &EntryPoint{
+				TLS: &TLS{DefaultBackend: "backend-onboarding"},
+			},
+		},
+	}
+	dynamicConfigs := configs{
+		"config": &types.Configuration{
+			Backends: map[string]*types.Backend{
+				"backend-onboarding": {
+					Servers: map[string]types.Server{
+						"server": {URL: onboarding.URL, Weight: 1},
+					},
+					LoadBalancer: &types.LoadBalancer{Method: "drr", Sticky: true},
+				},
+			},
+		},
+	}
+
+	srv := NewServer(globalConfig)
+	entryPoints, err := srv.loadConfig(dynamicConfigs, globalConfig)
+	require.NoError(t, err)
+
+	req := testhelpers.MustNewRequest(http.MethodGet, "https://tenant.example.com/", nil)
+	req.TLS = &tls.ConnectionState{ServerName: "tenant.example.com"}
+	recorder := httptest.NewRecorder()
+	entryPoints["https"].httpRouter.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Set-Cookie"), "_TRAEFIK_BACKEND_backend-onboarding=")
+}
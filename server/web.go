@@ -4,15 +4,18 @@ import (
 	"encoding/json"
 	"expvar"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"runtime"
+	"time"
 
 	"github.com/codegangsta/negroni"
 	"github.com/containous/mux"
 	"github.com/containous/traefik/autogen"
 	"github.com/containous/traefik/log"
 	"github.com/containous/traefik/middlewares"
+	"github.com/containous/traefik/provider"
 	"github.com/containous/traefik/safe"
 	"github.com/containous/traefik/types"
 	"github.com/containous/traefik/version"
@@ -39,6 +42,7 @@ type WebProvider struct {
 	Path       string            `description:"Root path for dashboard and API"`
 	server     *Server
 	Auth       *types.Auth
+	ViewTokens []ViewToken `description:"Read-only tokens of the dashboard and the API restricted to some frontends"`
 }
 
 var (
@@ -121,6 +125,15 @@ func (provider *WebProvider) Provide(configurationChan chan<- types.ConfigMessag
 	systemRouter.Methods("GET").Path(provider.Path + "api/providers/{provider}/frontends/{frontend}").HandlerFunc(provider.getFrontendHandler)
 	systemRouter.Methods("GET").Path(provider.Path + "api/providers/{provider}/frontends/{frontend}/routes").HandlerFunc(provider.getRoutesHandler)
 	systemRouter.Methods("GET").Path(provider.Path + "api/providers/{provider}/frontends/{frontend}/routes/{route}").HandlerFunc(provider.getRouteHandler)
+	systemRouter.Methods("GET").Path(provider.Path + "api/graph").HandlerFunc(provider.getGraphHandler)
+	systemRouter.Methods("GET").Path(provider.Path + "api/entrypoints/{entrypoint}/drain").HandlerFunc(provider.getDrainHandler)
+	systemRouter.Methods("POST").Path(provider.Path + "api/entrypoints/{entrypoint}/drain").HandlerFunc(provider.drainHandler)
+	systemRouter.Methods("DELETE").Path(provider.Path + "api/entrypoints/{entrypoint}/drain").HandlerFunc(provider.undrainHandler)
+	systemRouter.Methods("GET").Path(provider.Path + "api/metrics").HandlerFunc(provider.getMetricsSettingsHandler)
+	systemRouter.Methods("PUT").Path(provider.Path + "api/metrics").HandlerFunc(provider.metricsSettingsHandler)
+	systemRouter.Methods("GET").Path(provider.Path + "api/freeze").HandlerFunc(provider.getFreezeHandler)
+	systemRouter.Methods("POST").Path(provider.Path + "api/freeze").HandlerFunc(provider.freezeHandler)
+	systemRouter.Methods("DELETE").Path(provider.Path + "api/freeze").HandlerFunc(provider.unfreezeHandler)
 
 	// Expose dashboard
 	systemRouter.Methods("GET").Path(provider.Path).HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
@@ -137,6 +150,12 @@ func (provider *WebProvider) Provide(configurationChan chan<- types.ConfigMessag
 	go func() {
 		var err error
 		var negroni = negroni.New()
+		if len(provider.ViewTokens) > 0 {
+			if provider.Auth == nil {
+				log.Warn("The web view tokens have no effect without web authentication")
+			}
+			negroni.Use(newViewTokens(provider.ViewTokens, provider.Path, systemRouter))
+		}
 		if provider.Auth != nil {
 			authMiddleware, err := middlewares.NewAuthenticator(provider.Auth)
 			if err != nil {
@@ -160,10 +179,11 @@ func (provider *WebProvider) Provide(configurationChan chan<- types.ConfigMessag
 }
 
 // healthResponse combines data returned by thoas/stats with statistics (if
-// they are enabled).
+// they are enabled), and the synchronization status of the providers reporting it.
 type healthResponse struct {
 	*thoas_stats.Data
 	*middlewares.Stats
+	Providers map[string]provider.SyncStatus `json:"providers,omitempty"`
 }
 
 func (provider *WebProvider) getHealthHandler(response http.ResponseWriter, request *http.Request) {
@@ -171,6 +191,7 @@ func (provider *WebProvider) getHealthHandler(response http.ResponseWriter, requ
 	if statsRecorder != nil {
 		health.Stats = statsRecorder.Data()
 	}
+	health.Providers = provider.server.providersSyncStatus()
 	templatesRenderer.JSON(response, http.StatusOK, health)
 }
 
@@ -179,7 +200,7 @@ func (provider *WebProvider) getPingHandler(response http.ResponseWriter, reques
 }
 
 func (provider *WebProvider) getConfigHandler(response http.ResponseWriter, request *http.Request) {
-	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
+	currentConfigurations := provider.currentConfigurations(request)
 	templatesRenderer.JSON(response, http.StatusOK, currentConfigurations)
 }
 
@@ -197,7 +218,7 @@ func (provider *WebProvider) getVersionHandler(response http.ResponseWriter, req
 func (provider *WebProvider) getProviderHandler(response http.ResponseWriter, request *http.Request) {
 	vars := mux.Vars(request)
 	providerID := vars["provider"]
-	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
+	currentConfigurations := provider.currentConfigurations(request)
 	if provider, ok := currentConfigurations[providerID]; ok {
 		templatesRenderer.JSON(response, http.StatusOK, provider)
 	} else {
@@ -208,9 +229,9 @@ func (provider *WebProvider) getProviderHandler(response http.ResponseWriter, re
 func (provider *WebProvider) getBackendsHandler(response http.ResponseWriter, request *http.Request) {
 	vars := mux.Vars(request)
 	providerID := vars["provider"]
-	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
+	currentConfigurations := provider.currentConfigurations(request)
 	if provider, ok := currentConfigurations[providerID]; ok {
-		templatesRenderer.JSON(response, http.StatusOK, provider.Backends)
+		renderListing(response, request, provider.Backends)
 	} else {
 		http.NotFound(response, request)
 	}
@@ -220,7 +241,7 @@ func (provider *WebProvider) getBackendHandler(response http.ResponseWriter, req
 	vars := mux.Vars(request)
 	providerID := vars["provider"]
 	backendID := vars["backend"]
-	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
+	currentConfigurations := provider.currentConfigurations(request)
 	if provider, ok := currentConfigurations[providerID]; ok {
 		if backend, ok := provider.Backends[backendID]; ok {
 			templatesRenderer.JSON(response, http.StatusOK, backend)
@@ -234,10 +255,10 @@ func (provider *WebProvider) getServersHandler(response http.ResponseWriter, req
 	vars := mux.Vars(request)
 	providerID := vars["provider"]
 	backendID := vars["backend"]
-	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
+	currentConfigurations := provider.currentConfigurations(request)
 	if provider, ok := currentConfigurations[providerID]; ok {
 		if backend, ok := provider.Backends[backendID]; ok {
-			templatesRenderer.JSON(response, http.StatusOK, backend.Servers)
+			renderListing(response, request, backend.Servers)
 			return
 		}
 	}
@@ -249,7 +270,7 @@ func (provider *WebProvider) getServerHandler(response http.ResponseWriter, requ
 	providerID := vars["provider"]
 	backendID := vars["backend"]
 	serverID := vars["server"]
-	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
+	currentConfigurations := provider.currentConfigurations(request)
 	if provider, ok := currentConfigurations[providerID]; ok {
 		if backend, ok := provider.Backends[backendID]; ok {
 			if server, ok := backend.Servers[serverID]; ok {
@@ -264,9 +285,9 @@ func (provider *WebProvider) getServerHandler(response http.ResponseWriter, requ
 func (provider *WebProvider) getFrontendsHandler(response http.ResponseWriter, request *http.Request) {
 	vars := mux.Vars(request)
 	providerID := vars["provider"]
-	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
+	currentConfigurations := provider.currentConfigurations(request)
 	if provider, ok := currentConfigurations[providerID]; ok {
-		templatesRenderer.JSON(response, http.StatusOK, provider.Frontends)
+		renderListing(response, request, provider.Frontends)
 	} else {
 		http.NotFound(response, request)
 	}
@@ -276,7 +297,7 @@ func (provider *WebProvider) getFrontendHandler(response http.ResponseWriter, re
 	vars := mux.Vars(request)
 	providerID := vars["provider"]
 	frontendID := vars["frontend"]
-	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
+	currentConfigurations := provider.currentConfigurations(request)
 	if provider, ok := currentConfigurations[providerID]; ok {
 		if frontend, ok := provider.Frontends[frontendID]; ok {
 			templatesRenderer.JSON(response, http.StatusOK, frontend)
@@ -290,10 +311,10 @@ func (provider *WebProvider) getRoutesHandler(response http.ResponseWriter, requ
 	vars := mux.Vars(request)
 	providerID := vars["provider"]
 	frontendID := vars["frontend"]
-	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
+	currentConfigurations := provider.currentConfigurations(request)
 	if provider, ok := currentConfigurations[providerID]; ok {
 		if frontend, ok := provider.Frontends[frontendID]; ok {
-			templatesRenderer.JSON(response, http.StatusOK, frontend.Routes)
+			renderListing(response, request, frontend.Routes)
 			return
 		}
 	}
@@ -306,7 +327,7 @@ func (provider *WebProvider) getRouteHandler(response http.ResponseWriter, reque
 	providerID := vars["provider"]
 	frontendID := vars["frontend"]
 	routeID := vars["route"]
-	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
+	currentConfigurations := provider.currentConfigurations(request)
 	if provider, ok := currentConfigurations[providerID]; ok {
 		if frontend, ok := provider.Frontends[frontendID]; ok {
 			if route, ok := frontend.Routes[routeID]; ok {
@@ -331,3 +352,127 @@ func expvarHandler(w http.ResponseWriter, r *http.Request) {
 	})
 	fmt.Fprint(w, "\n}\n")
 }
+
+func (provider *WebProvider) getDrainHandler(response http.ResponseWriter, request *http.Request) {
+	vars := mux.Vars(request)
+	if status, ok := provider.server.entryPointDrainStatus(vars["entrypoint"]); ok {
+		templatesRenderer.JSON(response, http.StatusOK, status)
+	} else {
+		http.NotFound(response, request)
+	}
+}
+
+func (provider *WebProvider) drainHandler(response http.ResponseWriter, request *http.Request) {
+	if provider.ReadOnly {
+		response.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(response, "REST API is in read-only mode")
+		return
+	}
+	vars := mux.Vars(request)
+	if status, ok := provider.server.drainEntryPoint(vars["entrypoint"]); ok {
+		templatesRenderer.JSON(response, http.StatusAccepted, status)
+	} else {
+		http.NotFound(response, request)
+	}
+}
+
+// undrainHandler answers that the drain of an entrypoint cannot be undone, its server being shut down
+func (provider *WebProvider) undrainHandler(response http.ResponseWriter, request *http.Request) {
+	if provider.ReadOnly {
+		response.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(response, "REST API is in read-only mode")
+		return
+	}
+	vars := mux.Vars(request)
+	status, ok := provider.server.entryPointDrainStatus(vars["entrypoint"])
+	if !ok {
+		http.NotFound(response, request)
+		return
+	}
+	if status.Draining {
+		response.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(response, "entrypoint %s is drained, it only accepts connections again after a restart of traefik", status.EntryPoint)
+		return
+	}
+	templatesRenderer.JSON(response, http.StatusOK, status)
+}
+
+func (provider *WebProvider) getMetricsSettingsHandler(response http.ResponseWriter, request *http.Request) {
+	templatesRenderer.JSON(response, http.StatusOK, provider.server.metricsSettings.get())
+}
+
+func (provider *WebProvider) metricsSettingsHandler(response http.ResponseWriter, request *http.Request) {
+	if provider.ReadOnly {
+		response.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(response, "REST API is in read-only mode")
+		return
+	}
+	if provider.Metrics == nil || provider.Metrics.Prometheus == nil {
+		http.Error(response, "No metrics exporter is enabled", http.StatusBadRequest)
+		return
+	}
+
+	settings := new(metricsSettingsRepresentation)
+	if err := json.NewDecoder(request.Body).Decode(settings); err != nil {
+		log.Errorf("Error parsing metrics settings %+v", err)
+		http.Error(response, fmt.Sprintf("%+v", err), http.StatusBadRequest)
+		return
+	}
+	if err := provider.server.metricsSettings.update(settings); err != nil {
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+	templatesRenderer.JSON(response, http.StatusOK, provider.server.metricsSettings.get())
+}
+
+// freezeRequest is the optional body of a configuration freeze request
+type freezeRequest struct {
+	Reason  string `json:"reason"`
+	Timeout string `json:"timeout"`
+}
+
+func (provider *WebProvider) getFreezeHandler(response http.ResponseWriter, request *http.Request) {
+	templatesRenderer.JSON(response, http.StatusOK, provider.server.configFreeze.status())
+}
+
+func (provider *WebProvider) freezeHandler(response http.ResponseWriter, request *http.Request) {
+	if provider.ReadOnly {
+		response.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(response, "REST API is in read-only mode")
+		return
+	}
+
+	freeze := new(freezeRequest)
+	if err := json.NewDecoder(request.Body).Decode(freeze); err != nil && err != io.EOF {
+		log.Errorf("Error parsing freeze request %+v", err)
+		http.Error(response, fmt.Sprintf("%+v", err), http.StatusBadRequest)
+		return
+	}
+	maxTimeout := provider.server.freezeTimeout()
+	timeout := maxTimeout
+	if len(freeze.Timeout) > 0 {
+		var err error
+		timeout, err = time.ParseDuration(freeze.Timeout)
+		if err != nil || timeout <= 0 {
+			http.Error(response, fmt.Sprintf("Invalid freeze timeout %q", freeze.Timeout), http.StatusBadRequest)
+			return
+		}
+		if timeout > maxTimeout {
+			http.Error(response, fmt.Sprintf("Freeze timeout %s exceeds the maximum of %s", timeout, maxTimeout), http.StatusBadRequest)
+			return
+		}
+	}
+	if len(freeze.Reason) == 0 {
+		freeze.Reason = "requested through the API"
+	}
+	templatesRenderer.JSON(response, http.StatusOK, provider.server.configFreeze.freeze(freeze.Reason, timeout))
+}
+
+func (provider *WebProvider) unfreezeHandler(response http.ResponseWriter, request *http.Request) {
+	if provider.ReadOnly {
+		response.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(response, "REST API is in read-only mode")
+		return
+	}
+	templatesRenderer.JSON(response, http.StatusOK, provider.server.configFreeze.unfreeze())
+}
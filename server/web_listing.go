@@ -0,0 +1,133 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultListingPageSize is the number of elements of a listing page when only the page is requested
+	defaultListingPageSize = 100
+	// maxListingPageSize is the maximum number of elements of a listing page
+	maxListingPageSize = 1000
+)
+
+// listingParams are the query parameters of the API listing endpoints:
+// - search keeps the elements whose name contains the given text, ignoring case
+// - fields restricts the fields returned for each element
+// - page and per_page return one page of the elements ordered by name, the first page being 1
+type listingParams struct {
+	search  string
+	fields  []string
+	page    int
+	perPage int
+}
+
+func parseListingParams(query url.Values) (*listingParams, error) {
+	params := &listingParams{search: strings.ToLower(query.Get("search"))}
+	for _, field := range strings.Split(query.Get("fields"), ",") {
+		if field = strings.TrimSpace(field); len(field) > 0 {
+			params.fields = append(params.fields, field)
+		}
+	}
+
+	if value := query.Get("page"); len(value) > 0 {
+		page, err := strconv.Atoi(value)
+		if err != nil || page < 1 {
+			return nil, fmt.Errorf("invalid page %q", value)
+		}
+		params.page = page
+	}
+	if value := query.Get("per_page"); len(value) > 0 {
+		perPage, err := strconv.Atoi(value)
+		if err != nil || perPage < 1 || perPage > maxListingPageSize {
+			return nil, fmt.Errorf("invalid per_page %q, must be between 1 and %d", value, maxListingPageSize)
+		}
+		params.perPage = perPage
+		if params.page == 0 {
+			params.page = 1
+		}
+	} else if params.page > 0 {
+		params.perPage = defaultListingPageSize
+	}
+	return params, nil
+}
+
+// renderListing writes the elements of a listing, a map indexed by name, applying the listing parameters of the request.
+// Paginated listings report the total number of matching elements in the X-Total-Count header, and the next page
+// in the X-Next-Page header when there is one. Without parameters, the listing is written as is.
+func renderListing(response http.ResponseWriter, request *http.Request, listing interface{}) {
+	params, err := parseListingParams(request.URL.Query())
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(params.search) == 0 && len(params.fields) == 0 && params.page == 0 {
+		templatesRenderer.JSON(response, http.StatusOK, listing)
+		return
+	}
+
+	elements := reflect.ValueOf(listing)
+	var names []string
+	for _, key := range elements.MapKeys() {
+		name := key.String()
+		if strings.Contains(strings.ToLower(name), params.search) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	if params.page > 0 {
+		response.Header().Set("X-Total-Count", strconv.Itoa(len(names)))
+		start := (params.page - 1) * params.perPage
+		end := start + params.perPage
+		if start > len(names) {
+			start = len(names)
+		}
+		if end < len(names) {
+			response.Header().Set("X-Next-Page", strconv.Itoa(params.page+1))
+		} else {
+			end = len(names)
+		}
+		names = names[start:end]
+	}
+
+	page := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		element := elements.MapIndex(reflect.ValueOf(name)).Interface()
+		if len(params.fields) > 0 {
+			element, err = selectFields(element, params.fields)
+			if err != nil {
+				http.Error(response, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		page[name] = element
+	}
+	templatesRenderer.JSON(response, http.StatusOK, page)
+}
+
+// selectFields returns the given fields of the JSON representation of an element
+func selectFields(element interface{}, fields []string) (map[string]interface{}, error) {
+	data, err := json.Marshal(element)
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]interface{})
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	selected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected, nil
+}
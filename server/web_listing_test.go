@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderListing(t *testing.T) {
+	frontends := map[string]*types.Frontend{
+		"frontend-api":    {Backend: "backend-api", Priority: 10},
+		"frontend-web":    {Backend: "backend-web"},
+		"frontend-admin":  {Backend: "backend-admin"},
+		"frontend-static": {Backend: "backend-web"},
+	}
+
+	cases := []struct {
+		desc          string
+		query         string
+		expectedCode  int
+		expectedNames []string
+		expectedTotal string
+		expectedNext  string
+	}{
+		{
+			desc:          "no parameters",
+			expectedCode:  http.StatusOK,
+			expectedNames: []string{"frontend-admin", "frontend-api", "frontend-static", "frontend-web"},
+		},
+		{
+			desc:          "search",
+			query:         "search=A",
+			expectedCode:  http.StatusOK,
+			expectedNames: []string{"frontend-admin", "frontend-api", "frontend-static"},
+		},
+		{
+			desc:          "first page",
+			query:         "page=1&per_page=3",
+			expectedCode:  http.StatusOK,
+			expectedNames: []string{"frontend-admin", "frontend-api", "frontend-static"},
+			expectedTotal: "4",
+			expectedNext:  "2",
+		},
+		{
+			desc:          "last page",
+			query:         "page=2&per_page=3",
+			expectedCode:  http.StatusOK,
+			expectedNames: []string{"frontend-web"},
+			expectedTotal: "4",
+		},
+		{
+			desc:          "page past the end",
+			query:         "page=3&per_page=3",
+			expectedCode:  http.StatusOK,
+			expectedNames: []string{},
+			expectedTotal: "4",
+		},
+		{
+			desc:          "search and pagination",
+			query:         "search=web&per_page=1",
+			expectedCode:  http.StatusOK,
+			expectedNames: []string{"frontend-web"},
+			expectedTotal: "1",
+		},
+		{
+			desc:         "invalid page",
+			query:        "page=0",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "invalid page size",
+			query:        "per_page=5000",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			recorder := httptest.NewRecorder()
+			renderListing(recorder, testhelpers.MustNewRequest(http.MethodGet, "/api/providers/file/frontends?"+test.query, nil), frontends)
+
+			require.Equal(t, test.expectedCode, recorder.Code)
+			if test.expectedCode != http.StatusOK {
+				return
+			}
+			listing := make(map[string]json.RawMessage)
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &listing))
+			var names []string
+			for name := range listing {
+				names = append(names, name)
+			}
+			assert.Len(t, names, len(test.expectedNames))
+			for _, name := range test.expectedNames {
+				assert.Contains(t, listing, name)
+			}
+			assert.Equal(t, test.expectedTotal, recorder.Header().Get("X-Total-Count"))
+			assert.Equal(t, test.expectedNext, recorder.Header().Get("X-Next-Page"))
+		})
+	}
+}
+
+func TestRenderListingFields(t *testing.T) {
+	frontends := map[string]*types.Frontend{
+		"frontend-api": {Backend: "backend-api", Priority: 10, PassHostHeader: true},
+	}
+
+	recorder := httptest.NewRecorder()
+	renderListing(recorder, testhelpers.MustNewRequest(http.MethodGet, "/api/providers/file/frontends?fields=backend,priority,unknown", nil), frontends)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"frontend-api": {"backend": "backend-api", "priority": 10}}`, recorder.Body.String())
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/containous/traefik/types"
+)
+
+// viewTokenCookie holds the view token of the dashboard, given once with the token query parameter
+const viewTokenCookie = "traefik_view_token"
+
+// ViewToken is a read-only token of the dashboard and the API restricted to the frontends of some
+// providers, e.g. the frontends of a team. The frontends are selected by their names, the labels of the
+// containers and applications not being kept in the configurations built by the providers.
+type ViewToken struct {
+	Token     string   `description:"Token, given as a bearer token or with the token query parameter"`
+	Providers []string `description:"Providers whose frontends are visible, all when empty"`
+	Frontends []string `description:"Patterns of the names of the visible frontends, e.g. frontend-team-a-*, all when empty"`
+}
+
+// visible returns whether the frontend of the provider is visible with the token
+func (v *ViewToken) visible(providerName, frontendName string) bool {
+	if len(v.Providers) > 0 && !containsString(v.Providers, providerName) {
+		return false
+	}
+	if len(v.Frontends) == 0 {
+		return true
+	}
+	for _, pattern := range v.Frontends {
+		if wildcardMatch(pattern, frontendName) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardMatch returns whether the name matches the pattern, where * matches any sequence of characters,
+// including the slashes of the frontend names of the Kubernetes ingresses, and ? any single character
+func wildcardMatch(pattern, name string) bool {
+	// backtrack to the last star when a character does not match
+	p, n, starP, starN := 0, 0, -1, 0
+	for n < len(name) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == name[n]):
+			p++
+			n++
+		case p < len(pattern) && pattern[p] == '*':
+			starP, starN = p, n
+			p++
+		case starP >= 0:
+			starN++
+			p, n = starP+1, starN
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the visible frontends of the configurations, and their backends
+func (v *ViewToken) filter(configurations configs) configs {
+	filtered := make(configs)
+	for providerName, configuration := range configurations {
+		if configuration == nil || (len(v.Providers) > 0 && !containsString(v.Providers, providerName)) {
+			continue
+		}
+		visible := &types.Configuration{
+			Frontends: make(map[string]*types.Frontend),
+			Backends:  make(map[string]*types.Backend),
+		}
+		for frontendName, frontend := range configuration.Frontends {
+			if frontend == nil || !v.visible(providerName, frontendName) {
+				continue
+			}
+			visible.Frontends[frontendName] = frontend
+			for _, backendName := range frontendBackends(frontend) {
+				if backend, ok := configuration.Backends[backendName]; ok {
+					visible.Backends[backendName] = backend
+				}
+			}
+		}
+		filtered[providerName] = visible
+	}
+	return filtered
+}
+
+// frontendBackends returns the backends a frontend forwards to
+func frontendBackends(frontend *types.Frontend) []string {
+	backends := []string{frontend.Backend}
+	if frontend.Cutover != nil && frontend.Cutover.Backend != "" {
+		backends = append(backends, frontend.Cutover.Backend)
+	}
+	return backends
+}
+
+type viewTokenKey struct{}
+
+// viewTokens is a negroni middleware serving the requests with a view token without authentication, restricted
+// to reading the configurations and the dashboard. The other requests, including the ones with an unknown token,
+// go through the authentication of the API.
+type viewTokens struct {
+	tokens  []ViewToken
+	path    string
+	handler http.Handler
+}
+
+func newViewTokens(tokens []ViewToken, path string, handler http.Handler) *viewTokens {
+	return &viewTokens{tokens: tokens, path: path, handler: handler}
+}
+
+func (v *viewTokens) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	token, fromQuery := viewTokenFromRequest(r)
+	if token == "" {
+		next(rw, r)
+		return
+	}
+	view := v.lookup(token)
+	if view == nil {
+		// e.g. a bearer token of the authentication of the API
+		next(rw, r)
+		return
+	}
+	if !v.allowed(r) {
+		http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	if fromQuery {
+		// the token is exchanged for the cookie, keeping it out of the logs and the history of the following requests
+		http.SetCookie(rw, &http.Cookie{Name: viewTokenCookie, Value: token, Path: v.path, HttpOnly: true, Secure: r.TLS != nil})
+		query := r.URL.Query()
+		query.Del("token")
+		location := *r.URL
+		location.RawQuery = query.Encode()
+		rw.Header().Set("Referrer-Policy", "no-referrer")
+		http.Redirect(rw, r, location.RequestURI(), http.StatusSeeOther)
+		return
+	}
+	v.handler.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), viewTokenKey{}, view)))
+}
+
+// lookup returns the view of the token, nil if unknown
+func (v *viewTokens) lookup(token string) *ViewToken {
+	var view *ViewToken
+	for i := range v.tokens {
+		if subtle.ConstantTimeCompare([]byte(v.tokens[i].Token), []byte(token)) == 1 {
+			view = &v.tokens[i]
+		}
+	}
+	return view
+}
+
+// allowed returns whether the request reads the configurations, the routing graph, the version or the dashboard
+func (v *viewTokens) allowed(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	p := r.URL.Path
+	return p == v.path || p == v.path+"api" || p == v.path+"api/version" || p == v.path+"api/providers" ||
+		p == v.path+"api/graph" || strings.HasPrefix(p, v.path+"api/providers/") || strings.HasPrefix(p, v.path+"dashboard/")
+}
+
+// viewTokenFromRequest returns the view token of the request, and whether it was given with the query parameter
+func viewTokenFromRequest(r *http.Request) (string, bool) {
+	if authorization := r.Header.Get("Authorization"); strings.HasPrefix(authorization, "Bearer ") {
+		return strings.TrimPrefix(authorization, "Bearer "), false
+	}
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token, true
+	}
+	if cookie, err := r.Cookie(viewTokenCookie); err == nil {
+		return cookie.Value, false
+	}
+	return "", false
+}
+
+// currentConfigurations returns the current configurations visible with the view token of the request, if any
+func (provider *WebProvider) currentConfigurations(request *http.Request) configs {
+	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
+	if view, ok := request.Context().Value(viewTokenKey{}).(*ViewToken); ok {
+		return view.filter(currentConfigurations)
+	}
+	return currentConfigurations
+}
@@ -0,0 +1,285 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/mux"
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newViewConfigs() configs {
+	return configs{
+		"docker": {
+			Frontends: map[string]*types.Frontend{
+				"frontend-team-a-web": {Backend: "backend-team-a-web"},
+				"frontend-team-a-api": {Backend: "backend-shared", Cutover: &types.Cutover{Backend: "backend-team-a-api"}},
+				"frontend-team-b-web": {Backend: "backend-team-b-web"},
+			},
+			Backends: map[string]*types.Backend{
+				"backend-team-a-web": {},
+				"backend-team-a-api": {},
+				"backend-team-b-web": {},
+				"backend-shared":     {},
+			},
+		},
+		"file": {
+			Frontends: map[string]*types.Frontend{
+				"frontend-team-a-admin": {Backend: "backend-admin"},
+			},
+			Backends: map[string]*types.Backend{
+				"backend-admin": {},
+			},
+		},
+		"kubernetes": {
+			Frontends: map[string]*types.Frontend{
+				"team-a.example.com/api": {Backend: "team-a.example.com/api"},
+			},
+			Backends: map[string]*types.Backend{
+				"team-a.example.com/api": {},
+			},
+		},
+	}
+}
+
+func configNames(configurations configs) map[string][]string {
+	names := make(map[string][]string)
+	for providerName, configuration := range configurations {
+		names[providerName] = []string{}
+		for frontendName := range configuration.Frontends {
+			names[providerName] = append(names[providerName], frontendName)
+		}
+		for backendName := range configuration.Backends {
+			names[providerName] = append(names[providerName], backendName)
+		}
+		sort.Strings(names[providerName])
+	}
+	return names
+}
+
+func TestViewTokenFilter(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		view     ViewToken
+		expected map[string][]string
+	}{
+		{
+			desc: "frontend patterns",
+			view: ViewToken{Frontends: []string{"frontend-team-a-*"}},
+			expected: map[string][]string{
+				"docker":     {"frontend-team-a-web", "frontend-team-a-api", "backend-team-a-web", "backend-shared", "backend-team-a-api"},
+				"file":       {"frontend-team-a-admin", "backend-admin"},
+				"kubernetes": {},
+			},
+		},
+		{
+			desc: "frontend patterns with slashes",
+			view: ViewToken{Frontends: []string{"team-a.*"}},
+			expected: map[string][]string{
+				"docker":     {},
+				"file":       {},
+				"kubernetes": {"team-a.example.com/api", "team-a.example.com/api"},
+			},
+		},
+		{
+			desc: "providers and frontend patterns",
+			view: ViewToken{Providers: []string{"docker"}, Frontends: []string{"frontend-team-b-*", "frontend-team-a-web"}},
+			expected: map[string][]string{
+				"docker": {"frontend-team-b-web", "frontend-team-a-web", "backend-team-b-web", "backend-team-a-web"},
+			},
+		},
+		{
+			desc: "providers",
+			view: ViewToken{Providers: []string{"file"}},
+			expected: map[string][]string{
+				"file": {"frontend-team-a-admin", "backend-admin"},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			actual := configNames(test.view.filter(newViewConfigs()))
+			require.Len(t, actual, len(test.expected))
+			for providerName, names := range test.expected {
+				sort.Strings(names)
+				assert.Equal(t, names, actual[providerName], providerName)
+			}
+		})
+	}
+}
+
+func TestWildcardMatch(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{pattern: "frontend-team-a-*", name: "frontend-team-a-web", expected: true},
+		{pattern: "frontend-team-a-*", name: "frontend-team-b-web", expected: false},
+		{pattern: "*.example.com/*", name: "team-a.example.com/api/v1", expected: true},
+		{pattern: "*/api", name: "team-a.example.com/api/v1", expected: false},
+		{pattern: "frontend-?", name: "frontend-1", expected: true},
+		{pattern: "frontend-?", name: "frontend-12", expected: false},
+		{pattern: "*a*b", name: "xaybzb", expected: true},
+		{pattern: "*", name: "", expected: true},
+		{pattern: "", name: "frontend", expected: false},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expected, wildcardMatch(test.pattern, test.name), "%s %s", test.pattern, test.name)
+	}
+}
+
+func TestViewTokens(t *testing.T) {
+	server := &Server{}
+	server.currentConfigurations.Set(newViewConfigs())
+	provider := &WebProvider{Path: "/", server: server}
+	router := mux.NewRouter()
+	router.Methods("GET").Path("/api/providers").HandlerFunc(provider.getConfigHandler)
+	router.Methods("GET").Path("/api/providers/{provider}/frontends/{frontend}").HandlerFunc(provider.getFrontendHandler)
+	router.Methods("GET").Path("/api/graph").HandlerFunc(provider.getGraphHandler)
+	router.Methods("GET").Path("/health").HandlerFunc(provider.getPingHandler)
+
+	n := negroni.New(newViewTokens([]ViewToken{{Token: "team-a", Frontends: []string{"frontend-team-a-*"}}}, "/", router))
+	n.UseFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		// stands for the authentication of the API
+		if r.Header.Get("Authorization") != "Basic admin" {
+			http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next(rw, r)
+	})
+	n.UseHandler(router)
+
+	testCases := []struct {
+		desc             string
+		method           string
+		url              string
+		authorization    string
+		cookie           string
+		expectedCode     int
+		expectedCookie   bool
+		expectedLocation string
+		expected         map[string][]string
+		hidden           string
+	}{
+		{
+			desc:          "bearer token",
+			method:        http.MethodGet,
+			url:           "http://localhost/api/providers",
+			authorization: "Bearer team-a",
+			expectedCode:  http.StatusOK,
+			expected: map[string][]string{
+				"docker":     {"frontend-team-a-web", "frontend-team-a-api", "backend-team-a-web", "backend-shared", "backend-team-a-api"},
+				"file":       {"frontend-team-a-admin", "backend-admin"},
+				"kubernetes": {},
+			},
+		},
+		{
+			desc:             "query parameter",
+			method:           http.MethodGet,
+			url:              "http://localhost/dashboard/?token=team-a&tab=health",
+			expectedCode:     http.StatusSeeOther,
+			expectedCookie:   true,
+			expectedLocation: "/dashboard/?tab=health",
+		},
+		{
+			desc:          "routing graph",
+			method:        http.MethodGet,
+			url:           "http://localhost/api/graph",
+			authorization: "Bearer team-a",
+			expectedCode:  http.StatusOK,
+			hidden:        "frontend-team-b-web",
+		},
+		{
+			desc:         "cookie",
+			method:       http.MethodGet,
+			url:          "http://localhost/api/providers/docker/frontends/frontend-team-a-web",
+			cookie:       "team-a",
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:          "hidden frontend",
+			method:        http.MethodGet,
+			url:           "http://localhost/api/providers/docker/frontends/frontend-team-b-web",
+			authorization: "Bearer team-a",
+			expectedCode:  http.StatusNotFound,
+		},
+		{
+			desc:          "other route",
+			method:        http.MethodGet,
+			url:           "http://localhost/health",
+			authorization: "Bearer team-a",
+			expectedCode:  http.StatusForbidden,
+		},
+		{
+			desc:          "update",
+			method:        http.MethodPut,
+			url:           "http://localhost/api/providers/web",
+			authorization: "Bearer team-a",
+			expectedCode:  http.StatusForbidden,
+		},
+		{
+			desc:          "unknown token",
+			method:        http.MethodGet,
+			url:           "http://localhost/api/providers",
+			authorization: "Bearer team-c",
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			desc:          "authenticated",
+			method:        http.MethodGet,
+			url:           "http://localhost/api/providers",
+			authorization: "Basic admin",
+			expectedCode:  http.StatusOK,
+			expected: map[string][]string{
+				"docker":     {"frontend-team-a-web", "frontend-team-a-api", "frontend-team-b-web", "backend-team-a-web", "backend-team-a-api", "backend-team-b-web", "backend-shared"},
+				"file":       {"frontend-team-a-admin", "backend-admin"},
+				"kubernetes": {"team-a.example.com/api", "team-a.example.com/api"},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			req := testhelpers.MustNewRequest(test.method, test.url, nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			if test.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: viewTokenCookie, Value: test.cookie})
+			}
+			recorder := httptest.NewRecorder()
+			n.ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedCode, recorder.Code)
+			assert.Equal(t, test.expectedCookie, len(recorder.HeaderMap["Set-Cookie"]) > 0)
+			assert.Equal(t, test.expectedLocation, recorder.Header().Get("Location"))
+			if test.hidden != "" {
+				assert.NotContains(t, recorder.Body.String(), test.hidden)
+			}
+			if test.expected != nil {
+				actual := make(configs)
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &actual))
+				names := configNames(actual)
+				require.Len(t, names, len(test.expected))
+				for providerName, expected := range test.expected {
+					sort.Strings(expected)
+					assert.Equal(t, expected, names[providerName], providerName)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientTLSCreateTLSConfigWithoutCertificate(t *testing.T) {
+	clientTLS := &ClientTLS{InsecureSkipVerify: true}
+
+	config, err := clientTLS.CreateTLSConfig()
+	require.NoError(t, err)
+	assert.Empty(t, config.Certificates)
+	assert.True(t, config.InsecureSkipVerify)
+}
+
+func TestClientTLSCreateTLSConfigMissingKey(t *testing.T) {
+	clientTLS := &ClientTLS{Cert: "invalid"}
+
+	_, err := clientTLS.CreateTLSConfig()
+	assert.Error(t, err)
+}
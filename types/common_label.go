@@ -17,12 +17,60 @@ const (
 	LabelWeight = "traefik.weight"
 	// LabelFrontendAuthBasic Traefik label
 	LabelFrontendAuthBasic = "traefik.frontend.auth.basic"
+	// LabelFrontendAuthBasicUsersSecret Traefik label
+	LabelFrontendAuthBasicUsersSecret = "traefik.frontend.auth.basic.usersSecret"
+	// LabelFrontendAllowedUpgrades Traefik label
+	LabelFrontendAllowedUpgrades = "traefik.frontend.allowedUpgrades"
+	// LabelFrontendAuthBypassPaths Traefik label
+	LabelFrontendAuthBypassPaths = "traefik.frontend.auth.bypass.paths"
+	// LabelFrontendAuthBypassMethods Traefik label
+	LabelFrontendAuthBypassMethods = "traefik.frontend.auth.bypass.methods"
+	// LabelFrontendGroup Traefik label
+	LabelFrontendGroup = "traefik.frontend.group"
+	// LabelFrontendAccessLogDisabled Traefik label
+	LabelFrontendAccessLogDisabled = "traefik.frontend.accessLog.disabled"
+	// LabelFrontendAccessLogRedactQueryParams Traefik label
+	LabelFrontendAccessLogRedactQueryParams = "traefik.frontend.accessLog.redactQueryParams"
+	// LabelFrontendAccessLogRedactHeaders Traefik label
+	LabelFrontendAccessLogRedactHeaders = "traefik.frontend.accessLog.redactHeaders"
+	// LabelFrontendPathParamsNames Traefik label
+	LabelFrontendPathParamsNames = "traefik.frontend.pathParams.names"
+	// LabelFrontendPathParamsHeaderPrefix Traefik label
+	LabelFrontendPathParamsHeaderPrefix = "traefik.frontend.pathParams.headerPrefix"
+	// LabelFrontendFaultsDelay Traefik label
+	LabelFrontendFaultsDelay = "traefik.frontend.faults.delay"
+	// LabelFrontendFaultsDelayPercentage Traefik label
+	LabelFrontendFaultsDelayPercentage = "traefik.frontend.faults.delayPercentage"
+	// LabelFrontendFaultsErrorStatus Traefik label
+	LabelFrontendFaultsErrorStatus = "traefik.frontend.faults.errorStatus"
+	// LabelFrontendFaultsErrorPercentage Traefik label
+	LabelFrontendFaultsErrorPercentage = "traefik.frontend.faults.errorPercentage"
+	// LabelFrontendFaultsAbortPercentage Traefik label
+	LabelFrontendFaultsAbortPercentage = "traefik.frontend.faults.abortPercentage"
+	// LabelFrontendAuthForwardAddress Traefik label
+	LabelFrontendAuthForwardAddress = "traefik.frontend.auth.forward.address"
+	// LabelFrontendAuthForwardTrustForwardHeader Traefik label
+	LabelFrontendAuthForwardTrustForwardHeader = "traefik.frontend.auth.forward.trustForwardHeader"
+	// LabelFrontendAuthForwardTimeout Traefik label
+	LabelFrontendAuthForwardTimeout = "traefik.frontend.auth.forward.timeout"
+	// LabelFrontendAuthForwardTLSCA Traefik label
+	LabelFrontendAuthForwardTLSCA = "traefik.frontend.auth.forward.tls.ca"
+	// LabelFrontendAuthForwardTLSCert Traefik label
+	LabelFrontendAuthForwardTLSCert = "traefik.frontend.auth.forward.tls.cert"
+	// LabelFrontendAuthForwardTLSKey Traefik label
+	LabelFrontendAuthForwardTLSKey = "traefik.frontend.auth.forward.tls.key"
+	// LabelFrontendAuthForwardTLSInsecureSkipVerify Traefik label
+	LabelFrontendAuthForwardTLSInsecureSkipVerify = "traefik.frontend.auth.forward.tls.insecureSkipVerify"
 	// LabelFrontendEntryPoints Traefik label
 	LabelFrontendEntryPoints = "traefik.frontend.entryPoints"
 	// LabelFrontendPassHostHeader Traefik label
 	LabelFrontendPassHostHeader = "traefik.frontend.passHostHeader"
 	// LabelFrontendPriority Traefik label
 	LabelFrontendPriority = "traefik.frontend.priority"
+	// LabelFrontendProfile Traefik label
+	LabelFrontendProfile = "traefik.frontend.profile"
+	// LabelFrontendTimeout Traefik label
+	LabelFrontendTimeout = "traefik.frontend.timeout"
 	// LabelFrontendRule Traefik label
 	LabelFrontendRule = "traefik.frontend.rule"
 	// LabelFrontendRuleType Traefik label
@@ -43,12 +91,26 @@ const (
 	LabelBackendHealthcheckPath = "traefik.backend.healthcheck.path"
 	// LabelBackendHealthcheckInterval Traefik label
 	LabelBackendHealthcheckInterval = "traefik.backend.healthcheck.interval"
+	// LabelBackendDrainDuration Traefik label
+	LabelBackendDrainDuration = "traefik.backend.drain.duration"
+	// LabelBackendFailoverApp Traefik label
+	LabelBackendFailoverApp = "traefik.backend.failover.app"
 	// LabelBackendLoadbalancerMethod Traefik label
 	LabelBackendLoadbalancerMethod = "traefik.backend.loadbalancer.method"
 	// LabelBackendLoadbalancerSticky Traefik label
 	LabelBackendLoadbalancerSticky = "traefik.backend.loadbalancer.sticky"
+	// LabelBackendServerIdentity Traefik label
+	LabelBackendServerIdentity = "traefik.backend.serverIdentity"
+	// LabelBackendZone Traefik label
+	LabelBackendZone = "traefik.backend.zone"
+	// LabelBackendPreferLocalZone Traefik label
+	LabelBackendPreferLocalZone = "traefik.backend.preferLocalZone"
+	// LabelBackendServersTransport Traefik label
+	LabelBackendServersTransport = "traefik.backend.serversTransport"
 	// LabelBackendMaxconnAmount Traefik label
 	LabelBackendMaxconnAmount = "traefik.backend.maxconn.amount"
+	// LabelBackendMaxconnPerTask Traefik label
+	LabelBackendMaxconnPerTask = "traefik.backend.maxconn.perTask"
 	// LabelBackendMaxconnExtractorfunc Traefik label
 	LabelBackendMaxconnExtractorfunc = "traefik.backend.maxconn.extractorfunc"
 )
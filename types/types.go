@@ -13,11 +13,40 @@ import (
 
 // Backend holds backend configuration.
 type Backend struct {
-	Servers        map[string]Server `json:"servers,omitempty"`
-	CircuitBreaker *CircuitBreaker   `json:"circuitBreaker,omitempty"`
-	LoadBalancer   *LoadBalancer     `json:"loadBalancer,omitempty"`
-	MaxConn        *MaxConn          `json:"maxConn,omitempty"`
-	HealthCheck    *HealthCheck      `json:"healthCheck,omitempty"`
+	Servers          map[string]Server `json:"servers,omitempty"`
+	CircuitBreaker   *CircuitBreaker   `json:"circuitBreaker,omitempty"`
+	LoadBalancer     *LoadBalancer     `json:"loadBalancer,omitempty"`
+	MaxConn          *MaxConn          `json:"maxConn,omitempty"`
+	HealthCheck      *HealthCheck      `json:"healthCheck,omitempty"`
+	ServersTransport string            `json:"serversTransport,omitempty"`
+	Drain            *Drain            `json:"drain,omitempty"`
+	PriorityQueue    *PriorityQueue    `json:"priorityQueue,omitempty"`
+	GRPCTranscoding  *GRPCTranscoding  `json:"grpcTranscoding,omitempty"`
+}
+
+// GRPCTranscoding holds the transcoding of JSON requests to the gRPC methods of a backend, described by
+// the binary FileDescriptorSet DescriptorSet, as written by protoc --include_imports --descriptor_set_out.
+// Only the methods of Services, given by their fully qualified names, are transcoded when set.
+type GRPCTranscoding struct {
+	DescriptorSet string   `json:"descriptorSet,omitempty"`
+	Services      []string `json:"services,omitempty"`
+}
+
+// PriorityQueue holds the queueing of the requests of a backend beyond MaxConcurrency requests in flight.
+// The queued requests are forwarded as soon as a request completes, the high priority ones first:
+// requests are high priority when their frontend sets requestPriority to "high", or when they carry
+// the Header with the "high" value and their frontend sets requestPriority to "header".
+// Requests are rejected with a 503 when MaxQueued requests are already queued, or after waiting for Timeout.
+type PriorityQueue struct {
+	MaxConcurrency int64  `json:"maxConcurrency,omitempty"`
+	MaxQueued      int    `json:"maxQueued,omitempty"`
+	Timeout        string `json:"timeout,omitempty"`
+	Header         string `json:"header,omitempty"`
+}
+
+// Drain holds the configuration of the servers draining themselves, by answering with the X-Traefik-Drain: true header
+type Drain struct {
+	Duration string `json:"duration,omitempty"`
 }
 
 // MaxConn holds maximum connection configuration
@@ -47,6 +76,7 @@ type HealthCheck struct {
 type Server struct {
 	URL    string `json:"url,omitempty"`
 	Weight int    `json:"weight"`
+	Zone   string `json:"zone,omitempty"`
 }
 
 // Route holds route configuration.
@@ -122,9 +152,66 @@ type Frontend struct {
 	PassTLSCert          bool                 `json:"passTLSCert,omitempty"`
 	Priority             int                  `json:"priority"`
 	BasicAuth            []string             `json:"basicAuth"`
+	Auth                 *Auth                `json:"auth,omitempty"`
+	AuthBypass           *AuthBypass          `json:"authBypass,omitempty"`
 	WhitelistSourceRange []string             `json:"whitelistSourceRange,omitempty"`
+	AllowedUpgrades      []string             `json:"allowedUpgrades,omitempty"`
+	RateLimit            *RateLimit           `json:"rateLimit,omitempty"`
+	AccessLog            *FrontendAccessLog   `json:"accessLog,omitempty"`
+	PathParams           *PathParams          `json:"pathParams,omitempty"`
+	Faults               *Faults              `json:"faults,omitempty"`
+	Timeout              string               `json:"timeout,omitempty"`
+	Profile              string               `json:"profile,omitempty"`
 	Headers              Headers              `json:"headers,omitempty"`
 	Errors               map[string]ErrorPage `json:"errors,omitempty"`
+	Cutover              *Cutover             `json:"cutover,omitempty"`
+	RequestPriority      string               `json:"requestPriority,omitempty"`
+	IdleTimeout          string               `json:"idleTimeout,omitempty"`
+}
+
+// Cutover switches the frontend to another backend at a planned time, given in RFC 3339 format
+type Cutover struct {
+	Backend string `json:"backend,omitempty"`
+	At      string `json:"at,omitempty"`
+}
+
+// AuthBypass holds the requests excluded from the frontend authentication
+type AuthBypass struct {
+	Paths   []string `json:"paths,omitempty"`
+	Methods []string `json:"methods,omitempty"`
+}
+
+// RateLimit holds token bucket rate limiting configuration
+type RateLimit struct {
+	Average       int64  `json:"average,omitempty"`
+	Burst         int64  `json:"burst,omitempty"`
+	ExtractorFunc string `json:"extractorFunc,omitempty"`
+}
+
+// FrontendAccessLog holds the access log options of a frontend: its requests can be left out
+// of the access log, or have the values of some query parameters and headers redacted
+type FrontendAccessLog struct {
+	Disabled          bool     `json:"disabled,omitempty"`
+	RedactQueryParams []string `json:"redactQueryParams,omitempty"`
+	RedactHeaders     []string `json:"redactHeaders,omitempty"`
+}
+
+// PathParams holds the forwarding of the named segments matched by the frontend rules,
+// e.g. {id} in Path:/tenants/{id}, to the backend as request headers.
+// All the segments are forwarded when no name is given.
+type PathParams struct {
+	Names        []string `json:"names,omitempty"`
+	HeaderPrefix string   `json:"headerPrefix,omitempty"`
+}
+
+// Faults holds the faults injected into a percentage of the requests of a frontend, for resilience testing:
+// a delay before forwarding the request, an error response, or an aborted connection.
+type Faults struct {
+	Delay           string  `json:"delay,omitempty"`
+	DelayPercentage float64 `json:"delayPercentage,omitempty"`
+	ErrorStatus     int     `json:"errorStatus,omitempty"`
+	ErrorPercentage float64 `json:"errorPercentage,omitempty"`
+	AbortPercentage float64 `json:"abortPercentage,omitempty"`
 }
 
 // LoadBalancerMethod holds the method of load balancing to use.
@@ -135,11 +222,14 @@ const (
 	Wrr LoadBalancerMethod = iota
 	// Drr = Dynamic Round Robin
 	Drr
+	// Hash = Consistent hashing of the client IP on the server names
+	Hash
 )
 
 var loadBalancerMethodNames = []string{
 	"Wrr",
 	"Drr",
+	"Hash",
 }
 
 // NewLoadBalancerMethod create a new LoadBalancerMethod from a given LoadBalancer.
@@ -166,6 +256,10 @@ type Configuration struct {
 type ConfigMessage struct {
 	ProviderName  string
 	Configuration *Configuration
+	// Causes describe the provider events the configuration was built after, if known
+	Causes []string
+	// ServerReasons describe why servers were added or removed, by server name, if known
+	ServerReasons map[string]string
 }
 
 // Constraint hold a parsed constraint expresssion
@@ -292,11 +386,13 @@ type Cluster struct {
 	Store *Store
 }
 
-// Auth holds authentication configuration (BASIC, DIGEST, users)
+// Auth holds authentication configuration (BASIC, DIGEST, FORWARD, users)
 type Auth struct {
-	Basic       *Basic
-	Digest      *Digest
-	HeaderField string
+	Basic         *Basic         `json:"basic,omitempty"`
+	Digest        *Digest        `json:"digest,omitempty"`
+	Forward       *Forward       `json:"forward,omitempty"`
+	HeaderField   string         `json:"headerField,omitempty"`
+	Authorization *Authorization `json:"authorization,omitempty"`
 }
 
 // Users authentication users
@@ -314,6 +410,36 @@ type Digest struct {
 	UsersFile string
 }
 
+// Forward authentication
+// The request is forwarded to an authentication server, and let through only
+// when the server answers with a 2XX status code
+type Forward struct {
+	Address            string     `description:"Authentication server address" json:"address,omitempty"`
+	TLS                *ClientTLS `description:"Enable TLS support" json:"tls,omitempty"`
+	TrustForwardHeader bool       `description:"Trust X-Forwarded-* headers" json:"trustForwardHeader,omitempty"`
+	Timeout            string     `description:"Timeout of the requests to the authentication server" json:"timeout,omitempty"`
+}
+
+// Authorization decides whether the authenticated requests are allowed, from their principal, method and path.
+// The requests matching one of the rules are allowed, the others are allowed only when the policy server
+// at Address answers with a 2XX status code; without Address, they are denied.
+// The decisions of the policy server are cached by principal and route for CacheTTL.
+type Authorization struct {
+	Address  string              `description:"Authorization policy server address" json:"address,omitempty"`
+	TLS      *ClientTLS          `description:"Enable TLS support" json:"tls,omitempty"`
+	Timeout  string              `description:"Timeout of the requests to the policy server" json:"timeout,omitempty"`
+	Rules    []AuthorizationRule `description:"Requests allowed without asking the policy server" json:"rules,omitempty"`
+	CacheTTL string              `description:"Duration of the cached decisions of the policy server" json:"cacheTTL,omitempty"`
+}
+
+// AuthorizationRule allows the requests of the principals using one of the methods on one of the paths.
+// Paths support glob patterns, and an empty list matches everything.
+type AuthorizationRule struct {
+	Principals []string `json:"principals,omitempty"`
+	Methods    []string `json:"methods,omitempty"`
+	Paths      []string `json:"paths,omitempty"`
+}
+
 // CanonicalDomain returns a lower case domain with trim space
 func CanonicalDomain(domain string) string {
 	return strings.ToLower(strings.TrimSpace(domain))
@@ -371,3 +497,35 @@ type AccessLog struct {
 	FilePath string `json:"file,omitempty" description:"Access log file path. Stdout is used when omitted or empty"`
 	Format   string `json:"format,omitempty" description:"Access log format: json | common"`
 }
+
+// ClientIP holds the strategy determining the IP address of the clients, shared by the IP whitelists,
+// the rate limits, the maximum connections and the access log. The address of the peer is used when
+// neither the depth nor the trusted proxies are set.
+type ClientIP struct {
+	Depth          int            `description:"Use the IP address at this position from the right of the X-Forwarded-For header, 1 being the rightmost"`
+	TrustedProxies TrustedProxies `description:"Use the rightmost IP address of the peer and the X-Forwarded-For header which is not in these CIDRs"`
+}
+
+// TrustedProxies holds the CIDRs of the proxies trusted to forward the client IP
+type TrustedProxies []string
+
+//Set adds strings elem into the the parser
+//it splits str on "," and ";"
+func (t *TrustedProxies) Set(str string) error {
+	fargs := func(c rune) bool {
+		return c == ',' || c == ';'
+	}
+	*t = append(*t, strings.FieldsFunc(str, fargs)...)
+	return nil
+}
+
+//Get []string
+func (t *TrustedProxies) Get() interface{} { return TrustedProxies(*t) }
+
+//String return slice in a string
+func (t *TrustedProxies) String() string { return fmt.Sprintf("%v", *t) }
+
+//SetValue sets []string into the parser
+func (t *TrustedProxies) SetValue(val interface{}) {
+	*t = TrustedProxies(val.(TrustedProxies))
+}